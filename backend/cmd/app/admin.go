@@ -0,0 +1,306 @@
+package main
+
+import (
+	"Typecode-Registry/internal/data"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const adminUsage = `Usage: myserver admin [flags] <command> [arguments]
+
+Commands:
+  migrate           Apply all pending schema migrations (-status lists them instead)
+  import-reserved   Register typecodes reserved by the platform from a CSV file
+  export            Write all projects, extensions and items as JSON
+  check             Check the database for inconsistent typecodes and extensions
+
+Flags:
+`
+
+// adminCommand executes an administrative task and writes its result to out.
+type adminCommand func(app *application, args []string, out io.Writer) error
+
+// adminCommands maps the names of the admin subcommands to their implementation.
+var adminCommands = map[string]adminCommand{
+	"migrate":         adminMigrate,
+	"import-reserved": adminImportReserved,
+	"export":          adminExport,
+	"check":           adminCheck,
+}
+
+// errIntegrity is returned by the check command if problems were found.
+var errIntegrity = errors.New("database integrity check failed")
+
+// runAdmin is the entry point of the admin subcommand of the server binary.
+// It returns the exit code of the process: 0 on success, 1 if the task failed and 2 on invalid usage.
+func runAdmin(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("admin", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dsn := fs.String("db-dns", os.Getenv("TYPECODEREGISTRY_DB_DSN"), "PostgreSQL DSN")
+	loglevel := fs.String("loglevel", "warn", "Log level (debug, info, warn, error, fatal, panic)")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), adminUsage)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cmd, ok := adminCommands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown admin command %q\n", fs.Arg(0))
+		fs.Usage()
+		return 2
+	}
+
+	logger := createLogger(getLevelFromString(*loglevel))
+
+	db, err := sql.Open("postgres", *dsn)
+	if err != nil {
+		fmt.Fprintf(stderr, "opening database failed: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	if err := db.Ping(); err != nil {
+		fmt.Fprintf(stderr, "connecting to database failed: %v\n", err)
+		return 1
+	}
+
+	app := &application{
+		config: config{dns: *dsn, loglevel: *loglevel},
+		logger: &logger,
+		models: data.NewModels(db),
+	}
+
+	err = cmd(app, fs.Args()[1:], stdout)
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	default:
+		fmt.Fprintf(stderr, "admin %s: %v\n", fs.Arg(0), err)
+		return 1
+	}
+}
+
+// adminMigrate applies all pending migrations in order or, with -status, lists the state of every migration.
+func adminMigrate(app *application, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	status := fs.Bool("status", false, "List applied and pending migrations without applying them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := app.models.Migrations.EnsureTable(); err != nil {
+		return err
+	}
+
+	if *status {
+		migrations, err := data.Migrations()
+		if err != nil {
+			return err
+		}
+
+		applied, err := app.models.Migrations.Applied()
+		if err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			state := "pending"
+			if appliedAt, ok := applied[m.Version]; ok {
+				state = "applied " + appliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(out, "%04d_%s\t%s\n", m.Version, m.Name, state)
+		}
+		return nil
+	}
+
+	pending, err := app.models.Migrations.Pending()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range pending {
+		app.logger.Info().Msg(fmt.Sprintf("applying migration %04d_%s", m.Version, m.Name))
+		applied, err := app.models.Migrations.Apply(m)
+		if err != nil {
+			return err
+		}
+
+		if applied {
+			fmt.Fprintf(out, "applied %04d_%s\n", m.Version, m.Name)
+		}
+	}
+
+	if len(pending) == 0 {
+		fmt.Fprintln(out, "database schema is up to date")
+	}
+
+	return nil
+}
+
+// adminImportReserved registers platform typecodes from a CSV file with the columns typecode, name and table.
+// A header line and lines starting with # are ignored.
+func adminImportReserved(app *application, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("import-reserved", flag.ContinueOnError)
+	file := fs.String("file", "", "CSV file with the columns typecode,name,table (required)")
+	extension := fs.String("extension", "platform", "Name of the Hybris extension the typecodes are registered for")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *file == "" {
+		return errors.New("-file is required")
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reserved, err := parseReservedTypecodes(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", *file, err)
+	}
+
+	imported, err := app.models.Maintenance.ImportReserved(*extension, reserved)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "imported %d of %d reserved typecodes into extension %s\n", imported, len(reserved), *extension)
+	return nil
+}
+
+// parseReservedTypecodes reads typecode,name,table records. The table column is optional.
+func parseReservedTypecodes(r io.Reader) ([]data.ReservedTypecode, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var reserved []data.ReservedTypecode
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return reserved, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		typecode, err := strconv.ParseInt(strings.TrimSpace(record[0]), 10, 32)
+		if err != nil {
+			if line == 1 {
+				continue // header
+			}
+			return nil, fmt.Errorf("record %d: invalid typecode %q", line, record[0])
+		}
+
+		r := data.ScopeRanges[data.ScopeHybris]
+		if int32(typecode) < r.Start || int32(typecode) > r.End {
+			return nil, fmt.Errorf("record %d: typecode %d is outside of the Hybris range %d-%d", line, typecode, r.Start, r.End)
+		}
+
+		if len(record) < 2 || strings.TrimSpace(record[1]) == "" {
+			return nil, fmt.Errorf("record %d: name is missing", line)
+		}
+
+		entry := data.ReservedTypecode{Typecode: int32(typecode), Name: strings.TrimSpace(record[1])}
+		if len(record) > 2 {
+			entry.TableName = strings.TrimSpace(record[2])
+		}
+		reserved = append(reserved, entry)
+	}
+}
+
+// adminExport writes all projects, extensions and items as a single JSON document.
+func adminExport(app *application, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	outPath := fs.String("out", "", "Write the export to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	projects, err := app.models.Projects.ReadAll()
+	if err != nil {
+		return err
+	}
+
+	extensions, err := app.models.Extensions.ReadAll()
+	if err != nil {
+		return err
+	}
+
+	items, err := app.models.Items.ReadItems()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+
+	if *outPath != "" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "\t")
+	return enc.Encode(envelope{
+		"exported_at": time.Now().UTC(),
+		"projects":    projects,
+		"extensions":  extensions,
+		"items":       items,
+	})
+}
+
+// adminCheck reports inconsistencies in the database and fails if there are any.
+func adminCheck(app *application, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	problems, err := app.models.Maintenance.CheckIntegrity()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range problems {
+		fmt.Fprintf(out, "%s: %s\n", p.Check, p.Detail)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %d problem(s) found", errIntegrity, len(problems))
+	}
+
+	fmt.Fprintln(out, "no problems found")
+	return nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"Typecode-Registry/internal/data"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunAdminReturnsUsageExitCodeForInvalidCommands(t *testing.T) {
+	testCases := map[string][]string{
+		"noCommand":      {},
+		"unknownCommand": {"drop-everything"},
+		"unknownFlag":    {"-unknown", "migrate"},
+	}
+
+	for name, args := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			assert.Equal(t, 2, runAdmin(args, &stdout, &stderr))
+			assert.NotEmpty(t, stderr.String())
+		})
+	}
+}
+
+func TestMigrationsAreEmbeddedInOrder(t *testing.T) {
+	migrations, err := data.Migrations()
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, migrations)
+	assert.Equal(t, 1, migrations[0].Version)
+	for i := 1; i < len(migrations); i++ {
+		assert.Less(t, migrations[i-1].Version, migrations[i].Version)
+	}
+	for _, m := range migrations {
+		assert.NotContains(t, strings.ToUpper(m.SQL), "DROP TABLE", "migration %d must not drop tables", m.Version)
+	}
+}
+
+func TestAdminMigrateAppliesPendingMigrations(t *testing.T) {
+	db, mock, app := setupMockAndApp(t)
+	defer db.Close()
+
+	migrations, err := data.Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS schema_migration`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	applied := sqlmock.NewRows([]string{"version", "applied_at"})
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT version, applied_at FROM schema_migration`)).WillReturnRows(applied)
+
+	for _, m := range migrations {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock($1)`)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM schema_migration WHERE version = $1)`)).
+			WithArgs(m.Version).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec(regexp.QuoteMeta(m.SQL)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO schema_migration (version, name) VALUES ($1, $2)`)).
+			WithArgs(m.Version, m.Name).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+
+	var out bytes.Buffer
+	err = adminMigrate(app, nil, &out)
+
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "applied 0001_initial_schema")
+	checkExpectations(t, mock)
+}
+
+func TestAdminMigrateStatusListsAppliedMigrations(t *testing.T) {
+	db, mock, app := setupMockAndApp(t)
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS schema_migration`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	applied := sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT version, applied_at FROM schema_migration`)).WillReturnRows(applied)
+
+	var out bytes.Buffer
+	err := adminMigrate(app, []string{"-status"}, &out)
+
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "0001_initial_schema\tapplied 2024-05-01T12:00:00Z")
+	checkExpectations(t, mock)
+}
+
+func TestAdminCheckFailsWhenProblemsAreFound(t *testing.T) {
+	db, mock, app := setupMockAndApp(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`.+`).WillReturnRows(sqlmock.NewRows([]string{"detail"}).AddRow("typecode 20000 is used by A, B"))
+	for i := 0; i < 6; i++ {
+		mock.ExpectQuery(`.+`).WillReturnRows(sqlmock.NewRows([]string{"detail"}))
+	}
+
+	var out bytes.Buffer
+	err := adminCheck(app, nil, &out)
+
+	assert.True(t, errors.Is(err, errIntegrity))
+	assert.Equal(t, "duplicate-global-typecode: typecode 20000 is used by A, B\n", out.String())
+	checkExpectations(t, mock)
+}
+
+func TestAdminExportWritesAllEntities(t *testing.T) {
+	db, mock, app := setupMockAndApp(t)
+	defer db.Close()
+
+	mockReadAllProjectsQuery(mock, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}).
+		AddRow(1, "Project A", "Description A", time.Now(), 1))
+	mockReadAllExtensionsQuery(mock, "", sqlmock.NewRows([]string{"id", "project_id", "name", "description", "scope", "creation_date", "version", "item_count"}).
+		AddRow(1, 1, "Extension A", "", "Project", time.Now(), 1, 1))
+	mockReadAllItemsQuery(mock, sqlmock.NewRows([]string{"id", "scope", "project", "name", "table_name", "extension_id", "typecode", "creation_date", "version"}).
+		AddRow(1, "Project", "Project A", "Item A", "items_a", 1, 14000, time.Now(), 1))
+
+	var out bytes.Buffer
+	err := adminExport(app, nil, &out)
+	assert.NoError(t, err)
+
+	var export struct {
+		Projects   []data.Project   `json:"projects"`
+		Extensions []data.Extension `json:"extensions"`
+		Items      []data.Item      `json:"items"`
+	}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &export))
+	assert.Len(t, export.Projects, 1)
+	assert.Len(t, export.Extensions, 1)
+	assert.Equal(t, int32(14000), export.Items[0].Typecode)
+	checkExpectations(t, mock)
+}
+
+func TestParseReservedTypecodes(t *testing.T) {
+	t.Run("SkipsHeaderAndComments", func(t *testing.T) {
+		input := "typecode,name,table\n# core types\n1, Product, products\n2,Order\n"
+
+		reserved, err := parseReservedTypecodes(strings.NewReader(input))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []data.ReservedTypecode{
+			{Typecode: 1, Name: "Product", TableName: "products"},
+			{Typecode: 2, Name: "Order"},
+		}, reserved)
+	})
+
+	t.Run("RejectsTypecodeOutsideOfHybrisRange", func(t *testing.T) {
+		_, err := parseReservedTypecodes(strings.NewReader("20000,Custom,custom\n"))
+		assert.Error(t, err)
+	})
+
+	t.Run("RejectsMissingName", func(t *testing.T) {
+		_, err := parseReservedTypecodes(strings.NewReader("1\n"))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"Typecode-Registry/internal/data"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// auditRoleAssignment is the entity type of the audit entries of role assignments,
+// which are audited but not published to the change feed.
+const auditRoleAssignment = "role_assignment"
+
+// anonymousActor is the actor of changes made without authentication.
+const anonymousActor = "anonymous"
+
+// defaultAuditPageSize is the page size of the audit log if no limit is requested.
+const defaultAuditPageSize = 100
+
+// auditEntityTypes are the entity types of audit entries.
+var auditEntityTypes = []string{changeProject, changeExtension, changeItem, auditRoleAssignment}
+
+// auditChanges writes the changes of the request r to the audit log with models if auditing is enabled.
+// The actor is the subject of the principal of r.
+func (app *application) auditChanges(r *http.Request, models data.Models, changes ...pendingChange) error {
+	actor := anonymousActor
+	if principal := principalFromContext(r.Context()); principal != nil {
+		actor = principal.Subject
+	}
+	return app.writeAudit(models, actor, requestIDFromContext(r.Context()), changes...)
+}
+
+// writeAudit writes changes made by actor in the request with the given ID to the audit log if auditing is enabled.
+// models are bound to the transaction of the changes, so the changes are not stored without their audit entries.
+// Returns: An error if an entry cannot be encoded or written, the caller has to roll back the transaction.
+func (app *application) writeAudit(models data.Models, actor, requestID string, changes ...pendingChange) error {
+	if !app.config.audit || len(changes) == 0 {
+		return nil
+	}
+
+	entries := make([]*data.AuditEntry, 0, len(changes))
+	for _, c := range changes {
+		entry, err := app.auditEntry(models, c, changes)
+		if err != nil {
+			return fmt.Errorf("encoding audit entry of %s %s: %w", c.typ, c.action, err)
+		}
+		entry.Actor, entry.RequestID = actor, requestID
+		entries = append(entries, entry)
+	}
+
+	if err := models.Audit.Insert(entries...); err != nil {
+		return fmt.Errorf("writing %d audit entries of request %s by %s: %w", len(entries), requestID, actor, err)
+	}
+	return nil
+}
+
+// auditEntry returns the audit entry of c, one of changes, without actor and request ID.
+func (app *application) auditEntry(models data.Models, c pendingChange, changes []pendingChange) (*data.AuditEntry, error) {
+	resource := c.resource()
+	entry := &data.AuditEntry{Action: c.action, EntityType: c.typ, EntityID: resourceID(resource)}
+	if projectID := app.auditedProject(models, resource, changes); projectID != 0 {
+		entry.ProjectID = data.NullInt64{NullInt64: sql.NullInt64{Int64: projectID, Valid: true}}
+	}
+
+	var err error
+	if c.before != nil {
+		if entry.Before, err = json.Marshal(c.before); err != nil {
+			return nil, err
+		}
+	}
+	if c.after != nil {
+		if entry.After, err = json.Marshal(c.after); err != nil {
+			return nil, err
+		}
+	}
+	return entry, nil
+}
+
+// auditedProject returns the project of resource. Items of an extension changed by the same request,
+// which may have been deleted together with its items, belong to the project of the extension in changes.
+// Other extensions are read with models, which see the uncommitted changes.
+func (app *application) auditedProject(models data.Models, resource any, changes []pendingChange) int64 {
+	if item, ok := resource.(*data.Item); ok {
+		for _, c := range changes {
+			if extension, ok := c.resource().(*data.Extension); ok && extension.ID == item.ExtensionID {
+				return extension.ProjectID.Int64
+			}
+		}
+	}
+	return app.resourceProject(models, resource)
+}
+
+// resourceID returns the ID of a project, extension, item or role assignment.
+func resourceID(resource any) int64 {
+	switch resource := resource.(type) {
+	case *data.Project:
+		return resource.ID
+	case *data.Extension:
+		return resource.ID
+	case *data.Item:
+		return resource.ID
+	case *data.RoleAssignment:
+		return resource.ID
+	}
+	return 0
+}
+
+// roleChanges returns the audited changes of the roles changed by data.RoleAssignmentModel.SyncGroupRoles.
+func roleChanges(changes []data.RoleChange) []pendingChange {
+	pending := make([]pendingChange, 0, len(changes))
+	for _, rc := range changes {
+		c := pendingChange{typ: auditRoleAssignment}
+		switch {
+		case rc.Before == nil:
+			c.action, c.after = changeCreated, rc.After
+		case rc.After == nil:
+			c.action, c.before = changeDeleted, rc.Before
+		default:
+			c.action, c.before, c.after = changeUpdated, rc.Before, rc.After
+		}
+		pending = append(pending, c)
+	}
+	return pending
+}
+
+// readAuditedRole returns the role of a user in a project with models before it is changed, if auditing is enabled.
+// Returns: nil if auditing is disabled or the user has no role in the project.
+func (app *application) readAuditedRole(models data.Models, userID, projectID int64) (any, error) {
+	if !app.config.audit {
+		return nil, nil
+	}
+
+	assignment, err := models.Roles.Read(userID, projectID)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return assignment, nil
+}
+
+// readAuditQuery reads the filters and pagination of the audit log from the query string.
+// Returns: An error describing the first invalid parameter.
+func (app *application) readAuditQuery(qs url.Values) (data.AuditFilter, data.Filters, error) {
+	var filter data.AuditFilter
+	var filters data.Filters
+	var err error
+
+	filter.Actor = app.readString(qs, "actor", "")
+	filter.RequestID = app.readString(qs, "request_id", "")
+	filter.Action = app.readString(qs, "action", "")
+	if filter.Action != "" && !slices.Contains([]string{changeCreated, changeUpdated, changeDeleted}, filter.Action) {
+		return filter, filters, fmt.Errorf("invalid action %q", filter.Action)
+	}
+	filter.EntityType = app.readString(qs, "entity_type", "")
+	if filter.EntityType != "" && !slices.Contains(auditEntityTypes, filter.EntityType) {
+		return filter, filters, fmt.Errorf("invalid entity_type %q", filter.EntityType)
+	}
+
+	if filter.EntityID, err = app.readAuditID(qs, "entity_id"); err != nil {
+		return filter, filters, err
+	}
+	if filter.ProjectID, err = app.readAuditID(qs, "project_id"); err != nil {
+		return filter, filters, err
+	}
+
+	if filter.Since, err = app.readTime(qs, "since"); err != nil {
+		return filter, filters, err
+	}
+	if filter.Until, err = app.readTime(qs, "until"); err != nil {
+		return filter, filters, err
+	}
+	if filter.Since != nil && filter.Until != nil && !filter.Since.Before(*filter.Until) {
+		return filter, filters, errors.New("since must be before until")
+	}
+
+	if filters.Page, err = app.readInt(qs, "page", 1); err != nil {
+		return filter, filters, err
+	}
+	if filters.Page < 1 || filters.Page > 10_000_000 {
+		return filter, filters, errors.New("page must be between 1 and 10000000")
+	}
+	if filters.PageSize, err = app.readInt(qs, "limit", defaultAuditPageSize); err != nil {
+		return filter, filters, err
+	}
+	if filters.PageSize < 1 || filters.PageSize > data.MaxPageSize {
+		return filter, filters, fmt.Errorf("limit must be between 1 and %d", data.MaxPageSize)
+	}
+
+	return filter, filters, nil
+}
+
+// readAuditID returns the ID of key in the query string or 0 if the key is missing.
+// Returns: An error if the value is no positive integer.
+func (app *application) readAuditID(qs url.Values, key string) (int64, error) {
+	id, err := app.readInt(qs, key, 0)
+	if err == nil && id < 0 {
+		err = fmt.Errorf("%s must be a positive integer", key)
+	}
+	return int64(id), err
+}
+
+// getAuditLog handles the GET request for the audit log, newest entries first.
+// Global admins read all entries, admins of a project the entries of their project, filtered with project_id.
+//   - If a filter or the pagination is invalid, it returns a 400 Bad Request.
+func (app *application) getAuditLog(w http.ResponseWriter, r *http.Request) {
+	filter, filters, err := app.readAuditQuery(r.URL.Query())
+	if err != nil {
+		app.invalidParameterResponse(w, r, err)
+		return
+	}
+
+	a := app.access(r)
+	if filter.ProjectID != 0 {
+		err = a.requireProjectRole(filter.ProjectID, data.RoleAdmin)
+	} else {
+		err = a.requireGlobalAdmin("read the audit log of all projects")
+	}
+	if !app.authorize(w, r, err) {
+		return
+	}
+
+	entries, metadata, err := app.models.Audit.ReadAll(filter, filters)
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while reading the audit log: %v", err))
+		app.serverErrorResponse(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"entries": entries, "metadata": metadata}, nil)
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+	}
+}
+
+// deleteOldAuditEntries deletes the audit entries older than the retention period every interval.
+// It runs until the application exits.
+func (app *application) deleteOldAuditEntries(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		deleted, err := app.models.Audit.DeleteBefore(time.Now().Add(-app.config.auditRetention))
+		if err != nil {
+			app.logger.Error().Msg(fmt.Sprintf("Error while deleting old audit entries: %v", err))
+			continue
+		}
+		app.logger.Debug().Msg(fmt.Sprintf("deleted %d old audit entries", deleted))
+	}
+}
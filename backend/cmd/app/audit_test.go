@@ -0,0 +1,310 @@
+package main
+
+import (
+	"Typecode-Registry/internal/data"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func auditRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"count", "id", "time", "actor", "action", "entity_type", "entity_id", "project_id", "before", "after", "request_id"})
+}
+
+// mockInsertAudit expects a single audit entry to be written and captures its before and after JSON.
+func mockInsertAudit(mock sqlmock.Sqlmock, actor, action, entityType string, entityID int64, projectID any, before, after *capture) {
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO audit_log (actor, action, entity_type, entity_id, project_id, before, after, request_id)`)).
+		WithArgs(actor, action, entityType, entityID, projectID, before, after, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func TestUpdatesAreAuditedWithBeforeAndAfter(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	app.config.audit = true
+	mockReadProjectByIDQuery(mock, 2, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}).
+		AddRow(2, "Shop", "old", time.Now(), 1))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE project`)).
+		WithArgs("", "new", int64(2), int32(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+	var before, after capture
+	mockInsertAudit(mock, anonymousActor, changeUpdated, changeProject, 2, int64(2), &before, &after)
+	mock.ExpectCommit()
+
+	resp := serveRequest(app, http.MethodPut, "/projects/2", `{"description": "new"}`)
+
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	var beforeProject, afterProject data.Project
+	assert.NoError(t, json.Unmarshal([]byte(before.value.(string)), &beforeProject))
+	assert.NoError(t, json.Unmarshal([]byte(after.value.(string)), &afterProject))
+	assert.Equal(t, "old", beforeProject.Description)
+	assert.Equal(t, int32(1), beforeProject.Version)
+	assert.Equal(t, "new", afterProject.Description)
+	assert.Equal(t, int32(2), afterProject.Version)
+	checkExpectations(t, mock)
+}
+
+func TestCreatedItemIsAuditedWithTypecodeAllocatedInTransaction(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	app.config.audit = true
+	setupExtensionMock(mock, 1, sql.NullInt64{}, data.ScopeShared, "core", "", 0, true)
+	mock.ExpectBegin()
+	setupTypecodeMock(mock, data.ScopeShared, 20000, 20001)
+	setupInsertItemMock(mock, "Product", 1, "products", 20001)
+	var before, after capture
+	mockInsertAudit(mock, anonymousActor, changeCreated, changeItem, 1, nil, &before, &after)
+	mock.ExpectCommit()
+
+	resp := serveRequest(app, http.MethodPost, "/items", `{"name": "Product", "table_name": "products", "extension_id": 1}`)
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Nil(t, before.value)
+	var item data.Item
+	assert.NoError(t, json.Unmarshal([]byte(after.value.(string)), &item))
+	assert.Equal(t, int32(20001), item.Typecode)
+	checkExpectations(t, mock)
+}
+
+func TestRoleChangesAreAuditedWithActor(t *testing.T) {
+	mock, app, idp := setupRoleApp(t)
+	app.config.audit = true
+	mockReadMember(mock, 2, 4)
+	mockReadRole(mock, "alice", 2, data.RoleAdmin)
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE ra.user_id = $1 AND ra.project_id = $2`)).
+		WithArgs(int64(4), int64(2)).
+		WillReturnRows(roleAssignmentRows().AddRow(7, 4, "alice@example.com", 2, "Shop", "developer", "group", time.Now()))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO role_assignment (user_id, project_id, role, source)`)).
+		WithArgs(int64(4), int64(2), data.RoleAdmin).
+		WillReturnRows(sqlmock.NewRows([]string{"role_assignment_id", "creation_date", "created"}).AddRow(7, time.Now(), false))
+	var before, after capture
+	mockInsertAudit(mock, "alice", changeUpdated, auditRoleAssignment, 7, int64(2), &before, &after)
+	mock.ExpectCommit()
+
+	resp := serveAs(app, http.MethodPut, "/projects/2/members/4", idp.Token(t, idp.Claims("alice")), `{"role": "admin"}`)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, before.value, `"role":"developer"`)
+	assert.Contains(t, before.value, `"source":"group"`)
+	assert.Contains(t, after.value, `"role":"admin"`)
+	assert.Contains(t, after.value, `"source":"manual"`)
+	checkExpectations(t, mock)
+}
+
+func TestBatchIsAuditedWithOneStatement(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	app.config.audit = true
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO project (name, description)`)).
+		WithArgs("Alpha", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "creation_date", "version"}).AddRow(4, time.Now(), 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO project (name, description)`)).
+		WithArgs("Beta", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "creation_date", "version"}).AddRow(5, time.Now(), 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO audit_log`)).
+		WithArgs(anonymousActor, changeCreated, changeProject, int64(4), int64(4), nil, sqlmock.AnyArg(), sqlmock.AnyArg(),
+			anonymousActor, changeCreated, changeProject, int64(5), int64(5), nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	resp := serveRequest(app, http.MethodPost, "/batch", `{"operations": [
+		{"action": "create", "type": "project", "body": {"name": "Alpha"}},
+		{"action": "create", "type": "project", "body": {"name": "Beta"}}
+	]}`)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	checkExpectations(t, mock)
+}
+
+// mockDeleteItemsByExtension expects the items of the extension to be deleted and returns the given items,
+// whose project is Shop.
+func mockDeleteItemsByExtension(mock sqlmock.Sqlmock, extensionID int64, itemIDs ...int64) {
+	rows := sqlmock.NewRows([]string{"id", "scope", "project_name", "name", "table_name", "extension_id", "typecode", "creation_date", "version"})
+	for _, id := range itemIDs {
+		rows.AddRow(id, data.ScopeProject, "Shop", "Item", "item_table", extensionID, 30000+id, time.Now(), 1)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM item`)).WithArgs(extensionID).WillReturnRows(rows)
+}
+
+func TestCascadedDeletesAreAuditedAndPublished(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	app.config.audit = true
+	app.changes = newChangeFeed(10)
+	mockReadProjectByIDQuery(mock, 2, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}).
+		AddRow(2, "Shop", "", time.Now(), 1))
+	mock.ExpectBegin()
+	mockReadExtensionsByProjectQuery(mock, 2, sqlmock.NewRows([]string{"id", "project_id", "name", "description", "scope", "creation_date", "version", "item_count"}).
+		AddRow(3, 2, "Core", "", data.ScopeProject, time.Now(), 1, 2))
+	mockDeleteItemsByExtension(mock, 3, 10, 11)
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM extension WHERE project_id = $1`)).
+		WithArgs(int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM project WHERE id = $1 AND version = $2`)).
+		WithArgs(int64(2), int32(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO audit_log`)).
+		WithArgs(anonymousActor, changeDeleted, changeItem, int64(10), int64(2), sqlmock.AnyArg(), nil, sqlmock.AnyArg(),
+			anonymousActor, changeDeleted, changeItem, int64(11), int64(2), sqlmock.AnyArg(), nil, sqlmock.AnyArg(),
+			anonymousActor, changeDeleted, changeExtension, int64(3), int64(2), sqlmock.AnyArg(), nil, sqlmock.AnyArg(),
+			anonymousActor, changeDeleted, changeProject, int64(2), int64(2), sqlmock.AnyArg(), nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectCommit()
+
+	resp := serveRequest(app, http.MethodDelete, "/projects/2", "")
+
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	var events []string
+	for _, c := range app.changes.recent {
+		events = append(events, c.Event())
+		assert.Equal(t, int64(2), c.ProjectID, c.Event())
+	}
+	assert.Equal(t, []string{"item.deleted", "item.deleted", "extension.deleted", "project.deleted"}, events)
+	checkExpectations(t, mock)
+}
+
+func TestItemsDeletedWithExtensionInBatchAreAudited(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	app.config.audit = true
+	mock.ExpectBegin()
+	mockReadExtensionByIDQuery(mock, []driver.Value{int64(3)}, sqlmock.NewRows([]string{"id", "project_id", "name", "description", "scope", "creation_date", "version", "item_count"}).
+		AddRow(3, 2, "Core", "", data.ScopeProject, time.Now(), 1, 1))
+	mockDeleteItemsByExtension(mock, 3, 10)
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM extension WHERE id = $1 AND version = $2`)).
+		WithArgs(int64(3), int32(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO audit_log`)).
+		WithArgs(anonymousActor, changeDeleted, changeItem, int64(10), int64(2), sqlmock.AnyArg(), nil, sqlmock.AnyArg(),
+			anonymousActor, changeDeleted, changeExtension, int64(3), int64(2), sqlmock.AnyArg(), nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	resp := serveRequest(app, http.MethodPost, "/batch", `{"operations": [{"action": "delete", "type": "extension", "id": 3}]}`)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	checkExpectations(t, mock)
+}
+
+func TestChangeIsRolledBackIfAuditFails(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	app.config.audit = true
+	app.changes = newChangeFeed(10)
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO project (name, description)`)).
+		WithArgs("Alpha", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "creation_date", "version"}).AddRow(4, time.Now(), 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO audit_log`)).
+		WillReturnError(errors.New("disk full"))
+	mock.ExpectRollback()
+
+	resp := serveRequest(app, http.MethodPost, "/projects", `{"name": "Alpha"}`)
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.Empty(t, app.changes.recent, "the change is not published")
+	checkExpectations(t, mock)
+}
+
+func TestAuditLogIsReadByAdmins(t *testing.T) {
+	mock, app, idp := setupRoleApp(t)
+
+	resp := serveAs(app, http.MethodGet, "/audit", idp.Token(t, idp.Claims("alice")), "")
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+
+	mockReadRole(mock, "alice", 2, data.RoleAdmin)
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM audit_log`)).
+		WithArgs("", "", "", int64(0), int64(2), "", nil, nil, 100, 0).
+		WillReturnRows(auditRows().
+			AddRow(1, 9, time.Now(), "bob", changeDeleted, changeItem, 14, 2, []byte(`{"id":14,"name":"Product"}`), nil, "req-1"))
+
+	resp = serveAs(app, http.MethodGet, "/audit?project_id=2", idp.Token(t, idp.Claims("alice")), "")
+
+	if !assert.Equal(t, http.StatusOK, resp.Code) {
+		t.FailNow()
+	}
+	var body struct {
+		Entries  []data.AuditEntry `json:"entries"`
+		Metadata data.Metadata     `json:"metadata"`
+	}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	if assert.Len(t, body.Entries, 1) {
+		assert.Equal(t, "bob", body.Entries[0].Actor)
+		assert.JSONEq(t, `{"id":14,"name":"Product"}`, string(body.Entries[0].Before))
+		assert.Equal(t, "null", string(body.Entries[0].After))
+	}
+	assert.Equal(t, 1, body.Metadata.TotalRecords)
+	checkExpectations(t, mock)
+}
+
+func TestAuditLogCountsEntriesForPageBehindLastPage(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM audit_log`)).
+		WithArgs("bob", "", "", int64(0), int64(0), "", nil, nil, 10, 40).
+		WillReturnRows(auditRows())
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM audit_log`)).
+		WithArgs("bob", "", "", int64(0), int64(0), "", nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(25))
+
+	resp := serveRequest(app, http.MethodGet, "/audit?actor=bob&page=5&limit=10", "")
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var body struct {
+		Entries  []data.AuditEntry `json:"entries"`
+		Metadata data.Metadata     `json:"metadata"`
+	}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	assert.Empty(t, body.Entries)
+	assert.Equal(t, data.Metadata{CurrentPage: 5, PageSize: 10, FirstPage: 1, LastPage: 3, TotalRecords: 25}, body.Metadata)
+	checkExpectations(t, mock)
+}
+
+func TestLargeAuditIsInsertedInChunks(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	app.config.audit = true
+
+	// PostgreSQL accepts at most 65535 parameters per statement, 8 per entry.
+	changes := make([]pendingChange, 8200)
+	for i := range changes {
+		changes[i] = pendingChange{typ: changeProject, action: changeDeleted, before: &data.Project{ID: int64(i + 1), Name: "Shop"}}
+	}
+	for _, entries := range []int{8191, 9} {
+		args := make([]driver.Value, entries*8)
+		for i := range args {
+			args[i] = sqlmock.AnyArg()
+		}
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO audit_log (actor, action, entity_type, entity_id, project_id, before, after, request_id)`)).
+			WithArgs(args...).
+			WillReturnResult(sqlmock.NewResult(0, int64(entries)))
+	}
+
+	assert.NoError(t, app.writeAudit(app.models, "alice", "req-1", changes...))
+	checkExpectations(t, mock)
+}
+
+func TestInvalidAuditQueries(t *testing.T) {
+	targets := []string{
+		"/audit?action=renamed",
+		"/audit?entity_type=webhook",
+		"/audit?entity_id=-1",
+		"/audit?project_id=x",
+		"/audit?since=yesterday",
+		"/audit?since=2024-02-01&until=2024-01-01",
+		"/audit?limit=0",
+		"/audit?page=0",
+	}
+
+	for _, target := range targets {
+		_, mock, app := setupMockAndApp(t)
+
+		resp := serveRequest(app, http.MethodGet, target, "")
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code, target)
+		checkExpectations(t, mock)
+	}
+}
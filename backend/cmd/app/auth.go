@@ -0,0 +1,129 @@
+package main
+
+import (
+	"Typecode-Registry/internal/auth"
+	"Typecode-Registry/internal/data"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const principalContextKey = contextKey("principal")
+
+// newVerifier returns the verifier of bearer tokens configured by the -auth flags,
+// or nil if neither a JWKS URL nor a JWKS file is configured and requests are not authenticated.
+// Returns: an error if the configuration is incomplete or the JWKS file cannot be read.
+func newVerifier(cfg config) (*auth.Verifier, error) {
+	if cfg.authJWKSURL == "" && cfg.authJWKSFile == "" {
+		return nil, nil
+	}
+
+	var audience []string
+	for _, aud := range strings.Split(cfg.authAudience, ",") {
+		if aud = strings.TrimSpace(aud); aud != "" {
+			audience = append(audience, aud)
+		}
+	}
+
+	switch {
+	case cfg.authJWKSURL != "" && cfg.authJWKSFile != "":
+		return nil, errors.New("-auth-jwks-url and -auth-jwks-file must not be set both")
+	case cfg.authIssuer == "":
+		return nil, errors.New("-auth-issuer must be set to validate tokens")
+	case len(audience) == 0:
+		return nil, errors.New("-auth-audience must be set to validate tokens")
+	}
+
+	verifier := &auth.Verifier{Issuer: cfg.authIssuer, Audience: audience, Leeway: cfg.authLeeway}
+	if cfg.authJWKSFile != "" {
+		keys, err := auth.LoadKeySetFile(cfg.authJWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading -auth-jwks-file: %w", err)
+		}
+		verifier.Keys = keys
+	} else {
+		verifier.Keys = auth.NewRemoteKeySet(cfg.authJWKSURL, nil)
+	}
+
+	return verifier, nil
+}
+
+// isPublicRequest reports whether the request is answered without authentication:
+// the healthcheck for load balancers, the metrics for monitoring and the documentation of the API.
+func isPublicRequest(r *http.Request) bool {
+	switch {
+	case r.URL.Path == "/healthcheck", r.URL.Path == "/metrics", r.URL.Path == "/openapi.json":
+		return true
+	default:
+		return strings.HasPrefix(r.URL.Path, "/docs/")
+	}
+}
+
+// bearerToken returns the token of the Authorization header. Since browsers cannot set headers
+// for server-sent events, the change feed accepts the token in the query parameter access_token as well.
+// Returns: false if the request has no bearer token.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") && strings.TrimSpace(token) != "" {
+		return strings.TrimSpace(token), true
+	}
+
+	if r.Method == http.MethodGet && r.URL.Path == "/changes" {
+		if token := r.URL.Query().Get("access_token"); token != "" {
+			return token, true
+		}
+	}
+
+	return "", false
+}
+
+// authenticate validates the bearer token of every request except the public ones and stores
+// the authenticated principal in the request context. Bearer tokens are tokens of the identity provider
+// or API tokens, see authenticateAPIToken. Requests without valid token are answered
+// with 401 Unauthorized. If no verifier is configured, all requests pass anonymously.
+func (app *application) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.verifier == nil || isPublicRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := bearerToken(r)
+		if !ok {
+			app.unauthorizedResponse(w, r, "", "the request requires a bearer token in the Authorization header")
+			return
+		}
+
+		if strings.HasPrefix(token, data.APITokenPrefix) {
+			app.authenticateAPIToken(w, r, token, next)
+			return
+		}
+
+		principal, err := app.verifier.Verify(token)
+		if errors.Is(err, auth.ErrInvalidToken) {
+			app.logger.Info().Msg(fmt.Sprintf("rejected token of %s %s: %v", r.Method, r.URL.Path, err))
+			app.unauthorizedResponse(w, r, "invalid_token", err.Error())
+			return
+		}
+		if err != nil {
+			app.logger.Error().Msg(fmt.Sprintf("Error while validating token: %v", err))
+			app.errorResponse(w, r, http.StatusServiceUnavailable, codeInternal, "the signing keys of the identity provider are unavailable")
+			return
+		}
+
+		if err := app.provisionUser(r, principal); err != nil {
+			app.logger.Error().Msg(fmt.Sprintf("Error while provisioning user %s: %v", principal.Subject, err))
+		}
+
+		app.logger.Debug().Msg(fmt.Sprintf("authenticated %s for %s %s", principal.Subject, r.Method, r.URL.Path))
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalContextKey, principal)))
+	})
+}
+
+// principalFromContext returns the principal authenticated by authenticate, or nil for anonymous requests.
+func principalFromContext(ctx context.Context) *auth.Principal {
+	principal, _ := ctx.Value(principalContextKey).(*auth.Principal)
+	return principal
+}
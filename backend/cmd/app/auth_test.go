@@ -0,0 +1,155 @@
+package main
+
+import (
+	"Typecode-Registry/internal/auth"
+	"Typecode-Registry/internal/auth/authtest"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupAuthApp returns an application which authenticates requests with tokens of a local identity provider.
+func setupAuthApp(t *testing.T) (sqlmock.Sqlmock, *application, *authtest.Issuer) {
+	_, mock, app := setupMockAndApp(t)
+	idp := authtest.NewIssuer(t)
+	app.verifier = &auth.Verifier{Keys: idp.KeySet(t), Issuer: idp.Issuer, Audience: []string{idp.Audience}}
+	return mock, app, idp
+}
+
+func serveWithToken(app *application, method, target, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp := httptest.NewRecorder()
+	app.handler().ServeHTTP(resp, req)
+	return resp
+}
+
+func TestRequestsWithoutTokenAreUnauthorized(t *testing.T) {
+	mock, app, _ := setupAuthApp(t)
+
+	resp := serveWithToken(app, http.MethodGet, "/projects", "")
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Equal(t, `Bearer realm="typecode-registry"`, resp.Header().Get("WWW-Authenticate"))
+	assert.Equal(t, codeUnauthorized, decodeProblem(t, resp).Code)
+
+	resp = serveWithToken(app, http.MethodGet, "/unknown", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.Code, "unknown routes are not revealed")
+	checkExpectations(t, mock)
+}
+
+func TestRequestsWithInvalidTokenAreUnauthorized(t *testing.T) {
+	mock, app, idp := setupAuthApp(t)
+	claims := idp.Claims("alice")
+	claims["exp"] = time.Now().Add(-time.Hour).Unix()
+
+	for _, token := range []string{"not-a-token", idp.Token(t, claims), authtest.NewIssuer(t).Token(t, idp.Claims("alice"))} {
+		resp := serveWithToken(app, http.MethodGet, "/projects", token)
+
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		assert.Equal(t, `Bearer realm="typecode-registry", error="invalid_token"`, resp.Header().Get("WWW-Authenticate"))
+		assert.Contains(t, decodeProblem(t, resp).Detail, "invalid token")
+	}
+	checkExpectations(t, mock)
+}
+
+func TestAuthenticatedPrincipalIsInContext(t *testing.T) {
+	_, app, idp := setupAuthApp(t)
+
+	var principal *auth.Principal
+	handler := app.authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal = principalFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/projects", nil)
+	req.Header.Set("Authorization", "bearer "+idp.Token(t, idp.Claims("alice")))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if assert.NotNil(t, principal) {
+		assert.Equal(t, "alice", principal.Subject)
+		assert.Equal(t, "alice@example.com", principal.Email)
+	}
+}
+
+func TestValidTokenIsAccepted(t *testing.T) {
+	mock, app, idp := setupAuthApp(t)
+	mockReadAllProjectsQuery(mock, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}))
+
+	resp := serveWithToken(app, http.MethodGet, "/projects", idp.Token(t, idp.Claims("alice")))
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	checkExpectations(t, mock)
+}
+
+func TestPublicRoutesNeedNoToken(t *testing.T) {
+	_, app, _ := setupAuthApp(t)
+
+	for _, target := range []string{"/healthcheck", "/openapi.json", "/docs/"} {
+		resp := serveWithToken(app, http.MethodGet, target, "")
+		assert.Equal(t, http.StatusOK, resp.Code, target)
+	}
+}
+
+func TestChangeFeedAcceptsTokenInQuery(t *testing.T) {
+	_, app, idp := setupAuthApp(t)
+	token := idp.Token(t, idp.Claims("alice"))
+
+	req := httptest.NewRequest(http.MethodGet, "/changes?access_token="+token, nil)
+	tok, ok := bearerToken(req)
+	assert.True(t, ok)
+	assert.Equal(t, token, tok)
+
+	_, ok = bearerToken(httptest.NewRequest(http.MethodGet, "/projects?access_token="+token, nil))
+	assert.False(t, ok, "only the change feed accepts tokens in the query")
+
+	// The feed is disabled, so the authenticated request reaches the handler.
+	resp := serveWithToken(app, http.MethodGet, "/changes?access_token="+token, "")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	assert.Equal(t, codeChangeFeedDisabled, decodeProblem(t, resp).Code)
+}
+
+func TestUnavailableKeysAreNotBlamedOnTheToken(t *testing.T) {
+	_, app, idp := setupAuthApp(t)
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	app.verifier.Keys = auth.NewRemoteKeySet(server.URL, nil)
+
+	resp := serveWithToken(app, http.MethodGet, "/projects", idp.Token(t, idp.Claims("alice")))
+
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	assert.Empty(t, resp.Header().Get("WWW-Authenticate"))
+}
+
+func TestNewVerifier(t *testing.T) {
+	verifier, err := newVerifier(config{})
+	assert.NoError(t, err)
+	assert.Nil(t, verifier, "authentication is disabled without key set")
+
+	idp := authtest.NewIssuer(t)
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(file, idp.KeySetJSON(t), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	verifier, err = newVerifier(config{authJWKSFile: file, authIssuer: idp.Issuer, authAudience: " api://a, api://b ", authLeeway: time.Minute})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"api://a", "api://b"}, verifier.Audience)
+	assert.Equal(t, time.Minute, verifier.Leeway)
+
+	_, err = newVerifier(config{authJWKSURL: "https://login.example.com/keys", authAudience: "api://a"})
+	assert.EqualError(t, err, "-auth-issuer must be set to validate tokens")
+	_, err = newVerifier(config{authJWKSURL: "https://login.example.com/keys", authIssuer: idp.Issuer, authAudience: " , "})
+	assert.EqualError(t, err, "-auth-audience must be set to validate tokens")
+	_, err = newVerifier(config{authJWKSURL: "https://login.example.com/keys", authJWKSFile: file})
+	assert.Error(t, err)
+	_, err = newVerifier(config{authJWKSFile: filepath.Join(t.TempDir(), "missing.json"), authIssuer: idp.Issuer, authAudience: "api://a"})
+	assert.ErrorContains(t, err, "loading -auth-jwks-file")
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"Typecode-Registry/internal/auth"
+	"Typecode-Registry/internal/data"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// The permission model of the registry:
+//   - Global admins, principals with the app role -auth-admin-role, may do everything. Only they create projects,
+//     register users, manage extensions of the Shared scope with their items and webhooks of all projects.
+//   - Admins of a project manage the project, its extensions, its members and its webhooks.
+//   - Developers of a project create, update and delete the items of its extensions.
+//   - Service accounts have the role given to them in their project and no role in other projects.
+//   - Every authenticated principal may read everything except webhooks and their deliveries,
+//     which only those who may manage them read. API tokens without the write scope may only read,
+//     and API tokens cannot manage API tokens or service accounts.
+//
+// If authentication is disabled, requests have no principal and everything is permitted.
+
+// forbiddenError is returned by the permission checks if the principal lacks a permission.
+type forbiddenError struct {
+	message string
+}
+
+func (e *forbiddenError) Error() string {
+	return e.message
+}
+
+func forbidden(format string, args ...any) error {
+	return &forbiddenError{message: fmt.Sprintf(format, args...)}
+}
+
+// access checks the permissions of the principal of a request.
+type access struct {
+	principal   *auth.Principal
+	globalAdmin bool
+	// token is the API token the principal authenticated with, nil for tokens of the identity provider.
+	token  *data.APIToken
+	models data.Models
+	// projectRoles caches the roles of the principal, a batch may check the same project many times.
+	// The roles of service accounts are known in advance.
+	projectRoles map[int64]string
+}
+
+// access returns the permission checks for the principal of r.
+func (app *application) access(r *http.Request) *access {
+	principal := principalFromContext(r.Context())
+	a := &access{
+		principal:    principal,
+		globalAdmin:  principal != nil && app.config.authAdminRole != "" && slices.Contains(principal.Roles, app.config.authAdminRole),
+		token:        apiTokenFromContext(r.Context()),
+		models:       app.models,
+		projectRoles: make(map[int64]string),
+	}
+	if a.token != nil && a.token.ServiceAccount != nil {
+		a.projectRoles[a.token.ServiceAccount.ProjectID] = a.token.ServiceAccount.Role
+	}
+	return a
+}
+
+// unrestricted reports whether the principal may do everything.
+func (a *access) unrestricted() bool {
+	return a.principal == nil || a.globalAdmin
+}
+
+// requireGlobalAdmin checks that the principal is a global admin.
+func (a *access) requireGlobalAdmin(action string) error {
+	if a.unrestricted() {
+		return nil
+	}
+	return forbidden("only global admins may %s", action)
+}
+
+// requireProjectRole checks that the principal has role in the project. Admins have the permissions of developers as well.
+// Returns: a *forbiddenError or the error of reading the role.
+func (a *access) requireProjectRole(projectID int64, role string) error {
+	if a.unrestricted() {
+		return nil
+	}
+
+	actual, ok := a.projectRoles[projectID]
+	if !ok && (a.token == nil || a.token.ServiceAccount == nil) {
+		var err error
+		actual, err = a.models.Roles.ReadRole(a.principal.Subject, projectID)
+		if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+			return err
+		}
+		a.projectRoles[projectID] = actual
+	}
+
+	if actual == role || actual == data.RoleAdmin {
+		return nil
+	}
+	return forbidden("the %s role of project %d is required", role, projectID)
+}
+
+// requireSignIn checks that the principal did not authenticate with an API token,
+// so a leaked token cannot be used to create further tokens.
+func (a *access) requireSignIn() error {
+	if a.token != nil {
+		return forbidden("API tokens cannot manage API tokens or service accounts, sign in at the identity provider")
+	}
+	return nil
+}
+
+// requireExtensionRole checks that the principal has role in the project of a Project extension.
+// Extensions of other scopes are reserved to global admins.
+func (a *access) requireExtensionRole(extension *data.Extension, role string) error {
+	if extension.Scope == data.ScopeProject && extension.ProjectID.Valid {
+		return a.requireProjectRole(extension.ProjectID.Int64, role)
+	}
+	return a.requireGlobalAdmin(fmt.Sprintf("change extensions and items of the %s scope", extension.Scope))
+}
+
+// requireItemRole checks that the principal has role in the project of the extension of item, see requireExtensionRole.
+// The extension is read only if the principal is restricted.
+func (a *access) requireItemRole(item *data.Item, role string) error {
+	if a.unrestricted() {
+		return nil
+	}
+	if item.Scope != data.ScopeProject {
+		return a.requireGlobalAdmin(fmt.Sprintf("change items of the %s scope", item.Scope))
+	}
+
+	extension, err := a.models.Extensions.Read(item.ExtensionID)
+	if err != nil {
+		return err
+	}
+	return a.requireExtensionRole(extension, role)
+}
+
+// requireWebhookAdmin checks that the principal may manage a webhook: as admin of its project,
+// or as global admin for webhooks of all projects.
+func (a *access) requireWebhookAdmin(projectID data.NullInt64) error {
+	if projectID.Valid {
+		return a.requireProjectRole(projectID.Int64, data.RoleAdmin)
+	}
+	return a.requireGlobalAdmin("manage webhooks of all projects")
+}
+
+// requireWebhookIDAdmin checks like requireWebhookAdmin that the principal may manage the webhook with the given ID.
+// The webhook is read only if the principal is restricted.
+// Returns: a *forbiddenError, data.ErrRecordNotFound if the webhook does not exist or the error of reading it.
+func (a *access) requireWebhookIDAdmin(webhookID int64) error {
+	if a.unrestricted() {
+		return nil
+	}
+
+	webhook, err := a.models.Webhooks.Read(webhookID)
+	if err != nil {
+		return err
+	}
+	return a.requireWebhookAdmin(webhook.ProjectID)
+}
+
+// manageableWebhooks returns those of webhooks which the principal may manage, see requireWebhookAdmin.
+func (a *access) manageableWebhooks(webhooks []*data.Webhook) ([]*data.Webhook, error) {
+	if a.unrestricted() {
+		return webhooks, nil
+	}
+
+	manageable := []*data.Webhook{}
+	for _, webhook := range webhooks {
+		err := a.requireWebhookAdmin(webhook.ProjectID)
+		var forbiddenErr *forbiddenError
+		switch {
+		case err == nil:
+			manageable = append(manageable, webhook)
+		case !errors.As(err, &forbiddenErr):
+			return nil, err
+		}
+	}
+	return manageable, nil
+}
+
+// authorize answers the request with 403 Forbidden if err is a *forbiddenError, or with 500 Internal Server Error
+// for other errors of the permission check.
+// Returns: true if err is nil and the request may proceed.
+func (app *application) authorize(w http.ResponseWriter, r *http.Request, err error) bool {
+	if err == nil {
+		return true
+	}
+
+	var forbiddenErr *forbiddenError
+	if errors.As(err, &forbiddenErr) {
+		app.logger.Info().Msg(fmt.Sprintf("denied %s %s: %v", r.Method, r.URL.Path, err))
+		app.forbiddenResponse(w, r, forbiddenErr.message)
+		return false
+	}
+
+	app.logger.Error().Msg(fmt.Sprintf("Error while checking permissions: %v", err))
+	app.serverErrorResponse(w, r)
+	return false
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"Typecode-Registry/internal/auth/authtest"
+	"Typecode-Registry/internal/data"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const testAdminRole = "TypecodeRegistry.Admin"
+
+// setupRoleApp returns an application which authenticates requests and grants global admin to the app role testAdminRole.
+func setupRoleApp(t *testing.T) (sqlmock.Sqlmock, *application, *authtest.Issuer) {
+	mock, app, idp := setupAuthApp(t)
+	app.config.authAdminRole = testAdminRole
+	return mock, app, idp
+}
+
+// globalAdminToken returns a token of a principal with the app role of global admins.
+func globalAdminToken(t *testing.T, idp *authtest.Issuer) string {
+	claims := idp.Claims("root")
+	claims["roles"] = []string{testAdminRole}
+	return idp.Token(t, claims)
+}
+
+// mockReadRole expects the role of subject in the project to be read. An empty role means the subject is no member.
+func mockReadRole(mock sqlmock.Sqlmock, subject string, projectID int64, role string) {
+	rows := sqlmock.NewRows([]string{"role"})
+	if role != "" {
+		rows.AddRow(role)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE u.oauth_identifier = $1 AND ra.project_id = $2`)).
+		WithArgs(subject, projectID).
+		WillReturnRows(rows)
+}
+
+func serveAs(app *application, method, target, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := httptest.NewRecorder()
+	app.handler().ServeHTTP(resp, req)
+	return resp
+}
+
+func TestProjectAdminRoleIsRequiredToManageProjects(t *testing.T) {
+	testCases := map[string]string{
+		"developer":  data.RoleDeveloper,
+		"non-member": "",
+	}
+
+	for name, role := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mock, app, idp := setupRoleApp(t)
+			mockReadProjectByIDQuery(mock, 2, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}).
+				AddRow(2, "Shop", "", time.Now(), 1))
+			mockReadRole(mock, "alice", 2, role)
+
+			resp := serveAs(app, http.MethodDelete, "/projects/2", idp.Token(t, idp.Claims("alice")), "")
+
+			assert.Equal(t, http.StatusForbidden, resp.Code)
+			p := decodeProblem(t, resp)
+			assert.Equal(t, codeForbidden, p.Code)
+			assert.Equal(t, "the admin role of project 2 is required", p.Detail)
+			checkExpectations(t, mock)
+		})
+	}
+}
+
+func TestProjectAdminsAssignMembers(t *testing.T) {
+	mock, app, idp := setupRoleApp(t)
+	mockReadMember(mock, 2, 4)
+	mockReadRole(mock, "alice", 2, data.RoleAdmin)
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO role_assignment (user_id, project_id, role, source)`)).
+		WithArgs(int64(4), int64(2), data.RoleDeveloper).
+		WillReturnRows(sqlmock.NewRows([]string{"role_assignment_id", "creation_date", "created"}).AddRow(7, time.Now(), true))
+
+	resp := serveAs(app, http.MethodPut, "/projects/2/members/4", idp.Token(t, idp.Claims("alice")), `{"role": "developer"}`)
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	checkExpectations(t, mock)
+}
+
+func TestDevelopersCreateItemsInExtensionsOfTheirProject(t *testing.T) {
+	mock, app, idp := setupRoleApp(t)
+	setupExtensionMock(mock, 9, sql.NullInt64{Int64: 2, Valid: true}, data.ScopeProject, "core", "", 0, true)
+	mockReadRole(mock, "bob", 2, data.RoleDeveloper)
+	setupNextFreeTypecodeMock(mock, 2, 14000, 19999, 14000)
+	setupInsertItemMock(mock, "Product", 9, "products", 14000)
+	setupReadProjectNameMock(mock, 2, "Shop")
+
+	resp := serveAs(app, http.MethodPost, "/items", idp.Token(t, idp.Claims("bob")), `{"name": "Product", "table_name": "products", "extension_id": 9}`)
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	checkExpectations(t, mock)
+}
+
+func TestDevelopersCannotChangeExtensions(t *testing.T) {
+	mock, app, idp := setupRoleApp(t)
+	setupExtensionMock(mock, 9, sql.NullInt64{Int64: 2, Valid: true}, data.ScopeProject, "core", "", 0, true)
+	mockReadRole(mock, "bob", 2, data.RoleDeveloper)
+
+	resp := serveAs(app, http.MethodPut, "/extensions/9", idp.Token(t, idp.Claims("bob")), `{"name": "renamed"}`)
+
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	checkExpectations(t, mock)
+}
+
+func TestSharedScopeIsReservedToGlobalAdmins(t *testing.T) {
+	mock, app, idp := setupRoleApp(t)
+	mockReadItemDetailByItemIdQuery(mock, 1, newItemRows(1, 1))
+
+	resp := serveAs(app, http.MethodDelete, "/items/1", idp.Token(t, idp.Claims("alice")), "")
+
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Equal(t, "only global admins may change items of the Shared scope", decodeProblem(t, resp).Detail)
+
+	mockReadItemDetailByItemIdQuery(mock, 1, newItemRows(1, 1))
+	mockDeleteItemExecution(mock, 1, 1, 1)
+
+	resp = serveAs(app, http.MethodDelete, "/items/1", globalAdminToken(t, idp), "")
+
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	checkExpectations(t, mock)
+}
+
+func TestOnlyGlobalAdminsCreateProjects(t *testing.T) {
+	mock, app, idp := setupRoleApp(t)
+
+	resp := serveAs(app, http.MethodPost, "/projects", idp.Token(t, idp.Claims("alice")), `{"name": "Alpha"}`)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO project (name, description)`)).
+		WithArgs("Alpha", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "creation_date", "version"}).AddRow(4, time.Now(), 1))
+
+	resp = serveAs(app, http.MethodPost, "/projects", globalAdminToken(t, idp), `{"name": "Alpha"}`)
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	checkExpectations(t, mock)
+}
+
+func TestBatchFailsWithForbiddenOperation(t *testing.T) {
+	mock, app, idp := setupRoleApp(t)
+	mock.ExpectBegin()
+	setupExtensionMock(mock, 9, sql.NullInt64{Int64: 2, Valid: true}, data.ScopeProject, "core", "", 0, true)
+	mockReadRole(mock, "bob", 2, data.RoleDeveloper)
+	mock.ExpectRollback()
+
+	resp := serveAs(app, http.MethodPost, "/batch", idp.Token(t, idp.Claims("bob")), `{"operations": [{"action": "delete", "type": "extension", "id": 9}]}`)
+
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	var body struct {
+		Operation BatchResult `json:"operation"`
+	}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	assert.Equal(t, http.StatusForbidden, body.Operation.Status)
+	assert.Equal(t, "the admin role of project 2 is required", body.Operation.Error)
+	checkExpectations(t, mock)
+}
+
+func TestProjectRolesAreReadOncePerRequest(t *testing.T) {
+	mock, app, idp := setupRoleApp(t)
+	mockReadRole(mock, "bob", 2, data.RoleDeveloper)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	principal, err := app.verifier.Verify(idp.Token(t, idp.Claims("bob")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := app.access(req.WithContext(context.WithValue(req.Context(), principalContextKey, principal)))
+
+	assert.NoError(t, a.requireProjectRole(2, data.RoleDeveloper))
+	assert.Error(t, a.requireProjectRole(2, data.RoleAdmin))
+	assert.NoError(t, a.requireProjectRole(2, data.RoleDeveloper))
+	checkExpectations(t, mock)
+}
+
+func TestForbiddenResponsesAreNotStoredForIdempotencyKeys(t *testing.T) {
+	mock, app, idp := setupRoleApp(t)
+	mockReserveIdempotencyKey(mock, "key-1", "POST /projects alice", true)
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM idempotency_key WHERE key = $1 AND endpoint = $2`)).
+		WithArgs("key-1", "POST /projects alice").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	req := httptest.NewRequest(http.MethodPost, "/projects", strings.NewReader(projectRequestBody))
+	req.Header.Set("Authorization", "Bearer "+idp.Token(t, idp.Claims("alice")))
+	req.Header.Set("Idempotency-Key", "key-1")
+	resp := httptest.NewRecorder()
+	app.handler().ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	checkExpectations(t, mock)
+}
+
+func TestWebhookListContainsOnlyManagedWebhooks(t *testing.T) {
+	mock, app, idp := setupRoleApp(t)
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM webhook ORDER BY id`)).
+		WillReturnRows(webhookRows().
+			AddRow(1, nil, "https://ci.example.com/all", testWebhookSecret, "{item.created}", "", true, time.Now(), 1).
+			AddRow(3, 2, "https://ci.example.com/shop", testWebhookSecret, "{item.created}", "", true, time.Now(), 1).
+			AddRow(4, 5, "https://ci.example.com/docs", testWebhookSecret, "{item.created}", "", true, time.Now(), 1).
+			AddRow(6, 2, "https://ci.example.com/shop2", testWebhookSecret, "{item.deleted}", "", true, time.Now(), 1))
+	mockReadRole(mock, "alice", 2, data.RoleAdmin)
+	mockReadRole(mock, "alice", 5, data.RoleDeveloper)
+
+	resp := serveAs(app, http.MethodGet, "/webhooks", idp.Token(t, idp.Claims("alice")), "")
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var body struct {
+		Webhooks []data.Webhook `json:"webhooks"`
+	}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	if assert.Len(t, body.Webhooks, 2) {
+		assert.Equal(t, int64(3), body.Webhooks[0].ID)
+		assert.Equal(t, int64(6), body.Webhooks[1].ID)
+	}
+	checkExpectations(t, mock)
+}
+
+func TestProjectAdminRoleIsRequiredToReadWebhooks(t *testing.T) {
+	testCases := map[string]string{
+		"webhook":    "/webhooks/3",
+		"deliveries": "/webhooks/3/deliveries",
+		"delivery":   "/webhooks/3/deliveries/8",
+	}
+
+	for name, target := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mock, app, idp := setupRoleApp(t)
+			if name == "delivery" {
+				mock.ExpectQuery(regexp.QuoteMeta(`FROM webhook_delivery WHERE id = $1 AND webhook_id = $2`)).
+					WithArgs(int64(8), int64(3)).
+					WillReturnRows(deliveryRows().AddRow(8, 3, "item.created", "{}", nil, "succeeded", 1, 204, nil, nil, time.Now(), time.Now()))
+			}
+			mockReadWebhookQuery(mock, 3, "https://ci.example.com/hooks")
+			mockReadRole(mock, "bob", 2, data.RoleDeveloper)
+
+			resp := serveAs(app, http.MethodGet, target, idp.Token(t, idp.Claims("bob")), "")
+
+			assert.Equal(t, http.StatusForbidden, resp.Code)
+			assert.Equal(t, "the admin role of project 2 is required", decodeProblem(t, resp).Detail)
+			checkExpectations(t, mock)
+		})
+	}
+}
+
+func TestRedeliveryOfDeletedWebhookIsNotFound(t *testing.T) {
+	mock, app, idp := setupRoleApp(t)
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM webhook_delivery WHERE id = $1 AND webhook_id = $2`)).
+		WithArgs(int64(8), int64(3)).
+		WillReturnRows(deliveryRows().AddRow(8, 3, "item.created", "{}", nil, "failed", 3, 500, nil, nil, time.Now(), time.Now()))
+	// The webhook has been deleted after its delivery was read.
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM webhook WHERE id = $1`)).
+		WithArgs(int64(3)).
+		WillReturnRows(webhookRows())
+
+	resp := serveAs(app, http.MethodPost, "/webhooks/3/deliveries/8/redeliver", idp.Token(t, idp.Claims("alice")), "")
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.Equal(t, "no webhook with id 3 found", decodeProblem(t, resp).Detail)
+	checkExpectations(t, mock)
+}
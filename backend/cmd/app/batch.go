@@ -0,0 +1,567 @@
+package main
+
+import (
+	"Typecode-Registry/internal/data"
+	"Typecode-Registry/internal/validator"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const maxBatchOperations = 1000
+
+// Actions of batch operations.
+const (
+	batchCreate = "create"
+	batchUpdate = "update"
+	batchDelete = "delete"
+)
+
+// batchChangeActions maps the actions of batch operations to the actions of their changes.
+var batchChangeActions = map[string]string{
+	batchCreate: changeCreated,
+	batchUpdate: changeUpdated,
+	batchDelete: changeDeleted,
+}
+
+// Resource types of batch operations.
+const (
+	batchProject   = "project"
+	batchExtension = "extension"
+	batchItem      = "item"
+)
+
+// BatchRequest is the request object for executing several operations in a single transaction.
+type BatchRequest struct {
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchOperation creates, updates or deletes a single project, extension or item.
+// The body has the format of the request body of the corresponding endpoint.
+// ID and body may reference a field of the resource of an earlier operation by its ref, e.g. "$core.id".
+// Strings starting with "$$" are not resolved but passed on with a single "$".
+// If a version is given, the operation fails unless the resource still has this version.
+type BatchOperation struct {
+	Ref     string          `json:"ref,omitempty"`
+	Action  string          `json:"action"`
+	Type    string          `json:"type"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Version *int32          `json:"version,omitempty"`
+	Body    json.RawMessage `json:"body,omitempty"`
+}
+
+// BatchResult is the result of a single operation of a batch.
+type BatchResult struct {
+	Index    int    `json:"index"`
+	Ref      string `json:"ref,omitempty"`
+	Action   string `json:"action"`
+	Type     string `json:"type"`
+	Status   int    `json:"status,omitempty"`
+	Resource any    `json:"resource,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// batchError aborts a batch. The status and message are reported for the failed operation,
+// together with the invalid fields of its body if it has not been executed because of them.
+type batchError struct {
+	status  int
+	message string
+	fields  []validator.FieldError
+}
+
+func (e *batchError) Error() string {
+	return e.message
+}
+
+func newBatchError(status int, format string, args ...any) *batchError {
+	return &batchError{status: status, message: fmt.Sprintf(format, args...)}
+}
+
+// newBatchValidationError reports the invalid fields of the body of an operation.
+func newBatchValidationError(resource string, fields []validator.FieldError) *batchError {
+	return &batchError{status: http.StatusBadRequest, message: "invalid " + resource, fields: fields}
+}
+
+// batch handles the POST request to execute an ordered list of operations in a single database transaction.
+// Every operation creates, updates or deletes a project, extension or item, see BatchOperation.
+//   - If the request is invalid, e.g. empty or with duplicate refs, it returns a 400 Bad Request.
+//   - If all operations succeed, the transaction is committed and it returns a 200 OK with the result
+//     of every operation, including the created or updated resource.
+//   - If an operation fails, the transaction is rolled back. The response has the status of the failed
+//     operation, e.g. 404 Not Found, and reports the error together with the operations rolled back.
+func (app *application) batch(w http.ResponseWriter, r *http.Request) {
+	var req BatchRequest
+	err := app.readJSON(w, r, &req)
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Bad Request: Invalid JSON request: %v", err))
+		app.invalidBodyResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(len(req.Operations) >= 1 && len(req.Operations) <= maxBatchOperations, "operations", fmt.Sprintf("must contain 1 to %d operations", maxBatchOperations))
+
+	refs := make(map[string]map[string]any)
+	for i, op := range req.Operations {
+		if op.Ref == "" {
+			continue
+		}
+		_, duplicate := refs[op.Ref]
+		v.Check(!duplicate && !strings.ContainsAny(op.Ref, "$."), fmt.Sprintf("operations[%d].ref", i), fmt.Sprintf("%q is invalid or not unique", op.Ref))
+		refs[op.Ref] = nil
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	tx, models, err := app.models.BeginTx()
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+		return
+	}
+	defer tx.Rollback()
+
+	// Permissions are checked inside of the transaction, so resources created by earlier operations are found.
+	access := app.access(r)
+	access.models = models
+
+	app.logger.Debug().Msg(fmt.Sprintf("executing batch with %d operations", len(req.Operations)))
+	results := make([]BatchResult, 0, len(req.Operations))
+	changes := make([]pendingChange, 0, len(req.Operations))
+	for i, op := range req.Operations {
+		result := BatchResult{Index: i, Ref: op.Ref, Action: op.Action, Type: op.Type}
+
+		var before any
+		var cascaded []pendingChange
+		result.Status, before, result.Resource, err = app.executeBatchOperation(models, access, op, refs, &cascaded)
+		if err != nil {
+			_ = tx.Rollback()
+			app.batchFailed(w, r, result, results, err)
+			return
+		}
+		changes = append(changes, cascaded...)
+		changes = append(changes, pendingChange{typ: op.Type, action: batchChangeActions[op.Action], before: before, after: result.Resource})
+
+		if op.Ref != "" && result.Resource != nil {
+			refs[op.Ref], err = batchFields(result.Resource)
+			if err != nil {
+				_ = tx.Rollback()
+				app.batchFailed(w, r, result, results, err)
+				return
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	err = app.commitChanges(r, tx, models, changes...)
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while committing batch: %v", err))
+		app.serverErrorResponse(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"results": results}, nil)
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+		return
+	}
+}
+
+// batchFailed answers a batch whose operation failed with err after the transaction has been rolled back.
+// The problem details have the status of the failed operation and report it as member "operation",
+// together with the operations executed before, which have been rolled back, as member "rolled_back".
+func (app *application) batchFailed(w http.ResponseWriter, r *http.Request, failed BatchResult, executed []BatchResult, err error) {
+	var fields []validator.FieldError
+	var batchErr *batchError
+	var forbiddenErr *forbiddenError
+	switch {
+	case errors.As(err, &batchErr):
+		failed.Status = batchErr.status
+		failed.Error = batchErr.message
+		fields = batchErr.fields
+	case errors.As(err, &forbiddenErr):
+		failed.Status = http.StatusForbidden
+		failed.Error = forbiddenErr.message
+	default:
+		app.logger.Error().Msg(fmt.Sprintf("Error while executing operation %d of batch: %v", failed.Index, err))
+		failed.Status = http.StatusInternalServerError
+		failed.Error = http.StatusText(http.StatusInternalServerError)
+	}
+	failed.Resource = nil
+
+	rolledBack := make([]BatchResult, len(executed))
+	for i, result := range executed {
+		rolledBack[i] = BatchResult{Index: result.Index, Ref: result.Ref, Action: result.Action, Type: result.Type}
+	}
+
+	app.writeProblem(w, r, problem{
+		Status:     failed.Status,
+		Detail:     fmt.Sprintf("operation %d failed: %s", failed.Index, failed.Error),
+		Errors:     fields,
+		Extensions: map[string]any{"operation": failed, "rolled_back": rolledBack},
+	})
+}
+
+// executeBatchOperation resolves the references of op and executes it with models if access permits it.
+// The deletions of the items and extensions deleted together with an extension or project are stored in cascaded.
+// Returns: The status of the operation and the resource before and after it, or an error which aborts the batch.
+// The resource before is nil for created resources, the resource after is nil for deleted ones.
+func (app *application) executeBatchOperation(models data.Models, access *access, op BatchOperation, refs map[string]map[string]any, cascaded *[]pendingChange) (int, any, any, error) {
+	body, err := resolveBatchReferences(op.Body, refs)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	var id int64
+	switch op.Action {
+	case batchCreate:
+		if len(op.ID) > 0 {
+			return 0, nil, nil, newBatchError(http.StatusBadRequest, "id must not be set for action %q", op.Action)
+		}
+	case batchUpdate, batchDelete:
+		resolved, err := resolveBatchReferences(op.ID, refs)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		if json.Unmarshal(resolved, &id) != nil || id < 1 {
+			return 0, nil, nil, newBatchError(http.StatusBadRequest, "id must be a positive integer or a reference")
+		}
+	default:
+		return 0, nil, nil, newBatchError(http.StatusBadRequest, "invalid action %q", op.Action)
+	}
+
+	app.logger.Debug().Msg(fmt.Sprintf("executing batch operation %s %s %d", op.Action, op.Type, id))
+	switch op.Type {
+	case batchProject:
+		return batchProjectOperation(models, access, op.Action, id, op.Version, body, cascaded)
+	case batchExtension:
+		return batchExtensionOperation(models, access, op.Action, id, op.Version, body, cascaded)
+	case batchItem:
+		return batchItemOperation(models, access, op.Action, id, op.Version, body)
+	default:
+		return 0, nil, nil, newBatchError(http.StatusBadRequest, "invalid type %q", op.Type)
+	}
+}
+
+func batchProjectOperation(models data.Models, access *access, action string, id int64, version *int32, body json.RawMessage, cascaded *[]pendingChange) (int, any, any, error) {
+	if action == batchCreate {
+		var req ProjectRequest
+		if err := decodeBatchBody(body, &req); err != nil {
+			return 0, nil, nil, err
+		}
+		v := validator.New()
+		if req.validate(v); !v.Valid() {
+			return 0, nil, nil, newBatchValidationError(batchProject, v.Errors)
+		}
+
+		if err := access.requireGlobalAdmin("create projects"); err != nil {
+			return 0, nil, nil, err
+		}
+
+		project := data.Project{Name: req.Name, Description: req.Description}
+		return http.StatusCreated, nil, &project, models.Projects.Insert(&project)
+	}
+
+	project, err := models.Projects.Read(id)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		return 0, nil, nil, newBatchError(http.StatusNotFound, "no project with id %d found", id)
+	}
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	if err := access.requireProjectRole(project.ID, data.RoleAdmin); err != nil {
+		return 0, nil, nil, err
+	}
+	if err := checkBatchVersion(version, project.Version); err != nil {
+		return 0, nil, nil, err
+	}
+
+	if action == batchDelete {
+		extensions, items, err := models.Projects.Delete(id, project.Version, models.Items)
+		if err != nil {
+			return 0, nil, nil, batchEditConflict(err)
+		}
+		*cascaded = cascadedChanges(extensions, items)
+		return http.StatusNoContent, project, nil, nil
+	}
+
+	var req ProjectUpdateRequest
+	if err := decodeBatchBody(body, &req); err != nil {
+		return 0, nil, nil, err
+	}
+	v := validator.New()
+	if req.validate(v); !v.Valid() {
+		return 0, nil, nil, newBatchValidationError(batchProject, v.Errors)
+	}
+	before := *project
+	if err := models.Projects.Update(project, req.Name, req.Description); err != nil {
+		return 0, nil, nil, batchEditConflict(err)
+	}
+	if req.Name != "" {
+		project.Name = req.Name
+	}
+	project.Description = req.Description
+	return http.StatusOK, &before, project, nil
+}
+
+func batchExtensionOperation(models data.Models, access *access, action string, id int64, version *int32, body json.RawMessage, cascaded *[]pendingChange) (int, any, any, error) {
+	if action == batchCreate {
+		var req ExtensionRequest
+		if err := decodeBatchBody(body, &req); err != nil {
+			return 0, nil, nil, err
+		}
+		v := validator.New()
+		if req.validate(v); !v.Valid() {
+			return 0, nil, nil, newBatchValidationError(batchExtension, v.Errors)
+		}
+		if req.ProjectID != 0 {
+			_, err := models.Projects.Read(req.ProjectID)
+			if errors.Is(err, data.ErrRecordNotFound) {
+				return 0, nil, nil, newBatchError(http.StatusNotFound, "no project with id %d found", req.ProjectID)
+			}
+			if err != nil {
+				return 0, nil, nil, err
+			}
+		}
+
+		extension := data.Extension{
+			Name:        req.Name,
+			Scope:       req.Scope,
+			Description: req.Description,
+			ProjectID:   data.NullInt64{NullInt64: sql.NullInt64{Int64: req.ProjectID, Valid: req.ProjectID != 0}},
+		}
+		if err := access.requireExtensionRole(&extension, data.RoleAdmin); err != nil {
+			return 0, nil, nil, err
+		}
+		return http.StatusCreated, nil, &extension, models.Extensions.Insert(&extension)
+	}
+
+	extension, err := models.Extensions.Read(id)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		return 0, nil, nil, newBatchError(http.StatusNotFound, "no extension with id %d found", id)
+	}
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	if err := access.requireExtensionRole(extension, data.RoleAdmin); err != nil {
+		return 0, nil, nil, err
+	}
+	if err := checkBatchVersion(version, extension.Version); err != nil {
+		return 0, nil, nil, err
+	}
+
+	if action == batchDelete {
+		items, err := models.Extensions.DeleteWithItems(id, extension.Version, models.Items)
+		if err != nil {
+			return 0, nil, nil, batchEditConflict(err)
+		}
+		*cascaded = cascadedChanges(nil, items)
+		return http.StatusNoContent, extension, nil, nil
+	}
+
+	var req ExtensionUpdateRequest
+	if err := decodeBatchBody(body, &req); err != nil {
+		return 0, nil, nil, err
+	}
+	v := validator.New()
+	if req.validate(v); !v.Valid() {
+		return 0, nil, nil, newBatchValidationError(batchExtension, v.Errors)
+	}
+	before := *extension
+	if err := models.Extensions.Update(extension, req.Name, req.Description); err != nil {
+		return 0, nil, nil, batchEditConflict(err)
+	}
+	if req.Name != "" {
+		extension.Name = req.Name
+	}
+	extension.Description = req.Description
+	return http.StatusOK, &before, extension, nil
+}
+
+func batchItemOperation(models data.Models, access *access, action string, id int64, version *int32, body json.RawMessage) (int, any, any, error) {
+	if action == batchCreate {
+		var req ItemRequest
+		if err := decodeBatchBody(body, &req); err != nil {
+			return 0, nil, nil, err
+		}
+		v := validator.New()
+		if req.validate(v); !v.Valid() {
+			return 0, nil, nil, newBatchValidationError(batchItem, v.Errors)
+		}
+
+		extension, err := models.Extensions.Read(req.ExtensionId)
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return 0, nil, nil, newBatchError(http.StatusNotFound, "could not find extension with id %d in database", req.ExtensionId)
+		}
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		if err := access.requireExtensionRole(extension, data.RoleDeveloper); err != nil {
+			return 0, nil, nil, err
+		}
+
+		typecode, err := calculateTypecode(extension, &models.Items)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+
+		item := data.Item{
+			Name:        req.Name,
+			TableName:   req.TableName,
+			ExtensionID: req.ExtensionId,
+			Typecode:    typecode,
+			Scope:       extension.Scope,
+		}
+		if err := models.Items.Insert(&item); err != nil {
+			return 0, nil, nil, err
+		}
+		if item.Scope == data.ScopeProject && extension.ProjectID.Valid {
+			if err := models.Projects.ReadProjectName(extension.ProjectID.Int64, &item.Project); err != nil {
+				return 0, nil, nil, err
+			}
+		}
+		return http.StatusCreated, nil, &item, nil
+	}
+
+	item, err := models.Items.ReadItem(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil, nil, newBatchError(http.StatusNotFound, "no item detail with id %d found", id)
+	}
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	if err := access.requireItemRole(&item, data.RoleDeveloper); err != nil {
+		return 0, nil, nil, err
+	}
+	if err := checkBatchVersion(version, item.Version); err != nil {
+		return 0, nil, nil, err
+	}
+
+	if action == batchDelete {
+		return http.StatusNoContent, &item, nil, batchEditConflict(models.Items.DeleteItem(id, item.Version))
+	}
+
+	var req data.Item
+	if err := decodeBatchBody(body, &req); err != nil {
+		return 0, nil, nil, err
+	}
+	v := validator.New()
+	if validateItemUpdate(v, &req, id); !v.Valid() {
+		return 0, nil, nil, newBatchValidationError(batchItem, v.Errors)
+	}
+	before := item
+	item.Name = req.Name
+	item.TableName = req.TableName
+	if err := models.Items.UpdateItem(&item); err != nil {
+		return 0, nil, nil, batchEditConflict(err)
+	}
+	return http.StatusOK, &before, &item, nil
+}
+
+// decodeBatchBody decodes the body of a batch operation into dst.
+func decodeBatchBody(body json.RawMessage, dst any) error {
+	if len(body) == 0 {
+		return newBatchError(http.StatusBadRequest, "body is required")
+	}
+	if err := json.Unmarshal(body, dst); err != nil {
+		return newBatchError(http.StatusBadRequest, "invalid body: %v", err)
+	}
+	return nil
+}
+
+// checkBatchVersion fails with 412 Precondition Failed if a version is given which differs from the current one.
+func checkBatchVersion(version *int32, current int32) error {
+	if version != nil && *version != current {
+		return newBatchError(http.StatusPreconditionFailed, "version %d does not match the current version %d", *version, current)
+	}
+	return nil
+}
+
+// batchEditConflict turns data.ErrEditConflict into a 409 Conflict and returns all other errors unchanged.
+func batchEditConflict(err error) error {
+	if errors.Is(err, data.ErrEditConflict) {
+		return newBatchError(http.StatusConflict, "the resource has been changed concurrently")
+	}
+	return err
+}
+
+// batchFields returns the top level fields of the JSON representation of a resource, so they can be referenced.
+func batchFields(resource any) (map[string]any, error) {
+	js, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]any
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.UseNumber()
+	err = dec.Decode(&fields)
+	return fields, err
+}
+
+// resolveBatchReferences replaces all references like "$core.id" in raw with the referenced values.
+func resolveBatchReferences(raw json.RawMessage, refs map[string]map[string]any) (json.RawMessage, error) {
+	if len(raw) == 0 {
+		return raw, nil
+	}
+
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, newBatchError(http.StatusBadRequest, "invalid JSON: %v", err)
+	}
+
+	v, err := resolveBatchValue(v, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(v)
+}
+
+func resolveBatchValue(v any, refs map[string]map[string]any) (any, error) {
+	var err error
+	switch v := v.(type) {
+	case string:
+		if !strings.HasPrefix(v, "$") {
+			return v, nil
+		}
+		if strings.HasPrefix(v, "$$") {
+			return v[1:], nil
+		}
+
+		ref, field, _ := strings.Cut(v[1:], ".")
+		fields, ok := refs[ref]
+		if !ok || fields == nil {
+			return nil, newBatchError(http.StatusBadRequest, "reference %q does not refer to an earlier operation with a result", v)
+		}
+		value, ok := fields[field]
+		if !ok {
+			return nil, newBatchError(http.StatusBadRequest, "reference %q refers to an unknown field", v)
+		}
+		return value, nil
+	case map[string]any:
+		for key, value := range v {
+			if v[key], err = resolveBatchValue(value, refs); err != nil {
+				return nil, err
+			}
+		}
+	case []any:
+		for i, value := range v {
+			if v[i], err = resolveBatchValue(value, refs); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func serveBatch(app *application, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body))
+	resp := httptest.NewRecorder()
+	app.route().ServeHTTP(resp, req)
+	return resp
+}
+
+func TestBatchCreatesResourcesWithReferencesInOneTransaction(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO project (name, description)`)).
+		WithArgs("Alpha", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "creation_date", "version"}).AddRow(4, time.Now(), 1))
+	mockReadProjectByIDQuery(mock, 4, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}).
+		AddRow(4, "Alpha", "", time.Now(), 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO extension (name, description, scope, project_id)`)).
+		WithArgs("core", "", "Project", 4).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "creation_date", "version"}).AddRow(9, time.Now(), 1))
+	setupExtensionMock(mock, 9, sql.NullInt64{Int64: 4, Valid: true}, "Project", "core", "", 0, true)
+	setupNextFreeTypecodeMock(mock, 4, 14000, 19999, 14000)
+	setupInsertItemMock(mock, "Product", 9, "products", 14000)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT name FROM project WHERE id = $1`)).
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Alpha"))
+	mock.ExpectCommit()
+
+	resp := serveBatch(app, `{"operations": [
+		{"ref": "alpha", "action": "create", "type": "project", "body": {"name": "Alpha"}},
+		{"ref": "core", "action": "create", "type": "extension", "body": {"name": "core", "scope": "Project", "project_id": "$alpha.id"}},
+		{"action": "create", "type": "item", "body": {"name": "Product", "table_name": "products", "extension_id": "$core.id"}}
+	]}`)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var body struct {
+		Results []struct {
+			Index    int            `json:"index"`
+			Status   int            `json:"status"`
+			Resource map[string]any `json:"resource"`
+		} `json:"results"`
+	}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	assert.Len(t, body.Results, 3)
+	assert.Equal(t, http.StatusCreated, body.Results[2].Status)
+	assert.Equal(t, float64(14000), body.Results[2].Resource["typecode"])
+	assert.Equal(t, "Alpha", body.Results[2].Resource["project"])
+	checkExpectations(t, mock)
+}
+
+func TestBatchRollsBackWhenAnOperationFails(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO project (name, description)`)).
+		WithArgs("Alpha", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "creation_date", "version"}).AddRow(4, time.Now(), 1))
+	mockReadItemByItemIdNoRowsFound(mock, 99)
+	mock.ExpectRollback()
+
+	resp := serveBatch(app, `{"operations": [
+		{"ref": "alpha", "action": "create", "type": "project", "body": {"name": "Alpha"}},
+		{"action": "delete", "type": "item", "id": 99}
+	]}`)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.Equal(t, "application/problem+json", resp.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"type": "about:blank",
+		"title": "Not Found",
+		"status": 404,
+		"detail": "operation 1 failed: no item detail with id 99 found",
+		"instance": "/batch",
+		"code": "not_found",
+		"operation": {"index": 1, "action": "delete", "type": "item", "status": 404, "error": "no item detail with id 99 found"},
+		"rolled_back": [{"index": 0, "ref": "alpha", "action": "create", "type": "project"}]
+	}`, resp.Body.String())
+	checkExpectations(t, mock)
+}
+
+func TestBatchChecksVersionsOfUpdatedResources(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+
+	mock.ExpectBegin()
+	mockReadItemDetailByItemIdQuery(mock, 1, newItemRows(1, 3))
+	mock.ExpectRollback()
+
+	resp := serveBatch(app, `{"operations": [{"action": "update", "type": "item", "id": 1, "version": 2, "body": {"name": "A", "table_name": "a"}}]}`)
+
+	assert.Equal(t, http.StatusPreconditionFailed, resp.Code)
+	checkExpectations(t, mock)
+}
+
+func TestBatchRejectsInvalidRequests(t *testing.T) {
+	testCases := map[string]string{
+		"no operations":  `{"operations": []}`,
+		"duplicate refs": `{"operations": [{"ref": "a", "action": "create", "type": "project"}, {"ref": "a", "action": "create", "type": "project"}]}`,
+		"invalid ref":    `{"operations": [{"ref": "a.b", "action": "create", "type": "project"}]}`,
+		"no JSON":        `operations`,
+	}
+
+	for name, body := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, mock, app := setupMockAndApp(t)
+
+			resp := serveBatch(app, body)
+
+			assert.Equal(t, http.StatusBadRequest, resp.Code)
+			checkExpectations(t, mock)
+		})
+	}
+}
+
+func TestBatchRejectsUnresolvableReferences(t *testing.T) {
+	testCases := map[string]string{
+		"later operation": `{"operations": [{"action": "delete", "type": "project", "id": "$b.id"}, {"ref": "b", "action": "create", "type": "project", "body": {"name": "B"}}]}`,
+		"unknown ref":     `{"operations": [{"action": "create", "type": "extension", "body": {"name": "core", "scope": "Project", "project_id": "$missing.id"}}]}`,
+		"invalid action":  `{"operations": [{"action": "upsert", "type": "project"}]}`,
+		"invalid type":    `{"operations": [{"action": "create", "type": "typecode", "body": {}}]}`,
+	}
+
+	for name, body := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, mock, app := setupMockAndApp(t)
+			mock.ExpectBegin()
+			mock.ExpectRollback()
+
+			resp := serveBatch(app, body)
+
+			assert.Equal(t, http.StatusBadRequest, resp.Code)
+			checkExpectations(t, mock)
+		})
+	}
+}
+
+func TestResolveBatchReferences(t *testing.T) {
+	refs := map[string]map[string]any{"core": {"id": json.Number("9"), "name": "core"}}
+
+	resolved, err := resolveBatchReferences(json.RawMessage(`{"extension_id": "$core.id", "names": ["$core.name"], "price": "$$5"}`), refs)
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"extension_id": 9, "names": ["core"], "price": "$5"}`, string(resolved))
+}
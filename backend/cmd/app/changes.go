@@ -0,0 +1,441 @@
+package main
+
+import (
+	"Typecode-Registry/internal/data"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Types of the resources of changes.
+const (
+	changeItem      = "item"
+	changeExtension = "extension"
+	changeProject   = "project"
+)
+
+// Actions of changes.
+const (
+	changeCreated = "created"
+	changeUpdated = "updated"
+	changeDeleted = "deleted"
+)
+
+const (
+	// changeSubscriberBuffer is the number of changes a subscriber may lag behind before it is dropped.
+	changeSubscriberBuffer = 256
+	// changeKeepAliveInterval is the interval of the comments sent to keep idle change streams open.
+	changeKeepAliveInterval = 30 * time.Second
+	// changeRetryMillis is the reconnection delay suggested to clients of the change stream.
+	changeRetryMillis = 3000
+)
+
+// Change is an event of the change feed: a project, extension or item has been created, updated or deleted.
+// The resource is its new state, or its last state if it has been deleted. Deleting an extension or project
+// deletes its items and extensions as well, each with a change of its own before the change of the extension or project.
+type Change struct {
+	ID     int64  `json:"id"`
+	Type   string `json:"type"`
+	Action string `json:"action"`
+	// ProjectID is the project of the resource, or 0 if it belongs to no project.
+	ProjectID int64     `json:"project_id,omitempty"`
+	Time      time.Time `json:"time"`
+	Resource  any       `json:"resource"`
+}
+
+// Event returns the name of the server-sent event of the change, e.g. item.created.
+func (c Change) Event() string {
+	return c.Type + "." + c.Action
+}
+
+// changeFeed distributes changes to its subscribers and keeps the most recent ones, so a subscriber
+// which reconnects with the ID of the last change it received misses none of the changes in between.
+//
+// IDs are consecutive and start at the time the feed is created in microseconds, so the IDs of a restarted
+// server are greater than all earlier ones and a client resuming with an ID of an earlier run is detected.
+type changeFeed struct {
+	mu          sync.Mutex
+	recent      []Change
+	size        int
+	lastID      int64
+	subscribers map[*changeSubscription]struct{}
+	// extensionProjects maps extensions to their projects, which never change, to find the project of items.
+	extensionProjects map[int64]int64
+}
+
+// changeSubscription receives the changes published after it has been created.
+// C is closed if the subscriber lags behind too far or unsubscribes.
+type changeSubscription struct {
+	C chan Change
+}
+
+// newChangeFeed returns a feed which keeps the last size changes.
+func newChangeFeed(size int) *changeFeed {
+	return &changeFeed{
+		size:              size,
+		lastID:            time.Now().UnixMicro(),
+		subscribers:       make(map[*changeSubscription]struct{}),
+		extensionProjects: make(map[int64]int64),
+	}
+}
+
+// publish assigns the next ID to c, keeps it and passes it to all subscribers.
+// Subscribers whose buffer is full are dropped instead of blocking the publisher.
+func (f *changeFeed) publish(c Change) Change {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.lastID++
+	c.ID = f.lastID
+	if c.Time.IsZero() {
+		c.Time = time.Now().UTC()
+	}
+
+	if len(f.recent) == f.size {
+		f.recent = append(f.recent[:0], f.recent[1:]...)
+	}
+	f.recent = append(f.recent, c)
+
+	for sub := range f.subscribers {
+		select {
+		case sub.C <- c:
+		default:
+			delete(f.subscribers, sub)
+			close(sub.C)
+		}
+	}
+
+	return c
+}
+
+// subscribe returns a subscription to the changes after lastID together with the kept changes after lastID.
+// If lastID is 0, no changes are replayed. It also reports whether the replayed changes are complete,
+// which is not the case if lastID is unknown or changes after it are no longer kept.
+func (f *changeFeed) subscribe(lastID int64) (*changeSubscription, []Change, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sub := &changeSubscription{C: make(chan Change, changeSubscriberBuffer)}
+	f.subscribers[sub] = struct{}{}
+
+	if lastID == 0 {
+		return sub, nil, true
+	}
+
+	oldest := f.lastID - int64(len(f.recent)) + 1
+	if lastID < oldest-1 || lastID > f.lastID {
+		return sub, nil, false
+	}
+
+	missed := make([]Change, 0, f.lastID-lastID)
+	missed = append(missed, f.recent[lastID-oldest+1:]...)
+	return sub, missed, true
+}
+
+// unsubscribe stops passing changes to sub and closes its channel.
+func (f *changeFeed) unsubscribe(sub *changeSubscription) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.subscribers[sub]; ok {
+		delete(f.subscribers, sub)
+		close(sub.C)
+	}
+}
+
+// latestID returns the ID of the last published change.
+func (f *changeFeed) latestID() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.lastID
+}
+
+// extensionProject returns the project of the extension with the given ID and whether it is known.
+func (f *changeFeed) extensionProject(extensionID int64) (int64, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	projectID, ok := f.extensionProjects[extensionID]
+	return projectID, ok
+}
+
+// rememberExtension keeps the project of the extension for the changes of its items.
+func (f *changeFeed) rememberExtension(extension *data.Extension) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.extensionProjects[extension.ID] = extension.ProjectID.Int64
+}
+
+// rememberExtension keeps the project of extension for the changes of its items if the change feed is enabled.
+func (app *application) rememberExtension(extension *data.Extension) {
+	if app.changes != nil {
+		app.changes.rememberExtension(extension)
+	}
+}
+
+// pendingChange is a change which is audited inside of the transaction making it and published once it has been committed.
+// before is nil for created resources, after is nil for deleted ones.
+type pendingChange struct {
+	typ    string
+	action string
+	before any
+	after  any
+}
+
+// resource returns the resource of the change feed: the new state, or the last state of a deleted resource.
+func (c pendingChange) resource() any {
+	if c.after != nil {
+		return c.after
+	}
+	return c.before
+}
+
+// beginChanges starts the transaction of a change which is audited, so the change and its audit entries
+// are committed together by commitChanges. Without auditing, a single statement needs no transaction:
+// it returns no transaction and app.models.
+func (app *application) beginChanges() (*sql.Tx, data.Models, error) {
+	if !app.config.audit {
+		return nil, app.models, nil
+	}
+	return app.models.BeginTx()
+}
+
+// rollbackChanges rolls back tx unless it is nil, i.e. the change was made without transaction.
+// After a commit, the rollback does nothing.
+func rollbackChanges(tx *sql.Tx) {
+	if tx != nil {
+		_ = tx.Rollback()
+	}
+}
+
+// commitAudited writes the changes of the request r to the audit log with models, which are bound to tx,
+// and commits tx. A nil tx is not committed.
+// Returns: An error if the audit entries cannot be written or tx cannot be committed, the caller rolls tx back.
+func (app *application) commitAudited(r *http.Request, tx *sql.Tx, models data.Models, changes ...pendingChange) error {
+	if err := app.auditChanges(r, models, changes...); err != nil {
+		return err
+	}
+	if tx != nil {
+		return tx.Commit()
+	}
+	return nil
+}
+
+// commitChanges commits the changes of the request r together with their audit entries, see commitAudited.
+// Once committed, the changes are published to the change feed.
+func (app *application) commitChanges(r *http.Request, tx *sql.Tx, models data.Models, changes ...pendingChange) error {
+	if err := app.commitAudited(r, tx, models, changes...); err != nil {
+		return err
+	}
+	app.publishChanges(changes...)
+	return nil
+}
+
+// commitChange commits a single change, see commitChanges.
+func (app *application) commitChange(r *http.Request, tx *sql.Tx, models data.Models, typ, action string, before, after any) error {
+	return app.commitChanges(r, tx, models, pendingChange{typ: typ, action: action, before: before, after: after})
+}
+
+// cascadedChanges returns the deletions of the items and extensions deleted together with an extension or project,
+// items first, which are recorded before the deletion of the extension or project itself.
+func cascadedChanges(extensions []*data.Extension, items []data.Item) []pendingChange {
+	changes := make([]pendingChange, 0, len(items)+len(extensions)+1)
+	for i := range items {
+		changes = append(changes, pendingChange{typ: changeItem, action: changeDeleted, before: &items[i]})
+	}
+	for _, extension := range extensions {
+		changes = append(changes, pendingChange{typ: changeExtension, action: changeDeleted, before: extension})
+	}
+	return changes
+}
+
+// publishChanges publishes the changes in their order if the change feed is enabled.
+// The projects of the extensions are remembered first, so items of extensions deleted
+// by the same transaction are still assigned to their project.
+func (app *application) publishChanges(changes ...pendingChange) {
+	if app.changes == nil {
+		return
+	}
+
+	for _, c := range changes {
+		if extension, ok := c.resource().(*data.Extension); ok {
+			app.changes.rememberExtension(extension)
+		}
+	}
+
+	for _, c := range changes {
+		resource := c.resource()
+		app.changes.publish(Change{Type: c.typ, Action: c.action, ProjectID: app.resourceProject(app.models, resource), Resource: resource})
+	}
+}
+
+// resourceProject returns the project of a project, extension, item or role assignment,
+// or 0 if it belongs to no project. Extensions of items are read with models.
+func (app *application) resourceProject(models data.Models, resource any) int64 {
+	switch resource := resource.(type) {
+	case *data.Project:
+		return resource.ID
+	case *data.Extension:
+		return resource.ProjectID.Int64
+	case *data.Item:
+		return app.itemProject(models, resource)
+	case *data.RoleAssignment:
+		return resource.ProjectID
+	}
+	return 0
+}
+
+// itemProject returns the project of the extension of item, reading the extension if its project is not yet known.
+// Returns: 0 if the item belongs to no project or the extension cannot be read.
+func (app *application) itemProject(models data.Models, item *data.Item) int64 {
+	if item.Scope != "" && item.Scope != data.ScopeProject {
+		return 0
+	}
+	if app.changes != nil {
+		if projectID, ok := app.changes.extensionProject(item.ExtensionID); ok {
+			return projectID
+		}
+	}
+
+	extension, err := models.Extensions.Read(item.ExtensionID)
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while reading extension %d of changed item %d: %v", item.ExtensionID, item.ID, err))
+		return 0
+	}
+	app.rememberExtension(extension)
+	return extension.ProjectID.Int64
+}
+
+// readLastEventID returns the ID of the last change received by the client from the Last-Event-ID header,
+// which browsers send when they reconnect, or from the query parameter last_event_id for the first connection.
+// Returns: 0 if neither is set, or an error if the ID is not a positive integer.
+func readLastEventID(r *http.Request) (int64, error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+	if value == "" {
+		return 0, nil
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid last event id %q", value)
+	}
+	return id, nil
+}
+
+// streamChanges handles the GET request for the change feed as server-sent events.
+// Every change is sent as event named after type and action, e.g. item.created, with the change as JSON data
+// and its ID as event ID. With project_id only changes of the project, its extensions and their items are sent.
+// A client which resumes with the Last-Event-ID header first receives the changes it missed. If they are
+// no longer kept, it receives a reset event instead and has to reload the resources it shows.
+//   - If project_id or the last event ID is invalid, it returns a 400 Bad Request.
+//   - If the project does not exist, it returns a 404 Not Found.
+//   - If the change feed is disabled, it returns a 503 Service Unavailable.
+func (app *application) streamChanges(w http.ResponseWriter, r *http.Request) {
+	if app.changes == nil {
+		app.errorResponse(w, r, http.StatusServiceUnavailable, codeChangeFeedDisabled, "the change feed is disabled")
+		return
+	}
+
+	projectID, err := app.readInt(r.URL.Query(), "project_id", 0)
+	if err == nil && projectID < 0 {
+		err = errors.New("project_id must be a positive integer")
+	}
+	if err != nil {
+		app.invalidParameterResponse(w, r, err)
+		return
+	}
+
+	lastID, err := readLastEventID(r)
+	if err != nil {
+		app.invalidParameterResponse(w, r, err)
+		return
+	}
+
+	if projectID != 0 {
+		if _, ok := app.readProject(w, r, int64(projectID)); !ok {
+			return
+		}
+	}
+
+	sub, missed, complete := app.changes.subscribe(lastID)
+	defer app.changes.unsubscribe(sub)
+
+	// The stream stays open longer than the write timeout of the server.
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	app.logger.Debug().Msg(fmt.Sprintf("streaming changes of project %d after %d", projectID, lastID))
+
+	_, err = fmt.Fprintf(w, "retry: %d\n\n", changeRetryMillis)
+	if err == nil && !complete {
+		err = writeResetEvent(w, app.changes.latestID())
+	}
+	for _, c := range missed {
+		if err != nil {
+			break
+		}
+		if projectID == 0 || c.ProjectID == int64(projectID) {
+			err = writeChangeEvent(w, c)
+		}
+	}
+	if err == nil {
+		err = rc.Flush()
+	}
+
+	keepAlive := time.NewTicker(changeKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for err == nil {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			_, err = io.WriteString(w, ": keep-alive\n\n")
+		case c, ok := <-sub.C:
+			if !ok {
+				// The client lags behind too far, it resumes with its last event ID after reconnecting.
+				app.logger.Info().Msg("closing change stream of slow client")
+				return
+			}
+			if projectID != 0 && c.ProjectID != int64(projectID) {
+				continue
+			}
+			err = writeChangeEvent(w, c)
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+	}
+
+	app.logger.Debug().Msg(fmt.Sprintf("change stream closed: %v", err))
+}
+
+// writeChangeEvent writes c as server-sent event.
+func writeChangeEvent(w io.Writer, c Change) error {
+	js, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", c.ID, c.Event(), js)
+	return err
+}
+
+// writeResetEvent tells the client that it missed changes which are no longer kept.
+// The event has the ID of the last change, so the client resumes after it when reconnecting.
+func writeResetEvent(w io.Writer, lastID int64) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: reset\ndata: {\"reason\":\"changes after the last event id are no longer available\"}\n\n", lastID)
+	return err
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"Typecode-Registry/internal/data"
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// changeIDs returns the IDs of changes.
+func changeIDs(changes []Change) []int64 {
+	ids := []int64{}
+	for _, c := range changes {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
+
+func TestChangeFeedReplaysMissedChanges(t *testing.T) {
+	feed := newChangeFeed(3)
+	first := feed.publish(Change{Type: changeProject, Action: changeCreated}).ID
+	for i := 0; i < 3; i++ {
+		feed.publish(Change{Type: changeItem, Action: changeCreated})
+	}
+
+	_, missed, complete := feed.subscribe(first + 1)
+	assert.True(t, complete)
+	assert.Equal(t, []int64{first + 2, first + 3}, changeIDs(missed))
+
+	_, missed, complete = feed.subscribe(first)
+	assert.True(t, complete, "the oldest kept change directly follows the last received one")
+	assert.Equal(t, []int64{first + 1, first + 2, first + 3}, changeIDs(missed))
+
+	_, missed, complete = feed.subscribe(first + 3)
+	assert.True(t, complete)
+	assert.Empty(t, missed)
+
+	_, missed, complete = feed.subscribe(0)
+	assert.True(t, complete)
+	assert.Empty(t, missed)
+}
+
+func TestChangeFeedReportsGaps(t *testing.T) {
+	feed := newChangeFeed(2)
+	first := feed.publish(Change{}).ID
+	feed.publish(Change{})
+	feed.publish(Change{})
+
+	_, missed, complete := feed.subscribe(first - 1)
+	assert.False(t, complete, "changes after the last received one are no longer kept")
+	assert.Empty(t, missed)
+
+	_, _, complete = feed.subscribe(first + 10)
+	assert.False(t, complete, "ID of a change not yet published")
+
+	restarted := newChangeFeed(2)
+	restarted.publish(Change{})
+	_, _, complete = restarted.subscribe(first + 2)
+	assert.False(t, complete, "ID of an earlier run of the server")
+}
+
+func TestChangeFeedPassesChangesToSubscribers(t *testing.T) {
+	feed := newChangeFeed(10)
+	sub, _, _ := feed.subscribe(0)
+
+	published := feed.publish(Change{Type: changeExtension, Action: changeUpdated, ProjectID: 2})
+	received := <-sub.C
+	assert.Equal(t, published, received)
+	assert.Equal(t, "extension.updated", received.Event())
+	assert.False(t, received.Time.IsZero())
+
+	feed.unsubscribe(sub)
+	_, open := <-sub.C
+	assert.False(t, open)
+	feed.unsubscribe(sub)
+}
+
+func TestChangeFeedDropsSlowSubscribers(t *testing.T) {
+	feed := newChangeFeed(10)
+	sub, _, _ := feed.subscribe(0)
+
+	for i := 0; i <= changeSubscriberBuffer; i++ {
+		feed.publish(Change{})
+	}
+
+	received := 0
+	for range sub.C {
+		received++
+	}
+	assert.Equal(t, changeSubscriberBuffer, received)
+}
+
+func TestChangesAreAssignedToProjects(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	app.changes = newChangeFeed(10)
+	sub, _, _ := app.changes.subscribe(0)
+
+	setupExtensionMock(mock, 3, sql.NullInt64{Int64: 2, Valid: true}, data.ScopeProject, "core", "", 1, true)
+	app.publishChanges(
+		pendingChange{typ: changeProject, action: changeUpdated, after: &data.Project{ID: 2}},
+		pendingChange{typ: changeItem, action: changeDeleted, before: &data.Item{ID: 7, ExtensionID: 4, Scope: data.ScopeProject}},
+		pendingChange{typ: changeExtension, action: changeDeleted, before: &data.Extension{ID: 4, ProjectID: data.NullInt64{NullInt64: sql.NullInt64{Int64: 5, Valid: true}}}},
+		pendingChange{typ: changeItem, action: changeUpdated, after: &data.Item{ID: 8, ExtensionID: 3, Scope: data.ScopeProject}},
+		pendingChange{typ: changeItem, action: changeCreated, after: &data.Item{ID: 9, ExtensionID: 3, Scope: data.ScopeProject}},
+		pendingChange{typ: changeItem, action: changeCreated, after: &data.Item{ID: 10, ExtensionID: 1, Scope: data.ScopeShared}},
+	)
+
+	var projects []int64
+	for i := 0; i < 6; i++ {
+		projects = append(projects, (<-sub.C).ProjectID)
+	}
+	assert.Equal(t, []int64{2, 5, 5, 2, 2, 0}, projects)
+	checkExpectations(t, mock)
+}
+
+func TestCreatedProjectIsPublished(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	app.changes = newChangeFeed(10)
+	sub, _, _ := app.changes.subscribe(0)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO project (name, description)`)).
+		WithArgs("Alpha", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "creation_date", "version"}).AddRow(4, time.Now(), 1))
+
+	req := httptest.NewRequest(http.MethodPost, "/projects", strings.NewReader(`{"name": "Alpha"}`))
+	resp := httptest.NewRecorder()
+	app.handler().ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusCreated, resp.Code)
+
+	change := <-sub.C
+	assert.Equal(t, "project.created", change.Event())
+	assert.Equal(t, int64(4), change.ProjectID)
+	assert.Equal(t, "Alpha", change.Resource.(*data.Project).Name)
+	checkExpectations(t, mock)
+}
+
+func TestChangesOfFailedBatchAreNotPublished(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	app.changes = newChangeFeed(10)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO project (name, description)`)).
+		WithArgs("Alpha", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "creation_date", "version"}).AddRow(4, time.Now(), 1))
+	mockReadItemByItemIdNoRowsFound(mock, 99)
+	mock.ExpectRollback()
+
+	before := app.changes.latestID()
+	resp := serveBatch(app, `{"operations": [
+		{"action": "create", "type": "project", "body": {"name": "Alpha"}},
+		{"action": "delete", "type": "item", "id": 99}
+	]}`)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.Equal(t, before, app.changes.latestID())
+	checkExpectations(t, mock)
+}
+
+func TestStreamChangesRejectsInvalidRequests(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+
+	resp := httptest.NewRecorder()
+	app.handler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/changes", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	assert.Equal(t, codeChangeFeedDisabled, decodeProblem(t, resp).Code)
+
+	app.changes = newChangeFeed(10)
+	for _, target := range []string{"/changes?project_id=abc", "/changes?project_id=-1", "/changes?last_event_id=x"} {
+		resp = httptest.NewRecorder()
+		app.handler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusBadRequest, resp.Code, target)
+	}
+
+	mockReadProjectByIDQuery(mock, 3, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}))
+	resp = httptest.NewRecorder()
+	app.handler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/changes?project_id=3", nil))
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	checkExpectations(t, mock)
+}
+
+// sseEvent is a server-sent event read by readEvent.
+type sseEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+// readEvent reads the next event of the stream, skipping comments and the retry field.
+func readEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+	var e sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		field, value, _ := strings.Cut(line, ": ")
+		switch field {
+		case "id":
+			e.id = value
+		case "event":
+			e.event = value
+		case "data":
+			e.data = value
+		case "":
+			if e.event != "" {
+				return e
+			}
+		}
+	}
+}
+
+func TestStreamChangesResumesAfterLastEventID(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	app.changes = newChangeFeed(10)
+	server := httptest.NewServer(app.handler())
+	defer server.Close()
+
+	first := app.changes.publish(Change{Type: changeProject, Action: changeCreated, ProjectID: 2, Resource: &data.Project{ID: 2}})
+	app.changes.publish(Change{Type: changeProject, Action: changeCreated, ProjectID: 3, Resource: &data.Project{ID: 3}})
+	app.changes.publish(Change{Type: changeExtension, Action: changeCreated, ProjectID: 2, Resource: &data.Extension{ID: 5}})
+
+	mockReadProjectByIDQuery(mock, 2, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}).
+		AddRow(2, "Shop", "", time.Now(), 1))
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/changes?project_id=2", nil)
+	req.Header.Set("Last-Event-ID", fmt.Sprint(first.ID))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	r := bufio.NewReader(resp.Body)
+
+	replayed := readEvent(t, r)
+	assert.Equal(t, fmt.Sprint(first.ID+2), replayed.id)
+	assert.Equal(t, "extension.created", replayed.event)
+
+	app.changes.publish(Change{Type: changeItem, Action: changeCreated, Resource: &data.Item{ID: 6}})
+	live := app.changes.publish(Change{Type: changeItem, Action: changeDeleted, ProjectID: 2, Resource: &data.Item{ID: 7, Name: "Product"}})
+
+	e := readEvent(t, r)
+	assert.Equal(t, fmt.Sprint(live.ID), e.id)
+	assert.Equal(t, "item.deleted", e.event)
+
+	var change struct {
+		ID        int64          `json:"id"`
+		Type      string         `json:"type"`
+		Action    string         `json:"action"`
+		ProjectID int64          `json:"project_id"`
+		Resource  map[string]any `json:"resource"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(e.data), &change))
+	assert.Equal(t, live.ID, change.ID)
+	assert.Equal(t, int64(2), change.ProjectID)
+	assert.Equal(t, "Product", change.Resource["name"])
+	checkExpectations(t, mock)
+}
+
+func TestStreamChangesSendsResetForUnknownLastEventID(t *testing.T) {
+	_, _, app := setupMockAndApp(t)
+	app.changes = newChangeFeed(10)
+	server := httptest.NewServer(app.handler())
+	defer server.Close()
+
+	latest := app.changes.publish(Change{Type: changeProject, Action: changeCreated})
+
+	resp, err := http.Get(server.URL + "/changes?last_event_id=42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	e := readEvent(t, bufio.NewReader(resp.Body))
+	assert.Equal(t, "reset", e.event)
+	assert.Equal(t, fmt.Sprint(latest.ID), e.id)
+}
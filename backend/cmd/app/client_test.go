@@ -0,0 +1,140 @@
+package main
+
+import (
+	"Typecode-Registry/pkg/client"
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// The tests in this file run the public Go client against the real handlers to make sure both stay compatible.
+
+func setupClientAgainstHandlers(t *testing.T) (*client.Client, sqlmock.Sqlmock) {
+	db, mock, app := setupMockAndApp(t)
+	t.Cleanup(func() { _ = db.Close() })
+
+	server := setupHTTPServer(app)
+	t.Cleanup(server.Close)
+
+	c, err := client.New(server.URL, client.WithRetries(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, mock
+}
+
+func TestClientHealthcheck(t *testing.T) {
+	c, _ := setupClientAgainstHandlers(t)
+
+	assert.NoError(t, c.Healthcheck(context.Background()))
+}
+
+func TestClientListItems(t *testing.T) {
+	c, mock := setupClientAgainstHandlers(t)
+
+	created := time.Date(2024, 4, 1, 8, 0, 0, 0, time.UTC)
+	mockReadFilteredItemsQuery(mock, defaultItemListArgs, sqlmock.NewRows([]string{"count", "id", "scope", "project", "name", "table_name", "extension_id", "typecode", "creation_date", "version"}).
+		AddRow(1, 1, "Project", "Project A", "Item A", "items_a", 3, 14000, created, 1))
+
+	items, err := c.ListItems(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []client.Item{{
+		ID: 1, Scope: "Project", Project: "Project A", Name: "Item A", TableName: "items_a", ExtensionID: 3, Typecode: 14000, CreationDate: created, Version: 1,
+	}}, items)
+	checkExpectations(t, mock)
+}
+
+func TestClientGetItemReturnsErrNotFound(t *testing.T) {
+	c, mock := setupClientAgainstHandlers(t)
+	mockReadItemByItemIdNoRowsFound(mock, 5)
+
+	_, err := c.GetItem(context.Background(), 5)
+
+	assert.True(t, errors.Is(err, client.ErrNotFound), "got %v", err)
+	checkExpectations(t, mock)
+}
+
+func TestClientCreateItem(t *testing.T) {
+	c, mock := setupClientAgainstHandlers(t)
+	setupExtensionMock(mock, 1, sql.NullInt64{}, "Shared", "Test-Extension", "Test-Description", 1, true)
+	setupTypecodeMock(mock, "Shared", 20000, 20001)
+	setupInsertItemMock(mock, "TestItem", 1, "test_items", 20001)
+
+	item, err := c.CreateItem(context.Background(), client.ItemRequest{Name: "TestItem", TableName: "test_items", ExtensionID: 1})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(20001), item.Typecode)
+	assert.Equal(t, "Shared", item.Scope)
+	checkExpectations(t, mock)
+}
+
+func TestClientCreateItemReturnsErrBadRequest(t *testing.T) {
+	c, _ := setupClientAgainstHandlers(t)
+
+	_, err := c.CreateItem(context.Background(), client.ItemRequest{})
+
+	assert.True(t, errors.Is(err, client.ErrBadRequest), "got %v", err)
+}
+
+func TestClientUpdateAndDeleteItem(t *testing.T) {
+	c, mock := setupClientAgainstHandlers(t)
+
+	mockReadItemDetailByItemIdQuery(mock, 1, newItemRows(1, 3))
+	mockUpdateItemQuery(mock, "NewName", "new_table", 1, 3, nil)
+	mockReadItemDetailByItemIdQuery(mock, 1, newItemRows(1, 4))
+	mockDeleteItemExecution(mock, 1, 4, 1)
+
+	assert.NoError(t, c.UpdateItem(context.Background(), 1, client.ItemUpdateRequest{Name: "NewName", TableName: "new_table"}))
+	assert.NoError(t, c.DeleteItem(context.Background(), 1))
+	checkExpectations(t, mock)
+}
+
+func TestClientListExtensionsByScope(t *testing.T) {
+	c, mock := setupClientAgainstHandlers(t)
+	setupExtensionsMock(mock, "project")
+
+	extensions, err := c.ListExtensionsByScope(context.Background(), client.ScopeProject)
+
+	assert.NoError(t, err)
+	assert.Len(t, extensions, 2)
+	assert.Equal(t, "Test-Extension-1", extensions[0].Name)
+	checkExpectations(t, mock)
+}
+
+func TestClientCreateExtension(t *testing.T) {
+	c, mock := setupClientAgainstHandlers(t)
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO extension (name, description, scope, project_id)`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "creation_date", "version"}).AddRow(9, time.Now(), 1))
+
+	extension, err := c.CreateExtension(context.Background(), client.ExtensionRequest{Name: "core", Scope: client.ScopeProject, ProjectID: 2})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), extension.ID)
+	assert.Equal(t, int64(2), *extension.ProjectID)
+	checkExpectations(t, mock)
+}
+
+func TestClientProjects(t *testing.T) {
+	c, mock := setupClientAgainstHandlers(t)
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO project (name, description)`)).
+		WithArgs("Alpha", "First project").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "creation_date", "version"}).AddRow(4, time.Now(), 1))
+	mockReadAllProjectsQuery(mock, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}).
+		AddRow(4, "Alpha", "First project", time.Now(), 1))
+
+	project, err := c.CreateProject(context.Background(), client.ProjectRequest{Name: "Alpha", Description: "First project"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), project.ID)
+
+	projects, err := c.ListProjects(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "Alpha", projects[0].Name)
+	checkExpectations(t, mock)
+}
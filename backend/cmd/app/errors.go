@@ -0,0 +1,162 @@
+package main
+
+import (
+	"Typecode-Registry/internal/validator"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error codes of problem details. They are part of the API and must not be changed,
+// clients may rely on them to tell errors with the same status apart.
+const (
+	codeInvalidParameter     = "invalid_parameter"
+	codeInvalidBody          = "invalid_body"
+	codeBodyTooLarge         = "body_too_large"
+	codeUnauthorized         = "unauthorized"
+	codeForbidden            = "forbidden"
+	codeValidationFailed     = "validation_failed"
+	codeNotFound             = "not_found"
+	codeMethodNotAllowed     = "method_not_allowed"
+	codeEditConflict         = "edit_conflict"
+	codePreconditionFailed   = "precondition_failed"
+	codeIdempotencyKeyReused = "idempotency_key_reused"
+	codeRequestInProgress    = "request_in_progress"
+	codeChangeFeedDisabled   = "change_feed_disabled"
+	codeRateLimited          = "rate_limited"
+	codeInternal             = "internal_error"
+)
+
+// problemCodes are the error codes used for a status if there is no more specific one.
+var problemCodes = map[int]string{
+	http.StatusBadRequest:          codeValidationFailed,
+	http.StatusUnauthorized:        codeUnauthorized,
+	http.StatusForbidden:           codeForbidden,
+	http.StatusNotFound:            codeNotFound,
+	http.StatusMethodNotAllowed:    codeMethodNotAllowed,
+	http.StatusConflict:            codeEditConflict,
+	http.StatusPreconditionFailed:  codePreconditionFailed,
+	http.StatusInternalServerError: codeInternal,
+}
+
+// problemCode returns the error code used for status if there is no more specific one.
+func problemCode(status int) string {
+	if code, ok := problemCodes[status]; ok {
+		return code
+	}
+	if status >= http.StatusInternalServerError {
+		return codeInternal
+	}
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
+
+// problem is the body of every error response, the problem details of RFC 7807.
+// Besides the standard members it carries a stable error code, the ID of the request
+// and the invalid fields of a request body. Extensions are additional members of the object.
+type problem struct {
+	Type       string                 `json:"type"`
+	Title      string                 `json:"title"`
+	Status     int                    `json:"status"`
+	Detail     string                 `json:"detail,omitempty"`
+	Instance   string                 `json:"instance,omitempty"`
+	Code       string                 `json:"code"`
+	RequestID  string                 `json:"request_id,omitempty"`
+	Errors     []validator.FieldError `json:"errors,omitempty"`
+	Extensions map[string]any         `json:"-"`
+}
+
+// MarshalJSON writes the extensions of the problem as members next to the standard ones.
+func (p problem) MarshalJSON() ([]byte, error) {
+	type members problem
+	js, err := json.Marshal(members(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return js, err
+	}
+
+	var object map[string]any
+	if err := json.Unmarshal(js, &object); err != nil {
+		return nil, err
+	}
+	for name, value := range p.Extensions {
+		if _, ok := object[name]; !ok {
+			object[name] = value
+		}
+	}
+
+	return json.Marshal(object)
+}
+
+// errorResponse answers the request with the problem details of an error.
+// The detail is a message for humans, the code identifies the error for programs.
+func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	app.writeProblem(w, r, problem{Status: status, Code: code, Detail: detail})
+}
+
+// serverErrorResponse answers the request with 500 Internal Server Error.
+// The cause must have been logged by the caller, it is not disclosed to the client.
+func (app *application) serverErrorResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, http.StatusInternalServerError, codeInternal, "the server encountered a problem and could not process the request")
+}
+
+// unauthorizedResponse answers a request without valid bearer token with 401 Unauthorized.
+// The WWW-Authenticate header carries the error of RFC 6750, e.g. invalid_token, unless the token is missing.
+func (app *application) unauthorizedResponse(w http.ResponseWriter, r *http.Request, tokenError, detail string) {
+	challenge := `Bearer realm="typecode-registry"`
+	if tokenError != "" {
+		challenge += fmt.Sprintf(`, error=%q`, tokenError)
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	app.errorResponse(w, r, http.StatusUnauthorized, codeUnauthorized, detail)
+}
+
+// forbiddenResponse answers a request whose principal lacks the permission for it with 403 Forbidden.
+func (app *application) forbiddenResponse(w http.ResponseWriter, r *http.Request, detail string) {
+	app.errorResponse(w, r, http.StatusForbidden, codeForbidden, detail)
+}
+
+// notFoundResponse answers the request with 404 Not Found.
+func (app *application) notFoundResponse(w http.ResponseWriter, r *http.Request, detail string) {
+	app.errorResponse(w, r, http.StatusNotFound, codeNotFound, detail)
+}
+
+// invalidParameterResponse answers a request with an invalid path or query parameter with 400 Bad Request.
+func (app *application) invalidParameterResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.errorResponse(w, r, http.StatusBadRequest, codeInvalidParameter, err.Error())
+}
+
+// invalidBodyResponse answers a request whose body could not be decoded with 400 Bad Request,
+// or with 413 Content Too Large if the body exceeds the maximum size. A missing body is reported if err is nil.
+func (app *application) invalidBodyResponse(w http.ResponseWriter, r *http.Request, err error) {
+	if isBodyTooLarge(err) {
+		app.errorResponse(w, r, http.StatusRequestEntityTooLarge, codeBodyTooLarge, fmt.Sprintf("the request body must not be larger than %d bytes", app.maxBodySize()))
+		return
+	}
+	detail := "the request body must not be empty"
+	if err != nil {
+		detail = fmt.Sprintf("the request body is not a valid JSON object: %v", err)
+	}
+	app.errorResponse(w, r, http.StatusBadRequest, codeInvalidBody, detail)
+}
+
+// rateLimitExceededResponse answers a request of a client which exceeded its rate limit with 429 Too Many Requests.
+// The Retry-After header tells the client how many seconds to wait before the next request.
+func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	seconds := max(1, int(math.Ceil(retryAfter.Seconds())))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	app.errorResponse(w, r, http.StatusTooManyRequests, codeRateLimited, fmt.Sprintf("rate limit exceeded, retry in %d seconds", seconds))
+}
+
+// failedValidationResponse answers a request with invalid fields with 400 Bad Request.
+// All invalid fields are reported at once.
+func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, errs []validator.FieldError) {
+	app.writeProblem(w, r, problem{
+		Status: http.StatusBadRequest,
+		Code:   codeValidationFailed,
+		Detail: "the request contains invalid fields",
+		Errors: errs,
+	})
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"Typecode-Registry/internal/data"
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Media types of the representations of item listings.
+const (
+	mediaTypeJSON = "application/json"
+	mediaTypeCSV  = "text/csv"
+	mediaTypeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// listFormats maps the values of the format query parameter to the media types of item listings.
+var listFormats = map[string]string{
+	"json": mediaTypeJSON,
+	"csv":  mediaTypeCSV,
+	"xlsx": mediaTypeXLSX,
+}
+
+// itemColumns are the header of the exported typecode table, in the order of the values of itemRecord.
+var itemColumns = []string{"id", "scope", "project", "name", "table_name", "extension_id", "typecode", "creation_date", "version"}
+
+// itemRecord returns the values of item in the order of itemColumns.
+func itemRecord(item data.Item) []string {
+	return []string{
+		strconv.FormatInt(item.ID, 10),
+		item.Scope,
+		item.Project,
+		item.Name,
+		item.TableName,
+		strconv.FormatInt(item.ExtensionID, 10),
+		strconv.FormatInt(int64(item.Typecode), 10),
+		item.CreationDate.UTC().Format(time.RFC3339),
+		strconv.FormatInt(int64(item.Version), 10),
+	}
+}
+
+// negotiateListFormat returns the media type of the representation of an item listing.
+// The format query parameter takes precedence over the Accept header. Of the Accept header the first
+// supported media type is used, ignoring quality values. Without a supported media type it is JSON.
+// Returns: An error if the format query parameter is not json, csv or xlsx.
+func negotiateListFormat(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		mediaType, ok := listFormats[strings.ToLower(format)]
+		if !ok {
+			return "", fmt.Errorf("invalid format %q, must be json, csv or xlsx", format)
+		}
+		return mediaType, nil
+	}
+
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case mediaTypeJSON, mediaTypeCSV, mediaTypeXLSX:
+			return mediaType, nil
+		}
+	}
+
+	return mediaTypeJSON, nil
+}
+
+// itemExporter writes the typecode table in a file format. Header writes the column names,
+// Write a single item and Close completes the file.
+type itemExporter interface {
+	Header(columns []string) error
+	Write(item data.Item) error
+	Close() error
+}
+
+// newItemExporter returns the exporter writing the given media type to w.
+func newItemExporter(mediaType string, w io.Writer) itemExporter {
+	if mediaType == mediaTypeXLSX {
+		return newXLSXExporter(w)
+	}
+	return newCSVExporter(w)
+}
+
+// exportFileName returns the name of the downloaded file of the given media type.
+func exportFileName(name, mediaType string) string {
+	if mediaType == mediaTypeXLSX {
+		return name + ".xlsx"
+	}
+	return name + ".csv"
+}
+
+// writeItemList answers an item listing with the items matching filter in the negotiated format, see negotiateListFormat.
+// JSON listings contain the metadata of the pagination. Exports are streamed to the client without reading
+// all items into memory. The status and headers are only sent with the first item, so errors of the query
+// still result in 500 Internal Server Error. Later errors abort the response, so the client notices the truncated file.
+func (app *application) writeItemList(w http.ResponseWriter, r *http.Request, filter data.ItemFilter, filters data.Filters, name string) {
+	mediaType, err := negotiateListFormat(r)
+	if err != nil {
+		app.logger.Info().Msg(fmt.Sprintf("bad request in %s: %v", GetFunctionName(), err))
+		app.invalidParameterResponse(w, r, err)
+		return
+	}
+
+	if mediaType == mediaTypeJSON {
+		app.logger.Debug().Msg("reading items from database")
+		items, metadata, err := app.models.Items.ReadFilteredItems(filter, filters)
+		if err != nil {
+			app.logger.Error().Msg(fmt.Sprintf("Error fetching item details from database: %s", err))
+			app.serverErrorResponse(w, r)
+			return
+		}
+
+		app.logger.Debug().Msg(fmt.Sprintf("found %d items in database", len(items)))
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"items": items, "metadata": metadata}, nil)
+		if err != nil {
+			app.logger.Err(err)
+			app.serverErrorResponse(w, r)
+		}
+		return
+	}
+
+	var exporter itemExporter
+	count := 0
+	start := func() error {
+		w.Header().Set("Content-Type", mediaType)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": exportFileName(name, mediaType)}))
+		w.WriteHeader(http.StatusOK)
+		exporter = newItemExporter(mediaType, w)
+		return exporter.Header(itemColumns)
+	}
+
+	app.logger.Debug().Msg(fmt.Sprintf("exporting items from database as %s", mediaType))
+	err = app.models.Items.StreamFilteredItems(filter, filters, func(item data.Item) error {
+		if exporter == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		count++
+		return exporter.Write(item)
+	})
+	if err == nil && exporter == nil {
+		err = start()
+	}
+	if err == nil {
+		err = exporter.Close()
+	}
+	if err != nil {
+		if exporter == nil {
+			app.logger.Error().Msg(fmt.Sprintf("Error fetching item details from database: %s", err))
+			app.serverErrorResponse(w, r)
+			return
+		}
+		app.logger.Error().Msg(fmt.Sprintf("Error after exporting %d items: %s", count, err))
+		panic(http.ErrAbortHandler)
+	}
+
+	app.logger.Debug().Msg(fmt.Sprintf("exported %d items", count))
+}
+
+// csvExporter writes the typecode table as CSV with a header row, see RFC 4180.
+type csvExporter struct {
+	w *csv.Writer
+}
+
+func newCSVExporter(w io.Writer) *csvExporter {
+	return &csvExporter{w: csv.NewWriter(w)}
+}
+
+func (e *csvExporter) Header(columns []string) error {
+	return e.w.Write(columns)
+}
+
+func (e *csvExporter) Write(item data.Item) error {
+	return e.w.Write(itemRecord(item))
+}
+
+func (e *csvExporter) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// xlsxExporter writes the typecode table as an Office Open XML workbook with a single worksheet.
+// The parts of the workbook except the worksheet are constant, the rows of the worksheet are written
+// one by one into the zip archive. Strings are stored inline, so no shared string table has to be kept in memory.
+type xlsxExporter struct {
+	zw    *zip.Writer
+	sheet *bufio.Writer
+	row   int
+}
+
+// xlsxParts are the constant parts of the workbook, written before the worksheet.
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Typecodes" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+// xlsxNumericColumns are the indexes of itemColumns written as numbers instead of strings.
+var xlsxNumericColumns = map[int]bool{0: true, 5: true, 6: true, 8: true}
+
+func newXLSXExporter(w io.Writer) *xlsxExporter {
+	return &xlsxExporter{zw: zip.NewWriter(w)}
+}
+
+func (e *xlsxExporter) Header(columns []string) error {
+	for _, part := range xlsxParts {
+		f, err := e.zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err = io.WriteString(f, part.content); err != nil {
+			return err
+		}
+	}
+
+	f, err := e.zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	e.sheet = bufio.NewWriter(f)
+	e.sheet.WriteString(xml.Header)
+	e.sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	return e.writeRow(columns, nil)
+}
+
+func (e *xlsxExporter) Write(item data.Item) error {
+	return e.writeRow(itemRecord(item), xlsxNumericColumns)
+}
+
+// writeRow writes the next row of the worksheet. The values of the columns in numeric are written as numbers.
+func (e *xlsxExporter) writeRow(values []string, numeric map[int]bool) error {
+	e.row++
+	fmt.Fprintf(e.sheet, `<row r="%d">`, e.row)
+	for i, value := range values {
+		if numeric[i] {
+			fmt.Fprintf(e.sheet, `<c r="%s%d"><v>%s</v></c>`, xlsxColumn(i), e.row, value)
+			continue
+		}
+		fmt.Fprintf(e.sheet, `<c r="%s%d" t="inlineStr"><is><t>`, xlsxColumn(i), e.row)
+		if err := xml.EscapeText(e.sheet, []byte(value)); err != nil {
+			return err
+		}
+		e.sheet.WriteString(`</t></is></c>`)
+	}
+	_, err := e.sheet.WriteString(`</row>`)
+	return err
+}
+
+func (e *xlsxExporter) Close() error {
+	if e.sheet == nil {
+		return errors.New("xlsx export closed without header")
+	}
+	e.sheet.WriteString(`</sheetData></worksheet>`)
+	if err := e.sheet.Flush(); err != nil {
+		return err
+	}
+	return e.zw.Close()
+}
+
+// xlsxColumn returns the letters of the column with the zero based index i, e.g. A for 0 and AA for 26.
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func itemListRows() *sqlmock.Rows {
+	created := time.Date(2024, 4, 1, 8, 0, 0, 0, time.UTC)
+	return sqlmock.NewRows([]string{"count", "id", "scope", "project", "name", "table_name", "extension_id", "typecode", "creation_date", "version"}).
+		AddRow(2, 1, "Project", "Shop & Co <B2B>", "Product", "products", 3, 14000, created, 1).
+		AddRow(2, 2, "Shared", "-", "Order", "orders", 4, 20000, created, 2)
+}
+
+func serveExport(app *application, target, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	resp := httptest.NewRecorder()
+	app.handler().ServeHTTP(resp, req)
+	return resp
+}
+
+func TestNegotiateListFormat(t *testing.T) {
+	testCases := map[string]struct {
+		target    string
+		accept    string
+		mediaType string
+	}{
+		"default":                   {"/items", "", mediaTypeJSON},
+		"any":                       {"/items", "*/*", mediaTypeJSON},
+		"csv":                       {"/items", "text/csv", mediaTypeCSV},
+		"xlsx":                      {"/items", mediaTypeXLSX, mediaTypeXLSX},
+		"first supported":           {"/items", "text/html, text/csv;q=0.9, application/json", mediaTypeCSV},
+		"unsupported":               {"/items", "text/html", mediaTypeJSON},
+		"format parameter":          {"/items?format=xlsx", "", mediaTypeXLSX},
+		"format before accept":      {"/items?format=json", "text/csv", mediaTypeJSON},
+		"format parameter any case": {"/items?format=CSV", "", mediaTypeCSV},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			req.Header.Set("Accept", tc.accept)
+
+			mediaType, err := negotiateListFormat(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.mediaType, mediaType)
+		})
+	}
+
+	_, err := negotiateListFormat(httptest.NewRequest(http.MethodGet, "/items?format=pdf", nil))
+	assert.EqualError(t, err, `invalid format "pdf", must be json, csv or xlsx`)
+}
+
+func TestItemsAreExportedAsCSV(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	mockReadFilteredItemsQuery(mock, defaultItemListArgs, itemListRows())
+
+	resp := serveExport(app, "/items", "text/csv")
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "text/csv", resp.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=items.csv`, resp.Header().Get("Content-Disposition"))
+
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		itemColumns,
+		{"1", "Project", "Shop & Co <B2B>", "Product", "products", "3", "14000", "2024-04-01T08:00:00Z", "1"},
+		{"2", "Shared", "-", "Order", "orders", "4", "20000", "2024-04-01T08:00:00Z", "2"},
+	}, records)
+	checkExpectations(t, mock)
+}
+
+func TestExportHonorsFiltersOfListing(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	mock.ExpectQuery(regexp.QuoteMeta(`ORDER BY item.typecode DESC, item.id ASC`)).
+		WithArgs("shared", "", int64(0), int64(0), int32(20000), nil, "ord", nil, nil, nil, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "id", "scope", "project", "name", "table_name", "extension_id", "typecode", "creation_date", "version"}))
+
+	resp := serveExport(app, "/items?format=csv&scope=shared&typecode_min=20000&name=ord&sort=-typecode", "")
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "id,scope,project,name,table_name,extension_id,typecode,creation_date,version\n", resp.Body.String())
+	checkExpectations(t, mock)
+}
+
+func TestItemsAreExportedAsXLSX(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	mockReadFilteredItemsQuery(mock, defaultItemListArgs, itemListRows())
+
+	resp := serveExport(app, "/items?format=xlsx", "")
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, mediaTypeXLSX, resp.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=items.xlsx`, resp.Header().Get("Content-Disposition"))
+
+	body := resp.Body.Bytes()
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = rc.Close()
+		parts[f.Name] = string(content)
+	}
+	assert.Contains(t, parts, "[Content_Types].xml")
+	assert.Contains(t, parts, "_rels/.rels")
+	assert.Contains(t, parts, "xl/workbook.xml")
+	assert.Contains(t, parts, "xl/_rels/workbook.xml.rels")
+
+	var sheet struct {
+		Rows []struct {
+			R     int `xml:"r,attr"`
+			Cells []struct {
+				R      string `xml:"r,attr"`
+				T      string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal([]byte(parts["xl/worksheets/sheet1.xml"]), &sheet); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, sheet.Rows, 3)
+	assert.Len(t, sheet.Rows[0].Cells, len(itemColumns))
+	assert.Equal(t, "inlineStr", sheet.Rows[0].Cells[0].T)
+	assert.Equal(t, "id", sheet.Rows[0].Cells[0].Inline)
+
+	product := sheet.Rows[1]
+	assert.Equal(t, 2, product.R)
+	assert.Equal(t, "A2", product.Cells[0].R)
+	assert.Equal(t, "", product.Cells[0].T)
+	assert.Equal(t, "1", product.Cells[0].Value)
+	assert.Equal(t, "Shop & Co <B2B>", product.Cells[2].Inline)
+	assert.Equal(t, "G2", product.Cells[6].R)
+	assert.Equal(t, "14000", product.Cells[6].Value)
+	checkExpectations(t, mock)
+}
+
+func TestExportReturnsProblemWhenQueryFails(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	mockReadFilteredItemsQueryReturnsError(mock, defaultItemListArgs)
+
+	resp := serveExport(app, "/items", "text/csv")
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.Equal(t, codeInternal, decodeProblem(t, resp).Code)
+	assert.Empty(t, resp.Header().Get("Content-Disposition"))
+	checkExpectations(t, mock)
+}
+
+func TestExportWithInvalidFormatReturnsBadRequest(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+
+	resp := serveExport(app, "/items?format=pdf", "")
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, codeInvalidParameter, decodeProblem(t, resp).Code)
+	checkExpectations(t, mock)
+}
+
+func TestItemsOfProjectAreExported(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	mockReadProjectByIDQuery(mock, 5, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}).
+		AddRow(5, "Shop", "", time.Now(), 1))
+	mockReadFilteredItemsQuery(mock, []driver.Value{"", "", int64(5), int64(0), nil, nil, "", nil, nil, nil, 0}, itemListRows())
+
+	resp := serveExport(app, "/projects/5/items?project=Other", "text/csv")
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, `attachment; filename=project-5-items.csv`, resp.Header().Get("Content-Disposition"))
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+	checkExpectations(t, mock)
+}
+
+func TestItemsOfMissingProjectReturnNotFound(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	mockReadProjectByIDQuery(mock, 6, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}))
+
+	resp := serveExport(app, "/projects/6/items", "")
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	checkExpectations(t, mock)
+}
+
+func TestXLSXColumn(t *testing.T) {
+	assert.Equal(t, "A", xlsxColumn(0))
+	assert.Equal(t, "I", xlsxColumn(8))
+	assert.Equal(t, "Z", xlsxColumn(25))
+	assert.Equal(t, "AA", xlsxColumn(26))
+	assert.Equal(t, "AZ", xlsxColumn(51))
+	assert.Equal(t, "BA", xlsxColumn(52))
+}
+
+func TestExportIsAbortedWhenReadingFailsAfterFirstItem(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	mockReadFilteredItemsQuery(mock, defaultItemListArgs, itemListRows().RowError(1, errors.New("mock error")))
+
+	resp := httptest.NewRecorder()
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		app.handler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/items?format=csv", nil))
+	})
+	assert.Equal(t, http.StatusOK, resp.Code)
+	checkExpectations(t, mock)
+}
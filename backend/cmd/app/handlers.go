@@ -2,11 +2,13 @@ package main
 
 import (
 	"Typecode-Registry/internal/data"
+	"Typecode-Registry/internal/validator"
 	"database/sql"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
-	"strconv"
+	"net/url"
 	"strings"
 )
 
@@ -48,11 +50,7 @@ type ProjectUpdateRequest struct {
 // TODO: Add more checks to ensure the service is healthy.
 func (app *application) healthcheck(w http.ResponseWriter, r *http.Request) {
 	app.logger.Debug().Msg(fmt.Sprintf("Handling %s %s route", r.Method, r.URL.Path))
-	if r.Method != http.MethodGet {
-		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
-		return
-	}
-
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	_, err := w.Write([]byte("Service is up and running!"))
 	if err != nil {
@@ -60,117 +58,163 @@ func (app *application) healthcheck(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// getProjectsHandler handles the /projects route and calls the appropriate handler based on the request method.
-// It handles GET requests. Other requests will return a 405 Method Not Allowed.
-func (app *application) getProjectsHandler(w http.ResponseWriter, r *http.Request) {
-	app.logger.Debug().Msg(fmt.Sprintf("Handling %s %s route", r.Method, r.URL.Path))
-	switch r.Method {
-	case http.MethodGet:
-		app.getProjects(w)
-	case http.MethodPost:
-		app.createProject(w, r)
-	case http.MethodPut:
-		if strings.Contains(r.URL.Path, "/projects/") {
-			app.updateProject(w, r)
-		} else {
-			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
-		}
-	case http.MethodDelete:
-		if strings.Contains(r.URL.Path, "/projects/") {
-			app.deleteProject(w, r)
-		} else {
-			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
-		}
-	default:
-		app.logger.Error().Msg(fmt.Sprintf("%s not allowed on route %s ", r.Method, r.URL.Path))
-		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+// defaultItemPageSize is the page size used if a page but no limit is requested.
+const defaultItemPageSize = 100
+
+// readItemListQuery reads the filters, sorting and pagination of an item listing from the query string.
+// Without page and limit all matching items are returned.
+// Returns: An error describing the first invalid parameter.
+func (app *application) readItemListQuery(qs url.Values) (data.ItemFilter, data.Filters, error) {
+	var filter data.ItemFilter
+	filters := data.Filters{SortSafelist: data.ItemSortSafelist}
+	var err error
+
+	filter.Scope = app.readString(qs, "scope", "")
+	if filter.Scope != "" && !isKnownScope(filter.Scope) {
+		return filter, filters, fmt.Errorf("invalid scope %q", filter.Scope)
 	}
-}
+	filter.Project = app.readString(qs, "project", "")
+	filter.Name = app.readString(qs, "name", "")
 
-func (app *application) getItemsHandler(w http.ResponseWriter, r *http.Request) {
-	app.logger.Debug().Msg(fmt.Sprintf("Handling %s %s route", r.Method, r.URL.Path))
-	switch r.Method {
-	case http.MethodGet:
-		app.getItems(w)
-	case http.MethodPost:
-		app.createItem(w, r)
-	default:
-		app.logger.Error().Msg(fmt.Sprintf("%s not allowed on route %s ", r.Method, r.URL.Path))
-		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	extensionID, err := app.readInt(qs, "extension_id", 0)
+	if err != nil {
+		return filter, filters, err
 	}
-}
+	if extensionID < 0 {
+		return filter, filters, errors.New("extension_id must be a positive integer")
+	}
+	filter.ExtensionID = int64(extensionID)
 
-func (app *application) getItemHandler(w http.ResponseWriter, r *http.Request) {
-	app.logger.Debug().Msg(fmt.Sprintf("Handling %s %s route", r.Method, r.URL.Path))
-	switch r.Method {
-	case http.MethodGet:
-		app.getItem(w, r)
-	case http.MethodPut:
-		app.updateItem(w, r)
-	case http.MethodDelete:
-		app.deleteItem(w, r)
-	default:
-		app.logger.Error().Msg(fmt.Sprintf("%s not allowed on route %s ", r.Method, r.URL.Path))
-		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	if filter.TypecodeMin, err = app.readTypecode(qs, "typecode_min"); err != nil {
+		return filter, filters, err
+	}
+	if filter.TypecodeMax, err = app.readTypecode(qs, "typecode_max"); err != nil {
+		return filter, filters, err
+	}
+	if filter.TypecodeMin != nil && filter.TypecodeMax != nil && *filter.TypecodeMin > *filter.TypecodeMax {
+		return filter, filters, errors.New("typecode_min must not be greater than typecode_max")
+	}
+
+	if filter.CreatedAfter, err = app.readTime(qs, "created_after"); err != nil {
+		return filter, filters, err
+	}
+	if filter.CreatedBefore, err = app.readTime(qs, "created_before"); err != nil {
+		return filter, filters, err
 	}
+	if filter.CreatedAfter != nil && filter.CreatedBefore != nil && !filter.CreatedAfter.Before(*filter.CreatedBefore) {
+		return filter, filters, errors.New("created_after must be before created_before")
+	}
+
+	if filters.Page, err = app.readInt(qs, "page", 1); err != nil {
+		return filter, filters, err
+	}
+	if filters.Page < 1 || filters.Page > 10_000_000 {
+		return filter, filters, errors.New("page must be between 1 and 10000000")
+	}
+
+	defaultPageSize := 0
+	if qs.Has("page") {
+		defaultPageSize = defaultItemPageSize
+	}
+	if filters.PageSize, err = app.readInt(qs, "limit", defaultPageSize); err != nil {
+		return filter, filters, err
+	}
+	if qs.Has("limit") && (filters.PageSize < 1 || filters.PageSize > data.MaxPageSize) {
+		return filter, filters, fmt.Errorf("limit must be between 1 and %d", data.MaxPageSize)
+	}
+
+	filters.Sort = app.readString(qs, "sort", "id")
+	if !filters.SortValid() {
+		return filter, filters, fmt.Errorf("invalid sort field %q", filters.Sort)
+	}
+
+	return filter, filters, nil
 }
 
-// getItems handles the GET request for all items.
-// It returns all items stored in the database.
-// If there are no items in the database, it returns a 404 Not Found.
-// If there is an error while reading the items from the database, it returns a 500 Internal Server Error.
-func (app *application) getItems(w http.ResponseWriter) {
-	app.logger.Debug().Msg("reading items from database")
-	itemDetails, err := app.models.Items.ReadItems()
+// readTypecode returns the typecode of key in the query string or nil if the key is missing.
+func (app *application) readTypecode(qs url.Values, key string) (*int32, error) {
+	if qs.Get(key) == "" {
+		return nil, nil
+	}
+
+	typecode, err := app.readInt(qs, key, 0)
 	if err != nil {
-		app.logger.Error().Msg(fmt.Sprintf("Error fetching item details from database: %s", err))
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return nil, err
 	}
+	if typecode < 0 || typecode > math.MaxInt32 {
+		return nil, fmt.Errorf("%s must be between 0 and %d", key, math.MaxInt32)
+	}
+
+	value := int32(typecode)
+	return &value, nil
+}
 
-	app.logger.Debug().Msg(fmt.Sprintf("found %d items in database", len(itemDetails)))
+// isKnownScope reports whether scope is one of the scopes of data.ScopeRanges, ignoring case.
+func isKnownScope(scope string) bool {
+	for known := range data.ScopeRanges {
+		if strings.EqualFold(known, scope) {
+			return true
+		}
+	}
+	return false
+}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"items": itemDetails}, nil)
+// getItems handles the GET request for all items.
+// It returns the items matching the filters of the query string, see readItemListQuery,
+// together with metadata containing the total number of matching items.
+// With Accept: text/csv or the XLSX media type, or the format query parameter, the items are exported as file, see writeItemList.
+// If a query parameter is invalid, it returns a 400 Bad Request.
+// If there is an error while reading the items from the database, it returns a 500 Internal Server Error.
+func (app *application) getItems(w http.ResponseWriter, r *http.Request) {
+	filter, filters, err := app.readItemListQuery(r.URL.Query())
 	if err != nil {
-		app.logger.Err(err)
-		http.Error(w, "error while trying to write item details to http response!", http.StatusInternalServerError)
+		app.logger.Info().Msg(fmt.Sprintf("bad request in %s: %v", GetFunctionName(), err))
+		app.invalidParameterResponse(w, r, err)
 		return
 	}
+
+	app.writeItemList(w, r, filter, filters, "items")
 }
 
 // getItem handles the GET request for a specific item detail.
-// It extracts the item ID from the URL and returns the details of the item with that ID.
+// It extracts the item ID from the URL and returns the details of the item with that ID and its version as ETag.
 // If the ID is not a valid integer, it returns a 400 Bad Request.
 // If the item with the specified ID is not found, it returns a 404 Not Found.
+// If the If-None-Match header matches the ETag, it returns a 304 Not Modified without body.
 func (app *application) getItem(w http.ResponseWriter, r *http.Request) {
-	id := r.URL.Path[len("/items/"):]
-	idInt, err := strconv.ParseInt(id, 10, 64)
+	idInt, err := app.readIDParam(r)
 	if err != nil {
-		app.logger.Warn().Msg(fmt.Sprintf("Bad Request in %s using id %s",
-			GetFunctionName(),
-			id))
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		app.logger.Warn().Msg(fmt.Sprintf("Bad Request in %s: %v", GetFunctionName(), err))
+		app.invalidParameterResponse(w, r, err)
 		return
 	}
 
-	app.logger.Debug().Msg(fmt.Sprintf("Reading item details from database using id %s", id))
+	app.logger.Debug().Msg(fmt.Sprintf("Reading item details from database using id %d", idInt))
 
 	item, err := app.models.Items.ReadItem(idInt)
 	if err != nil {
 		app.logger.Err(err)
 		if errors.Is(err, sql.ErrNoRows) {
-			http.Error(w, fmt.Sprintf("no item detail with id %d found", idInt), http.StatusNotFound)
+			app.notFoundResponse(w, r, fmt.Sprintf("no item detail with id %d found", idInt))
 		} else {
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			app.serverErrorResponse(w, r)
 		}
 		return
 	}
 
 	app.logger.Debug().Msg(fmt.Sprintf("found item with id %d in database", idInt))
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"item": item}, nil)
+	if app.notModified(w, r, item.Version) {
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", etag(item.Version))
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"item": item}, headers)
 	if err != nil {
 		app.logger.Err(err)
-		http.Error(w, "error while trying to write item detail to http response!", http.StatusInternalServerError)
+		app.serverErrorResponse(w, r)
 		return
 	}
 }
@@ -178,22 +222,14 @@ func (app *application) getItem(w http.ResponseWriter, r *http.Request) {
 // updateItem handles the PUT request for a specific item.
 // It extracts the item ID from the URL and updates the details of the item with that ID.
 // If the ID is not a valid integer, it returns a 400 Bad Request.
+// If the item with the specified ID is not found, it returns a 404 Not Found.
+// If the If-Match header does not match the ETag of the item, it returns a 412 Precondition Failed.
+// The new ETag is returned with the 204 No Content response.
 func (app *application) updateItem(w http.ResponseWriter, r *http.Request) {
-	id := r.URL.Path[len("/items/"):]
-	idInt, err := strconv.ParseInt(id, 10, 64)
+	idInt, err := app.readIDParam(r)
 	if err != nil {
-		app.logger.Info().Msg(fmt.Sprintf("bad request in %s using id %s",
-			GetFunctionName(),
-			id))
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
-		return
-	}
-
-	if idInt < 1 {
-		app.logger.Info().Msg(fmt.Sprintf("bad request in %s using id %d",
-			GetFunctionName(),
-			idInt))
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		app.logger.Info().Msg(fmt.Sprintf("bad request in %s: %v", GetFunctionName(), err))
+		app.invalidParameterResponse(w, r, err)
 		return
 	}
 
@@ -202,39 +238,76 @@ func (app *application) updateItem(w http.ResponseWriter, r *http.Request) {
 	err = app.readJSON(w, r, &item)
 	if err != nil {
 		app.logger.Error().Msg(fmt.Sprintf("failed to decode request body: %v", err))
-		http.Error(w, "failed to decode request body", http.StatusBadRequest)
+		app.invalidBodyResponse(w, r, err)
 		return
 	}
 
 	app.logger.Debug().Msg(fmt.Sprintf("successfully decoded request body %v", item))
 	app.logger.Debug().Msg(fmt.Sprintf("validating item: %v", item))
 
-	if item.Name == "" || item.TableName == "" {
+	v := validator.New()
+	validateItemUpdate(v, &item, idInt)
+	if !v.Valid() {
 		app.logger.Error().Msg(fmt.Sprintf("Bad Request: Invalid item update request with data: %v", item))
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
+	item.ID = idInt
+
+	app.logger.Debug().Msg(fmt.Sprintf("Valid item: %v", item))
 
-	if item.ID == 0 {
-		item.ID = idInt
-	} else if item.ID != idInt {
-		app.logger.Error().Msg(fmt.Sprintf("ID in request body does not match ID in URL: %d != %d", item.ID, idInt))
-		http.Error(w, "ID in request body does not match ID in URL", http.StatusBadRequest)
+	current, err := app.models.Items.ReadItem(idInt)
+	if err != nil {
+		app.logger.Err(err)
+		if errors.Is(err, sql.ErrNoRows) {
+			app.notFoundResponse(w, r, fmt.Sprintf("no item detail with id %d found", idInt))
+		} else {
+			app.serverErrorResponse(w, r)
+		}
 		return
 	}
 
-	app.logger.Debug().Msg(fmt.Sprintf("Valid item: %v", item))
+	if !app.authorize(w, r, app.access(r).requireItemRole(&current, data.RoleDeveloper)) {
+		return
+	}
+
+	if app.preconditionFailed(w, r, current.Version) {
+		return
+	}
+	item.Version = current.Version
+
+	tx, models, err := app.beginChanges()
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+		return
+	}
+	defer rollbackChanges(tx)
+
 	app.logger.Debug().Msg(fmt.Sprintf("Updating item with id %d", idInt))
-	err = app.models.Items.UpdateItem(&item)
+	err = models.Items.UpdateItem(&item)
 	if err != nil {
-		msg := fmt.Sprintf("update failed: %v", err)
-		http.Error(w, msg, http.StatusInternalServerError)
+		if errors.Is(err, data.ErrEditConflict) {
+			app.editConflictResponse(w, r)
+			return
+		}
+		app.logger.Error().Msg(fmt.Sprintf("update of item with id %d failed: %v", idInt, err))
+		app.serverErrorResponse(w, r)
 		return
 	}
 
 	app.logger.Debug().Msg(fmt.Sprintf("successfully updated item with id %d", idInt))
+	before := current
+	current.Name, current.TableName, current.Version = item.Name, item.TableName, item.Version
+	err = app.commitChange(r, tx, models, changeItem, changeUpdated, &before, &current)
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("update of item with id %d failed: %v", idInt, err))
+		app.serverErrorResponse(w, r)
+		return
+	}
 	app.logger.Debug().Msg("Writing response")
 
+	w.Header().Set("ETag", etag(item.Version))
 	w.WriteHeader(http.StatusNoContent)
 	app.logger.Debug().Msg("Response written")
 }
@@ -242,21 +315,60 @@ func (app *application) updateItem(w http.ResponseWriter, r *http.Request) {
 // deleteItem handles the DELETE request for a specific item.
 // It extracts the item ID from the URL and deletes the item with that ID.
 // If the ID is not a valid integer, it returns a 400 Bad Request.
+// If the item with the specified ID is not found, it returns a 404 Not Found.
+// If the If-Match header does not match the ETag of the item, it returns a 412 Precondition Failed.
+// If the item has been changed concurrently without If-Match, it returns a 409 Conflict.
 // When the item is successfully deleted, it returns a 204 No Content status.
 func (app *application) deleteItem(w http.ResponseWriter, r *http.Request) {
-	id := r.URL.Path[len("/items/"):]
-	idInt, err := strconv.ParseInt(id, 10, 64)
+	idInt, err := app.readIDParam(r)
 	if err != nil {
-		app.logger.Warn().Msg(fmt.Sprintf("Bad Request in %s using id %s",
-			GetFunctionName(),
-			id))
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+		app.logger.Warn().Msg(fmt.Sprintf("Bad Request in %s: %v", GetFunctionName(), err))
+		app.invalidParameterResponse(w, r, err)
+		return
 	}
 
-	err = app.models.Items.DeleteItem(idInt)
+	current, err := app.models.Items.ReadItem(idInt)
 	if err != nil {
 		app.logger.Err(err)
-		http.Error(w, "error during deleting the requested item, no rows affected.", http.StatusInternalServerError)
+		if errors.Is(err, sql.ErrNoRows) {
+			app.notFoundResponse(w, r, fmt.Sprintf("no item detail with id %d found", idInt))
+		} else {
+			app.serverErrorResponse(w, r)
+		}
+		return
+	}
+
+	if !app.authorize(w, r, app.access(r).requireItemRole(&current, data.RoleDeveloper)) {
+		return
+	}
+
+	if app.preconditionFailed(w, r, current.Version) {
+		return
+	}
+
+	tx, models, err := app.beginChanges()
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+		return
+	}
+	defer rollbackChanges(tx)
+
+	err = models.Items.DeleteItem(idInt, current.Version)
+	if errors.Is(err, data.ErrEditConflict) {
+		app.editConflictResponse(w, r)
+		return
+	}
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+		return
+	}
+	err = app.commitChange(r, tx, models, changeItem, changeDeleted, &current, nil)
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
@@ -271,20 +383,21 @@ func (app *application) deleteItem(w http.ResponseWriter, r *http.Request) {
 func (app *application) createItem(w http.ResponseWriter, r *http.Request) {
 	if r.Body == nil {
 		app.logger.Error().Msg("Bad Request: Empty request body")
-		http.Error(w, "Bad Request: Empty request body", http.StatusBadRequest)
+		app.invalidBodyResponse(w, r, nil)
 		return
 	}
 
 	var itemReq ItemRequest
 	err := app.readJSON(w, r, &itemReq)
 	if err != nil {
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		app.invalidBodyResponse(w, r, err)
 		return
 	}
 
 	app.logger.Debug().Msg(fmt.Sprintf("Item request: %v received", itemReq))
-	if itemReq.Name == "" || itemReq.TableName == "" || itemReq.ExtensionId < 1 {
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+	v := validator.New()
+	if itemReq.validate(v); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
 		app.logger.Error().Msg(fmt.Sprintf("Bad Request: Invalid item create request with data: %v", itemReq))
 		return
 	}
@@ -292,14 +405,26 @@ func (app *application) createItem(w http.ResponseWriter, r *http.Request) {
 	extension, err := app.models.Extensions.Read(itemReq.ExtensionId)
 	if err != nil {
 		msg := fmt.Sprintf("could not find extension with id %d in database", itemReq.ExtensionId)
-		http.Error(w, msg, http.StatusNotFound)
+		app.notFoundResponse(w, r, msg)
 		app.logger.Error().Msg(msg)
 		return
 	}
 
-	typecode, err := calculateTypecode(extension, &app.models.Items)
+	if !app.authorize(w, r, app.access(r).requireExtensionRole(extension, data.RoleDeveloper)) {
+		return
+	}
+
+	tx, models, err := app.beginChanges()
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+		return
+	}
+	defer rollbackChanges(tx)
+
+	typecode, err := calculateTypecode(extension, &models.Items)
 	if err != nil {
-		http.Error(w, "Internal Server Error during calculation of typecode", http.StatusInternalServerError)
+		app.serverErrorResponse(w, r)
 		app.logger.Error().Msg(fmt.Sprintf("Error while calculating typecode for scope: %s", extension.Scope))
 		return
 	}
@@ -311,125 +436,86 @@ func (app *application) createItem(w http.ResponseWriter, r *http.Request) {
 		Typecode:    typecode,
 	}
 
-	err = app.models.Items.BeginTransaction()
-	if err != nil {
-		app.logger.Err(err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		return
-	}
-
-	err = app.models.Items.Insert(item)
+	err = models.Items.Insert(item)
 	if err != nil {
 		app.logger.Err(err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		_ = app.models.Items.Rollback()
+		app.serverErrorResponse(w, r)
 		return
 	}
 
 	item.Scope = extension.Scope
 	if item.Scope == data.ScopeProject && extension.ProjectID.Valid {
-		err = app.models.Projects.ReadProjectName(int64(extension.ProjectID.Int64), &item.Project)
+		err = models.Projects.ReadProjectName(int64(extension.ProjectID.Int64), &item.Project)
 		if err != nil {
 			app.logger.Err(err)
-			http.Error(w,
-				fmt.Sprintf("error while reading project with id %d %v", extension.ProjectID.Int64, err),
-				http.StatusInternalServerError)
-			_ = app.models.Items.Rollback()
+			app.serverErrorResponse(w, r)
 			return
 		}
 	}
 
-	err = app.models.Items.CommitTransaction()
+	app.rememberExtension(extension)
+	err = app.commitChange(r, tx, models, changeItem, changeCreated, nil, item)
 	if err != nil {
 		app.logger.Err(err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		app.serverErrorResponse(w, r)
 		return
 	}
 
 	headers := make(http.Header)
 	headers.Set("Location", fmt.Sprintf("/items/%d", item.ID))
+	headers.Set("ETag", etag(item.Version))
 
 	err = app.writeJSON(w, http.StatusCreated, envelope{"item": item}, headers)
 	if err != nil {
 		app.logger.Err(err)
-		http.Error(w, "error while trying to write item to http response.", http.StatusInternalServerError)
+		app.serverErrorResponse(w, r)
 		return
 	}
 }
 
-// getExtensionsHandler handles the /extensions route and calls the appropriate handler based on the request method.
-// It supports GET and POST requests. Other requests will return a 405 Method Not Allowed.
-//
-// Parameters:
-//   - w: The http.ResponseWriter to write the response to.
-//   - r: The http.Request containing the request details.
-func (app *application) getExtensionsHandler(w http.ResponseWriter, r *http.Request) {
-	app.logger.Debug().Msg(fmt.Sprintf("Handling %s %s route", r.Method, r.URL.Path))
-	switch r.Method {
-	case http.MethodGet:
-		app.getExtensions(w, r)
-	case http.MethodPost:
-		app.createExtension(w, r)
-	case http.MethodPut:
-		if strings.Contains(r.URL.Path, "/extensions/") {
-			app.updateExtension(w, r)
-		} else {
-			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
-		}
-
-	case http.MethodDelete:
-		if strings.Contains(r.URL.Path, "/extensions/") {
-			app.deleteExtension(w, r)
-		} else {
-			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
-		}
-	default:
-		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
-	}
-}
-
 func (app *application) deleteExtension(w http.ResponseWriter, r *http.Request) {
 	app.logger.Debug().Msg("reading extension id from url")
-	id := r.URL.Path[len("/extensions/"):]
-	idInt, err := strconv.ParseInt(id, 10, 64)
+	idInt, err := app.readIDParam(r)
 	if err != nil {
-		app.logger.Warn().Msg(fmt.Sprintf("Bad Request in %s using id %s",
-			GetFunctionName(),
-			id))
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+		app.logger.Warn().Msg(fmt.Sprintf("Bad Request in %s: %v", GetFunctionName(), err))
+		app.invalidParameterResponse(w, r, err)
+		return
 	}
 
-	app.logger.Debug().Msg("starting transaction")
-	// Begin a transaction
-	err = app.models.Items.BeginTransaction()
-	if err != nil {
-		app.logger.Err(err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	extension, ok := app.readExtension(w, r, idInt)
+	if !ok || !app.authorize(w, r, app.access(r).requireExtensionRole(extension, data.RoleAdmin)) {
 		return
 	}
 
-	app.logger.Debug().Msg("deleting items from extension")
+	if app.preconditionFailed(w, r, extension.Version) {
+		return
+	}
 
-	err = app.models.Items.DeleteItemsByExtension(idInt)
+	tx, models, err := app.beginChanges()
 	if err != nil {
 		app.logger.Err(err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		_ = app.models.Items.Rollback()
+		app.serverErrorResponse(w, r)
 		return
 	}
+	defer rollbackChanges(tx)
 
-	err = app.models.Extensions.Delete(idInt)
+	app.logger.Debug().Msg("deleting extension together with its items")
+	items, err := models.Extensions.DeleteWithItems(idInt, extension.Version, models.Items)
+	if errors.Is(err, data.ErrEditConflict) {
+		app.editConflictResponse(w, r)
+		return
+	}
 	if err != nil {
 		app.logger.Err(err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		_ = app.models.Items.Rollback()
+		app.serverErrorResponse(w, r)
 		return
 	}
 
-	err = app.models.Items.CommitTransaction()
+	changes := append(cascadedChanges(nil, items), pendingChange{typ: changeExtension, action: changeDeleted, before: extension})
+	err = app.commitChanges(r, tx, models, changes...)
 	if err != nil {
 		app.logger.Err(err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		app.serverErrorResponse(w, r)
 		return
 	}
 
@@ -446,29 +532,32 @@ func (app *application) deleteExtension(w http.ResponseWriter, r *http.Request)
 //
 // If the extension ID is not a valid integer, it returns a 400 Bad Request.
 // If the request body is empty or invalid, it returns a 400 Bad Request.
+// If the extension with the specified ID is not found, it returns a 404 Not Found.
+// If the If-Match header does not match the ETag of the extension, it returns a 412 Precondition Failed.
+// If the extension has been changed concurrently without If-Match, it returns a 409 Conflict.
 // If there is an error while updating the extension in the database, it returns a 500 Internal Server Error.
 func (app *application) updateExtension(w http.ResponseWriter, r *http.Request) {
 	app.logger.Debug().Msg("reading extension id from url")
-	id := r.URL.Path[len("/extensions/"):]
-	idInt, err := strconv.ParseInt(id, 10, 64)
+	idInt, err := app.readIDParam(r)
 	if err != nil {
-		app.logger.Warn().Msg(fmt.Sprintf("Bad Request in %s using id %s",
-			GetFunctionName(),
-			id))
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+		app.logger.Warn().Msg(fmt.Sprintf("Bad Request in %s: %v", GetFunctionName(), err))
+		app.invalidParameterResponse(w, r, err)
+		return
 	}
 
 	if r.Body == nil {
 		app.logger.Error().Msg("Bad Request: Empty request body")
-		http.Error(w, "Bad Request: Empty request body", http.StatusBadRequest)
+		app.invalidBodyResponse(w, r, nil)
 		return
 	}
 
 	app.logger.Debug().Msg("reading extension from database")
-	extension, err := app.models.Extensions.Read(idInt)
-	if err != nil {
-		app.logger.Error().Msg(fmt.Sprintf("Error while reading extension with id %d: %v", idInt, err))
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	extension, ok := app.readExtension(w, r, idInt)
+	if !ok || !app.authorize(w, r, app.access(r).requireExtensionRole(extension, data.RoleAdmin)) {
+		return
+	}
+
+	if app.preconditionFailed(w, r, extension.Version) {
 		return
 	}
 
@@ -479,37 +568,60 @@ func (app *application) updateExtension(w http.ResponseWriter, r *http.Request)
 	err = app.readJSON(w, r, &extensionUpdateRequest)
 	if err != nil {
 		app.logger.Error().Msg(fmt.Sprintf("Bad Request: Invalid JSON request: %v", r.Body))
-		http.Error(w, "could not read extension update request content from request body", http.StatusBadRequest)
+		app.invalidBodyResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if extensionUpdateRequest.validate(v); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	tx, models, err := app.beginChanges()
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
 		return
 	}
+	defer rollbackChanges(tx)
 
 	app.logger.Debug().Msg(fmt.Sprintf("Updating extension with id %d", idInt))
-	err = app.models.Extensions.Update(extension, extensionUpdateRequest.Name, extensionUpdateRequest.Description)
+	before := *extension
+	err = models.Extensions.Update(extension, extensionUpdateRequest.Name, extensionUpdateRequest.Description)
+	if errors.Is(err, data.ErrEditConflict) {
+		app.editConflictResponse(w, r)
+		return
+	}
 	if err != nil {
 		app.logger.Error().Msg(fmt.Sprintf("Error while updating extension with id %d: %v", idInt, err))
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		app.serverErrorResponse(w, r)
 		return
 	}
 
-	app.logger.Debug().Msg(fmt.Sprintf("Sending confirmation to client"))
-	headers := make(http.Header)
-	headers.Set("Location", fmt.Sprintf("/extensions/%d", extension.ID))
+	if extensionUpdateRequest.Name != "" {
+		extension.Name = extensionUpdateRequest.Name
+	}
+	extension.Description = extensionUpdateRequest.Description
+	err = app.commitChange(r, tx, models, changeExtension, changeUpdated, &before, extension)
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while updating extension with id %d: %v", idInt, err))
+		app.serverErrorResponse(w, r)
+		return
+	}
 
-	err = app.writeJSON(w, http.StatusNoContent, nil, headers)
+	app.logger.Debug().Msg(fmt.Sprintf("Sending confirmation to client"))
+	w.Header().Set("Location", fmt.Sprintf("/extensions/%d", extension.ID))
+	w.Header().Set("ETag", etag(extension.Version))
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func (app *application) createExtension(w http.ResponseWriter, r *http.Request) {
 	app.logger.Info().Msg("got request to create extension")
 	app.logger.Info().Msg("Validating request")
-	if "/extensions" != r.URL.Path {
-		app.logger.Error().Msg(fmt.Sprintf("Bad Request: Invalid path: %s", r.URL.Path))
-		http.Error(w, "Bad Request", http.StatusBadRequest)
-		return
-	}
-
 	if r.Body == nil {
 		app.logger.Error().Msg("Bad Request: Empty request body")
-		http.Error(w, "Bad Request: Empty request body", http.StatusBadRequest)
+		app.invalidBodyResponse(w, r, nil)
 		return
 	}
 
@@ -517,13 +629,14 @@ func (app *application) createExtension(w http.ResponseWriter, r *http.Request)
 	err := app.readJSON(w, r, &requestData)
 	if err != nil {
 		app.logger.Error().Msg(fmt.Sprintf("Bad Request: Invalid JSON request: %v", err))
-		http.Error(w, "Bad Request data! Wrong content format!", http.StatusBadRequest)
+		app.invalidBodyResponse(w, r, err)
 		return
 	}
 
-	if requestData.Name == "" || requestData.Scope == "" || requestData.Scope == data.ScopeProject && requestData.ProjectID < 1 || data.ScopeShared == requestData.Scope && 0 != requestData.ProjectID {
+	v := validator.New()
+	if requestData.validate(v); !v.Valid() {
 		app.logger.Error().Msg(fmt.Sprintf("Bad Request: Invalid extension create request with data: %v", requestData))
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
@@ -543,44 +656,125 @@ func (app *application) createExtension(w http.ResponseWriter, r *http.Request)
 		extension.ProjectID = data.NullInt64{NullInt64: sql.NullInt64{Int64: requestData.ProjectID, Valid: true}}
 	}
 
+	if !app.authorize(w, r, app.access(r).requireExtensionRole(&extension, data.RoleAdmin)) {
+		return
+	}
+
+	tx, models, err := app.beginChanges()
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+		return
+	}
+	defer rollbackChanges(tx)
+
 	app.logger.Info().Msg(fmt.Sprintf("Creating extension: %v", extension))
-	err = app.models.Extensions.Insert(&extension)
+	err = models.Extensions.Insert(&extension)
+	if err == nil {
+		err = app.commitChange(r, tx, models, changeExtension, changeCreated, nil, &extension)
+	}
 	if err != nil {
 		app.logger.Err(err)
-		http.Error(w, "Internal Server Error during creation of new extension", http.StatusInternalServerError)
+		app.serverErrorResponse(w, r)
 		return
 	}
 
 	headers := make(http.Header)
 	headers.Set("Location", fmt.Sprintf("/extensions/%d", extension.ID))
+	headers.Set("ETag", etag(extension.Version))
 
 	err = app.writeJSON(w, http.StatusCreated, envelope{"extension": extension}, headers)
 	if err != nil {
 		app.logger.Err(err)
-		http.Error(w, "error while trying to write extension to http response.", http.StatusInternalServerError)
+		app.serverErrorResponse(w, r)
 		return
 	}
 }
 
-// getExtensions handles the GET request for all extensions.
-// It reads the URL path and calls the appropriate handler based on the path.
-// If the path is `/extensions`, it returns all extensions stored in the database.
-// If the path is `/extensions/<scope>`, it returns all extensions with the specified scope.
-// If the scope is not valid, it returns a 400 Bad Request.
-func (app *application) getExtensions(w http.ResponseWriter, r *http.Request) {
-	path := r.URL.Path
-	basePath := "/extensions"
-
-	if path == basePath {
-		app.handleGetAllExtensions(w, r)
-	} else if strings.HasPrefix(path, basePath+"/") {
-		scope := path[len(basePath+"/"):]
+// getExtension handles the GET request for a specific extension.
+// It returns the extension with the ID of the URL together with the number of its items and its version as ETag.
+// If the ID is not a valid integer, it returns a 400 Bad Request.
+// If the extension with the specified ID is not found, it returns a 404 Not Found.
+// If the If-None-Match header matches the ETag, it returns a 304 Not Modified without body.
+// If the URL names a scope instead of an ID, in any case, it returns all extensions of the scope.
+func (app *application) getExtension(w http.ResponseWriter, r *http.Request) {
+	if scope := r.PathValue("id"); isScope(scope) {
 		app.handleGetExtensionsByScope(scope, w, r)
-	} else {
-		http.NotFound(w, r)
+		return
+	}
+
+	idInt, err := app.readIDParam(r)
+	if err != nil {
+		app.logger.Warn().Msg(fmt.Sprintf("Bad Request in %s: %v", GetFunctionName(), err))
+		app.invalidParameterResponse(w, r, err)
+		return
+	}
+
+	extension, ok := app.readExtension(w, r, idInt)
+	if !ok || app.notModified(w, r, extension.Version) {
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", etag(extension.Version))
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"extension": extension}, headers)
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+		return
 	}
 }
 
+// getExtensionItems handles the GET request for the items of a specific extension.
+// It accepts the same filters, sorting and pagination as getItems, except for extension_id which is taken from the URL.
+// If the ID or a query parameter is invalid, it returns a 400 Bad Request.
+// If the extension with the specified ID is not found, it returns a 404 Not Found.
+func (app *application) getExtensionItems(w http.ResponseWriter, r *http.Request) {
+	idInt, err := app.readIDParam(r)
+	if err != nil {
+		app.logger.Warn().Msg(fmt.Sprintf("Bad Request in %s: %v", GetFunctionName(), err))
+		app.invalidParameterResponse(w, r, err)
+		return
+	}
+
+	qs := r.URL.Query()
+	qs.Del("extension_id")
+	filter, filters, err := app.readItemListQuery(qs)
+	if err != nil {
+		app.logger.Info().Msg(fmt.Sprintf("bad request in %s: %v", GetFunctionName(), err))
+		app.invalidParameterResponse(w, r, err)
+		return
+	}
+	filter.ExtensionID = idInt
+
+	if _, ok := app.readExtension(w, r, idInt); !ok {
+		return
+	}
+
+	app.logger.Debug().Msg(fmt.Sprintf("reading items of extension %d from database", idInt))
+	app.writeItemList(w, r, filter, filters, fmt.Sprintf("extension-%d-items", idInt))
+}
+
+// readExtension reads the extension with the given ID and answers the request with
+// 404 Not Found if it does not exist or 500 Internal Server Error if the query fails.
+// Returns: The extension and true, or false if the response has already been written.
+func (app *application) readExtension(w http.ResponseWriter, r *http.Request, id int64) (*data.Extension, bool) {
+	app.logger.Debug().Msg(fmt.Sprintf("Reading extension from database using id %d", id))
+	extension, err := app.models.Extensions.Read(id)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.notFoundResponse(w, r, fmt.Sprintf("no extension with id %d found", id))
+		} else {
+			app.logger.Error().Msg(fmt.Sprintf("Error while reading extension with id %d: %v", id, err))
+			app.serverErrorResponse(w, r)
+		}
+		return nil, false
+	}
+
+	return extension, true
+}
+
 // handleGetAllExtensions handles the GET request for all extensions.
 // It returns all extensions stored in the database.
 // If there are no extensions in the database, it returns a 404 Not Found.
@@ -588,35 +782,34 @@ func (app *application) handleGetAllExtensions(w http.ResponseWriter, r *http.Re
 	extensions, err := app.models.Extensions.ReadAll()
 	if err != nil {
 		app.logger.Err(err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		app.serverErrorResponse(w, r)
 		return
 	}
 	err = app.writeJSON(w, http.StatusOK, envelope{"extensions": extensions}, nil)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("error while trying to write extensions to http response. error: %s", err), http.StatusInternalServerError)
+		app.serverErrorResponse(w, r)
 		app.logger.Err(err)
 		return
 	}
 }
 
+// isScope reports whether value names a scope, compared case-insensitively.
+func isScope(value string) bool {
+	return strings.EqualFold(value, data.ScopeShared) || strings.EqualFold(value, data.ScopeProject)
+}
+
 // handleGetExtensionsByScope handles the GET request for all extensions with the specified scope.
-// It reads the scope from the URL and returns all extensions with that scope.
-// If the scope is not valid, it returns a 400 Bad Request.
+// It returns all extensions with that scope.
 func (app *application) handleGetExtensionsByScope(scope string, w http.ResponseWriter, r *http.Request) {
-	if strings.ToLower(scope) != "project" && strings.ToLower(scope) != "shared" {
-		app.logger.Warn().Msg(fmt.Sprintf("Invalid scope: %s | responding with %s", scope, http.StatusText(http.StatusBadRequest)))
-		http.Error(w, "Invalid scope", http.StatusBadRequest)
-		return
-	}
 	extensions, err := app.models.Extensions.ReadAll(scope)
 	if err != nil {
 		app.logger.Err(err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		app.serverErrorResponse(w, r)
 		return
 	}
 	err = app.writeJSON(w, http.StatusOK, envelope{"extensions": extensions}, nil)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("error while trying to write extensions to http response. error: %s", err), http.StatusInternalServerError)
+		app.serverErrorResponse(w, r)
 		app.logger.Err(err)
 		return
 	}
@@ -626,11 +819,11 @@ func (app *application) handleGetExtensionsByScope(scope string, w http.Response
 // It returns all projects stored in the database.
 // If there are no projects in the database, it returns a 404 Not Found.
 // If there is an error while reading the projects from the database, it returns a 500 Internal Server Error.
-func (app *application) getProjects(w http.ResponseWriter) {
+func (app *application) getProjects(w http.ResponseWriter, r *http.Request) {
 	projects, err := app.models.Projects.ReadAll()
 	if err != nil {
 		app.logger.Error().Msg(fmt.Sprintf("Error while trying to read project records from database: %s", err))
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		app.serverErrorResponse(w, r)
 		return
 	}
 
@@ -638,15 +831,128 @@ func (app *application) getProjects(w http.ResponseWriter) {
 	if err != nil {
 		msg := fmt.Sprintf("Error while trying to write projects to http response. error: %s", err)
 		app.logger.Error().Msg(msg)
-		http.Error(w, msg, http.StatusInternalServerError)
+		app.serverErrorResponse(w, r)
+		return
+	}
+}
+
+// getProject handles the GET request for a specific project.
+// The version of the project is returned as ETag.
+// If the ID is not a valid integer, it returns a 400 Bad Request.
+// If the project with the specified ID is not found, it returns a 404 Not Found.
+// If the If-None-Match header matches the ETag, it returns a 304 Not Modified without body.
+func (app *application) getProject(w http.ResponseWriter, r *http.Request) {
+	idInt, err := app.readIDParam(r)
+	if err != nil {
+		app.logger.Warn().Msg(fmt.Sprintf("Bad Request in %s: %v", GetFunctionName(), err))
+		app.invalidParameterResponse(w, r, err)
+		return
+	}
+
+	project, ok := app.readProject(w, r, idInt)
+	if !ok || app.notModified(w, r, project.Version) {
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", etag(project.Version))
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"project": project}, headers)
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+		return
+	}
+}
+
+// getProjectExtensions handles the GET request for the extensions of a specific project.
+// If the ID is not a valid integer, it returns a 400 Bad Request.
+// If the project with the specified ID is not found, it returns a 404 Not Found.
+func (app *application) getProjectExtensions(w http.ResponseWriter, r *http.Request) {
+	idInt, err := app.readIDParam(r)
+	if err != nil {
+		app.logger.Warn().Msg(fmt.Sprintf("Bad Request in %s: %v", GetFunctionName(), err))
+		app.invalidParameterResponse(w, r, err)
+		return
+	}
+
+	if _, ok := app.readProject(w, r, idInt); !ok {
+		return
+	}
+
+	app.logger.Debug().Msg(fmt.Sprintf("reading extensions of project %d from database", idInt))
+	extensions, err := app.models.Extensions.ReadAllByProject(idInt)
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while reading extensions of project %d: %v", idInt, err))
+		app.serverErrorResponse(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"extensions": extensions}, nil)
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
 		return
 	}
 }
 
+// getProjectItems handles the GET request for the items of the extensions of a specific project.
+// It accepts the same filters, sorting, pagination and formats as getItems, except for project which is taken from the URL.
+// If the ID or a query parameter is invalid, it returns a 400 Bad Request.
+// If the project with the specified ID is not found, it returns a 404 Not Found.
+func (app *application) getProjectItems(w http.ResponseWriter, r *http.Request) {
+	idInt, err := app.readIDParam(r)
+	if err != nil {
+		app.logger.Warn().Msg(fmt.Sprintf("Bad Request in %s: %v", GetFunctionName(), err))
+		app.invalidParameterResponse(w, r, err)
+		return
+	}
+
+	qs := r.URL.Query()
+	qs.Del("project")
+	filter, filters, err := app.readItemListQuery(qs)
+	if err != nil {
+		app.logger.Info().Msg(fmt.Sprintf("bad request in %s: %v", GetFunctionName(), err))
+		app.invalidParameterResponse(w, r, err)
+		return
+	}
+
+	if _, ok := app.readProject(w, r, idInt); !ok {
+		return
+	}
+	filter.ProjectID = idInt
+
+	app.logger.Debug().Msg(fmt.Sprintf("reading items of project %d from database", idInt))
+	app.writeItemList(w, r, filter, filters, fmt.Sprintf("project-%d-items", idInt))
+}
+
+// readProject reads the project with the given ID and answers the request with
+// 404 Not Found if it does not exist or 500 Internal Server Error if the query fails.
+// Returns: The project and true, or false if the response has already been written.
+func (app *application) readProject(w http.ResponseWriter, r *http.Request, id int64) (*data.Project, bool) {
+	app.logger.Debug().Msg(fmt.Sprintf("Reading project from database using id %d", id))
+	project, err := app.models.Projects.Read(id)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.notFoundResponse(w, r, fmt.Sprintf("no project with id %d found", id))
+		} else {
+			app.logger.Error().Msg(fmt.Sprintf("Error while reading project with id %d: %v", id, err))
+			app.serverErrorResponse(w, r)
+		}
+		return nil, false
+	}
+
+	return project, true
+}
+
 func (app *application) createProject(w http.ResponseWriter, r *http.Request) {
+	if !app.authorize(w, r, app.access(r).requireGlobalAdmin("create projects")) {
+		return
+	}
+
 	if r.Body == nil {
 		app.logger.Error().Msg("Bad Request: Empty request body")
-		http.Error(w, "Bad Request: Empty request body", http.StatusBadRequest)
+		app.invalidBodyResponse(w, r, nil)
 		return
 	}
 
@@ -656,14 +962,15 @@ func (app *application) createProject(w http.ResponseWriter, r *http.Request) {
 	err := app.readJSON(w, r, &projectRequest)
 	if err != nil {
 		app.logger.Error().Msg(fmt.Sprintf("Bad Request: Invalid JSON request: %v", r.Body))
-		http.Error(w, "could not read project creation request content from body", http.StatusBadRequest)
+		app.invalidBodyResponse(w, r, err)
 		return
 	}
 
 	app.logger.Debug().Msg("validating request")
-	if projectRequest.Name == "" {
-		app.logger.Error().Msg(fmt.Sprintf("project request name mustn't be empty at the same time!"))
-		http.Error(w, "project name mustn't be empty!", http.StatusBadRequest)
+	v := validator.New()
+	if projectRequest.validate(v); !v.Valid() {
+		app.logger.Error().Msg(fmt.Sprintf("Bad Request: Invalid project create request with data: %v", projectRequest))
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 	app.logger.Debug().Msg("request valid!")
@@ -675,46 +982,58 @@ func (app *application) createProject(w http.ResponseWriter, r *http.Request) {
 		Description: projectRequest.Description,
 	}
 
-	err = app.models.Projects.Insert(&project)
+	tx, models, err := app.beginChanges()
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+		return
+	}
+	defer rollbackChanges(tx)
+
+	err = models.Projects.Insert(&project)
+	if err == nil {
+		err = app.commitChange(r, tx, models, changeProject, changeCreated, nil, &project)
+	}
 	if err != nil {
 		app.logger.Err(err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		app.serverErrorResponse(w, r)
 		return
 	}
 
 	headers := make(http.Header)
 	headers.Set("Location", fmt.Sprintf("/projects/%d", project.ID))
+	headers.Set("ETag", etag(project.Version))
 
 	err = app.writeJSON(w, http.StatusCreated, envelope{"project": project}, headers)
 	if err != nil {
 		app.logger.Err(err)
-		http.Error(w, "error while trying to write item to http response", http.StatusInternalServerError)
+		app.serverErrorResponse(w, r)
 		return
 	}
 }
 
 func (app *application) updateProject(w http.ResponseWriter, r *http.Request) {
 	app.logger.Debug().Msg("reading project id from url")
-	id := r.URL.Path[len("/projects/"):]
-	idInt, err := strconv.ParseInt(id, 10, 64)
+	idInt, err := app.readIDParam(r)
 	if err != nil {
-		app.logger.Warn().Msg(fmt.Sprintf("Bad Request in %s using id %s",
-			GetFunctionName(),
-			id))
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+		app.logger.Warn().Msg(fmt.Sprintf("Bad Request in %s: %v", GetFunctionName(), err))
+		app.invalidParameterResponse(w, r, err)
+		return
 	}
 
 	if r.Body == nil {
 		app.logger.Error().Msg("Bad Request: Empty request body")
-		http.Error(w, "Bad Request: Empty request body", http.StatusBadRequest)
+		app.invalidBodyResponse(w, r, nil)
 		return
 	}
 
 	app.logger.Debug().Msg("reading project from database")
-	project, err := app.models.Projects.Read(idInt)
-	if err != nil {
-		app.logger.Error().Msg(fmt.Sprintf("Error while reading project with id %d: %v", idInt, err))
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	project, ok := app.readProject(w, r, idInt)
+	if !ok || !app.authorize(w, r, app.access(r).requireProjectRole(project.ID, data.RoleAdmin)) {
+		return
+	}
+
+	if app.preconditionFailed(w, r, project.Version) {
 		return
 	}
 
@@ -725,47 +1044,97 @@ func (app *application) updateProject(w http.ResponseWriter, r *http.Request) {
 	err = app.readJSON(w, r, &projectUpdateRequest)
 	if err != nil {
 		app.logger.Error().Msg(fmt.Sprintf("Bad Request: Invalid JSON request: %v", r.Body))
-		http.Error(w, "could not read project update request content from request body", http.StatusBadRequest)
+		app.invalidBodyResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if projectUpdateRequest.validate(v); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	tx, models, err := app.beginChanges()
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
 		return
 	}
+	defer rollbackChanges(tx)
 
 	app.logger.Debug().Msg(fmt.Sprintf("Updating project with id %d", idInt))
-	err = app.models.Projects.Update(project, projectUpdateRequest.Name, projectUpdateRequest.Description)
+	before := *project
+	err = models.Projects.Update(project, projectUpdateRequest.Name, projectUpdateRequest.Description)
+	if errors.Is(err, data.ErrEditConflict) {
+		app.editConflictResponse(w, r)
+		return
+	}
 	if err != nil {
 		app.logger.Error().Msg(fmt.Sprintf("Error while updating project with id %d: %v", idInt, err))
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		app.serverErrorResponse(w, r)
 		return
 	}
 
-	app.logger.Debug().Msg(fmt.Sprintf("Sending confirmation to client"))
-	headers := make(http.Header)
-	headers.Set("Location", fmt.Sprintf("/projects/%d", project.ID))
-
-	err = app.writeJSON(w, http.StatusNoContent, nil, headers)
+	if projectUpdateRequest.Name != "" {
+		project.Name = projectUpdateRequest.Name
+	}
+	project.Description = projectUpdateRequest.Description
+	err = app.commitChange(r, tx, models, changeProject, changeUpdated, &before, project)
 	if err != nil {
-		app.logger.Err(err)
-		http.Error(w, "error while trying to write project to http response.", http.StatusInternalServerError)
+		app.logger.Error().Msg(fmt.Sprintf("Error while updating project with id %d: %v", idInt, err))
+		app.serverErrorResponse(w, r)
 		return
 	}
+
+	app.logger.Debug().Msg(fmt.Sprintf("Sending confirmation to client"))
+	w.Header().Set("Location", fmt.Sprintf("/projects/%d", project.ID))
+	w.Header().Set("ETag", etag(project.Version))
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func (app *application) deleteProject(w http.ResponseWriter, r *http.Request) {
 	app.logger.Debug().Msg("reading project id from url")
-	id := r.URL.Path[len("/projects/"):]
-	idInt, err := strconv.ParseInt(id, 10, 64)
+	idInt, err := app.readIDParam(r)
+	if err != nil {
+		app.logger.Warn().Msg(fmt.Sprintf("Bad Request in %s: %v", GetFunctionName(), err))
+		app.invalidParameterResponse(w, r, err)
+		return
+	}
+
+	project, ok := app.readProject(w, r, idInt)
+	if !ok || !app.authorize(w, r, app.access(r).requireProjectRole(project.ID, data.RoleAdmin)) {
+		return
+	}
+
+	if app.preconditionFailed(w, r, project.Version) {
+		return
+	}
+
+	tx, models, err := app.beginChanges()
 	if err != nil {
-		app.logger.Warn().Msg(fmt.Sprintf("Bad Request in %s using id %s",
-			GetFunctionName(),
-			id))
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+		return
 	}
+	defer rollbackChanges(tx)
 
 	app.logger.Debug().Msg("deleting project from database")
 
-	err = app.models.Projects.Delete(idInt, app.models.Items)
+	extensions, items, err := models.Projects.Delete(idInt, project.Version, models.Items)
+	if errors.Is(err, data.ErrEditConflict) {
+		app.editConflictResponse(w, r)
+		return
+	}
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while deleting project with id %d: %v", idInt, err))
+		app.serverErrorResponse(w, r)
+		return
+	}
+	changes := append(cascadedChanges(extensions, items), pendingChange{typ: changeProject, action: changeDeleted, before: project})
+	err = app.commitChanges(r, tx, models, changes...)
 	if err != nil {
 		app.logger.Error().Msg(fmt.Sprintf("Error while deleting project with id %d: %v", idInt, err))
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		app.serverErrorResponse(w, r)
 		return
 	}
 
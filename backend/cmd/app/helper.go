@@ -7,8 +7,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/common-nighthawk/go-figure"
 )
@@ -44,10 +47,9 @@ func PrintHeader() {
 // It also checks if the request body only contains a single JSON object.
 // Returns: An error if the request body cannot be read or if the body contains more than one JSON object.
 func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
-	// Set the maximum size of the request body to 1MB. Security measure to prevent DoS attacks.
-	maxBytes := 1_048_576
+	// Limit the size of the request body, see -max-body-size. Security measure to prevent DoS attacks.
 	// Create new Reader which limits the size of the request body which can be read from the body.
-	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
+	r.Body = http.MaxBytesReader(w, r.Body, app.maxBodySize())
 
 	dec := json.NewDecoder(r.Body)
 	if err := dec.Decode(dst); err != nil {
@@ -63,6 +65,68 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any
 	return nil
 }
 
+// readIDParam reads the {id} wildcard of the matched route pattern.
+// Returns: The ID or an error if the value is not a positive integer.
+func (app *application) readIDParam(r *http.Request) (int64, error) {
+	return app.readPathID(r, "id")
+}
+
+// readPathID reads the wildcard name of the matched route pattern, e.g. delivery_id of /webhooks/{id}/deliveries/{delivery_id}.
+// Returns: The ID or an error if the value is not a positive integer.
+func (app *application) readPathID(r *http.Request, name string) (int64, error) {
+	value := r.PathValue(name)
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid %s %q", name, value)
+	}
+
+	return id, nil
+}
+
+// readString returns the value of key in the query string or defaultValue if the key is missing.
+func (app *application) readString(qs url.Values, key string, defaultValue string) string {
+	s := strings.TrimSpace(qs.Get(key))
+	if s == "" {
+		return defaultValue
+	}
+
+	return s
+}
+
+// readInt returns the integer value of key in the query string or defaultValue if the key is missing.
+// Returns: An error if the value is not an integer.
+func (app *application) readInt(qs url.Values, key string, defaultValue int) (int, error) {
+	s := strings.TrimSpace(qs.Get(key))
+	if s == "" {
+		return defaultValue, nil
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return defaultValue, fmt.Errorf("%s must be an integer value", key)
+	}
+
+	return i, nil
+}
+
+// readTime returns the time of key in the query string or nil if the key is missing.
+// Both RFC 3339 timestamps and plain dates like 2024-01-31 (midnight UTC) are accepted.
+// Returns: An error if the value has none of these formats.
+func (app *application) readTime(qs url.Values, key string) (*time.Time, error) {
+	s := strings.TrimSpace(qs.Get(key))
+	if s == "" {
+		return nil, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s must be a date (2006-01-02) or an RFC 3339 timestamp", key)
+}
+
 // writeJSON is a utility method of the application struct that facilitates the process of sending data to the client.
 // It takes in a http.ResponseWriter, a status code, a map of data, and a set of headers.
 // The primary function of this method is to convert the provided data into a format that can be easily consumed by the client.
@@ -73,7 +137,9 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any
 func (app *application) writeJSON(w http.ResponseWriter, status int, data envelope, headers http.Header) error {
 	js, err := json.MarshalIndent(data, "", "\t")
 	if err != nil {
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(marshalFailedProblem))
 		app.logger.Printf("Error occurred during writing json data. Err: %v", err)
 		return err
 	}
@@ -96,6 +162,117 @@ func (app *application) writeJSON(w http.ResponseWriter, status int, data envelo
 	return nil
 }
 
+// marshalFailedProblem is the problem written by writeJSON and writeProblem if the response cannot be marshalled.
+const marshalFailedProblem = `{"type": "about:blank", "title": "Internal Server Error", "status": 500, "code": "` + codeInternal + `"}` + "\n"
+
+// writeProblem is the counterpart of writeJSON for error responses.
+// It writes the problem with the content type application/problem+json of RFC 7807.
+// Missing members are completed: the title from the status, the instance from the request path
+// and the request ID from the request context, see assignRequestID.
+func (app *application) writeProblem(w http.ResponseWriter, r *http.Request, p problem) {
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	if p.Code == "" {
+		p.Code = problemCode(p.Status)
+	}
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+	p.RequestID = requestIDFromContext(r.Context())
+
+	app.logger.Debug().Msg(fmt.Sprintf("Sending %d %s for %s %s: %s", p.Status, p.Code, r.Method, r.URL.Path, p.Detail))
+
+	js, err := json.MarshalIndent(p, "", "\t")
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while marshalling problem details: %v", err))
+		js = []byte(marshalFailedProblem)
+		p.Status = http.StatusInternalServerError
+	} else {
+		js = append(js, '\n')
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	if _, err := w.Write(js); err != nil {
+		app.logger.Err(err)
+	}
+}
+
+// etag returns the entity tag of a resource with the given version.
+func etag(version int32) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// etagMatches reports whether the comma separated entity tags of an If-Match or If-None-Match header
+// contain the entity tag of version or the wildcard *.
+// Weak tags (W/"1") only match if weak is true, since If-Match requires the strong comparison.
+func etagMatches(header string, version int32, weak bool) bool {
+	tag := etag(version)
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// preconditionFailed compares the If-Match header of a PUT or DELETE request with the current version of a resource.
+// If the header is present and does not match, it answers the request with 412 Precondition Failed.
+// Returns: true if the response has been written.
+func (app *application) preconditionFailed(w http.ResponseWriter, r *http.Request, version int32) bool {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" || etagMatches(ifMatch, version, false) {
+		return false
+	}
+
+	app.logger.Info().Msg(fmt.Sprintf("If-Match %s of %s %s does not match the current ETag %s", ifMatch, r.Method, r.URL.Path, etag(version)))
+	w.Header().Set("ETag", etag(version))
+	app.errorResponse(w, r, http.StatusPreconditionFailed, codePreconditionFailed, "the resource has been modified in the meantime, reload it and try again")
+	return true
+}
+
+// notModified compares the If-None-Match header of a GET request with the current version of a resource.
+// If it matches, it answers the request with 304 Not Modified.
+// Returns: true if the response has been written.
+func (app *application) notModified(w http.ResponseWriter, r *http.Request, version int32) bool {
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	if ifNoneMatch == "" || !etagMatches(ifNoneMatch, version, true) {
+		return false
+	}
+
+	w.Header().Set("ETag", etag(version))
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// editConflictResponse answers a request whose update failed with data.ErrEditConflict,
+// i.e. the resource has been changed by another request after it has been read.
+// Clients which sent If-Match receive 412 Precondition Failed, all others 409 Conflict.
+func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Request) {
+	app.logger.Info().Msg(fmt.Sprintf("edit conflict during %s %s", r.Method, r.URL.Path))
+	msg := "the resource has been modified in the meantime, reload it and try again"
+	if r.Header.Get("If-Match") != "" {
+		app.errorResponse(w, r, http.StatusPreconditionFailed, codePreconditionFailed, msg)
+		return
+	}
+	app.errorResponse(w, r, http.StatusConflict, codeEditConflict, msg)
+}
+
 // calculateTypecode determines the next available typecode for a given scope.
 // This function is crucial for ensuring that each item within a specific scope receives a unique typecode.
 // Currently, it supports 'data.ScopeHybris', 'data.ScopeShared' and 'data.ScopeProject'
@@ -131,3 +131,25 @@ func TestCalculateTypecodeReturnsNegativeTypecodeWhenInvalidScopeIsPassed(t *tes
 		t.Fatalf("Expected error but got none!")
 	}
 }
+
+func TestETagMatchesComparesEntityTagLists(t *testing.T) {
+	tests := []struct {
+		header string
+		weak   bool
+		match  bool
+	}{
+		{`"3"`, false, true},
+		{`"2", "3"`, false, true},
+		{`*`, false, true},
+		{`"4"`, false, false},
+		{`W/"3"`, false, false},
+		{`W/"3"`, true, true},
+		{`3`, true, false},
+	}
+
+	for _, test := range tests {
+		if match := etagMatches(test.header, 3, test.weak); match != test.match {
+			t.Errorf("etagMatches(%s, 3, %t) = %t, expected %t", test.header, test.weak, match, test.match)
+		}
+	}
+}
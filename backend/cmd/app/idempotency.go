@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+	"unicode/utf8"
+)
+
+// maxIdempotencyKeySize is the maximum number of characters of an Idempotency-Key header.
+const maxIdempotencyKeySize = 255
+
+// replayedHeaders are the response headers which are stored together with the response of an idempotent request.
+var replayedHeaders = []string{"Content-Type", "Location", "ETag"}
+
+// responseRecorder passes the response of a handler to the client and keeps a copy of its status and body.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// idempotent wraps the handler of a POST route to support the Idempotency-Key header.
+// The first request with a key is executed and its response is stored for the configured retention period.
+// Repeated requests with the same key and body receive the stored response with the header Idempotent-Replayed
+// instead of being executed again, so retrying a request never allocates a second typecode.
+//
+//   - If the key has already been used for a different body, it returns a 422 Unprocessable Entity.
+//   - If the first request with the key is still being processed, it returns a 409 Conflict.
+//
+// Responses with a 5xx status or 403 Forbidden are not stored, so the request can be retried with the same key,
+// e.g. after the principal has been given a role.
+// The key is released as well if the handler panics.
+// Keys of authenticated requests are scoped to their principal, so nobody receives the stored response of another principal.
+// Requests without the header are passed to the handler unchanged.
+func (app *application) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next(w, r)
+			return
+		}
+
+		if utf8.RuneCountInString(key) > maxIdempotencyKeySize {
+			app.errorResponse(w, r, http.StatusBadRequest, codeInvalidParameter, fmt.Sprintf("Idempotency-Key must not be longer than %d characters", maxIdempotencyKeySize))
+			return
+		}
+
+		var body []byte
+		if r.Body != nil {
+			var err error
+			body, err = io.ReadAll(http.MaxBytesReader(w, r.Body, app.maxBodySize()))
+			if isBodyTooLarge(err) {
+				app.invalidBodyResponse(w, r, err)
+				return
+			}
+			if err != nil {
+				app.logger.Error().Msg(fmt.Sprintf("Bad Request: could not read request body: %v", err))
+				app.errorResponse(w, r, http.StatusBadRequest, codeInvalidBody, fmt.Sprintf("could not read request body: %v", err))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		hash := sha256.Sum256(body)
+		requestHash := hex.EncodeToString(hash[:])
+		endpoint := r.Method + " " + r.URL.Path
+		if principal := principalFromContext(r.Context()); principal != nil {
+			endpoint += " " + principal.Subject
+		}
+
+		stored, err := app.models.Idempotency.Reserve(key, endpoint, requestHash, time.Now().Add(-app.config.idempotencyRetention))
+		if err != nil {
+			app.logger.Error().Msg(fmt.Sprintf("Error while reserving idempotency key for %s: %v", endpoint, err))
+			app.serverErrorResponse(w, r)
+			return
+		}
+
+		if stored != nil {
+			switch {
+			case stored.RequestHash != requestHash:
+				app.errorResponse(w, r, http.StatusUnprocessableEntity, codeIdempotencyKeyReused, "Idempotency-Key has already been used for a different request")
+			case stored.StatusCode == 0:
+				app.errorResponse(w, r, http.StatusConflict, codeRequestInProgress, "a request with this Idempotency-Key is still being processed")
+			default:
+				app.logger.Debug().Msg(fmt.Sprintf("replaying response of %s for idempotency key %s", endpoint, key))
+				app.replayResponse(w, stored.StatusCode, stored.Headers, stored.Body)
+			}
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w}
+		defer func() {
+			// recoverPanic answers the request, but the key would stay reserved until it expires.
+			if err := recover(); err != nil {
+				app.releaseIdempotencyKey(key, endpoint)
+				panic(err)
+			}
+		}()
+		next(rec, r)
+
+		if rec.status == 0 || rec.status == http.StatusForbidden || rec.status >= http.StatusInternalServerError {
+			app.releaseIdempotencyKey(key, endpoint)
+			return
+		}
+
+		headers := make(http.Header)
+		for _, name := range replayedHeaders {
+			if value := rec.Header().Get(name); value != "" {
+				headers.Set(name, value)
+			}
+		}
+		js, _ := json.Marshal(headers)
+
+		err = app.models.Idempotency.Complete(key, endpoint, rec.status, string(js), rec.body.Bytes())
+		if err != nil {
+			app.logger.Error().Msg(fmt.Sprintf("Error while storing response for idempotency key of %s: %v", endpoint, err))
+		}
+	}
+}
+
+// releaseIdempotencyKey releases the key reserved by idempotent, so the request can be retried with it.
+func (app *application) releaseIdempotencyKey(key, endpoint string) {
+	if err := app.models.Idempotency.Release(key, endpoint); err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while releasing idempotency key for %s: %v", endpoint, err))
+	}
+}
+
+// replayResponse writes a response stored by idempotent.
+func (app *application) replayResponse(w http.ResponseWriter, status int, headers string, body []byte) {
+	var stored http.Header
+	if err := json.Unmarshal([]byte(headers), &stored); err == nil {
+		for name, values := range stored {
+			w.Header()[name] = values
+		}
+	}
+
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(status)
+	if _, err := w.Write(body); err != nil {
+		app.logger.Err(err)
+	}
+}
+
+// deleteExpiredIdempotencyKeys deletes the idempotency keys older than the retention period every interval.
+// It runs until the application exits.
+func (app *application) deleteExpiredIdempotencyKeys(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		deleted, err := app.models.Idempotency.DeleteExpired(time.Now().Add(-app.config.idempotencyRetention))
+		if err != nil {
+			app.logger.Error().Msg(fmt.Sprintf("Error while deleting expired idempotency keys: %v", err))
+			continue
+		}
+		app.logger.Debug().Msg(fmt.Sprintf("deleted %d expired idempotency keys", deleted))
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// projectRequestHash is the SHA-256 hash of projectRequestBody.
+const (
+	projectRequestBody = `{"name": "Alpha"}`
+	projectRequestHash = "6323bf043b122415cecc5cdeb797d7696e4239e8ca7f6868d974d1a6caf6e77c"
+)
+
+func mockReserveIdempotencyKey(mock sqlmock.Sqlmock, key, endpoint string, claimed bool) {
+	query := regexp.QuoteMeta(`INSERT INTO idempotency_key (key, endpoint, request_hash)`)
+	rows := sqlmock.NewRows([]string{"key"})
+	if claimed {
+		rows.AddRow(key)
+	}
+	mock.ExpectQuery(query).WithArgs(key, endpoint, sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnRows(rows)
+}
+
+func mockReadIdempotentResponse(mock sqlmock.Sqlmock, key, endpoint, requestHash string, status any, headers any, body []byte) {
+	query := regexp.QuoteMeta(`SELECT key, endpoint, request_hash, status_code, headers, body, creation_date
+		FROM idempotency_key`)
+	mock.ExpectQuery(query).WithArgs(key, endpoint).WillReturnRows(
+		sqlmock.NewRows([]string{"key", "endpoint", "request_hash", "status_code", "headers", "body", "creation_date"}).
+			AddRow(key, endpoint, requestHash, status, headers, body, time.Now()))
+}
+
+func postWithIdempotencyKey(app *application, path, key, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+	req.Header.Set("Idempotency-Key", key)
+	resp := httptest.NewRecorder()
+	app.route().ServeHTTP(resp, req)
+	return resp
+}
+
+func TestFirstRequestWithIdempotencyKeyStoresResponse(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	app.config.idempotencyRetention = time.Hour
+
+	mockReserveIdempotencyKey(mock, "key-1", "POST /projects", true)
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO project (name, description)`)).
+		WithArgs("Alpha", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "creation_date", "version"}).AddRow(4, time.Now(), 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE idempotency_key`)).
+		WithArgs("key-1", "POST /projects", http.StatusCreated, `{"Content-Type":["application/json"],"Etag":["\"1\""],"Location":["/projects/4"]}`, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	resp := postWithIdempotencyKey(app, "/projects", "key-1", projectRequestBody)
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Empty(t, resp.Header().Get("Idempotent-Replayed"))
+	checkExpectations(t, mock)
+}
+
+func TestRepeatedRequestWithIdempotencyKeyReplaysResponse(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+
+	mockReserveIdempotencyKey(mock, "key-1", "POST /projects", false)
+	mockReadIdempotentResponse(mock, "key-1", "POST /projects", projectRequestHash, http.StatusCreated,
+		`{"Location":["/projects/4"]}`, []byte(`{"project": {"id": 4}}`))
+
+	resp := postWithIdempotencyKey(app, "/projects", "key-1", projectRequestBody)
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Equal(t, "true", resp.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, "/projects/4", resp.Header().Get("Location"))
+	assert.Equal(t, `{"project": {"id": 4}}`, resp.Body.String())
+	checkExpectations(t, mock)
+}
+
+func TestIdempotencyKeyReusedWithDifferentBodyReturnsUnprocessableEntity(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+
+	mockReserveIdempotencyKey(mock, "key-1", "POST /projects", false)
+	mockReadIdempotentResponse(mock, "key-1", "POST /projects", projectRequestHash, http.StatusCreated, "{}", nil)
+
+	resp := postWithIdempotencyKey(app, "/projects", "key-1", `{"name": "Beta"}`)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	checkExpectations(t, mock)
+}
+
+func TestIdempotencyKeyOfRunningRequestReturnsConflict(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+
+	mockReserveIdempotencyKey(mock, "key-1", "POST /projects", false)
+	mockReadIdempotentResponse(mock, "key-1", "POST /projects", projectRequestHash, nil, nil, nil)
+
+	resp := postWithIdempotencyKey(app, "/projects", "key-1", projectRequestBody)
+
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	checkExpectations(t, mock)
+}
+
+func TestIdempotencyKeyIsReleasedWhenRequestFails(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+
+	mockReserveIdempotencyKey(mock, "key-1", "POST /projects", true)
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO project (name, description)`)).WillReturnError(sql.ErrConnDone)
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM idempotency_key WHERE key = $1 AND endpoint = $2`)).
+		WithArgs("key-1", "POST /projects").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	resp := postWithIdempotencyKey(app, "/projects", "key-1", projectRequestBody)
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	checkExpectations(t, mock)
+}
+
+func TestIdempotencyKeyIsReleasedWhenHandlerPanics(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	mockReserveIdempotencyKey(mock, "key-1", "POST /panic", true)
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM idempotency_key WHERE key = $1 AND endpoint = $2`)).
+		WithArgs("key-1", "POST /panic").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	handler := app.recoverPanic(app.idempotent(func(w http.ResponseWriter, r *http.Request) {
+		panic("handler failed")
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/panic", bytes.NewBufferString(projectRequestBody))
+	req.Header.Set("Idempotency-Key", "key-1")
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	checkExpectations(t, mock)
+}
+
+func TestTooLongIdempotencyKeyReturnsBadRequest(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+
+	resp := postWithIdempotencyKey(app, "/items", string(bytes.Repeat([]byte("k"), maxIdempotencyKeySize+1)), `{}`)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	checkExpectations(t, mock)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"Typecode-Registry/internal/auth"
 	"Typecode-Registry/internal/data"
 	"database/sql"
 	"flag"
@@ -13,7 +14,6 @@ import (
 	"time"
 
 	_ "github.com/lib/pq"
-	"github.com/rs/cors"
 )
 
 // config holds the configuration for the application.
@@ -21,6 +21,62 @@ type config struct {
 	port     int
 	dns      string // dated name service => db connection string.
 	loglevel string
+	// idempotencyRetention is how long responses of requests with an Idempotency-Key header are kept.
+	idempotencyRetention time.Duration
+	// changeBuffer is the number of changes kept for clients resuming the change feed, 0 disables the feed.
+	changeBuffer int
+	// webhookAttempts is the number of attempts of a webhook delivery before it has failed.
+	webhookAttempts int
+	// webhookBackoff is the delay after the first failed attempt, it doubles with every further one.
+	webhookBackoff time.Duration
+	// webhookTimeout is how long a webhook receiver may take to answer.
+	webhookTimeout time.Duration
+	// webhookRetention is how long the log of completed webhook deliveries is kept.
+	webhookRetention time.Duration
+	// webhookAllowPrivateHosts permits webhook receivers with loopback, link-local and private addresses.
+	webhookAllowPrivateHosts bool
+	// authJWKSURL and authJWKSFile are the source of the keys signing bearer tokens. Without both, requests are anonymous.
+	authJWKSURL  string
+	authJWKSFile string
+	// authIssuer is the required issuer of bearer tokens.
+	authIssuer string
+	// authAudience is the comma separated list of accepted audiences of bearer tokens.
+	authAudience string
+	// authLeeway is the tolerated clock skew when checking the expiry of bearer tokens.
+	authLeeway time.Duration
+	// authAdminRole is the app role (roles claim) of the global admins of the registry.
+	authAdminRole string
+	// authProvisionUsers registers unknown users on their first request.
+	authProvisionUsers bool
+	// authGroupRoles maps groups of the identity provider to roles in projects, see parseGroupRoles.
+	authGroupRoles string
+	// audit records all changes of projects, extensions, items and roles in the audit log.
+	audit bool
+	// auditRetention is how long entries of the audit log are kept, 0 keeps them forever.
+	auditRetention time.Duration
+	// rateLimitIPRead and rateLimitIPWrite limit the reads and writes of every IP address, see parseRateLimit.
+	rateLimitIPRead  string
+	rateLimitIPWrite string
+	// rateLimitPrincipalRead and rateLimitPrincipalWrite limit the reads and writes of every authenticated principal.
+	rateLimitPrincipalRead  string
+	rateLimitPrincipalWrite string
+	// trustForwardedFor identifies clients by the X-Forwarded-For header of a proxy instead of the remote address,
+	// and requests over TLS by its X-Forwarded-Proto header.
+	trustForwardedFor bool
+	// maxBodySize is the maximum number of bytes of a request body.
+	maxBodySize int64
+	// corsAllowedOrigins, corsAllowedMethods and corsAllowedHeaders are the comma separated origins, methods and
+	// request headers of cross-origin requests, see newCORS.
+	corsAllowedOrigins string
+	corsAllowedMethods string
+	corsAllowedHeaders string
+	// corsAllowCredentials allows cross-origin requests with cookies or client certificates.
+	corsAllowCredentials bool
+	// tlsCertFile and tlsKeyFile are the certificate and key of the server, which serves HTTPS if both are set.
+	tlsCertFile string
+	tlsKeyFile  string
+	// hstsMaxAge is how long browsers keep using HTTPS after a request over TLS, 0 sends no Strict-Transport-Security header.
+	hstsMaxAge time.Duration
 }
 
 // application holds the application-wide dependencies.
@@ -28,6 +84,17 @@ type application struct {
 	config config
 	logger *zerolog.Logger
 	models data.Models
+	// changes is the change feed, or nil if it is disabled.
+	changes *changeFeed
+	// webhooks sends the deliveries of webhooks, or is nil if they are not sent, e.g. in tests.
+	webhooks *webhookDispatcher
+	// verifier validates bearer tokens, or is nil if requests are not authenticated.
+	verifier *auth.Verifier
+	// provisioner registers users when they sign in, or is nil if users are registered by admins only.
+	provisioner *provisioner
+	// ipLimits and principalLimits are the rate limits of IP addresses and principals, or nil if they are not limited.
+	ipLimits        rateLimiters
+	principalLimits rateLimiters
 }
 
 // parseArgs parses the command-line arguments and returns the configuration.
@@ -37,6 +104,36 @@ func parseArgs() config {
 	flag.IntVar(&cfg.port, "port", 8080, "API server port")
 	flag.StringVar(&cfg.dns, "db-dns", os.Getenv("TYPECODEREGISTRY_DB_DSN"), "PostgreSQL DSN")
 	flag.StringVar(&cfg.loglevel, "loglevel", "info", "Log level (debug, info, warn, error, fatal, panic)")
+	flag.DurationVar(&cfg.idempotencyRetention, "idempotency-retention", 24*time.Hour, "How long responses of requests with an Idempotency-Key header are kept")
+	flag.IntVar(&cfg.changeBuffer, "change-buffer", 1000, "Number of changes kept for clients resuming the change feed, 0 disables the feed")
+	flag.IntVar(&cfg.webhookAttempts, "webhook-attempts", 8, "Number of attempts of a webhook delivery before it has failed")
+	flag.DurationVar(&cfg.webhookBackoff, "webhook-backoff", 30*time.Second, "Delay after the first failed attempt of a webhook delivery, doubling with every further one")
+	flag.DurationVar(&cfg.webhookTimeout, "webhook-timeout", 10*time.Second, "How long a webhook receiver may take to answer")
+	flag.DurationVar(&cfg.webhookRetention, "webhook-retention", 30*24*time.Hour, "How long the log of completed webhook deliveries is kept")
+	flag.BoolVar(&cfg.webhookAllowPrivateHosts, "webhook-allow-private-hosts", false, "Allow webhook receivers with loopback, link-local and private addresses, e.g. in development")
+	flag.StringVar(&cfg.authJWKSURL, "auth-jwks-url", os.Getenv("TYPECODEREGISTRY_JWKS_URL"), "URL of the JSON Web Key Set of the identity provider, enables authentication")
+	flag.StringVar(&cfg.authJWKSFile, "auth-jwks-file", "", "File with the JSON Web Key Set of the identity provider, enables authentication")
+	flag.StringVar(&cfg.authIssuer, "auth-issuer", os.Getenv("TYPECODEREGISTRY_ISSUER"), "Required issuer of bearer tokens")
+	flag.StringVar(&cfg.authAudience, "auth-audience", os.Getenv("TYPECODEREGISTRY_AUDIENCE"), "Comma separated accepted audiences of bearer tokens")
+	flag.DurationVar(&cfg.authLeeway, "auth-leeway", time.Minute, "Tolerated clock skew when checking the expiry of bearer tokens")
+	flag.StringVar(&cfg.authAdminRole, "auth-admin-role", "TypecodeRegistry.Admin", "App role of the tokens of global admins")
+	flag.BoolVar(&cfg.authProvisionUsers, "auth-provision-users", true, "Register unknown users with the claims of their first token")
+	flag.StringVar(&cfg.authGroupRoles, "auth-group-roles", "", "Comma separated group:project_id:role entries granting roles in projects to the members of groups")
+	flag.BoolVar(&cfg.audit, "audit", true, "Record all changes of projects, extensions, items and roles in the audit log")
+	flag.DurationVar(&cfg.auditRetention, "audit-retention", 2*365*24*time.Hour, "How long entries of the audit log are kept, 0 keeps them forever")
+	flag.StringVar(&cfg.rateLimitIPRead, "ratelimit-ip-read", "100:200", "Reads per second and burst (rate[:burst]) of every IP address, 0 disables the limit")
+	flag.StringVar(&cfg.rateLimitIPWrite, "ratelimit-ip-write", "20:40", "Writes per second and burst (rate[:burst]) of every IP address, 0 disables the limit")
+	flag.StringVar(&cfg.rateLimitPrincipalRead, "ratelimit-principal-read", "50:100", "Reads per second and burst (rate[:burst]) of every authenticated principal, 0 disables the limit")
+	flag.StringVar(&cfg.rateLimitPrincipalWrite, "ratelimit-principal-write", "10:20", "Writes per second and burst (rate[:burst]) of every authenticated principal, 0 disables the limit")
+	flag.BoolVar(&cfg.trustForwardedFor, "trust-forwarded-for", false, "Trust the X-Forwarded-For and X-Forwarded-Proto headers of a proxy to identify clients and TLS, set only behind a proxy")
+	flag.Int64Var(&cfg.maxBodySize, "max-body-size", defaultMaxBodySize, "Maximum number of bytes of a request body")
+	flag.StringVar(&cfg.corsAllowedOrigins, "cors-allowed-origins", "*", "Comma separated origins allowed to call the API from browsers, * allows every origin")
+	flag.StringVar(&cfg.corsAllowedMethods, "cors-allowed-methods", "GET,POST,PUT,DELETE,OPTIONS", "Comma separated methods of cross-origin requests")
+	flag.StringVar(&cfg.corsAllowedHeaders, "cors-allowed-headers", "Accept,Content-Type,Content-Length,Accept-Encoding,X-CSRF-Token,Authorization,If-Match,If-None-Match,Idempotency-Key,X-Request-Id", "Comma separated request headers of cross-origin requests")
+	flag.BoolVar(&cfg.corsAllowCredentials, "cors-allow-credentials", false, "Allow cross-origin requests with cookies or client certificates, requires listed origins")
+	flag.StringVar(&cfg.tlsCertFile, "tls-cert-file", "", "Certificate file of the server, serves HTTPS together with -tls-key-file")
+	flag.StringVar(&cfg.tlsKeyFile, "tls-key-file", "", "Private key file of the server, serves HTTPS together with -tls-cert-file")
+	flag.DurationVar(&cfg.hstsMaxAge, "hsts-max-age", 365*24*time.Hour, "How long browsers keep using HTTPS after a request over TLS, 0 disables HSTS")
 	flag.Parse()
 	return cfg
 }
@@ -112,12 +209,44 @@ func createLogger(level zerolog.Level) zerolog.Logger {
 }
 
 // main is the entry point of the application.
+// "myserver admin ..." runs maintenance tasks on the database instead of starting the API server.
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		os.Exit(runAdmin(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	PrintHeader()
 
 	cfg := parseArgs()
 	logger := createLogger(getLevelFromString(cfg.loglevel))
 
+	verifier, err := newVerifier(cfg)
+	if err != nil {
+		logger.Fatal().Msg(fmt.Sprintf("invalid authentication settings: %v", err))
+	}
+	if verifier == nil {
+		logger.Warn().Msg("no -auth-jwks-url or -auth-jwks-file set, requests are not authenticated")
+	}
+	provisioner, err := newProvisioner(cfg)
+	if err != nil {
+		logger.Fatal().Msg(fmt.Sprintf("invalid authentication settings: %v", err))
+	}
+	ipLimits, err := newRateLimiters(cfg.rateLimitIPRead, cfg.rateLimitIPWrite)
+	if err != nil {
+		logger.Fatal().Msg(fmt.Sprintf("invalid rate limit of IP addresses: %v", err))
+	}
+	principalLimits, err := newRateLimiters(cfg.rateLimitPrincipalRead, cfg.rateLimitPrincipalWrite)
+	if err != nil {
+		logger.Fatal().Msg(fmt.Sprintf("invalid rate limit of principals: %v", err))
+	}
+	c, err := newCORS(cfg)
+	if err != nil {
+		logger.Fatal().Msg(fmt.Sprintf("invalid CORS settings: %v", err))
+	}
+	if (cfg.tlsCertFile == "") != (cfg.tlsKeyFile == "") {
+		logger.Fatal().Msg("-tls-cert-file and -tls-key-file must be set together")
+	}
+
 	logger.Debug().Msg("opening connection to database...")
 
 	db, err := sql.Open("postgres", cfg.dns)
@@ -145,29 +274,50 @@ func main() {
 		config: cfg,
 		logger: &logger,
 		models: data.NewModels(db),
+		// Deliveries queued by earlier runs are sent as well, so the dispatcher runs even without change feed.
+		webhooks:        newWebhookDispatcher(cfg.webhookTimeout, cfg.webhookAllowPrivateHosts),
+		verifier:        verifier,
+		provisioner:     provisioner,
+		ipLimits:        ipLimits,
+		principalLimits: principalLimits,
+	}
+	if cfg.changeBuffer > 0 {
+		app.changes = newChangeFeed(cfg.changeBuffer)
 	}
 
 	app.logger.Info().Msg(fmt.Sprintf("API server will start on port %d", app.config.port))
 
 	addr := fmt.Sprintf(":%d", app.config.port)
 
-	c := cors.New(cors.Options{
-		AllowedOrigins: []string{"*"}, // Allow all origins
-		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders: []string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization"},
-	})
-
-	handler := c.Handler(app.route())
+	handler := c.Handler(app.handler())
 	server := &http.Server{
-		Addr:         addr,
-		Handler:      handler,
-		IdleTimeout:  time.Minute,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 30 * time.Second,
+		Addr:           addr,
+		Handler:        handler,
+		IdleTimeout:    time.Minute,
+		MaxHeaderBytes: maxHeaderSize,
+		ReadTimeout:    10 * time.Second,
+		WriteTimeout:   30 * time.Second,
+	}
+
+	go app.deleteExpiredIdempotencyKeys(time.Hour)
+	go app.deliverWebhooks(webhookPollInterval)
+	go app.deleteOldWebhookDeliveries(time.Hour)
+	go app.pruneRateLimiters(time.Minute)
+	if cfg.auditRetention > 0 {
+		go app.deleteOldAuditEntries(time.Hour)
+	}
+	if app.changes != nil {
+		go app.watchChangesForWebhooks()
+	} else {
+		app.logger.Warn().Msg("the change feed is disabled, webhooks receive no changes")
 	}
 
 	app.logger.Info().Msg("API server is up and running")
 
-	err = server.ListenAndServe()
+	if cfg.tlsCertFile != "" {
+		err = server.ListenAndServeTLS(cfg.tlsCertFile, cfg.tlsKeyFile)
+	} else {
+		err = server.ListenAndServe()
+	}
 	app.logger.Err(err)
 }
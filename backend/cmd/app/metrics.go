@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+)
+
+// metrics handles the GET request for the metrics of the server in the text format of Prometheus.
+// It reports the number of requests rejected by the rate limits, per limit and class of requests.
+func (app *application) metrics(w http.ResponseWriter, r *http.Request) {
+	var b bytes.Buffer
+	b.WriteString("# HELP typecode_registry_throttled_requests_total Requests answered with 429 Too Many Requests.\n")
+	b.WriteString("# TYPE typecode_registry_throttled_requests_total counter\n")
+	for _, limit := range []struct {
+		name     string
+		limiters rateLimiters
+	}{{"ip", app.ipLimits}, {"principal", app.principalLimits}} {
+		for _, class := range []string{rateClassRead, rateClassWrite} {
+			var throttled int64
+			if limiter := limit.limiters[class]; limiter != nil {
+				throttled = limiter.throttled.Load()
+			}
+			fmt.Fprintf(&b, "typecode_registry_throttled_requests_total{limit=%q,class=%q} %d\n", limit.name, class, throttled)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if _, err := w.Write(b.Bytes()); err != nil {
+		app.logger.Err(err)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+)
+
+// contextKey is the type of the keys of values the middlewares store in the request context.
+type contextKey string
+
+const requestIDContextKey = contextKey("requestID")
+
+// maxRequestIDSize is the maximum number of characters of an X-Request-Id header accepted from clients.
+const maxRequestIDSize = 128
+
+// handler returns the handler of the API server: the routes of route wrapped by the middlewares
+// which every request passes through.
+func (app *application) handler() http.Handler {
+	return app.secureHeaders(app.assignRequestID(app.recoverPanic(app.limitByIP(app.authenticate(app.limitByPrincipal(app.unmatchedRoutes(app.route())))))))
+}
+
+// assignRequestID assigns an ID to every request, which is stored in the request context and
+// sent back in the X-Request-Id header. It is part of every error response, so a reported error
+// can be found in the logs. An ID sent by the client or a proxy is used if it is valid.
+func (app *application) assignRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-Id")
+		if !validRequestID(id) {
+			b := make([]byte, 16)
+			if _, err := rand.Read(b); err != nil {
+				app.logger.Error().Msg(fmt.Sprintf("Error while generating request id: %v", err))
+			}
+			id = hex.EncodeToString(b)
+		}
+
+		w.Header().Set("X-Request-Id", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDContextKey, id)))
+	})
+}
+
+// validRequestID reports whether id is a non-empty string of at most maxRequestIDSize visible ASCII characters.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDSize {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// requestIDFromContext returns the ID assigned to the request by assignRequestID or an empty string.
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+// recoverPanic answers requests whose handler panics with 500 Internal Server Error
+// instead of dropping the connection.
+func (app *application) recoverPanic(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if err := recover(); err != nil {
+				// The handler aborted a response which has already been started, see writeItemList.
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+				app.logger.Error().Msg(fmt.Sprintf("panic during %s %s: %v", r.Method, r.URL.Path, err))
+				w.Header().Set("Connection", "close")
+				app.serverErrorResponse(w, r)
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// statusRecorder keeps the status and headers written by a handler without sending them.
+type statusRecorder struct {
+	header http.Header
+	status int
+}
+
+func (rec *statusRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	rec.WriteHeader(http.StatusOK)
+	return len(b), nil
+}
+
+// unmatchedRoutes answers requests which match no route of mux with problem details.
+// The ServeMux answers them with 404 Not Found or 405 Method Not Allowed in plain text;
+// its status and the Allow header are kept. Redirects to the canonical path are passed on unchanged.
+func (app *application) unmatchedRoutes(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &statusRecorder{header: make(http.Header)}
+		h.ServeHTTP(rec, r)
+		if rec.status < http.StatusBadRequest {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		if allow := rec.header.Get("Allow"); allow != "" {
+			w.Header().Set("Allow", allow)
+		}
+		detail := fmt.Sprintf("no route for %s %s", r.Method, r.URL.Path)
+		if rec.status == http.StatusMethodNotAllowed {
+			detail = fmt.Sprintf("method %s is not allowed for %s", r.Method, r.URL.Path)
+		}
+		app.errorResponse(w, r, rec.status, problemCode(rec.status), detail)
+	})
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"Typecode-Registry/internal/validator"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeProblem(t *testing.T, resp *httptest.ResponseRecorder) problem {
+	t.Helper()
+	assert.Equal(t, "application/problem+json", resp.Header().Get("Content-Type"))
+
+	var p problem
+	if err := json.Unmarshal(resp.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestRequestIDIsAssignedAndReportedInProblems(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/items/abc", nil)
+	resp := httptest.NewRecorder()
+	app.handler().ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	id := resp.Header().Get("X-Request-Id")
+	assert.Len(t, id, 32)
+
+	p := decodeProblem(t, resp)
+	assert.Equal(t, problem{
+		Type:      "about:blank",
+		Title:     "Bad Request",
+		Status:    http.StatusBadRequest,
+		Detail:    `invalid id "abc"`,
+		Instance:  "/items/abc",
+		Code:      codeInvalidParameter,
+		RequestID: id,
+	}, p)
+	checkExpectations(t, mock)
+}
+
+func TestValidRequestIDOfClientIsKept(t *testing.T) {
+	testCases := map[string]bool{
+		"trace-42":                    true,
+		"":                            false,
+		"with space":                  false,
+		strings.Repeat("a", 129):      false,
+		strings.Repeat("a", 128):      true,
+		"line\nbreak":                 false,
+		"4b2f9c6e-0d8a-4e61-a3f5-c2b": true,
+	}
+
+	for id, kept := range testCases {
+		_, _, app := setupMockAndApp(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/healthcheck", nil)
+		req.Header.Set("X-Request-Id", id)
+		resp := httptest.NewRecorder()
+		app.handler().ServeHTTP(resp, req)
+
+		assert.Equal(t, kept, resp.Header().Get("X-Request-Id") == id, "request id %q", id)
+		assert.NotEmpty(t, resp.Header().Get("X-Request-Id"))
+	}
+}
+
+func TestFailedValidationReportsAllInvalidFields(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/extensions", strings.NewReader(`{"scope": "Project"}`))
+	resp := httptest.NewRecorder()
+	app.handler().ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	p := decodeProblem(t, resp)
+	assert.Equal(t, codeValidationFailed, p.Code)
+	assert.Equal(t, []validator.FieldError{
+		{Field: "name", Message: "must be provided"},
+		{Field: "project_id", Message: "must be provided for scope Project"},
+	}, p.Errors)
+	checkExpectations(t, mock)
+}
+
+func TestUnmatchedRoutesAreAnsweredWithProblems(t *testing.T) {
+	_, _, app := setupMockAndApp(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/typecodes", nil)
+	resp := httptest.NewRecorder()
+	app.handler().ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.Equal(t, codeNotFound, decodeProblem(t, resp).Code)
+
+	req = httptest.NewRequest(http.MethodPatch, "/items/1", nil)
+	resp = httptest.NewRecorder()
+	app.handler().ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+	assert.Equal(t, "DELETE, GET, HEAD, PUT", resp.Header().Get("Allow"))
+	assert.Equal(t, codeMethodNotAllowed, decodeProblem(t, resp).Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/docs", nil)
+	resp = httptest.NewRecorder()
+	app.handler().ServeHTTP(resp, req)
+
+	// The status of the redirect depends on the Go version.
+	assert.True(t, resp.Code >= 300 && resp.Code < 400, "got %d", resp.Code)
+	assert.Equal(t, "/docs/", resp.Header().Get("Location"))
+}
+
+func TestPanicIsAnsweredWithInternalServerError(t *testing.T) {
+	_, _, app := setupMockAndApp(t)
+
+	handler := app.assignRequestID(app.recoverPanic(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("typecode range of scope Hybris is not supported")
+	})))
+	req := httptest.NewRequest(http.MethodPost, "/items", nil)
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	p := decodeProblem(t, resp)
+	assert.Equal(t, codeInternal, p.Code)
+	assert.NotContains(t, p.Detail, "Hybris")
+	assert.Equal(t, resp.Header().Get("X-Request-Id"), p.RequestID)
+}
+
+func TestProblemExtensionsAreMembersOfTheObject(t *testing.T) {
+	js, err := json.Marshal(problem{
+		Type:       "about:blank",
+		Title:      "Not Found",
+		Status:     http.StatusNotFound,
+		Code:       codeNotFound,
+		Extensions: map[string]any{"rolled_back": []int{}, "code": "overwritten"},
+	})
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type": "about:blank", "title": "Not Found", "status": 404, "code": "not_found", "rolled_back": []}`, string(js))
+}
@@ -15,35 +15,49 @@ import (
 	"time"
 )
 
-func setupUpdateTest(mock sqlmock.Sqlmock, app *application, item data.Item, result driver.Result, err error) (*httptest.ResponseRecorder, sqlmock.Sqlmock) {
+func setupUpdateTest(mock sqlmock.Sqlmock, app *application, item data.Item, err error) (*httptest.ResponseRecorder, sqlmock.Sqlmock) {
 	body, _ := json.Marshal(item)
 	req, _ := http.NewRequest(http.MethodPut, "/items/1", bytes.NewBuffer(body))
 	resp := httptest.NewRecorder()
 
-	query := `UPDATE item
-    SET name = $1, table_name = $2
-    WHERE id = $3
-    AND (name != $1 OR table_name != $2)`
-	exec := mock.ExpectExec(regexp.QuoteMeta(query)).WithArgs(item.Name, item.TableName, item.ID)
-	if result != nil {
-		exec.WillReturnResult(result)
-	} else if err != nil {
-		exec.WillReturnError(err)
-	}
+	mockReadItemDetailByItemIdQuery(mock, item.ID, newItemRows(item.ID, 1))
+	mockUpdateItemQuery(mock, item.Name, item.TableName, item.ID, 1, err)
 
-	app.updateItem(resp, req)
+	app.route().ServeHTTP(resp, req)
 	return resp, mock
 }
 
+// newItemRows returns the result of reading the item with the given ID and version.
+func newItemRows(id int64, version int32) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "scope", "project", "name", "table_name", "extension_id", "typecode", "creation_date", "version"}).
+		AddRow(id, data.ScopeShared, "-", "TestItem", "test_table", 1, 20000, time.Now(), version)
+}
+
+// mockUpdateItemQuery expects the update of an item with the given version. Without err the version is incremented.
+func mockUpdateItemQuery(mock sqlmock.Sqlmock, name, tableName string, id int64, version int32, err error) {
+	query := regexp.QuoteMeta(`UPDATE item
+	SET name = $1, table_name = $2,
+		version = CASE WHEN name = $1 AND table_name = $2 THEN version ELSE version + 1 END
+	WHERE id = $3
+	AND version = $4
+	RETURNING version`)
+	expectation := mock.ExpectQuery(query).WithArgs(name, tableName, id, version)
+	if err != nil {
+		expectation.WillReturnError(err)
+	} else {
+		expectation.WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(version + 1))
+	}
+}
+
 func setupExtensionsMock(mock sqlmock.Sqlmock, scope string) {
 	extensions := []data.Extension{
 		{ID: 1, Name: "Test-Extension-1", Description: "Test-Description-1", Scope: "Project", CreationDate: time.Now(), ItemCount: 1},
 		{ID: 3, Name: "Test-Extension-3", Description: "Test-Description-3", Scope: "Project", CreationDate: time.Now(), ItemCount: 2},
 	}
 
-	returnRows := sqlmock.NewRows([]string{"id", "project_id", "name", "description", "scope", "creation_date", "item_count"}).
-		AddRow(extensions[0].ID, extensions[0].ProjectID.Int64, extensions[0].Name, extensions[0].Description, extensions[0].Scope, extensions[0].CreationDate, extensions[0].ItemCount).
-		AddRow(extensions[1].ID, extensions[1].ProjectID.Int64, extensions[1].Name, extensions[1].Description, extensions[1].Scope, extensions[1].CreationDate, extensions[1].ItemCount)
+	returnRows := sqlmock.NewRows([]string{"id", "project_id", "name", "description", "scope", "creation_date", "version", "item_count"}).
+		AddRow(extensions[0].ID, extensions[0].ProjectID.Int64, extensions[0].Name, extensions[0].Description, extensions[0].Scope, extensions[0].CreationDate, 1, extensions[0].ItemCount).
+		AddRow(extensions[1].ID, extensions[1].ProjectID.Int64, extensions[1].Name, extensions[1].Description, extensions[1].Scope, extensions[1].CreationDate, 1, extensions[1].ItemCount)
 	mockReadAllExtensionsQuery(mock, scope, returnRows)
 }
 
@@ -51,10 +65,10 @@ func setupExtensionMock(mock sqlmock.Sqlmock, id int64, projectId sql.NullInt64,
 	extensionArgs := []driver.Value{id}
 	var extensionRows *sqlmock.Rows
 	if returnRow {
-		extensionRows = sqlmock.NewRows([]string{"id", "project_id", "name", "description", "scope", "creation_date", "item_count"}).
-			AddRow(id, projectId, name, description, scope, time.Now(), itemCount)
+		extensionRows = sqlmock.NewRows([]string{"id", "project_id", "name", "description", "scope", "creation_date", "version", "item_count"}).
+			AddRow(id, projectId, name, description, scope, time.Now(), 1, itemCount)
 	} else {
-		extensionRows = sqlmock.NewRows([]string{"id", "project_id", "name", "description", "scope", "creation_date", "item_count"})
+		extensionRows = sqlmock.NewRows([]string{"id", "project_id", "name", "description", "scope", "creation_date", "version", "item_count"})
 	}
 
 	mockReadExtensionByIDQuery(mock, extensionArgs, extensionRows)
@@ -74,7 +88,7 @@ func setupNextFreeTypecodeMock(mock sqlmock.Sqlmock, projectId int64, scopeLowRa
 
 func setupInsertItemMock(mock sqlmock.Sqlmock, name string, extensionID int64, tableName string, typecode int32) {
 	insertArgs := []driver.Value{name, extensionID, tableName, typecode}
-	insertRows := sqlmock.NewRows([]string{"id", "creation_date"}).AddRow(1, time.Now())
+	insertRows := sqlmock.NewRows([]string{"id", "creation_date", "version"}).AddRow(1, time.Now(), 1)
 	mockInsertItemQuery(mock, insertArgs, insertRows)
 }
 
@@ -90,9 +104,9 @@ func mockHTTPGetRequest(app *application, t *testing.T, path string, status int)
 	checkHTTPResponse(resp, status, t)
 }
 
-func mockDeleteItemExecution(mock sqlmock.Sqlmock, id int, lastInsertID int64, rowsAffected int64) {
-	query := regexp.QuoteMeta(`DELETE FROM item WHERE id = $1`)
-	mock.ExpectExec(query).WithArgs(id).WillReturnResult(sqlmock.NewResult(lastInsertID, rowsAffected))
+func mockDeleteItemExecution(mock sqlmock.Sqlmock, id int, version int32, rowsAffected int64) {
+	query := regexp.QuoteMeta(`DELETE FROM item WHERE id = $1 AND version = $2`)
+	mock.ExpectExec(query).WithArgs(id, version).WillReturnResult(sqlmock.NewResult(0, rowsAffected))
 }
 
 func setupReadProjectNameMock(mock sqlmock.Sqlmock, id int64, name string) {
@@ -102,7 +116,7 @@ func setupReadProjectNameMock(mock sqlmock.Sqlmock, id int64, name string) {
 
 func mockReadExtensionByIDQuery(mock sqlmock.Sqlmock, args []driver.Value, returnRows *sqlmock.Rows) {
 	query := regexp.QuoteMeta(`
-	   	SELECT e.id, e.project_id, e.name, e.description, e.scope, e.creation_date, COUNT(i.id) AS item_count
+	   	SELECT e.id, e.project_id, e.name, e.description, e.scope, e.creation_date, e.version, COUNT(i.id) AS item_count
 		FROM extension e
 		LEFT JOIN item i ON e.id = i.extension_id
 		WHERE e.id = $1
@@ -114,7 +128,7 @@ func mockReadAllExtensionsQuery(mock sqlmock.Sqlmock, scope string, returnRows *
 	var query string
 	if len(scope) == 0 {
 		query = regexp.QuoteMeta(`
-		SELECT e.id, e.project_id, e.name, e.description, e.scope, e.creation_date, COUNT(i.id) AS item_count
+		SELECT e.id, e.project_id, e.name, e.description, e.scope, e.creation_date, e.version, COUNT(i.id) AS item_count
 		FROM extension e
 		LEFT JOIN item i ON e.id = i.extension_id
 		GROUP BY e.id
@@ -126,7 +140,7 @@ func mockReadAllExtensionsQuery(mock sqlmock.Sqlmock, scope string, returnRows *
 		}
 	} else {
 		query = regexp.QuoteMeta(`
-		SELECT e.id, e.project_id, e.name, e.description, e.scope, e.creation_date, COUNT(i.id) AS item_count
+		SELECT e.id, e.project_id, e.name, e.description, e.scope, e.creation_date, e.version, COUNT(i.id) AS item_count
 		FROM extension e
 		LEFT JOIN item i ON e.id = i.extension_id
 		WHERE LOWER(e.scope) = LOWER($1)
@@ -140,6 +154,22 @@ func mockReadAllExtensionsQuery(mock sqlmock.Sqlmock, scope string, returnRows *
 	}
 }
 
+func mockReadProjectByIDQuery(mock sqlmock.Sqlmock, id int64, returnRows *sqlmock.Rows) {
+	query := regexp.QuoteMeta(`SELECT id, name, description, creation_date, version FROM project WHERE id = $1`)
+	mock.ExpectQuery(query).WithArgs(id).WillReturnRows(returnRows)
+}
+
+func mockReadExtensionsByProjectQuery(mock sqlmock.Sqlmock, projectID int64, returnRows *sqlmock.Rows) {
+	query := regexp.QuoteMeta(`
+		SELECT e.id, e.project_id, e.name, e.description, e.scope, e.creation_date, e.version, COUNT(i.id) AS item_count
+		FROM extension e
+		LEFT JOIN item i ON e.id = i.extension_id
+		WHERE e.project_id = $1
+		GROUP BY e.id
+		ORDER BY e.id`)
+	mock.ExpectQuery(query).WithArgs(projectID).WillReturnRows(returnRows)
+}
+
 func mockReadAllItemsQuery(mock sqlmock.Sqlmock, returnRows *sqlmock.Rows) {
 	query := regexp.QuoteMeta(`SELECT item.id,
 	    extension.scope, 
@@ -148,7 +178,8 @@ func mockReadAllItemsQuery(mock sqlmock.Sqlmock, returnRows *sqlmock.Rows) {
        item.table_name,
        extension.id, 
        item.typecode,
-	   item.creation_date
+	   item.creation_date,
+	   item.version
 	FROM item
 	JOIN extension ON item.extension_id = extension.id
 	LEFT JOIN project ON extension.project_id = project.id`)
@@ -156,29 +187,30 @@ func mockReadAllItemsQuery(mock sqlmock.Sqlmock, returnRows *sqlmock.Rows) {
 	mock.ExpectQuery(query).WillReturnRows(returnRows)
 }
 
-func mockReadAllItemsQueryReturnsError(mock sqlmock.Sqlmock) {
-	query := regexp.QuoteMeta(`SELECT item.id,
-	    extension.scope, 
-       COALESCE(project.name, '-') AS project_name,
-       item.name, 
-       item.table_name,
-       extension.id, 
-       item.typecode,
-	   item.creation_date
-	FROM item
-	JOIN extension ON item.extension_id = extension.id
-	LEFT JOIN project ON extension.project_id = project.id`)
+// defaultItemListArgs are the query arguments of GET /items without query string.
+var defaultItemListArgs = []driver.Value{"", "", int64(0), int64(0), nil, nil, "", nil, nil, nil, 0}
 
-	mock.ExpectQuery(query).WillReturnError(errors.New("mock error"))
+func mockReadFilteredItemsQuery(mock sqlmock.Sqlmock, args []driver.Value, returnRows *sqlmock.Rows) {
+	query := regexp.QuoteMeta(`SELECT count(*) OVER(),
+		item.id,`)
+
+	mock.ExpectQuery(query).WithArgs(args...).WillReturnRows(returnRows)
+}
+
+func mockReadFilteredItemsQueryReturnsError(mock sqlmock.Sqlmock, args []driver.Value) {
+	query := regexp.QuoteMeta(`SELECT count(*) OVER(),
+		item.id,`)
+
+	mock.ExpectQuery(query).WithArgs(args...).WillReturnError(errors.New("mock error"))
 }
 
 func mockReadAllProjectsQuery(mock sqlmock.Sqlmock, rows *sqlmock.Rows) {
-	query := regexp.QuoteMeta(`SELECT * FROM project ORDER BY id`)
+	query := regexp.QuoteMeta(`SELECT id, name, description, creation_date, version FROM project ORDER BY id`)
 	mock.ExpectQuery(query).WillReturnRows(rows)
 }
 
 func mockReadAllProjectsQueryReturnsError(mock sqlmock.Sqlmock) {
-	query := regexp.QuoteMeta(`SELECT * FROM project ORDER BY id`)
+	query := regexp.QuoteMeta(`SELECT id, name, description, creation_date, version FROM project ORDER BY id`)
 	mock.ExpectQuery(query).WillReturnError(errors.New("mock error"))
 }
 
@@ -206,7 +238,7 @@ func mockInsertItemQuery(mock sqlmock.Sqlmock, args []driver.Value, returnRows *
 	query := regexp.QuoteMeta(
 		`INSERT INTO item (name, extension_id, table_name, typecode)
 		VALUES ($1, $2, $3, $4)
-		RETURNING id, creation_date`)
+		RETURNING id, creation_date, version`)
 	mock.ExpectQuery(query).WithArgs(args...).WillReturnRows(returnRows)
 }
 
@@ -214,7 +246,7 @@ func mockInsertItemQueryToReturnError(mock sqlmock.Sqlmock, args []driver.Value)
 	query := regexp.QuoteMeta(`
 		INSERT INTO item (name, extension_id, table_name, typecode)
 		VALUES ($1, $2, $3, $4)
-		RETURNING id, creation_date`)
+		RETURNING id, creation_date, version`)
 	mock.ExpectQuery(query).WithArgs(args...).WillReturnError(errors.New("mock error"))
 }
 
@@ -226,7 +258,8 @@ func mockReadItemDetailByItemIdQuery(mock sqlmock.Sqlmock, id int64, returnRows
        item.table_name,
        extension.id, 
        item.typecode,
-	   item.creation_date
+	   item.creation_date,
+	   item.version
 	FROM item
 	JOIN extension ON item.extension_id = extension.id
 	LEFT JOIN project ON extension.project_id = project.id
@@ -242,7 +275,8 @@ func mockReadItemByItemIdNoRowsFound(mock sqlmock.Sqlmock, id int64) {
        item.table_name,
        extension.id, 
        item.typecode,
-	   item.creation_date
+	   item.creation_date,
+	   item.version
 	FROM item
 	JOIN extension ON item.extension_id = extension.id
 	LEFT JOIN project ON extension.project_id = project.id
@@ -288,14 +322,27 @@ func mockUpdateExtensionQuery(mock sqlmock.Sqlmock, name, description string, id
 	query := regexp.QuoteMeta(`
         UPDATE extension
         SET name = COALESCE(NULLIF($1, ''), name), 
-            description = $2
-        WHERE id = $3`)
-	mock.ExpectExec(query).WithArgs(name, description, id).WillReturnResult(sqlmock.NewResult(1, 1))
+            description = $2,
+            version = version + 1
+        WHERE id = $3 AND version = $4
+        RETURNING version`)
+	mock.ExpectQuery(query).WithArgs(name, description, id, 1).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+}
+
+func mockUpdateExtensionQueryReturnsNoRows(mock sqlmock.Sqlmock, id int64) {
+	query := regexp.QuoteMeta(`
+        UPDATE extension
+        SET name = COALESCE(NULLIF($1, ''), name), 
+            description = $2,
+            version = version + 1
+        WHERE id = $3 AND version = $4
+        RETURNING version`)
+	mock.ExpectQuery(query).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), id, 1).WillReturnRows(sqlmock.NewRows([]string{"version"}))
 }
 
 func mockReadExtensionByIDQueryReturnsError(mock sqlmock.Sqlmock, id int64, err error) {
 	query := regexp.QuoteMeta(`
-       	SELECT e.id, e.project_id, e.name, e.description, e.scope, e.creation_date, COUNT(i.id) AS item_count
+       	SELECT e.id, e.project_id, e.name, e.description, e.scope, e.creation_date, e.version, COUNT(i.id) AS item_count
         FROM extension e
         LEFT JOIN item i ON e.id = i.extension_id
         WHERE e.id = $1
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// openAPIFiles contains the OpenAPI document of the API and the Swagger UI page rendering it.
+// The document is checked against the routes and handlers by openapi_test.go, so keep it up to date.
+//
+//go:embed openapi
+var openAPIFiles embed.FS
+
+// openAPI handles the GET request for the OpenAPI document of the API.
+func (app *application) openAPI(w http.ResponseWriter, r *http.Request) {
+	http.ServeFileFS(w, r, openAPIFiles, "openapi/openapi.json")
+}
+
+// swaggerUI returns the handler serving the Swagger UI for the OpenAPI document below /docs/.
+func (app *application) swaggerUI() http.Handler {
+	files, err := fs.Sub(openAPIFiles, "openapi")
+	if err != nil {
+		panic(err)
+	}
+	return http.StripPrefix("/docs/", http.FileServerFS(files))
+}
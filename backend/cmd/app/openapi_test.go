@@ -0,0 +1,531 @@
+package main
+
+import (
+	"Typecode-Registry/internal/auth"
+	"Typecode-Registry/internal/auth/authtest"
+	"Typecode-Registry/internal/data"
+	"Typecode-Registry/internal/validator"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// The tests in this file fail when routes, request and response structs or the behavior of the handlers
+// diverge from the OpenAPI document in openapi/openapi.json.
+
+// openAPISpec is the decoded OpenAPI document.
+type openAPISpec map[string]any
+
+func loadOpenAPISpec(t *testing.T) openAPISpec {
+	js, err := openAPIFiles.ReadFile("openapi/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var spec openAPISpec
+	if err := json.Unmarshal(js, &spec); err != nil {
+		t.Fatalf("invalid OpenAPI document: %v", err)
+	}
+	return spec
+}
+
+// object returns the object at the given keys below node.
+func object(node any, keys ...string) map[string]any {
+	for _, key := range keys {
+		m, _ := node.(map[string]any)
+		node = m[key]
+	}
+	m, _ := node.(map[string]any)
+	return m
+}
+
+// resolve follows the $ref of node, if there is one.
+func (s openAPISpec) resolve(node map[string]any) map[string]any {
+	for node["$ref"] != nil {
+		ref := node["$ref"].(string)
+		node = object(map[string]any(s), strings.Split(strings.TrimPrefix(ref, "#/"), "/")...)
+	}
+	return node
+}
+
+// operations returns the patterns of all documented operations, e.g. "GET /items/{id}".
+func (s openAPISpec) operations() []string {
+	var patterns []string
+	for path, item := range object(map[string]any(s), "paths") {
+		for method := range item.(map[string]any) {
+			if method != "parameters" {
+				patterns = append(patterns, strings.ToUpper(method)+" "+path)
+			}
+		}
+	}
+	slices.Sort(patterns)
+	return patterns
+}
+
+// validate checks value against schema and returns a description of every violation.
+// Objects must not contain properties which are not documented, unless the schema has no properties at all.
+func (s openAPISpec) validate(schema map[string]any, value any, at string) []string {
+	schema = s.resolve(schema)
+
+	if alternatives, ok := schema["oneOf"].([]any); ok {
+		for _, alternative := range alternatives {
+			if len(s.validate(alternative.(map[string]any), value, at)) == 0 {
+				return nil
+			}
+		}
+		return []string{fmt.Sprintf("%s: %v matches none of the alternatives", at, value)}
+	}
+
+	if value == nil {
+		if schema["nullable"] == true || schema["type"] == nil {
+			return nil
+		}
+		return []string{fmt.Sprintf("%s: must not be null", at)}
+	}
+
+	var violations []string
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: %v is not an object", at, value)}
+		}
+		for _, required := range asSlice(schema["required"]) {
+			if _, ok := obj[required.(string)]; !ok {
+				violations = append(violations, fmt.Sprintf("%s: required property %s is missing", at, required))
+			}
+		}
+		properties := object(schema, "properties")
+		for key, v := range obj {
+			property, ok := properties[key].(map[string]any)
+			if !ok {
+				if len(properties) > 0 {
+					violations = append(violations, fmt.Sprintf("%s: property %s is not documented", at, key))
+				}
+				continue
+			}
+			violations = append(violations, s.validate(property, v, at+"."+key)...)
+		}
+	case "array":
+		arr, ok := value.([]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: %v is not an array", at, value)}
+		}
+		for i, v := range arr {
+			violations = append(violations, s.validate(object(schema, "items"), v, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return []string{fmt.Sprintf("%s: %v is not a string", at, value)}
+		}
+		if enum := asSlice(schema["enum"]); enum != nil && !slices.Contains(enum, any(str)) {
+			violations = append(violations, fmt.Sprintf("%s: %q is not one of %v", at, str, enum))
+		}
+	case "integer":
+		if n, ok := value.(float64); !ok || n != math.Trunc(n) {
+			violations = append(violations, fmt.Sprintf("%s: %v is not an integer", at, value))
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			violations = append(violations, fmt.Sprintf("%s: %v is not a number", at, value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			violations = append(violations, fmt.Sprintf("%s: %v is not a boolean", at, value))
+		}
+	}
+	return violations
+}
+
+func asSlice(v any) []any {
+	s, _ := v.([]any)
+	return s
+}
+
+func TestOpenAPIDocumentsAllRoutes(t *testing.T) {
+	spec := loadOpenAPISpec(t)
+	documented := spec.operations()
+
+	source, err := os.ReadFile("routes.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, match := range regexp.MustCompile(`mux\.Handle(?:Func)?\("([A-Z]+ /[^"]*)",`).FindAllStringSubmatch(string(source), -1) {
+		pattern := match[1]
+		if pattern == "GET /docs/" {
+			continue
+		}
+		assert.Contains(t, documented, pattern, "route is not documented in the OpenAPI document")
+	}
+
+	// The extensions of a scope are listed by the route of extension IDs, see getExtension.
+	routes := map[string]string{
+		"GET /extensions/shared":  "GET /extensions/{id}",
+		"GET /extensions/project": "GET /extensions/{id}",
+	}
+	mux := (&application{}).route()
+	for _, pattern := range documented {
+		method, path, _ := strings.Cut(pattern, " ")
+		req := httptest.NewRequest(method, strings.ReplaceAll(path, "{id}", "1"), nil)
+		_, matched := mux.Handler(req)
+		route, ok := routes[pattern]
+		if !ok {
+			route = pattern
+		}
+		assert.Equal(t, route, matched, "documented operation has no route")
+	}
+}
+
+func TestOpenAPISchemasMatchStructs(t *testing.T) {
+	spec := loadOpenAPISpec(t)
+
+	testCases := []struct {
+		schema string
+		value  any
+		// response schemas document all fields which are always sent as required.
+		response bool
+	}{
+		{"Item", data.Item{}, true},
+		{"Extension", data.Extension{}, true},
+		{"Project", data.Project{}, true},
+		{"Metadata", data.Metadata{}, true},
+		{"SearchHit", data.SearchHit{}, true},
+		{"BatchResult", BatchResult{}, true},
+		{"Change", Change{}, true},
+		{"AuditEntry", data.AuditEntry{}, true},
+		{"Webhook", data.Webhook{}, true},
+		{"WebhookDelivery", data.WebhookDelivery{}, true},
+		{"User", data.User{}, true},
+		{"RoleAssignment", data.RoleAssignment{}, true},
+		{"ServiceAccount", data.ServiceAccount{}, true},
+		{"APIToken", data.APIToken{}, true},
+		{"ItemRequest", ItemRequest{}, false},
+		{"ExtensionRequest", ExtensionRequest{}, false},
+		{"ExtensionUpdateRequest", ExtensionUpdateRequest{}, false},
+		{"ProjectRequest", ProjectRequest{}, false},
+		{"ProjectUpdateRequest", ProjectUpdateRequest{}, false},
+		{"BatchRequest", BatchRequest{}, false},
+		{"BatchOperation", BatchOperation{}, false},
+		{"WebhookRequest", WebhookRequest{}, false},
+		{"WebhookUpdateRequest", WebhookUpdateRequest{}, false},
+		{"UserRequest", UserRequest{}, false},
+		{"RoleRequest", RoleRequest{}, false},
+		{"ServiceAccountRequest", ServiceAccountRequest{}, false},
+		{"TokenRequest", TokenRequest{}, false},
+		{"Problem", problem{}, true},
+		{"FieldError", validator.FieldError{}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.schema, func(t *testing.T) {
+			schema := object(map[string]any(spec), "components", "schemas", tc.schema)
+			if schema == nil {
+				t.Fatalf("schema %s is not documented", tc.schema)
+			}
+
+			var fields, required []string
+			typ := reflect.TypeOf(tc.value)
+			for i := 0; i < typ.NumField(); i++ {
+				name, options, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+				if name == "-" {
+					continue
+				}
+				fields = append(fields, name)
+				if options != "omitempty" || typ.Field(i).Type.Kind() == reflect.Struct {
+					required = append(required, name)
+				}
+			}
+
+			var properties []string
+			for name := range object(schema, "properties") {
+				properties = append(properties, name)
+			}
+			assert.ElementsMatch(t, fields, properties)
+
+			if tc.response {
+				var documented []string
+				for _, name := range asSlice(schema["required"]) {
+					documented = append(documented, name.(string))
+				}
+				assert.ElementsMatch(t, required, documented)
+			}
+		})
+	}
+
+	// PUT /items/{id} decodes data.Item, but only some of its fields are used.
+	itemFields := object(spec.resolve(object(map[string]any(spec), "components", "schemas", "Item")), "properties")
+	for name := range object(map[string]any(spec), "components", "schemas", "ItemUpdateRequest", "properties") {
+		assert.Contains(t, itemFields, name)
+	}
+}
+
+func TestHandlersConformToOpenAPI(t *testing.T) {
+	spec := loadOpenAPISpec(t)
+
+	testCases := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		mock   func(mock sqlmock.Sqlmock)
+		status int
+		// authenticated requires a bearer token, which is not sent.
+		authenticated bool
+	}{
+		{name: "healthcheck", method: http.MethodGet, target: "/healthcheck", status: http.StatusOK},
+		{name: "healthcheck without token", method: http.MethodGet, target: "/healthcheck", status: http.StatusOK, authenticated: true},
+		{name: "list items without token", method: http.MethodGet, target: "/items", status: http.StatusUnauthorized, authenticated: true},
+		{name: "OpenAPI document", method: http.MethodGet, target: "/openapi.json", status: http.StatusOK},
+		{
+			name: "list items", method: http.MethodGet, target: "/items", status: http.StatusOK,
+			mock: func(mock sqlmock.Sqlmock) {
+				mockReadFilteredItemsQuery(mock, defaultItemListArgs, sqlmock.NewRows([]string{"count", "id", "scope", "project", "name", "table_name", "extension_id", "typecode", "creation_date", "version"}).
+					AddRow(1, 1, "Project", "Project A", "Item A", "items_a", 3, 14000, time.Now(), 1))
+			},
+		},
+		{name: "list items with invalid limit", method: http.MethodGet, target: "/items?limit=0", status: http.StatusBadRequest},
+		{
+			name: "list audit entries", method: http.MethodGet, target: "/audit?entity_type=item&entity_id=14", status: http.StatusOK,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`FROM audit_log`)).
+					WillReturnRows(auditRows().
+						AddRow(1, 9, time.Now(), "bob", changeUpdated, changeItem, 14, nil, []byte(`{"id":14,"name":"Product"}`), []byte(`{"id":14,"name":"Products"}`), "req-1"))
+			},
+		},
+		{name: "create item with invalid fields", method: http.MethodPost, target: "/items", body: `{"name": "TestItem"}`, status: http.StatusBadRequest},
+		{name: "create batch without operations", method: http.MethodPost, target: "/batch", body: `{"operations": []}`, status: http.StatusBadRequest},
+		{
+			name: "get item", method: http.MethodGet, target: "/items/1", status: http.StatusOK,
+			mock: func(mock sqlmock.Sqlmock) { mockReadItemDetailByItemIdQuery(mock, 1, newItemRows(1, 1)) },
+		},
+		{
+			name: "get missing item", method: http.MethodGet, target: "/items/5", status: http.StatusNotFound,
+			mock: func(mock sqlmock.Sqlmock) { mockReadItemByItemIdNoRowsFound(mock, 5) },
+		},
+		{
+			name: "create item", method: http.MethodPost, target: "/items", status: http.StatusCreated,
+			body: `{"name": "TestItem", "table_name": "test_items", "extension_id": 1}`,
+			mock: func(mock sqlmock.Sqlmock) {
+				setupExtensionMock(mock, 1, sql.NullInt64{}, "Shared", "Test-Extension", "Test-Description", 1, true)
+				setupTypecodeMock(mock, "Shared", 20000, 20001)
+				setupInsertItemMock(mock, "TestItem", 1, "test_items", 20001)
+			},
+		},
+		{
+			name: "update item", method: http.MethodPut, target: "/items/1", status: http.StatusNoContent,
+			body: `{"name": "NewName", "table_name": "new_table"}`,
+			mock: func(mock sqlmock.Sqlmock) {
+				mockReadItemDetailByItemIdQuery(mock, 1, newItemRows(1, 1))
+				mockUpdateItemQuery(mock, "NewName", "new_table", 1, 1, nil)
+			},
+		},
+		{
+			name: "delete item", method: http.MethodDelete, target: "/items/1", status: http.StatusNoContent,
+			mock: func(mock sqlmock.Sqlmock) {
+				mockReadItemDetailByItemIdQuery(mock, 1, newItemRows(1, 1))
+				mockDeleteItemExecution(mock, 1, 1, 1)
+			},
+		},
+		{
+			name: "list extensions", method: http.MethodGet, target: "/extensions", status: http.StatusOK,
+			mock: func(mock sqlmock.Sqlmock) { setupExtensionsMock(mock, "") },
+		},
+		{
+			name: "list shared extensions", method: http.MethodGet, target: "/extensions/shared", status: http.StatusOK,
+			mock: func(mock sqlmock.Sqlmock) { setupExtensionsMock(mock, "shared") },
+		},
+		{
+			name: "get extension", method: http.MethodGet, target: "/extensions/1", status: http.StatusOK,
+			mock: func(mock sqlmock.Sqlmock) {
+				setupExtensionMock(mock, 1, sql.NullInt64{Int64: 2, Valid: true}, "Project", "core", "", 3, true)
+			},
+		},
+		{
+			name: "list projects", method: http.MethodGet, target: "/projects", status: http.StatusOK,
+			mock: func(mock sqlmock.Sqlmock) {
+				mockReadAllProjectsQuery(mock, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}).
+					AddRow(4, "Alpha", "First project", time.Now(), 1))
+			},
+		},
+		{
+			name: "list extensions of project", method: http.MethodGet, target: "/projects/4/extensions", status: http.StatusOK,
+			mock: func(mock sqlmock.Sqlmock) {
+				mockReadProjectByIDQuery(mock, 4, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}).
+					AddRow(4, "Alpha", "", time.Now(), 1))
+				mockReadExtensionsByProjectQuery(mock, 4, sqlmock.NewRows([]string{"id", "project_id", "name", "description", "scope", "creation_date", "version", "item_count"}).
+					AddRow(1, 4, "core", "", "Project", time.Now(), 1, 0))
+			},
+		},
+		{
+			name: "search", method: http.MethodGet, target: "/search?q=prod", status: http.StatusOK,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT type, id, name, detail, typecode, rank FROM (`)).
+					WillReturnRows(sqlmock.NewRows([]string{"type", "id", "name", "detail", "typecode", "rank"}).
+						AddRow("item", 7, "prod", "products", 14000, 1.0).
+						AddRow("project", 2, "prod shop", "", nil, 0.75))
+			},
+		},
+		{
+			name: "create webhook", method: http.MethodPost, target: "/webhooks", status: http.StatusCreated,
+			body: `{"url": "https://ci.example.com/hooks", "events": ["item.created"]}`,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO webhook`)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "creation_date", "version"}).AddRow(3, time.Now(), 1))
+			},
+		},
+		{
+			name: "list webhook deliveries", method: http.MethodGet, target: "/webhooks/3/deliveries", status: http.StatusOK,
+			mock: func(mock sqlmock.Sqlmock) {
+				mockReadWebhookQuery(mock, 3, "https://ci.example.com/hooks")
+				mock.ExpectQuery(regexp.QuoteMeta(`ORDER BY id DESC`)).
+					WithArgs(int64(3), 50).
+					WillReturnRows(deliveryRows().
+						AddRow(9, 3, "item.created", `{"event":"item.created"}`, nil, "pending", 1, 503, "the receiver answered with status 503", time.Now(), time.Now(), nil).
+						AddRow(8, 3, "ping", `{"event":"ping"}`, nil, "succeeded", 1, 200, nil, nil, time.Now(), time.Now()))
+			},
+		},
+		{
+			name: "ping webhook", method: http.MethodPost, target: "/webhooks/3/ping", status: http.StatusAccepted,
+			mock: func(mock sqlmock.Sqlmock) {
+				mockReadWebhookQuery(mock, 3, "https://ci.example.com/hooks")
+				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO webhook_delivery`)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "status", "next_attempt", "creation_date"}).AddRow(12, "pending", time.Now(), time.Now()))
+			},
+		},
+		{
+			name: "list users", method: http.MethodGet, target: "/users", status: http.StatusOK,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`FROM "user" ORDER BY id`)).
+					WillReturnRows(userRows().AddRow(4, "alice-sub", "alice@example.com", "Alice", time.Now()))
+			},
+		},
+		{
+			name: "assign project role", method: http.MethodPut, target: "/projects/2/members/4", status: http.StatusCreated,
+			body: `{"role": "developer"}`,
+			mock: func(mock sqlmock.Sqlmock) {
+				mockReadMember(mock, 2, 4)
+				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO role_assignment`)).
+					WillReturnRows(sqlmock.NewRows([]string{"role_assignment_id", "creation_date", "created"}).AddRow(7, time.Now(), true))
+			},
+		},
+		{
+			name: "list project members", method: http.MethodGet, target: "/projects/2/members", status: http.StatusOK,
+			mock: func(mock sqlmock.Sqlmock) {
+				mockReadProjectByIDQuery(mock, 2, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}).
+					AddRow(2, "Shop", "", time.Now(), 1))
+				mock.ExpectQuery(regexp.QuoteMeta(`WHERE ra.project_id = $1 ORDER BY ra.user_id`)).
+					WillReturnRows(roleAssignmentRows().AddRow(7, 4, "alice@example.com", 2, "Shop", "developer", "manual", time.Now()))
+			},
+		},
+		{
+			name: "create service account", method: http.MethodPost, target: "/projects/2/service-accounts", status: http.StatusCreated,
+			body: `{"name": "Jenkins", "role": "developer"}`,
+			mock: func(mock sqlmock.Sqlmock) {
+				mockReadProjectByIDQuery(mock, 2, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}).
+					AddRow(2, "Shop", "", time.Now(), 1))
+				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO service_account`)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "creation_date"}).AddRow(3, time.Now()))
+			},
+		},
+		{
+			name: "list service account tokens", method: http.MethodGet, target: "/projects/2/service-accounts/3/tokens", status: http.StatusOK,
+			mock: func(mock sqlmock.Sqlmock) {
+				mockReadProjectByIDQuery(mock, 2, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}).
+					AddRow(2, "Shop", "", time.Now(), 1))
+				mock.ExpectQuery(regexp.QuoteMeta(`FROM service_account WHERE id = $1`)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "name", "role", "creation_date"}).AddRow(3, 2, "Jenkins", "developer", time.Now()))
+				mock.ExpectQuery(regexp.QuoteMeta(`WHERE t.service_account_id = $1`)).
+					WillReturnRows(apiTokenRows().AddRow(9, "CI", "tcr_01234567", nil, 3, "{read}", time.Now().Add(time.Hour), time.Now(), nil, time.Now()))
+			},
+		},
+		{
+			name: "failed batch", method: http.MethodPost, target: "/batch", status: http.StatusNotFound,
+			body: `{"operations": [{"ref": "alpha", "action": "create", "type": "project", "body": {"name": "Alpha"}}, {"action": "delete", "type": "item", "id": 5}]}`,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO project (name, description)`)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "creation_date", "version"}).AddRow(4, time.Now(), 1))
+				mockReadItemByItemIdNoRowsFound(mock, 5)
+				mock.ExpectRollback()
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mock, app := setupMockAndApp(t)
+			if tc.mock != nil {
+				tc.mock(mock)
+			}
+			if tc.authenticated {
+				idp := authtest.NewIssuer(t)
+				app.verifier = &auth.Verifier{Keys: idp.KeySet(t), Issuer: idp.Issuer, Audience: []string{idp.Audience}}
+			}
+			mux := app.route()
+
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			resp := httptest.NewRecorder()
+			app.handler().ServeHTTP(resp, req)
+			assert.Equal(t, tc.status, resp.Code)
+			checkExpectations(t, mock)
+
+			// A documented path without wildcards takes precedence over the pattern of the route.
+			_, pattern := mux.Handler(req)
+			path := req.URL.Path
+			if object(map[string]any(spec), "paths", path) == nil {
+				_, path, _ = strings.Cut(pattern, " ")
+			}
+			operation := object(map[string]any(spec), "paths", path, strings.ToLower(tc.method))
+			response := spec.resolve(object(operation, "responses", fmt.Sprint(resp.Code)))
+			if response == nil {
+				t.Fatalf("status %d of %s is not documented", resp.Code, pattern)
+			}
+
+			content := object(response, "content")
+			if len(content) == 0 {
+				assert.Empty(t, resp.Body.String(), "response of status %d must not have a body", resp.Code)
+				return
+			}
+
+			mediaType, _, err := mime.ParseMediaType(resp.Header().Get("Content-Type"))
+			assert.NoError(t, err)
+			media := object(content, mediaType)
+			if media == nil {
+				t.Fatalf("content type %s of status %d is not documented", mediaType, resp.Code)
+			}
+			if mediaType != "application/json" && mediaType != "application/problem+json" {
+				return
+			}
+
+			var body any
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+			assert.Empty(t, spec.validate(object(media, "schema"), body, "body"))
+		})
+	}
+}
+
+func TestSwaggerUIIsServed(t *testing.T) {
+	_, _, app := setupMockAndApp(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/docs/", nil)
+	resp := httptest.NewRecorder()
+	app.route().ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), "swagger-initializer.js")
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"Typecode-Registry/internal/auth"
+	"Typecode-Registry/internal/data"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// groupRole is a role in a project granted to the members of a group of the identity provider.
+type groupRole struct {
+	group     string
+	projectID int64
+	role      string
+}
+
+// parseGroupRoles parses the -auth-group-roles mapping, a comma separated list of group:project_id:role entries,
+// e.g. 0b7c3e7a-0000-0000-0000-000000000001:2:developer.
+func parseGroupRoles(mapping string) ([]groupRole, error) {
+	var roles []groupRole
+	for _, entry := range strings.Split(mapping, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		if len(parts) != 3 || parts[0] == "" {
+			return nil, fmt.Errorf("-auth-group-roles: %q is not of the form group:project_id:role", entry)
+		}
+		projectID, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || projectID < 1 {
+			return nil, fmt.Errorf("-auth-group-roles: %q has no valid project ID", entry)
+		}
+		if !slices.Contains(data.Roles, parts[2]) {
+			return nil, fmt.Errorf("-auth-group-roles: %q has an unknown role, must be one of %s", entry, strings.Join(data.Roles, ", "))
+		}
+
+		roles = append(roles, groupRole{group: parts[0], projectID: projectID, role: parts[2]})
+	}
+
+	return roles, nil
+}
+
+// provisioner registers the users of the identity provider when they sign in and grants them the roles of their
+// groups. Each token is a sign-in, so users are provisioned once per token and not on every request.
+type provisioner struct {
+	groupRoles []groupRole
+
+	mu sync.Mutex
+	// signIns maps the subjects of provisioned users to the expiry of their last token.
+	signIns map[string]time.Time
+}
+
+// newProvisioner returns the provisioner configured by -auth-provision-users and -auth-group-roles,
+// or nil if users are registered by admins only.
+// Returns: an error if the group roles are invalid or set without provisioning.
+func newProvisioner(cfg config) (*provisioner, error) {
+	groupRoles, err := parseGroupRoles(cfg.authGroupRoles)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.authProvisionUsers {
+		if len(groupRoles) > 0 {
+			return nil, errors.New("-auth-group-roles requires -auth-provision-users")
+		}
+		return nil, nil
+	}
+
+	return &provisioner{groupRoles: groupRoles, signIns: make(map[string]time.Time)}, nil
+}
+
+// signIn reports whether the token of principal has not been provisioned yet and remembers it.
+// Entries of expired tokens are removed, so the map holds only the users signed in at the moment.
+func (p *provisioner) signIn(principal *auth.Principal) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if expiry, ok := p.signIns[principal.Subject]; ok && expiry.Equal(principal.ExpiresAt) {
+		return false
+	}
+
+	now := time.Now()
+	for subject, expiry := range p.signIns {
+		if expiry.Before(now) {
+			delete(p.signIns, subject)
+		}
+	}
+	p.signIns[principal.Subject] = principal.ExpiresAt
+	return true
+}
+
+// forget removes the sign-in of principal, so it is provisioned again with its next request.
+func (p *provisioner) forget(principal *auth.Principal) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.signIns, principal.Subject)
+}
+
+// projectRoles returns the IDs of all projects with group roles and the roles the groups grant to a member of groups.
+// A member of several groups gets the highest of their roles.
+func (p *provisioner) projectRoles(groups []string) ([]int64, map[int64]string) {
+	var projectIDs []int64
+	roles := make(map[int64]string)
+	for _, gr := range p.groupRoles {
+		if !slices.Contains(projectIDs, gr.projectID) {
+			projectIDs = append(projectIDs, gr.projectID)
+		}
+		if slices.Contains(groups, gr.group) && roles[gr.projectID] != data.RoleAdmin {
+			roles[gr.projectID] = gr.role
+		}
+	}
+	return projectIDs, roles
+}
+
+// syncGroupRoles re-evaluates the roles granted by the groups of principal to the user with the given ID.
+// Changed roles are audited with the user as actor in the same transaction.
+func (app *application) syncGroupRoles(r *http.Request, principal *auth.Principal, userID int64) error {
+	tx, models, err := app.beginChanges()
+	if err != nil {
+		return err
+	}
+	defer rollbackChanges(tx)
+
+	projectIDs, roles := app.provisioner.projectRoles(principal.Groups)
+	changes, err := models.Roles.SyncGroupRoles(userID, projectIDs, roles)
+	if err != nil {
+		return err
+	}
+	if err := app.writeAudit(models, principal.Subject, requestIDFromContext(r.Context()), roleChanges(changes)...); err != nil {
+		return err
+	}
+	if tx != nil {
+		return tx.Commit()
+	}
+	return nil
+}
+
+// provisionUser registers the user of principal on the first request r with a new token, taking email and name
+// from its claims, and re-evaluates the roles granted by the user's groups. Changed roles are audited with the user
+// as actor.
+// Returns: an error if the user or the roles cannot be stored. The next request tries again.
+func (app *application) provisionUser(r *http.Request, principal *auth.Principal) error {
+	if app.provisioner == nil || !app.provisioner.signIn(principal) {
+		return nil
+	}
+
+	user := data.User{OAuthIdentifier: principal.Subject, Email: principal.Email, Name: principal.Name}
+	created, err := app.models.Users.Provision(&user)
+	if err == nil && len(app.provisioner.groupRoles) > 0 {
+		err = app.syncGroupRoles(r, principal, user.ID)
+	}
+	if err != nil {
+		app.provisioner.forget(principal)
+		return err
+	}
+
+	if created {
+		app.logger.Info().Msg(fmt.Sprintf("registered user %d for %s", user.ID, principal.Subject))
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"Typecode-Registry/internal/auth"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGroupRoles(t *testing.T) {
+	roles, err := parseGroupRoles(" devs:2:developer, ,admins:2:admin")
+	assert.NoError(t, err)
+	assert.Equal(t, []groupRole{{"devs", 2, "developer"}, {"admins", 2, "admin"}}, roles)
+
+	for _, mapping := range []string{"devs:2", ":2:admin", "devs:two:admin", "devs:0:admin", "devs:2:owner"} {
+		_, err := parseGroupRoles(mapping)
+		assert.Error(t, err, mapping)
+	}
+}
+
+func TestNewProvisioner(t *testing.T) {
+	p, err := newProvisioner(config{})
+	assert.NoError(t, err)
+	assert.Nil(t, p, "users are registered by admins only")
+
+	_, err = newProvisioner(config{authGroupRoles: "devs:2:developer"})
+	assert.EqualError(t, err, "-auth-group-roles requires -auth-provision-users")
+
+	p, err = newProvisioner(config{authProvisionUsers: true, authGroupRoles: "devs:2:developer"})
+	assert.NoError(t, err)
+	assert.Len(t, p.groupRoles, 1)
+}
+
+func TestGroupsGrantTheirHighestRole(t *testing.T) {
+	p, err := newProvisioner(config{authProvisionUsers: true, authGroupRoles: "devs:2:developer,admins:2:admin,devs:3:developer,ops:4:admin"})
+	assert.NoError(t, err)
+
+	projectIDs, roles := p.projectRoles([]string{"admins", "devs"})
+
+	assert.Equal(t, []int64{2, 3, 4}, projectIDs)
+	assert.Equal(t, map[int64]string{2: "admin", 3: "developer"}, roles)
+}
+
+func TestUsersAreProvisionedOncePerToken(t *testing.T) {
+	mock, app, idp := setupRoleApp(t)
+	var err error
+	app.provisioner, err = newProvisioner(config{authProvisionUsers: true, authGroupRoles: "devs:2:developer,ops:3:admin"})
+	assert.NoError(t, err)
+	app.config.audit = true
+
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "user" (oauth_identifier, email, name)`)).
+		WithArgs("alice", "alice@example.com", "alice").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "creation_date", "created"}).AddRow(4, time.Now(), true))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM role_assignment`)).
+		WithArgs(int64(4), "{2,3}", "{2}").
+		WillReturnRows(sqlmock.NewRows([]string{"role_assignment_id", "project_id", "role", "creation_date"}).AddRow(6, 3, "admin", time.Now()))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO role_assignment (user_id, project_id, role, source)`)).
+		WithArgs(int64(4), int64(2), "developer").
+		WillReturnRows(sqlmock.NewRows([]string{"role_assignment_id", "creation_date", "previous"}).AddRow(7, time.Now(), ""))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO audit_log`)).
+		WithArgs("alice", changeDeleted, auditRoleAssignment, int64(6), int64(3), sqlmock.AnyArg(), nil, sqlmock.AnyArg(),
+			"alice", changeCreated, auditRoleAssignment, int64(7), int64(2), nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	for range 2 {
+		mockReadAllProjectsQuery(mock, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}))
+	}
+
+	claims := idp.Claims("alice")
+	claims["groups"] = []string{"devs", "testers"}
+	token := idp.Token(t, claims)
+	for range 2 {
+		resp := serveAs(app, http.MethodGet, "/projects", token, "")
+		assert.Equal(t, http.StatusOK, resp.Code)
+	}
+	checkExpectations(t, mock)
+}
+
+func TestFailedProvisioningIsRetried(t *testing.T) {
+	p, err := newProvisioner(config{authProvisionUsers: true})
+	assert.NoError(t, err)
+	principal := &auth.Principal{Subject: "alice", ExpiresAt: time.Now().Add(time.Hour)}
+
+	assert.True(t, p.signIn(principal))
+	assert.False(t, p.signIn(principal), "the token has been provisioned")
+	p.forget(principal)
+	assert.True(t, p.signIn(principal))
+	assert.True(t, p.signIn(&auth.Principal{Subject: "alice", ExpiresAt: time.Now().Add(2 * time.Hour)}), "a new token is a new sign-in")
+}
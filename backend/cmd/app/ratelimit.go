@@ -0,0 +1,239 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Classes of requests with separate rate limits. Reads only query the registry, writes change it
+// and include the allocation of typecodes by POST /items and POST /batch.
+const (
+	rateClassRead  = "read"
+	rateClassWrite = "write"
+)
+
+// rateBucket is the token bucket of a client. It holds the tokens left at the time of the last request.
+type rateBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter limits the requests of every client to rate requests per second with bursts of up to burst requests.
+// Each client has a token bucket which is refilled at rate and from which every request takes a token.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*rateBucket
+
+	// throttled counts the requests rejected by the limiter.
+	throttled atomic.Int64
+}
+
+// newRateLimiter returns a limiter of rate requests per second with bursts of up to burst requests.
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{rate: rate, burst: float64(burst), now: time.Now, buckets: make(map[string]*rateBucket)}
+}
+
+// allow takes a token from the bucket of client.
+// Returns: false and the time until the next token is available if the bucket is empty.
+func (l *rateLimiter) allow(client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &rateBucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		l.throttled.Add(1)
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// prune removes the buckets which have been refilled completely, they are recreated on the next request.
+func (l *rateLimiter) prune() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for client, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// rateLimiters are the limiters of the classes of requests. A class without limiter is not limited.
+type rateLimiters map[string]*rateLimiter
+
+// parseRateLimit parses a rate limit of the form rate[:burst], e.g. 10 or 10:50, in requests per second.
+// Without burst, a client may send the requests of one second at once.
+// Returns: nil if the limit is empty or 0, which disables the limit.
+func parseRateLimit(limit string) (*rateLimiter, error) {
+	limit = strings.TrimSpace(limit)
+	if limit == "" {
+		return nil, nil
+	}
+
+	value, burstValue, hasBurst := strings.Cut(limit, ":")
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || rate < 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+		return nil, fmt.Errorf("invalid rate %q, must be a number of requests per second", value)
+	}
+	if rate == 0 {
+		return nil, nil
+	}
+
+	burst := int(math.Ceil(rate))
+	if hasBurst {
+		burst, err = strconv.Atoi(burstValue)
+		if err != nil || burst < 1 {
+			return nil, fmt.Errorf("invalid burst %q, must be a positive integer", burstValue)
+		}
+	}
+	return newRateLimiter(rate, burst), nil
+}
+
+// newRateLimiters returns the limiters of reads and writes of the given limits, see parseRateLimit.
+// Returns: nil if neither class is limited.
+func newRateLimiters(read, write string) (rateLimiters, error) {
+	limiters := rateLimiters{}
+	for _, l := range []struct{ class, limit string }{{rateClassRead, read}, {rateClassWrite, write}} {
+		limiter, err := parseRateLimit(l.limit)
+		if err != nil {
+			return nil, fmt.Errorf("%s limit: %w", l.class, err)
+		}
+		if limiter != nil {
+			limiters[l.class] = limiter
+		}
+	}
+	if len(limiters) == 0 {
+		return nil, nil
+	}
+	return limiters, nil
+}
+
+// rateClass returns the class of the rate limits of r.
+func rateClass(r *http.Request) string {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return rateClassRead
+	default:
+		return rateClassWrite
+	}
+}
+
+// clientIP returns the IP address of the client of r. Behind a proxy which appends the address of
+// its client to the X-Forwarded-For header, the last address of the header is used if trustForwardedFor is set.
+func clientIP(r *http.Request, trustForwardedFor bool) string {
+	if trustForwardedFor {
+		if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+			addresses := strings.Split(forwarded[len(forwarded)-1], ",")
+			if ip := net.ParseIP(strings.TrimSpace(addresses[len(addresses)-1])); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// limitByIP limits the requests of every IP address with the limiters of app.ipLimits.
+// It runs before authentication, so requests with invalid tokens are limited as well.
+func (app *application) limitByIP(next http.Handler) http.Handler {
+	return app.limitRate(next, app.ipLimits, func(r *http.Request) string {
+		return clientIP(r, app.config.trustForwardedFor)
+	})
+}
+
+// limitByPrincipal limits the requests of every authenticated principal with the limiters of app.principalLimits.
+// Anonymous requests are limited by limitByIP only.
+func (app *application) limitByPrincipal(next http.Handler) http.Handler {
+	return app.limitRate(next, app.principalLimits, func(r *http.Request) string {
+		if principal := principalFromContext(r.Context()); principal != nil {
+			return principal.Subject
+		}
+		return ""
+	})
+}
+
+// limitRate answers requests of clients which exceed the rate limit of the class of the request
+// with 429 Too Many Requests and a Retry-After header. The client of a request is identified by client,
+// which returns an empty string for requests which are not limited. Public requests, e.g. the healthcheck, are never limited.
+func (app *application) limitRate(next http.Handler, limiters rateLimiters, client func(r *http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limiter := limiters[rateClass(r)]
+		if limiter == nil || isPublicRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		id := client(r)
+		if id == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if ok, retryAfter := limiter.allow(id); !ok {
+			app.rateLimitExceededResponse(w, r, retryAfter)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// pruneRateLimiters removes the buckets of clients which have not sent requests for a while every interval,
+// so the limiters do not keep a bucket for every client ever seen. It runs until the application exits.
+func (app *application) pruneRateLimiters(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for _, limiters := range []rateLimiters{app.ipLimits, app.principalLimits} {
+			for _, limiter := range limiters {
+				limiter.prune()
+			}
+		}
+	}
+}
+
+// defaultMaxBodySize is the maximum size of request bodies if none is configured.
+const defaultMaxBodySize = 1_048_576
+
+// maxHeaderSize is the maximum size of the request line and headers of a request.
+const maxHeaderSize = 64 << 10
+
+// maxBodySize returns the maximum number of bytes of a request body.
+func (app *application) maxBodySize() int64 {
+	if app.config.maxBodySize > 0 {
+		return app.config.maxBodySize
+	}
+	return defaultMaxBodySize
+}
+
+// isBodyTooLarge reports whether err was returned because a request body exceeded the limit of maxBodySize.
+func isBodyTooLarge(err error) bool {
+	var maxBytesError *http.MaxBytesError
+	return errors.As(err, &maxBytesError)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock returns a clock for rate limiters which only advances when the test moves it.
+func fakeClock(l *rateLimiter) *time.Time {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+	return &now
+}
+
+func TestParseRateLimit(t *testing.T) {
+	testCases := map[string]struct {
+		rate  float64
+		burst float64
+		err   bool
+	}{
+		"":       {},
+		"0":      {},
+		"0:10":   {},
+		"10":     {rate: 10, burst: 10},
+		"0.5":    {rate: 0.5, burst: 1},
+		"10:50":  {rate: 10, burst: 50},
+		"ten":    {err: true},
+		"-1":     {err: true},
+		"10:0":   {err: true},
+		"10:x":   {err: true},
+		"10:1.5": {err: true},
+	}
+
+	for limit, tc := range testCases {
+		limiter, err := parseRateLimit(limit)
+		if tc.err {
+			assert.Error(t, err, limit)
+			continue
+		}
+		if !assert.NoError(t, err, limit) {
+			continue
+		}
+		if tc.rate == 0 {
+			assert.Nil(t, limiter, limit)
+			continue
+		}
+		if assert.NotNil(t, limiter, limit) {
+			assert.Equal(t, tc.rate, limiter.rate, limit)
+			assert.Equal(t, tc.burst, limiter.burst, limit)
+		}
+	}
+}
+
+func TestNewRateLimiters(t *testing.T) {
+	limiters, err := newRateLimiters("0", "")
+	assert.NoError(t, err)
+	assert.Nil(t, limiters)
+
+	limiters, err = newRateLimiters("", "5:10")
+	assert.NoError(t, err)
+	assert.Nil(t, limiters[rateClassRead])
+	assert.NotNil(t, limiters[rateClassWrite])
+
+	_, err = newRateLimiters("fast", "")
+	assert.ErrorContains(t, err, "read limit")
+}
+
+func TestRateLimiterRefillsBuckets(t *testing.T) {
+	limiter := newRateLimiter(2, 3)
+	now := fakeClock(limiter)
+
+	for i := 0; i < 3; i++ {
+		ok, _ := limiter.allow("alice")
+		assert.True(t, ok, "request %d of the burst", i+1)
+	}
+	ok, retryAfter := limiter.allow("alice")
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, retryAfter)
+
+	ok, _ = limiter.allow("bob")
+	assert.True(t, ok, "clients have buckets of their own")
+
+	*now = now.Add(500 * time.Millisecond)
+	ok, _ = limiter.allow("alice")
+	assert.True(t, ok)
+	ok, _ = limiter.allow("alice")
+	assert.False(t, ok)
+	assert.Equal(t, int64(2), limiter.throttled.Load())
+}
+
+func TestRateLimiterPrunesFullBuckets(t *testing.T) {
+	limiter := newRateLimiter(1, 2)
+	now := fakeClock(limiter)
+	limiter.allow("alice")
+	limiter.allow("alice")
+	limiter.allow("bob")
+
+	*now = now.Add(time.Second)
+	limiter.prune()
+	assert.Len(t, limiter.buckets, 1)
+	assert.Contains(t, limiter.buckets, "alice")
+
+	*now = now.Add(time.Second)
+	limiter.prune()
+	assert.Empty(t, limiter.buckets)
+}
+
+func TestClientIP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/items", nil)
+	req.RemoteAddr = "10.0.0.1:51234"
+	req.Header.Add("X-Forwarded-For", "203.0.113.9, 198.51.100.7")
+
+	assert.Equal(t, "10.0.0.1", clientIP(req, false))
+	assert.Equal(t, "198.51.100.7", clientIP(req, true))
+
+	req.Header.Set("X-Forwarded-For", "unknown")
+	assert.Equal(t, "10.0.0.1", clientIP(req, true))
+}
+
+func TestRequestsOfIPAreThrottled(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	app.ipLimits = rateLimiters{rateClassRead: newRateLimiter(1, 2)}
+	fakeClock(app.ipLimits[rateClassRead])
+
+	serveFrom := func(ip, method, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.RemoteAddr = ip + ":1234"
+		resp := httptest.NewRecorder()
+		app.handler().ServeHTTP(resp, req)
+		return resp
+	}
+
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusBadRequest, serveFrom("192.0.2.1", http.MethodGet, "/items/abc").Code)
+	}
+	resp := serveFrom("192.0.2.1", http.MethodGet, "/items/abc")
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Equal(t, "1", resp.Header().Get("Retry-After"))
+	p := decodeProblem(t, resp)
+	assert.Equal(t, codeRateLimited, p.Code)
+	assert.NotEmpty(t, p.RequestID)
+
+	assert.Equal(t, http.StatusBadRequest, serveFrom("192.0.2.2", http.MethodGet, "/items/abc").Code, "other addresses are not throttled")
+	assert.Equal(t, http.StatusBadRequest, serveFrom("192.0.2.1", http.MethodDelete, "/items/abc").Code, "writes are not limited")
+	assert.Equal(t, http.StatusOK, serveFrom("192.0.2.1", http.MethodGet, "/healthcheck").Code, "the healthcheck is never limited")
+
+	resp = serveFrom("192.0.2.1", http.MethodGet, "/metrics")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `typecode_registry_throttled_requests_total{limit="ip",class="read"} 1`)
+	assert.Contains(t, resp.Body.String(), `typecode_registry_throttled_requests_total{limit="principal",class="write"} 0`)
+	checkExpectations(t, mock)
+}
+
+func TestRequestsOfPrincipalAreThrottled(t *testing.T) {
+	mock, app, idp := setupRoleApp(t)
+	app.principalLimits = rateLimiters{rateClassWrite: newRateLimiter(1, 1)}
+	fakeClock(app.principalLimits[rateClassWrite])
+	alice := idp.Token(t, idp.Claims("alice"))
+
+	assert.Equal(t, http.StatusBadRequest, serveAs(app, http.MethodPut, "/items/abc", alice, "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serveAs(app, http.MethodPut, "/items/abc", alice, "").Code)
+	assert.Equal(t, http.StatusBadRequest, serveAs(app, http.MethodPut, "/items/abc", idp.Token(t, idp.Claims("bob")), "").Code)
+	assert.Equal(t, http.StatusUnauthorized, serveAs(app, http.MethodPut, "/items/abc", "invalid", "").Code, "requests without principal are limited by IP only")
+	checkExpectations(t, mock)
+}
+
+func TestBodyLargerThanMaxSizeIsRejected(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	app.config.maxBodySize = 16
+	body := `{"name": "` + strings.Repeat("a", 32) + `"}`
+
+	resp := serveRequest(app, http.MethodPost, "/projects", body)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
+	assert.Equal(t, codeBodyTooLarge, decodeProblem(t, resp).Code)
+
+	req := httptest.NewRequest(http.MethodPost, "/projects", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", "large")
+	resp = httptest.NewRecorder()
+	app.handler().ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
+	checkExpectations(t, mock)
+}
@@ -2,15 +2,72 @@ package main
 
 import "net/http"
 
+// route registers all routes of the API using method and path patterns.
+// Requests for a known path with a method that is not registered are answered by the ServeMux
+// with 405 Method Not Allowed and an Allow header listing the registered methods.
+// Unknown paths are answered with 404 Not Found. See unmatchedRoutes for their problem details.
 func (app *application) route() *http.ServeMux {
 	mux := http.NewServeMux()
-	mux.HandleFunc("/", http.NotFound)
-	mux.HandleFunc("/healthcheck", app.healthcheck)
-	mux.HandleFunc("/items", app.getItemsHandler)
-	mux.HandleFunc("/items/", app.getItemHandler)
-	mux.HandleFunc("/extensions", app.getExtensionsHandler)
-	mux.HandleFunc("/extensions/", app.getExtensionsHandler)
-	mux.HandleFunc("/projects", app.getProjectsHandler)
-	mux.HandleFunc("/projects/", app.getProjectsHandler)
+	mux.HandleFunc("GET /healthcheck", app.healthcheck)
+	mux.HandleFunc("GET /metrics", app.metrics)
+	mux.HandleFunc("GET /openapi.json", app.openAPI)
+	mux.Handle("GET /docs/", app.swaggerUI())
+
+	mux.HandleFunc("GET /search", app.search)
+	mux.HandleFunc("POST /batch", app.idempotent(app.batch))
+	mux.HandleFunc("GET /changes", app.streamChanges)
+	mux.HandleFunc("GET /audit", app.getAuditLog)
+
+	mux.HandleFunc("GET /items", app.getItems)
+	mux.HandleFunc("POST /items", app.idempotent(app.createItem))
+	mux.HandleFunc("GET /items/{id}", app.getItem)
+	mux.HandleFunc("PUT /items/{id}", app.updateItem)
+	mux.HandleFunc("DELETE /items/{id}", app.deleteItem)
+
+	mux.HandleFunc("GET /extensions", app.handleGetAllExtensions)
+	mux.HandleFunc("POST /extensions", app.idempotent(app.createExtension))
+	// Also lists the extensions of a scope, e.g. /extensions/shared, see getExtension.
+	mux.HandleFunc("GET /extensions/{id}", app.getExtension)
+	mux.HandleFunc("GET /extensions/{id}/items", app.getExtensionItems)
+	mux.HandleFunc("PUT /extensions/{id}", app.updateExtension)
+	mux.HandleFunc("DELETE /extensions/{id}", app.deleteExtension)
+
+	mux.HandleFunc("GET /projects", app.getProjects)
+	mux.HandleFunc("POST /projects", app.idempotent(app.createProject))
+	mux.HandleFunc("GET /projects/{id}", app.getProject)
+	mux.HandleFunc("GET /projects/{id}/extensions", app.getProjectExtensions)
+	mux.HandleFunc("GET /projects/{id}/items", app.getProjectItems)
+	mux.HandleFunc("PUT /projects/{id}", app.updateProject)
+	mux.HandleFunc("DELETE /projects/{id}", app.deleteProject)
+	mux.HandleFunc("GET /projects/{id}/members", app.getProjectMembers)
+	mux.HandleFunc("PUT /projects/{id}/members/{user_id}", app.assignProjectRole)
+	mux.HandleFunc("DELETE /projects/{id}/members/{user_id}", app.revokeProjectRole)
+	mux.HandleFunc("GET /projects/{id}/service-accounts", app.getServiceAccounts)
+	mux.HandleFunc("POST /projects/{id}/service-accounts", app.idempotent(app.createServiceAccount))
+	mux.HandleFunc("DELETE /projects/{id}/service-accounts/{account_id}", app.deleteServiceAccount)
+	// Token creation is not idempotent, the stored response would keep the token.
+	mux.HandleFunc("GET /projects/{id}/service-accounts/{account_id}/tokens", app.getServiceAccountTokens)
+	mux.HandleFunc("POST /projects/{id}/service-accounts/{account_id}/tokens", app.createServiceAccountToken)
+	mux.HandleFunc("DELETE /projects/{id}/service-accounts/{account_id}/tokens/{token_id}", app.revokeServiceAccountToken)
+
+	mux.HandleFunc("GET /users", app.getUsers)
+	mux.HandleFunc("POST /users", app.idempotent(app.createUser))
+	mux.HandleFunc("GET /users/{id}", app.getUser)
+	mux.HandleFunc("GET /users/{id}/roles", app.getUserRoles)
+
+	mux.HandleFunc("GET /tokens", app.getTokens)
+	mux.HandleFunc("POST /tokens", app.createToken)
+	mux.HandleFunc("DELETE /tokens/{id}", app.revokeToken)
+
+	mux.HandleFunc("GET /webhooks", app.getWebhooks)
+	// Webhook creation is not idempotent, the stored response would keep the secret.
+	mux.HandleFunc("POST /webhooks", app.createWebhook)
+	mux.HandleFunc("GET /webhooks/{id}", app.getWebhook)
+	mux.HandleFunc("PUT /webhooks/{id}", app.updateWebhook)
+	mux.HandleFunc("DELETE /webhooks/{id}", app.deleteWebhook)
+	mux.HandleFunc("POST /webhooks/{id}/ping", app.pingWebhook)
+	mux.HandleFunc("GET /webhooks/{id}/deliveries", app.getWebhookDeliveries)
+	mux.HandleFunc("GET /webhooks/{id}/deliveries/{delivery_id}", app.getWebhookDelivery)
+	mux.HandleFunc("POST /webhooks/{id}/deliveries/{delivery_id}/redeliver", app.redeliverWebhookDelivery)
 	return mux
 }
@@ -15,6 +15,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"regexp"
 	"testing"
 	"time"
 )
@@ -181,8 +182,8 @@ func TestCreateItemReturnsInternalServerErrorWhenInsertFails(t *testing.T) {
 	}
 
 	itemReq := ItemRequest{
-		Name:        "Test Item",
-		TableName:   "Test Table",
+		Name:        "TestItem",
+		TableName:   "test_table",
 		ExtensionId: 1,
 	}
 
@@ -196,9 +197,7 @@ func TestCreateItemReturnsInternalServerErrorWhenInsertFails(t *testing.T) {
 		20000,
 	}
 
-	mock.ExpectBegin()
 	mockInsertItemQueryToReturnError(mock, insertArgs)
-	mock.ExpectRollback()
 
 	// Mock HTTP Request
 	server := setupHTTPServer(app)
@@ -237,8 +236,8 @@ func TestCreateItemRoutesReturnsStatusInternalServerErrorWhenScopeSharedRangeEnd
 
 	// Create a new ItemRequest
 	itemReq := ItemRequest{
-		Name:        "Test Item",
-		TableName:   "Test Table",
+		Name:        "TestItem",
+		TableName:   "test_table",
 		ExtensionId: 1,
 	}
 
@@ -279,10 +278,34 @@ func TestCallingExtensionsRoutePassingInvalidScopeReturnsStatusBadRequest(t *tes
 	_ = getAndTestHTTPResponse(t, server, "/extensions/invalid", http.StatusBadRequest)
 }
 
+func TestCallingExtensionsRouteWithCapitalizedScopeSucceeds(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	mockReadAllExtensionsQuery(mock, "Project", sqlmock.NewRows([]string{"id", "project_id", "name", "description", "scope", "creation_date", "version", "item_count"}))
+
+	server := setupHTTPServer(app)
+	defer server.Close()
+
+	_ = getAndTestHTTPResponse(t, server, "/extensions/Project", http.StatusOK)
+	checkExpectations(t, mock)
+}
+
+func TestCallingExtensionsRouteWithMixedCaseScopeSucceeds(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	mockReadAllExtensionsQuery(mock, "SHARED", sqlmock.NewRows([]string{"id", "project_id", "name", "description", "scope", "creation_date", "version", "item_count"}))
+	mockReadAllExtensionsQuery(mock, "sHaReD", sqlmock.NewRows([]string{"id", "project_id", "name", "description", "scope", "creation_date", "version", "item_count"}))
+
+	server := setupHTTPServer(app)
+	defer server.Close()
+
+	_ = getAndTestHTTPResponse(t, server, "/extensions/SHARED", http.StatusOK)
+	_ = getAndTestHTTPResponse(t, server, "/extensions/sHaReD", http.StatusOK)
+	checkExpectations(t, mock)
+}
+
 func TestSendingNotExistingExtensionIDReturnsStatusNotFound(t *testing.T) {
 	db, mock, app := setupMockAndApp(t)
 
-	itemReq := ItemRequest{Name: "Test-Name", TableName: "Test-Table-Name", ExtensionId: 1}
+	itemReq := ItemRequest{Name: "TestName", TableName: "test_table_name", ExtensionId: 1}
 
 	setupExtensionMock(mock, 1, sql.NullInt64{}, "dummy", "dummy", "dummy", 0, false)
 
@@ -344,7 +367,7 @@ func TestSendingRequestWithInvalidRequestBodyReturnsStatusBadRequest(t *testing.
 	resp := sendMockHTTPRequest(
 		server.URL,
 		"/items",
-		FaultyRequestBody{Name: "Test-Name", TableName: "Test-Table-Name", Dummy: "Dummy"},
+		FaultyRequestBody{Name: "TestName", TableName: "test_table_name", Dummy: "Dummy"},
 		t)
 
 	if resp.StatusCode != http.StatusBadRequest {
@@ -360,9 +383,9 @@ func TestProjectsRouteReturnsAllProjects(t *testing.T) {
 		{ID: 2, Name: "Test-Project-2", Description: "Test-Description-2", CreationDate: time.Now()},
 	}
 
-	returnRows := sqlmock.NewRows([]string{"id", "name", "description", "creation_date"}).
-		AddRow(projects[0].ID, projects[0].Name, projects[0].Description, projects[0].CreationDate).
-		AddRow(projects[1].ID, projects[1].Name, projects[1].Description, projects[1].CreationDate)
+	returnRows := sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}).
+		AddRow(projects[0].ID, projects[0].Name, projects[0].Description, projects[0].CreationDate, 1).
+		AddRow(projects[1].ID, projects[1].Name, projects[1].Description, projects[1].CreationDate, 1)
 	mockReadAllProjectsQuery(mock, returnRows)
 
 	// Mock HTTP Request
@@ -394,33 +417,78 @@ func TestIfInvalidRoutesHTTPMethodsReturnMethodNotAllowed(t *testing.T) {
 		{"/items", "PUT", http.StatusMethodNotAllowed},
 		{"/items", "PATCH", http.StatusMethodNotAllowed},
 		{"/items", "DELETE", http.StatusMethodNotAllowed},
-		{"/items", "HEAD", http.StatusMethodNotAllowed},
 		{"/items", "CONNECT", http.StatusMethodNotAllowed},
 		{"/items", "OPTIONS", http.StatusMethodNotAllowed},
 		{"/items", "TRACE", http.StatusMethodNotAllowed},
 		{"/items", "PATCH", http.StatusMethodNotAllowed},
 		{"/items/1", "POST", http.StatusMethodNotAllowed},
-		{"/items/1", "HEAD", http.StatusMethodNotAllowed},
 		{"/items/1", "PATCH", http.StatusMethodNotAllowed},
 		{"/items/1", "CONNECT", http.StatusMethodNotAllowed},
 		{"/items/1", "OPTIONS", http.StatusMethodNotAllowed},
 		{"/items/1", "TRACE", http.StatusMethodNotAllowed},
-		{"/healthcheck", "HEAD", http.StatusMethodNotAllowed},
 		{"/healthcheck", "POST", http.StatusMethodNotAllowed},
 		{"/healthcheck", "PUT", http.StatusMethodNotAllowed},
 		{"/healthcheck", "DELETE", http.StatusMethodNotAllowed},
 		{"/healthcheck", "CONNECT", http.StatusMethodNotAllowed},
-		{"/healthcheck", "HEAD", http.StatusMethodNotAllowed},
 		{"/healthcheck", "OPTIONS", http.StatusMethodNotAllowed},
 		{"/healthcheck", "TRACE", http.StatusMethodNotAllowed},
 		{"/healthcheck", "PATCH", http.StatusMethodNotAllowed},
-		{"/projects", "HEAD", http.StatusMethodNotAllowed},
 		{"/projects", "DELETE", http.StatusMethodNotAllowed},
 		{"/projects", "CONNECT", http.StatusMethodNotAllowed},
-		{"/projects", "HEAD", http.StatusMethodNotAllowed},
 		{"/projects", "OPTIONS", http.StatusMethodNotAllowed},
 		{"/projects", "TRACE", http.StatusMethodNotAllowed},
 		{"/projects", "PATCH", http.StatusMethodNotAllowed},
+		{"/projects/1", "POST", http.StatusMethodNotAllowed},
+		{"/extensions/1", "POST", http.StatusMethodNotAllowed},
+	}
+
+	testRouting(t, tests, mux)
+}
+
+func TestMethodNotAllowedResponseListsAllowedMethods(t *testing.T) {
+	_, _, app := setupMockAndApp(t)
+	mux := app.route()
+
+	tests := map[string]string{
+		"/items":         "GET, HEAD, POST",
+		"/items/1":       "DELETE, GET, HEAD, PUT",
+		"/extensions/1":  "DELETE, GET, HEAD, PUT",
+		"/projects/1":    "DELETE, GET, HEAD, PUT",
+		"/healthcheck":   "GET, HEAD",
+		"/extensions/id": "DELETE, GET, HEAD, PUT",
+	}
+
+	for route, allowed := range tests {
+		req, _ := http.NewRequest(http.MethodPatch, route, nil)
+		resp := httptest.NewRecorder()
+
+		mux.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.Code, route)
+		assert.Equal(t, allowed, resp.Header().Get("Allow"), route)
+	}
+}
+
+func TestUnknownSubPathsReturnNotFound(t *testing.T) {
+	_, _, app := setupMockAndApp(t)
+	mux := app.route()
+
+	tests := []TestRoute{
+		{"/items/", "GET", http.StatusNotFound},
+		{"/items/1/extra", "GET", http.StatusNotFound},
+		{"/projects/abc/extra", "PUT", http.StatusNotFound},
+		{"/extensions/project/1", "GET", http.StatusNotFound},
+	}
+
+	testRouting(t, tests, mux)
+}
+
+func TestHeadRequestOnHealthcheckSucceeds(t *testing.T) {
+	_, _, app := setupMockAndApp(t)
+	mux := app.route()
+
+	tests := []TestRoute{
+		{"/healthcheck", "HEAD", http.StatusOK},
 	}
 
 	testRouting(t, tests, mux)
@@ -480,14 +548,14 @@ func TestItemRouteReturnsAllItems(t *testing.T) {
 	db, mock, app := setupMockAndApp(t)
 
 	testItems := []data.Item{
-		{ID: 1, Scope: "Project", Project: "Project A", Name: "Test-Item-1", TableName: "Test-Table-1", ExtensionID: 10000, Typecode: 1, CreationDate: time.Now()},
-		{ID: 2, Scope: "Shared", Project: "", Name: "Test-Item-2", TableName: "Test-Table-2", ExtensionID: 10001, Typecode: 1, CreationDate: time.Now()},
+		{ID: 1, Scope: "Project", Project: "Project A", Name: "Test-Item-1", TableName: "test_table_1", ExtensionID: 10000, Typecode: 1, CreationDate: time.Now()},
+		{ID: 2, Scope: "Shared", Project: "", Name: "Test-Item-2", TableName: "test_table_2", ExtensionID: 10001, Typecode: 1, CreationDate: time.Now()},
 	}
 
-	returnRows := sqlmock.NewRows([]string{"id", "scope", "project", "name", "table_name", "extension_id", "typecode", "creation_date"}).
-		AddRow(testItems[0].ID, testItems[0].Scope, testItems[0].Project, testItems[0].Name, testItems[0].TableName, testItems[0].ExtensionID, testItems[0].Typecode, testItems[0].CreationDate).
-		AddRow(testItems[1].ID, testItems[1].Scope, testItems[1].Project, testItems[1].Name, testItems[1].TableName, testItems[1].ExtensionID, testItems[1].Typecode, testItems[1].CreationDate)
-	mockReadAllItemsQuery(mock, returnRows)
+	returnRows := sqlmock.NewRows([]string{"count", "id", "scope", "project", "name", "table_name", "extension_id", "typecode", "creation_date", "version"}).
+		AddRow(2, testItems[0].ID, testItems[0].Scope, testItems[0].Project, testItems[0].Name, testItems[0].TableName, testItems[0].ExtensionID, testItems[0].Typecode, testItems[0].CreationDate, 1).
+		AddRow(2, testItems[1].ID, testItems[1].Scope, testItems[1].Project, testItems[1].Name, testItems[1].TableName, testItems[1].ExtensionID, testItems[1].Typecode, testItems[1].CreationDate, 1)
+	mockReadFilteredItemsQuery(mock, defaultItemListArgs, returnRows)
 
 	server := setupHTTPServer(app)
 	defer server.Close()
@@ -514,10 +582,123 @@ func TestItemRouteReturnsAllItems(t *testing.T) {
 	_ = db.Close()
 }
 
+func TestItemRoutePassesFiltersAndPaginationToDatabase(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+
+	createdAfter := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	createdBefore := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta(`ORDER BY item.typecode DESC, item.id ASC`)).
+		WithArgs("project", "Alpha", int64(0), int64(3), int32(14000), int32(14999), "prod", createdAfter, createdBefore, 10, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "id", "scope", "project", "name", "table_name", "extension_id", "typecode", "creation_date", "version"}).
+			AddRow(25, 11, "Project", "Alpha", "Product", "products", 3, 14010, createdAfter, 1))
+
+	server := setupHTTPServer(app)
+	defer server.Close()
+
+	resp := getAndTestHTTPResponse(t, server, "/items?scope=project&project=Alpha&extension_id=3&typecode_min=14000&typecode_max=14999"+
+		"&name=prod&created_after=2024-01-01&created_before=2024-02-01T12:00:00Z&page=2&limit=10&sort=-typecode", http.StatusOK)
+
+	var response struct {
+		Items    []data.Item   `json:"items"`
+		Metadata data.Metadata `json:"metadata"`
+	}
+	getResponse(resp, &response, t)
+
+	assert.Len(t, response.Items, 1)
+	assert.Equal(t, data.Metadata{CurrentPage: 2, PageSize: 10, FirstPage: 1, LastPage: 3, TotalRecords: 25}, response.Metadata)
+	checkExpectations(t, mock)
+}
+
+func TestItemRouteUsesDefaultPageSizeWhenOnlyPageIsGiven(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+
+	mockReadFilteredItemsQuery(mock, []driver.Value{"", "", int64(0), int64(0), nil, nil, "", nil, nil, defaultItemPageSize, 0},
+		sqlmock.NewRows([]string{"count", "id", "scope", "project", "name", "table_name", "extension_id", "typecode", "creation_date", "version"}))
+
+	server := setupHTTPServer(app)
+	defer server.Close()
+
+	resp := getAndTestHTTPResponse(t, server, "/items?page=1", http.StatusOK)
+
+	var response struct {
+		Items    []data.Item   `json:"items"`
+		Metadata data.Metadata `json:"metadata"`
+	}
+	getResponse(resp, &response, t)
+
+	assert.NotNil(t, response.Items)
+	assert.Empty(t, response.Items)
+	assert.Equal(t, data.Metadata{CurrentPage: 1, PageSize: defaultItemPageSize}, response.Metadata)
+	checkExpectations(t, mock)
+}
+
+func TestItemRouteCountsItemsForPageBehindLastPage(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+
+	mockReadFilteredItemsQuery(mock, []driver.Value{"", "", int64(0), int64(0), nil, nil, "", nil, nil, 10, 40},
+		sqlmock.NewRows([]string{"count", "id", "scope", "project", "name", "table_name", "extension_id", "typecode", "creation_date", "version"}))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*)
+	FROM item`)).
+		WithArgs("", "", int64(0), int64(0), nil, nil, "", nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(25))
+
+	server := setupHTTPServer(app)
+	defer server.Close()
+
+	resp := getAndTestHTTPResponse(t, server, "/items?page=5&limit=10", http.StatusOK)
+
+	var response struct {
+		Items    []data.Item   `json:"items"`
+		Metadata data.Metadata `json:"metadata"`
+	}
+	getResponse(resp, &response, t)
+
+	assert.Empty(t, response.Items)
+	assert.Equal(t, data.Metadata{CurrentPage: 5, PageSize: 10, FirstPage: 1, LastPage: 3, TotalRecords: 25}, response.Metadata)
+	checkExpectations(t, mock)
+}
+
+func TestItemRouteMatchesNameLiterally(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+
+	mockReadFilteredItemsQuery(mock, []driver.Value{"", "", int64(0), int64(0), nil, nil, `My\_Item\%`, nil, nil, nil, 0},
+		sqlmock.NewRows([]string{"count", "id", "scope", "project", "name", "table_name", "extension_id", "typecode", "creation_date", "version"}))
+
+	server := setupHTTPServer(app)
+	defer server.Close()
+
+	getAndTestHTTPResponse(t, server, "/items?name=My_Item%25", http.StatusOK)
+	checkExpectations(t, mock)
+}
+
+func TestItemRouteReturnsStatusBadRequestForInvalidQueryParameters(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	mux := app.route()
+
+	tests := []TestRoute{
+		{"/items?page=0", "GET", http.StatusBadRequest},
+		{"/items?page=abc", "GET", http.StatusBadRequest},
+		{"/items?limit=0", "GET", http.StatusBadRequest},
+		{"/items?limit=1001", "GET", http.StatusBadRequest},
+		{"/items?sort=creation_date%20DESC", "GET", http.StatusBadRequest},
+		{"/items?sort=-unknown", "GET", http.StatusBadRequest},
+		{"/items?scope=galaxy", "GET", http.StatusBadRequest},
+		{"/items?extension_id=-1", "GET", http.StatusBadRequest},
+		{"/items?typecode_min=-5", "GET", http.StatusBadRequest},
+		{"/items?typecode_max=3000000000", "GET", http.StatusBadRequest},
+		{"/items?typecode_min=200&typecode_max=100", "GET", http.StatusBadRequest},
+		{"/items?created_after=yesterday", "GET", http.StatusBadRequest},
+		{"/items?created_after=2024-02-01&created_before=2024-01-01", "GET", http.StatusBadRequest},
+	}
+
+	testRouting(t, tests, mux)
+	checkExpectations(t, mock)
+}
+
 func TestItemRouteReturnsStatusInternalServerErrorWhenDatabaseReturnsError(t *testing.T) {
 	db, mock, app := setupMockAndApp(t)
 
-	mockReadAllItemsQueryReturnsError(mock)
+	mockReadFilteredItemsQueryReturnsError(mock, defaultItemListArgs)
 
 	// Mock HTTP Request
 	server := setupHTTPServer(app)
@@ -573,15 +754,15 @@ func TestGetItemDetailRouteReturnsCorrectItem(t *testing.T) {
 		ID:           1,
 		Scope:        "Project",
 		Project:      "Test-Project",
-		Name:         "Test-Item",
-		TableName:    "Test-Table",
+		Name:         "TestItem",
+		TableName:    "test_table",
 		ExtensionID:  1,
 		Typecode:     10000,
 		CreationDate: time.Now(),
 	}
 
-	returnRows := sqlmock.NewRows([]string{"id", "scope", "project", "name", "table_name", "extension_id", "typecode", "creation_date"}).
-		AddRow(testItem.ID, testItem.Scope, testItem.Project, testItem.Name, testItem.TableName, testItem.ExtensionID, testItem.Typecode, testItem.CreationDate)
+	returnRows := sqlmock.NewRows([]string{"id", "scope", "project", "name", "table_name", "extension_id", "typecode", "creation_date", "version"}).
+		AddRow(testItem.ID, testItem.Scope, testItem.Project, testItem.Name, testItem.TableName, testItem.ExtensionID, testItem.Typecode, testItem.CreationDate, 1)
 	mockReadItemDetailByItemIdQuery(mock, testItem.ID, returnRows)
 
 	server := setupHTTPServer(app)
@@ -613,7 +794,8 @@ func TestIfItemsRouteHTTPDeleteRequestDeletesItemInDatabase(t *testing.T) {
 	db, mock, app := setupMockAndApp(t)
 	dummyItemId := 1
 
-	mockDeleteItemExecution(mock, dummyItemId, 0, 1)
+	mockReadItemDetailByItemIdQuery(mock, int64(dummyItemId), newItemRows(int64(dummyItemId), 1))
+	mockDeleteItemExecution(mock, dummyItemId, 1, 1)
 
 	server := setupHTTPServer(app)
 	defer server.Close()
@@ -627,16 +809,17 @@ func TestIfItemsRouteHTTPDeleteRequestDeletesItemInDatabase(t *testing.T) {
 	_ = db.Close()
 }
 
-func TestIfNoRowDeletedStatusInternalServerErrorIsReturned(t *testing.T) {
+func TestIfNoRowDeletedStatusConflictIsReturned(t *testing.T) {
 	db, mock, app := setupMockAndApp(t)
 	dummyItemId := 1
 
-	mockDeleteItemExecution(mock, dummyItemId, 0, 0)
+	mockReadItemDetailByItemIdQuery(mock, int64(dummyItemId), newItemRows(int64(dummyItemId), 1))
+	mockDeleteItemExecution(mock, dummyItemId, 1, 0)
 
 	server := setupHTTPServer(app)
 	defer server.Close()
 
-	_ = deleteAndTestHTTPResponse(t, server, fmt.Sprintf("/items/%d", dummyItemId), http.StatusInternalServerError)
+	_ = deleteAndTestHTTPResponse(t, server, fmt.Sprintf("/items/%d", dummyItemId), http.StatusConflict)
 
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unfulfilled expectations: %s", err)
@@ -645,6 +828,38 @@ func TestIfNoRowDeletedStatusInternalServerErrorIsReturned(t *testing.T) {
 	_ = db.Close()
 }
 
+func TestDeletingMissingItemReturnsStatusNotFound(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	mockReadItemByItemIdNoRowsFound(mock, 2)
+
+	server := setupHTTPServer(app)
+	defer server.Close()
+
+	_ = deleteAndTestHTTPResponse(t, server, "/items/2", http.StatusNotFound)
+	checkExpectations(t, mock)
+}
+
+func TestInvalidIDsReturnStatusBadRequestWithoutDatabaseAccess(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	mux := app.route()
+
+	tests := []TestRoute{
+		{"/items/abc", "DELETE", http.StatusBadRequest},
+		{"/items/0", "GET", http.StatusBadRequest},
+		{"/extensions/abc", "PUT", http.StatusBadRequest},
+		{"/extensions/abc", "DELETE", http.StatusBadRequest},
+		{"/projects/abc", "PUT", http.StatusBadRequest},
+		{"/projects/-1", "DELETE", http.StatusBadRequest},
+		{"/extensions/abc", "GET", http.StatusBadRequest},
+		{"/extensions/0/items", "GET", http.StatusBadRequest},
+		{"/projects/abc", "GET", http.StatusBadRequest},
+		{"/projects/abc/extensions", "GET", http.StatusBadRequest},
+	}
+
+	testRouting(t, tests, mux)
+	checkExpectations(t, mock)
+}
+
 func TestUpdateItem(t *testing.T) {
 	_, mock, app := setupMockAndApp(t)
 
@@ -652,7 +867,7 @@ func TestUpdateItem(t *testing.T) {
 		req, _ := http.NewRequest(http.MethodPut, "/items/invalid", nil)
 		resp := httptest.NewRecorder()
 
-		app.updateItem(resp, req)
+		app.route().ServeHTTP(resp, req)
 
 		assert.Equal(t, http.StatusBadRequest, resp.Code)
 	})
@@ -661,7 +876,7 @@ func TestUpdateItem(t *testing.T) {
 		req, _ := http.NewRequest(http.MethodPut, "/items/0", nil)
 		resp := httptest.NewRecorder()
 
-		app.updateItem(resp, req)
+		app.route().ServeHTTP(resp, req)
 
 		assert.Equal(t, http.StatusBadRequest, resp.Code)
 	})
@@ -670,7 +885,7 @@ func TestUpdateItem(t *testing.T) {
 		req, _ := http.NewRequest(http.MethodPut, "/items/1", bytes.NewBuffer([]byte(`invalid`)))
 		resp := httptest.NewRecorder()
 
-		app.updateItem(resp, req)
+		app.route().ServeHTTP(resp, req)
 
 		assert.Equal(t, http.StatusBadRequest, resp.Code)
 	})
@@ -681,7 +896,7 @@ func TestUpdateItem(t *testing.T) {
 		req, _ := http.NewRequest(http.MethodPut, "/items/1", bytes.NewBuffer(body))
 		resp := httptest.NewRecorder()
 
-		app.updateItem(resp, req)
+		app.route().ServeHTTP(resp, req)
 
 		assert.Equal(t, http.StatusBadRequest, resp.Code)
 	})
@@ -692,7 +907,7 @@ func TestUpdateItem(t *testing.T) {
 		req, _ := http.NewRequest(http.MethodPut, "/items/1", bytes.NewBuffer(body))
 		resp := httptest.NewRecorder()
 
-		app.updateItem(resp, req)
+		app.route().ServeHTTP(resp, req)
 
 		assert.Equal(t, http.StatusBadRequest, resp.Code)
 	})
@@ -703,24 +918,25 @@ func TestUpdateItem(t *testing.T) {
 		req, _ := http.NewRequest(http.MethodPut, "/items/1", bytes.NewBuffer(body))
 		resp := httptest.NewRecorder()
 
-		app.updateItem(resp, req)
+		app.route().ServeHTTP(resp, req)
 
 		assert.Equal(t, http.StatusBadRequest, resp.Code)
 	})
 
 	t.Run("InternalServerErrorWhenUpdateFails", func(t *testing.T) {
-		item := data.Item{ID: 1, Name: "New Name", TableName: "New Table Name"}
-		resp, mock := setupUpdateTest(mock, app, item, nil, errors.New("mock error"))
+		item := data.Item{ID: 1, Name: "NewName", TableName: "new_table_name"}
+		resp, mock := setupUpdateTest(mock, app, item, errors.New("mock error"))
 
 		assert.Equal(t, http.StatusInternalServerError, resp.Code)
 		checkExpectations(t, mock)
 	})
 
 	t.Run("Success", func(t *testing.T) {
-		item := data.Item{ID: 1, Name: "New Name", TableName: "New Table Name"}
-		resp, mock := setupUpdateTest(mock, app, item, sqlmock.NewResult(1, 1), nil)
+		item := data.Item{ID: 1, Name: "NewName", TableName: "new_table_name"}
+		resp, mock := setupUpdateTest(mock, app, item, nil)
 
 		assert.Equal(t, http.StatusNoContent, resp.Code)
+		assert.Equal(t, `"2"`, resp.Header().Get("ETag"))
 		checkExpectations(t, mock)
 	})
 }
@@ -732,7 +948,7 @@ func TestReadExtension(t *testing.T) {
 		req, _ := http.NewRequest(http.MethodGet, "/extensions/invalid", nil)
 		resp := httptest.NewRecorder()
 
-		app.getExtensions(resp, req)
+		app.route().ServeHTTP(resp, req)
 
 		assert.Equal(t, http.StatusBadRequest, resp.Code)
 	})
@@ -775,13 +991,13 @@ func TestCreateExtension(t *testing.T) {
 		t.Fatalf("error marshalling data: %v", err)
 	}
 
-	t.Run("BadRequestWithInvalidRoute", func(t *testing.T) {
+	t.Run("MethodNotAllowedWithInvalidRoute", func(t *testing.T) {
 		req, _ := http.NewRequest(http.MethodPost, "/extensions/invalid", bytes.NewBuffer(jsonData))
 		resp := httptest.NewRecorder()
 
-		app.createExtension(resp, req)
+		app.route().ServeHTTP(resp, req)
 
-		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.Code)
 	})
 
 	t.Run("BadRequestWithNilBody", func(t *testing.T) {
@@ -816,8 +1032,8 @@ func TestCreateItem(t *testing.T) {
 	}
 
 	itemRequest := ItemRequest{
-		Name:        "Test-Item",
-		TableName:   "Test-Item-Table",
+		Name:        "TestItem",
+		TableName:   "test_item_table",
 		ExtensionId: 1,
 	}
 
@@ -840,15 +1056,13 @@ func TestCreateItem(t *testing.T) {
 
 	t.Run("StatusInternalServerErrorWhenReadingProjectNameFails", func(t *testing.T) {
 		_, mock, app := setupMockAndApp(t)
-		req, _ := http.NewRequest(http.MethodPost, "/items", bytes.NewBuffer([]byte(`{"name": "Test-Item", "table_name": "Test-Item-Table", "extension_id": 1}`)))
+		req, _ := http.NewRequest(http.MethodPost, "/items", bytes.NewBuffer([]byte(`{"name": "TestItem", "table_name": "test_item_table", "extension_id": 1}`)))
 		resp := httptest.NewRecorder()
 
 		setupExtensionMock(mock, testExtensionForProject.ID, sql.NullInt64{Int64: testExtensionForProject.ProjectID.Int64, Valid: true}, testExtensionForProject.Scope, testExtensionForProject.Name, testExtensionForProject.Description, testExtensionForProject.ItemCount, true)
 		setupNextFreeTypecodeMock(mock, testExtensionForProject.ProjectID.Int64, data.ScopeRanges[data.ScopeProject].Start, data.ScopeRanges[data.ScopeProject].End, 14000)
-		mock.ExpectBegin()
 		setupInsertItemMock(mock, itemRequest.Name, itemRequest.ExtensionId, itemRequest.TableName, 14000)
 		mockReadProjectNameReturnsError(mock, testProject.ID)
-		mock.ExpectRollback()
 
 		app.createItem(resp, req)
 
@@ -859,9 +1073,7 @@ func TestCreateItem(t *testing.T) {
 		_, mock, app := setupMockAndApp(t)
 		setupExtensionMock(mock, 1, sql.NullInt64{}, "Shared", "Test-Extension", "Test-Description", 1, true)
 		setupTypecodeMock(mock, "Shared", 20000, 20001)
-		mock.ExpectBegin()
-		setupInsertItemMock(mock, "Test-Item", 1, "Test-Item-Table-Name", 20001)
-		mock.ExpectCommit()
+		setupInsertItemMock(mock, "TestItem", 1, "test_item_table_name", 20001)
 
 		server := setupHTTPServer(app)
 		defer server.Close()
@@ -869,14 +1081,14 @@ func TestCreateItem(t *testing.T) {
 		resp := sendMockHTTPRequest(
 			server.URL,
 			"/items",
-			ItemRequest{Name: "Test-Item", TableName: "Test-Item-Table-Name", ExtensionId: 1},
+			ItemRequest{Name: "TestItem", TableName: "test_item_table_name", ExtensionId: 1},
 			t)
 
 		checkHTTPResponse(resp, http.StatusCreated, t)
 
 		expectedValues := ExpectedItemValues{
-			Name:        "Test-Item",
-			TableName:   "Test-Item-Table-Name",
+			Name:        "TestItem",
+			TableName:   "test_item_table_name",
 			ExtensionID: 1,
 			Typecode:    20001,
 		}
@@ -903,9 +1115,7 @@ func TestCreateItem(t *testing.T) {
 			20000,
 		}
 
-		mock.ExpectBegin()
 		mockInsertItemQueryToReturnError(mock, insertArgs)
-		mock.ExpectRollback()
 
 		// Mock HTTP Request
 		server := setupHTTPServer(app)
@@ -932,10 +1142,8 @@ func TestCreateItem(t *testing.T) {
 
 		setupExtensionMock(mock, testExtensionForProject.ID, sql.NullInt64{Int64: testExtensionForProject.ProjectID.Int64, Valid: true}, testExtensionForProject.Scope, testExtensionForProject.Name, testExtensionForProject.Description, testExtensionForProject.ItemCount, true)
 		setupNextFreeTypecodeMock(mock, testExtensionForProject.ProjectID.Int64, data.ScopeRanges[data.ScopeProject].Start, data.ScopeRanges[data.ScopeProject].End, 14000)
-		mock.ExpectBegin()
 		setupInsertItemMock(mock, itemRequest.Name, testExtensionForProject.ID, itemRequest.TableName, 14000)
 		setupReadProjectNameMock(mock, int64(testExtensionForProject.ProjectID.Int64), testProject.Name)
-		mock.ExpectCommit()
 
 		// Mock HTTP Request
 		server := setupHTTPServer(app)
@@ -946,8 +1154,8 @@ func TestCreateItem(t *testing.T) {
 		checkHTTPResponse(resp, http.StatusCreated, t)
 
 		expectedValues := ExpectedItemValues{
-			Name:        "Test-Item",
-			TableName:   "Test-Item-Table",
+			Name:        "TestItem",
+			TableName:   "test_item_table",
 			ExtensionID: 1,
 			Typecode:    14000,
 		}
@@ -969,7 +1177,7 @@ func TestUpdateExtension(t *testing.T) {
 		req, _ := http.NewRequest(http.MethodPut, "/extensions/invalid", nil)
 		resp := httptest.NewRecorder()
 
-		app.updateExtension(resp, req)
+		app.route().ServeHTTP(resp, req)
 
 		assert.Equal(t, http.StatusBadRequest, resp.Code)
 	})
@@ -978,7 +1186,7 @@ func TestUpdateExtension(t *testing.T) {
 		req, _ := http.NewRequest(http.MethodPut, "/extensions/38", nil)
 		resp := httptest.NewRecorder()
 
-		app.updateExtension(resp, req)
+		app.route().ServeHTTP(resp, req)
 
 		assert.Equal(t, http.StatusBadRequest, resp.Code)
 	})
@@ -986,10 +1194,10 @@ func TestUpdateExtension(t *testing.T) {
 	t.Run("InternalServerErrorOnFailingRead", func(t *testing.T) {
 		mockReadExtensionByIDQueryReturnsError(mock, int64(38), errors.New("mock error"))
 
-		req, _ := http.NewRequest(http.MethodPut, "/extensions/38", bytes.NewBuffer([]byte(`{"name": "Updated Name"}`)))
+		req, _ := http.NewRequest(http.MethodPut, "/extensions/38", bytes.NewBuffer([]byte(`{"name": "updated_name"}`)))
 		resp := httptest.NewRecorder()
 
-		app.updateExtension(resp, req)
+		app.route().ServeHTTP(resp, req)
 
 		assert.Equal(t, http.StatusInternalServerError, resp.Code)
 	})
@@ -1000,21 +1208,268 @@ func TestUpdateExtension(t *testing.T) {
 		req, _ := http.NewRequest(http.MethodPut, "/extensions/38", bytes.NewBuffer([]byte(`{"name":`)))
 		resp := httptest.NewRecorder()
 
-		app.updateExtension(resp, req)
+		app.route().ServeHTTP(resp, req)
 
 		assert.Equal(t, http.StatusBadRequest, resp.Code)
 	})
 
 	t.Run("SuccessfulUpdate", func(t *testing.T) {
 		setupExtensionMock(mock, 38, sql.NullInt64{}, "Shared", "Test-Extension", "Test-Description", 1, true)
-		mockUpdateExtensionQuery(mock, "Updated Name", "Updated Description", int64(38))
+		mockUpdateExtensionQuery(mock, "updated_name", "Updated Description", int64(38))
 
-		req, _ := http.NewRequest(http.MethodPut, "/extensions/38", bytes.NewBuffer([]byte(`{"name": "Updated Name", "description": "Updated Description"}`)))
+		req, _ := http.NewRequest(http.MethodPut, "/extensions/38", bytes.NewBuffer([]byte(`{"name": "updated_name", "description": "Updated Description"}`)))
 		resp := httptest.NewRecorder()
 
-		app.updateExtension(resp, req)
+		app.route().ServeHTTP(resp, req)
 
 		assert.Equal(t, http.StatusNoContent, resp.Code)
+		assert.Equal(t, `"2"`, resp.Header().Get("ETag"))
+		assert.Empty(t, resp.Header().Get("Content-Type"))
+		assert.Empty(t, resp.Body.String())
 	})
 
 }
+
+func TestReadProject(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	server := setupHTTPServer(app)
+	defer server.Close()
+
+	projectRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}).
+			AddRow(1, "Test-Project", "Test-Description", time.Now(), 1)
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockReadProjectByIDQuery(mock, 1, projectRows())
+		resp := getAndTestHTTPResponse(t, server, "/projects/1", http.StatusOK)
+
+		var body struct {
+			Project data.Project `json:"project"`
+		}
+		getResponse(resp, &body, t)
+		assert.Equal(t, "Test-Project", body.Project.Name)
+		checkExpectations(t, mock)
+	})
+
+	t.Run("NotFoundWhenProjectDoesNotExist", func(t *testing.T) {
+		mockReadProjectByIDQuery(mock, 2, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}))
+		mockHTTPGetRequest(app, t, "/projects/2", http.StatusNotFound)
+		checkExpectations(t, mock)
+	})
+
+	t.Run("ExtensionsOfProjectSucceeds", func(t *testing.T) {
+		mockReadProjectByIDQuery(mock, 1, projectRows())
+		mockReadExtensionsByProjectQuery(mock, 1, sqlmock.NewRows([]string{"id", "project_id", "name", "description", "scope", "creation_date", "version", "item_count"}).
+			AddRow(3, 1, "Test-Extension", "Test-Description", data.ScopeProject, time.Now(), 1, 2))
+		resp := getAndTestHTTPResponse(t, server, "/projects/1/extensions", http.StatusOK)
+
+		var body struct {
+			Extensions []data.Extension `json:"extensions"`
+		}
+		getResponse(resp, &body, t)
+		assert.Len(t, body.Extensions, 1)
+		assert.Equal(t, int64(1), body.Extensions[0].ProjectID.Int64)
+		checkExpectations(t, mock)
+	})
+
+	t.Run("ExtensionsOfMissingProjectReturnNotFound", func(t *testing.T) {
+		mockReadProjectByIDQuery(mock, 2, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}))
+		mockHTTPGetRequest(app, t, "/projects/2/extensions", http.StatusNotFound)
+		checkExpectations(t, mock)
+	})
+}
+
+func TestReadSingleExtension(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	server := setupHTTPServer(app)
+	defer server.Close()
+
+	t.Run("Success", func(t *testing.T) {
+		setupExtensionMock(mock, 1, sql.NullInt64{}, data.ScopeShared, "Test-Extension", "Test-Description", 4, true)
+		resp := getAndTestHTTPResponse(t, server, "/extensions/1", http.StatusOK)
+
+		var body struct {
+			Extension data.Extension `json:"extension"`
+		}
+		getResponse(resp, &body, t)
+		assert.Equal(t, "Test-Extension", body.Extension.Name)
+		assert.Equal(t, 4, body.Extension.ItemCount)
+		checkExpectations(t, mock)
+	})
+
+	t.Run("NotFoundWhenExtensionDoesNotExist", func(t *testing.T) {
+		setupExtensionMock(mock, 2, sql.NullInt64{}, "", "", "", 0, false)
+		mockHTTPGetRequest(app, t, "/extensions/2", http.StatusNotFound)
+		checkExpectations(t, mock)
+	})
+
+	t.Run("InternalServerErrorWhenReadFails", func(t *testing.T) {
+		mockReadExtensionByIDQueryReturnsError(mock, 3, errors.New("mock error"))
+		mockHTTPGetRequest(app, t, "/extensions/3", http.StatusInternalServerError)
+		checkExpectations(t, mock)
+	})
+
+	t.Run("ItemsOfExtensionUseExtensionIDFromURL", func(t *testing.T) {
+		setupExtensionMock(mock, 1, sql.NullInt64{}, data.ScopeShared, "Test-Extension", "Test-Description", 1, true)
+		mockReadFilteredItemsQuery(mock,
+			[]driver.Value{"", "", int64(0), int64(1), nil, nil, "", nil, nil, 10, 0},
+			sqlmock.NewRows([]string{"count", "id", "scope", "project", "name", "table_name", "extension_id", "typecode", "creation_date", "version"}).
+				AddRow(1, 7, data.ScopeShared, "-", "TestItem", "test_table", 1, 20000, time.Now(), 1))
+		resp := getAndTestHTTPResponse(t, server, "/extensions/1/items?extension_id=5&page=1&limit=10", http.StatusOK)
+
+		var body struct {
+			Items    []data.Item   `json:"items"`
+			Metadata data.Metadata `json:"metadata"`
+		}
+		getResponse(resp, &body, t)
+		assert.Len(t, body.Items, 1)
+		assert.Equal(t, 1, body.Metadata.TotalRecords)
+		checkExpectations(t, mock)
+	})
+
+	t.Run("ItemsOfMissingExtensionReturnNotFound", func(t *testing.T) {
+		setupExtensionMock(mock, 2, sql.NullInt64{}, "", "", "", 0, false)
+		mockHTTPGetRequest(app, t, "/extensions/2/items", http.StatusNotFound)
+		checkExpectations(t, mock)
+	})
+}
+
+func TestConditionalRequests(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+
+	serve := func(method, path string, body []byte, header, value string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		resp := httptest.NewRecorder()
+		app.route().ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("GetReturnsVersionAsETag", func(t *testing.T) {
+		mockReadItemDetailByItemIdQuery(mock, 1, newItemRows(1, 4))
+		resp := serve(http.MethodGet, "/items/1", nil, "", "")
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, `"4"`, resp.Header().Get("ETag"))
+		checkExpectations(t, mock)
+	})
+
+	t.Run("GetWithMatchingIfNoneMatchReturnsNotModified", func(t *testing.T) {
+		mockReadProjectByIDQuery(mock, 1, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}).
+			AddRow(1, "Test-Project", "Test-Description", time.Now(), 2))
+		resp := serve(http.MethodGet, "/projects/1", nil, "If-None-Match", `"1", W/"2"`)
+
+		assert.Equal(t, http.StatusNotModified, resp.Code)
+		assert.Equal(t, `"2"`, resp.Header().Get("ETag"))
+		assert.Empty(t, resp.Body.String())
+		checkExpectations(t, mock)
+	})
+
+	t.Run("PutWithOutdatedIfMatchReturnsPreconditionFailed", func(t *testing.T) {
+		mockReadItemDetailByItemIdQuery(mock, 1, newItemRows(1, 2))
+		resp := serve(http.MethodPut, "/items/1", []byte(`{"name": "NewName", "table_name": "new_table"}`), "If-Match", `"1"`)
+
+		assert.Equal(t, http.StatusPreconditionFailed, resp.Code)
+		assert.Equal(t, `"2"`, resp.Header().Get("ETag"))
+		checkExpectations(t, mock)
+	})
+
+	t.Run("PutProjectWithMatchingIfMatchSucceeds", func(t *testing.T) {
+		mockReadProjectByIDQuery(mock, 1, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}).
+			AddRow(1, "Test-Project", "Test-Description", time.Now(), 2))
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE project`)).
+			WithArgs("", "New", int64(1), int32(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+		resp := serve(http.MethodPut, "/projects/1", []byte(`{"description": "New"}`), "If-Match", `"2"`)
+
+		assert.Equal(t, http.StatusNoContent, resp.Code)
+		assert.Equal(t, `"3"`, resp.Header().Get("ETag"))
+		assert.Empty(t, resp.Header().Get("Content-Type"))
+		assert.Empty(t, resp.Body.String())
+		checkExpectations(t, mock)
+	})
+
+	t.Run("PutWithMatchingIfMatchSucceeds", func(t *testing.T) {
+		mockReadItemDetailByItemIdQuery(mock, 1, newItemRows(1, 2))
+		mockUpdateItemQuery(mock, "NewName", "new_table", 1, 2, nil)
+		resp := serve(http.MethodPut, "/items/1", []byte(`{"name": "NewName", "table_name": "new_table"}`), "If-Match", `"2"`)
+
+		assert.Equal(t, http.StatusNoContent, resp.Code)
+		assert.Equal(t, `"3"`, resp.Header().Get("ETag"))
+		checkExpectations(t, mock)
+	})
+
+	t.Run("PutWithWeakIfMatchReturnsPreconditionFailed", func(t *testing.T) {
+		setupExtensionMock(mock, 38, sql.NullInt64{}, data.ScopeShared, "Test-Extension", "Test-Description", 1, true)
+		resp := serve(http.MethodPut, "/extensions/38", []byte(`{"name": "updated_name"}`), "If-Match", `W/"1"`)
+
+		assert.Equal(t, http.StatusPreconditionFailed, resp.Code)
+		checkExpectations(t, mock)
+	})
+
+	t.Run("ConcurrentUpdateWithoutIfMatchReturnsConflict", func(t *testing.T) {
+		setupExtensionMock(mock, 38, sql.NullInt64{}, data.ScopeShared, "Test-Extension", "Test-Description", 1, true)
+		mockUpdateExtensionQueryReturnsNoRows(mock, 38)
+		resp := serve(http.MethodPut, "/extensions/38", []byte(`{"name": "updated_name"}`), "", "")
+
+		assert.Equal(t, http.StatusConflict, resp.Code)
+		checkExpectations(t, mock)
+	})
+
+	t.Run("ConcurrentUpdateWithIfMatchReturnsPreconditionFailed", func(t *testing.T) {
+		setupExtensionMock(mock, 38, sql.NullInt64{}, data.ScopeShared, "Test-Extension", "Test-Description", 1, true)
+		mockUpdateExtensionQueryReturnsNoRows(mock, 38)
+		resp := serve(http.MethodPut, "/extensions/38", []byte(`{"name": "updated_name"}`), "If-Match", `"1"`)
+
+		assert.Equal(t, http.StatusPreconditionFailed, resp.Code)
+		checkExpectations(t, mock)
+	})
+
+	t.Run("DeleteWithOutdatedIfMatchReturnsPreconditionFailed", func(t *testing.T) {
+		mockReadProjectByIDQuery(mock, 1, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}).
+			AddRow(1, "Test-Project", "Test-Description", time.Now(), 5))
+		resp := serve(http.MethodDelete, "/projects/1", nil, "If-Match", `"4"`)
+
+		assert.Equal(t, http.StatusPreconditionFailed, resp.Code)
+		checkExpectations(t, mock)
+	})
+
+	t.Run("DeleteWithMatchingIfMatchSucceeds", func(t *testing.T) {
+		mockReadItemDetailByItemIdQuery(mock, 1, newItemRows(1, 2))
+		mockDeleteItemExecution(mock, 1, 2, 1)
+		resp := serve(http.MethodDelete, "/items/1", nil, "If-Match", `"2"`)
+
+		assert.Equal(t, http.StatusNoContent, resp.Code)
+		checkExpectations(t, mock)
+	})
+
+	t.Run("DeleteAfterConcurrentUpdateReturnsPreconditionFailed", func(t *testing.T) {
+		mockReadItemDetailByItemIdQuery(mock, 1, newItemRows(1, 2))
+		mockDeleteItemExecution(mock, 1, 2, 0)
+		resp := serve(http.MethodDelete, "/items/1", nil, "If-Match", `"2"`)
+
+		assert.Equal(t, http.StatusPreconditionFailed, resp.Code)
+		checkExpectations(t, mock)
+	})
+
+	t.Run("DeleteOfConcurrentlyUpdatedProjectIsRolledBack", func(t *testing.T) {
+		mockReadProjectByIDQuery(mock, 1, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}).
+			AddRow(1, "Test-Project", "Test-Description", time.Now(), 5))
+		mock.ExpectBegin()
+		mockReadExtensionsByProjectQuery(mock, 1, sqlmock.NewRows([]string{"id", "project_id", "name", "description", "scope", "creation_date", "version", "item_count"}))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM extension WHERE project_id = $1`)).
+			WithArgs(int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM project WHERE id = $1 AND version = $2`)).
+			WithArgs(int64(1), int32(5)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+		resp := serve(http.MethodDelete, "/projects/1", nil, "", "")
+
+		assert.Equal(t, http.StatusConflict, resp.Code)
+		checkExpectations(t, mock)
+	})
+}
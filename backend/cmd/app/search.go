@@ -0,0 +1,81 @@
+package main
+
+import (
+	"Typecode-Registry/internal/data"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	maxSearchTermSize  = 255
+)
+
+// searchLinks maps the type of a search hit to the route of the resource.
+var searchLinks = map[string]string{
+	data.SearchTypeItem:      "/items/%d",
+	data.SearchTypeExtension: "/extensions/%d",
+	data.SearchTypeProject:   "/projects/%d",
+}
+
+// search handles the GET request to search items, extensions and projects.
+// Query parameters:
+//   - q: The search term (required). It matches names, table names, descriptions and typecode prefixes.
+//   - type: Comma separated list of types to search (item, extension, project). Defaults to all types.
+//   - limit: The maximum number of hits, 1 to 100 (default 20).
+//
+// The hits are ordered by rank and link to their resource.
+// If a query parameter is invalid, it returns a 400 Bad Request.
+func (app *application) search(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	term := app.readString(qs, "q", "")
+	if term == "" || utf8.RuneCountInString(term) > maxSearchTermSize {
+		app.errorResponse(w, r, http.StatusBadRequest, codeInvalidParameter, fmt.Sprintf("q must contain 1 to %d characters", maxSearchTermSize))
+		return
+	}
+
+	types := data.SearchTypes
+	if t := app.readString(qs, "type", ""); t != "" {
+		types = nil
+		for _, searchType := range strings.Split(strings.ToLower(t), ",") {
+			searchType = strings.TrimSpace(searchType)
+			if !slices.Contains(data.SearchTypes, searchType) {
+				app.errorResponse(w, r, http.StatusBadRequest, codeInvalidParameter, fmt.Sprintf("invalid type %q", searchType))
+				return
+			}
+			if !slices.Contains(types, searchType) {
+				types = append(types, searchType)
+			}
+		}
+	}
+
+	limit, err := app.readInt(qs, "limit", defaultSearchLimit)
+	if err != nil || limit < 1 || limit > maxSearchLimit {
+		app.errorResponse(w, r, http.StatusBadRequest, codeInvalidParameter, fmt.Sprintf("limit must be between 1 and %d", maxSearchLimit))
+		return
+	}
+
+	app.logger.Debug().Msg(fmt.Sprintf("searching %v for %q", types, term))
+	hits, err := app.models.Search.Search(term, types, limit)
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while searching for %q: %v", term, err))
+		app.serverErrorResponse(w, r)
+		return
+	}
+
+	for i := range hits {
+		hits[i].Link = fmt.Sprintf(searchLinks[hits[i].Type], hits[i].ID)
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"hits": hits}, nil)
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+		return
+	}
+}
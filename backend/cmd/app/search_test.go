@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchReturnsRankedHitsWithLinks(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT type, id, name, detail, typecode, rank FROM (`)).
+		WithArgs("prod_1", `%prod\_1%`, `prod\_1%`, defaultSearchLimit).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "id", "name", "detail", "typecode", "rank"}).
+			AddRow("item", 7, "prod_1", "products", 14000, 1.0).
+			AddRow("project", 2, "prod_1 shop", "", nil, 0.75))
+
+	req := httptest.NewRequest(http.MethodGet, "/search?q=prod_1", nil)
+	resp := httptest.NewRecorder()
+	app.route().ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var body struct {
+		Hits []struct {
+			Type     string  `json:"type"`
+			ID       int64   `json:"id"`
+			Typecode *int32  `json:"typecode"`
+			Rank     float64 `json:"rank"`
+			Link     string  `json:"link"`
+		} `json:"hits"`
+	}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	assert.Len(t, body.Hits, 2)
+	assert.Equal(t, "/items/7", body.Hits[0].Link)
+	assert.Equal(t, int32(14000), *body.Hits[0].Typecode)
+	assert.Equal(t, "/projects/2", body.Hits[1].Link)
+	assert.Nil(t, body.Hits[1].Typecode)
+	checkExpectations(t, mock)
+}
+
+func TestSearchOnlyQueriesRequestedTypes(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+
+	mock.ExpectQuery(`FROM project\s+WHERE name ILIKE \$2 OR description ILIKE \$2\s+\) AS hits`).
+		WithArgs("core", "%core%", "core%", 5).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "id", "name", "detail", "typecode", "rank"}))
+
+	req := httptest.NewRequest(http.MethodGet, "/search?q=core&type=Project&limit=5", nil)
+	resp := httptest.NewRecorder()
+	app.route().ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"hits": []}`, resp.Body.String())
+	checkExpectations(t, mock)
+}
+
+func TestSearchReturnsStatusBadRequestForInvalidParameters(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+
+	tests := []TestRoute{
+		{"/search", "GET", http.StatusBadRequest},
+		{"/search?q=%20", "GET", http.StatusBadRequest},
+		{"/search?q=a&type=user", "GET", http.StatusBadRequest},
+		{"/search?q=a&limit=0", "GET", http.StatusBadRequest},
+		{"/search?q=a&limit=101", "GET", http.StatusBadRequest},
+		{"/search?q=a", "POST", http.StatusMethodNotAllowed},
+	}
+
+	testRouting(t, tests, app.route())
+	checkExpectations(t, mock)
+}
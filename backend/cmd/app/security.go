@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/rs/cors"
+)
+
+// exposedHeaders are the response headers of the API which scripts of other origins may read.
+var exposedHeaders = []string{"ETag", "Location", "Idempotent-Replayed", "X-Request-Id", "WWW-Authenticate", "Retry-After"}
+
+// Content security policies of the responses. The API itself serves no active content, the Swagger UI below /docs/
+// loads its scripts and styles from jsDelivr and requests the OpenAPI document and the API from the server.
+const (
+	apiContentSecurityPolicy  = "default-src 'none'; frame-ancestors 'none'"
+	docsContentSecurityPolicy = "default-src 'none'; script-src 'self' https://cdn.jsdelivr.net; " +
+		"style-src 'self' 'unsafe-inline' https://cdn.jsdelivr.net; img-src 'self' data:; connect-src 'self'; frame-ancestors 'none'"
+)
+
+// splitList splits a comma separated list and drops empty entries.
+func splitList(list string) []string {
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// newCORS returns the handler of cross-origin requests of the configured origins, methods and headers.
+// Without allowed origins, browsers do not allow scripts of other origins to call the API.
+// Returns: An error if credentials are allowed for every origin or a method is not a valid token.
+func newCORS(cfg config) (*cors.Cors, error) {
+	origins := splitList(cfg.corsAllowedOrigins)
+	if cfg.corsAllowCredentials && slices.Contains(origins, "*") {
+		return nil, errors.New("-cors-allow-credentials requires the allowed origins to be listed, not *")
+	}
+
+	methods := splitList(cfg.corsAllowedMethods)
+	for i, method := range methods {
+		if strings.ContainsAny(method, " \t()<>@;:\\\"/[]?={}") {
+			return nil, fmt.Errorf("invalid method %q in -cors-allowed-methods", method)
+		}
+		methods[i] = strings.ToUpper(method)
+	}
+
+	options := cors.Options{
+		AllowedOrigins:   origins,
+		AllowedMethods:   methods,
+		AllowedHeaders:   splitList(cfg.corsAllowedHeaders),
+		ExposedHeaders:   exposedHeaders,
+		AllowCredentials: cfg.corsAllowCredentials,
+	}
+	// Without origins, the handler would allow every origin.
+	if len(origins) == 0 {
+		options.AllowOriginFunc = func(string) bool { return false }
+	}
+	return cors.New(options), nil
+}
+
+// isHTTPS reports whether the client sent r over TLS, either to the server or, if the server trusts
+// its proxy, to the proxy which tells so in the X-Forwarded-Proto header.
+func (app *application) isHTTPS(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	return app.config.trustForwardedFor && strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// secureHeaders adds the security headers to every response: a content security policy, which lets only the
+// Swagger UI load scripts and styles, nosniff, so browsers keep to the content type, a ban on framing the responses,
+// no referrer for links, and HSTS for requests over TLS so browsers keep using HTTPS.
+func (app *application) secureHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		if strings.HasPrefix(r.URL.Path, "/docs/") {
+			h.Set("Content-Security-Policy", docsContentSecurityPolicy)
+		} else {
+			h.Set("Content-Security-Policy", apiContentSecurityPolicy)
+		}
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("X-Frame-Options", "DENY")
+		h.Set("Referrer-Policy", "no-referrer")
+		if app.config.hstsMaxAge > 0 && app.isHTTPS(r) {
+			h.Set("Strict-Transport-Security", fmt.Sprintf("max-age=%d; includeSubDomains", int64(app.config.hstsMaxAge.Seconds())))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// corsConfig returns the configuration of cross-origin requests of the given origins with the default methods and headers.
+func corsConfig(origins string) config {
+	return config{
+		corsAllowedOrigins: origins,
+		corsAllowedMethods: "GET,POST,PUT,DELETE,OPTIONS",
+		corsAllowedHeaders: "Content-Type,Authorization,If-Match,Idempotency-Key",
+	}
+}
+
+// preflight sends the preflight request of a cross-origin request of origin through the CORS handler of cfg.
+func preflight(t *testing.T, cfg config, origin, method, headers string) *httptest.ResponseRecorder {
+	t.Helper()
+	c, err := newCORS(cfg)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, _, app := setupMockAndApp(t)
+	app.config = cfg
+
+	req := httptest.NewRequest(http.MethodOptions, "/items", nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", method)
+	if headers != "" {
+		req.Header.Set("Access-Control-Request-Headers", headers)
+	}
+	resp := httptest.NewRecorder()
+	c.Handler(app.handler()).ServeHTTP(resp, req)
+	return resp
+}
+
+func TestPreflightOfAllowedOrigin(t *testing.T) {
+	cfg := corsConfig("https://registry.example.com, https://*.tools.example.com")
+
+	for _, origin := range []string{"https://registry.example.com", "https://ci.tools.example.com"} {
+		resp := preflight(t, cfg, origin, http.MethodPut, "authorization,if-match")
+
+		assert.Equal(t, http.StatusNoContent, resp.Code, origin)
+		assert.Equal(t, origin, resp.Header().Get("Access-Control-Allow-Origin"), origin)
+		assert.Equal(t, http.MethodPut, resp.Header().Get("Access-Control-Allow-Methods"), origin)
+		assert.Equal(t, "Authorization, If-Match", resp.Header().Get("Access-Control-Allow-Headers"), origin)
+		assert.Empty(t, resp.Header().Get("Access-Control-Allow-Credentials"), origin)
+	}
+}
+
+func TestPreflightOfOtherOriginIsDenied(t *testing.T) {
+	cfg := corsConfig("https://registry.example.com")
+
+	testCases := map[string]struct {
+		origin, method, headers string
+	}{
+		"other origin":        {"https://evil.example.com", http.MethodPost, ""},
+		"subdomain of origin": {"https://x.registry.example.com", http.MethodPost, ""},
+		"other method":        {"https://registry.example.com", http.MethodPatch, ""},
+		"other header":        {"https://registry.example.com", http.MethodPost, "x-secret"},
+	}
+
+	for name, tc := range testCases {
+		resp := preflight(t, cfg, tc.origin, tc.method, tc.headers)
+
+		assert.Empty(t, resp.Header().Get("Access-Control-Allow-Origin"), name)
+		assert.Empty(t, resp.Header().Get("Access-Control-Allow-Methods"), name)
+	}
+}
+
+func TestPreflightWithoutAllowedOriginsIsDenied(t *testing.T) {
+	resp := preflight(t, corsConfig(""), "http://localhost:4200", http.MethodGet, "")
+
+	assert.Empty(t, resp.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestPreflightWithCredentials(t *testing.T) {
+	cfg := corsConfig("https://registry.example.com")
+	cfg.corsAllowCredentials = true
+
+	resp := preflight(t, cfg, "https://registry.example.com", http.MethodGet, "")
+
+	assert.Equal(t, "https://registry.example.com", resp.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", resp.Header().Get("Access-Control-Allow-Credentials"))
+}
+
+func TestInvalidCORSSettings(t *testing.T) {
+	cfg := corsConfig("*")
+	cfg.corsAllowCredentials = true
+	_, err := newCORS(cfg)
+	assert.Error(t, err, "credentials for every origin")
+
+	cfg = corsConfig("*")
+	cfg.corsAllowedMethods = "GET,PO ST"
+	_, err = newCORS(cfg)
+	assert.Error(t, err, "invalid method")
+}
+
+func TestCrossOriginResponsesExposeHeaders(t *testing.T) {
+	c, err := newCORS(corsConfig("*"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, mock, app := setupMockAndApp(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/items/abc", nil)
+	req.Header.Set("Origin", "https://registry.example.com")
+	resp := httptest.NewRecorder()
+	c.Handler(app.handler()).ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, "*", resp.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, resp.Header().Get("Access-Control-Expose-Headers"), "X-Request-Id")
+	assert.Contains(t, resp.Header().Get("Access-Control-Expose-Headers"), "Retry-After")
+	checkExpectations(t, mock)
+}
+
+func TestSecurityHeadersAreSet(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+
+	resp := serveRequest(app, http.MethodGet, "/items/abc", "")
+	assert.Equal(t, apiContentSecurityPolicy, resp.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "nosniff", resp.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "DENY", resp.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "no-referrer", resp.Header().Get("Referrer-Policy"))
+
+	resp = serveRequest(app, http.MethodGet, "/docs/", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, docsContentSecurityPolicy, resp.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "nosniff", resp.Header().Get("X-Content-Type-Options"))
+	checkExpectations(t, mock)
+}
+
+func TestHSTSIsSetForTLSOnly(t *testing.T) {
+	_, _, app := setupMockAndApp(t)
+	app.config.hstsMaxAge = 365 * 24 * time.Hour
+
+	resp := serveRequest(app, http.MethodGet, "/healthcheck", "")
+	assert.Empty(t, resp.Header().Get("Strict-Transport-Security"), "plain HTTP")
+
+	req := httptest.NewRequest(http.MethodGet, "/healthcheck", nil)
+	req.TLS = &tls.ConnectionState{}
+	resp = httptest.NewRecorder()
+	app.handler().ServeHTTP(resp, req)
+	assert.Equal(t, "max-age=31536000; includeSubDomains", resp.Header().Get("Strict-Transport-Security"))
+
+	req = httptest.NewRequest(http.MethodGet, "/healthcheck", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	resp = httptest.NewRecorder()
+	app.handler().ServeHTTP(resp, req)
+	assert.Empty(t, resp.Header().Get("Strict-Transport-Security"), "the proxy is not trusted")
+
+	app.config.trustForwardedFor = true
+	resp = httptest.NewRecorder()
+	app.handler().ServeHTTP(resp, req)
+	assert.Equal(t, "max-age=31536000; includeSubDomains", resp.Header().Get("Strict-Transport-Security"))
+
+	app.config.hstsMaxAge = 0
+	resp = httptest.NewRecorder()
+	app.handler().ServeHTTP(resp, req)
+	assert.Empty(t, resp.Header().Get("Strict-Transport-Security"), "HSTS is disabled")
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"Typecode-Registry/internal/auth"
+	"Typecode-Registry/internal/data"
+	"Typecode-Registry/internal/validator"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+)
+
+const (
+	// defaultTokenLifetime is the lifetime of API tokens created without expiry.
+	defaultTokenLifetime = 90 * 24 * time.Hour
+	// maxTokenLifetime limits the lifetime of API tokens, so forgotten tokens expire.
+	maxTokenLifetime = 366 * 24 * time.Hour
+	// tokenUsageResolution is the precision of the last use of API tokens, which is not stored on every request.
+	tokenUsageResolution = time.Minute
+)
+
+const apiTokenContextKey = contextKey("apiToken")
+
+// TokenRequest is the body of a request to create an API token.
+// Tokens created without expiry expire after defaultTokenLifetime.
+type TokenRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// ServiceAccountRequest is the body of a request to create a service account of a project.
+type ServiceAccountRequest struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// generateAPIToken returns a new random API token.
+func generateAPIToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return data.APITokenPrefix + hex.EncodeToString(b), nil
+}
+
+// authenticateAPIToken authenticates a request with an API token and passes it to next with the principal and
+// the token in its context. The principal of a personal access token is its user, the principal of a service
+// account has the subject service-account:{id}. Tokens without the write scope may only read.
+func (app *application) authenticateAPIToken(w http.ResponseWriter, r *http.Request, raw string, next http.Handler) {
+	token, err := app.models.Tokens.Authenticate(raw)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.logger.Info().Msg(fmt.Sprintf("rejected API token of %s %s", r.Method, r.URL.Path))
+		app.unauthorizedResponse(w, r, "invalid_token", "invalid token: unknown, revoked or expired API token")
+		return
+	}
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while reading API token: %v", err))
+		app.serverErrorResponse(w, r)
+		return
+	}
+
+	if token.LastUsedAt == nil || time.Since(*token.LastUsedAt) >= tokenUsageResolution {
+		if err := app.models.Tokens.Touch(token.ID); err != nil {
+			app.logger.Error().Msg(fmt.Sprintf("Error while storing the use of API token %d: %v", token.ID, err))
+		}
+	}
+
+	principal := &auth.Principal{ExpiresAt: token.ExpiresAt}
+	if token.User != nil {
+		principal.Subject, principal.Email, principal.Name = token.User.OAuthIdentifier, token.User.Email, token.User.Name
+	} else {
+		principal.Subject = fmt.Sprintf("service-account:%d", token.ServiceAccount.ID)
+		principal.Name = token.ServiceAccount.Name
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && !slices.Contains(token.Scopes, data.TokenScopeWrite) {
+		app.forbiddenResponse(w, r, "the API token has no write scope")
+		return
+	}
+
+	app.logger.Debug().Msg(fmt.Sprintf("authenticated %s with API token %d for %s %s", principal.Subject, token.ID, r.Method, r.URL.Path))
+	ctx := context.WithValue(r.Context(), principalContextKey, principal)
+	next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, apiTokenContextKey, token)))
+}
+
+// apiTokenFromContext returns the API token the request was authenticated with, or nil.
+func apiTokenFromContext(ctx context.Context) *data.APIToken {
+	token, _ := ctx.Value(apiTokenContextKey).(*data.APIToken)
+	return token
+}
+
+// readSignedInUser reads the registered user of the principal, who manages personal access tokens.
+// Returns: The user and true, or false if the response has already been written.
+func (app *application) readSignedInUser(w http.ResponseWriter, r *http.Request) (*data.User, bool) {
+	principal := principalFromContext(r.Context())
+	if principal == nil {
+		app.forbiddenResponse(w, r, "personal access tokens require authentication")
+		return nil, false
+	}
+	if !app.authorize(w, r, app.access(r).requireSignIn()) {
+		return nil, false
+	}
+
+	user, err := app.models.Users.ReadByOAuthIdentifier(principal.Subject)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.forbiddenResponse(w, r, fmt.Sprintf("the user %s is not registered", principal.Subject))
+		return nil, false
+	}
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while reading user %s: %v", principal.Subject, err))
+		app.serverErrorResponse(w, r)
+		return nil, false
+	}
+
+	return user, true
+}
+
+// readServiceAccountParams reads the project with the {id} and its service account with the {account_id} of the URL.
+// Returns: The project, the service account and true, or false if the response has already been written.
+func (app *application) readServiceAccountParams(w http.ResponseWriter, r *http.Request) (*data.Project, *data.ServiceAccount, bool) {
+	projectID, err := app.readIDParam(r)
+	if err != nil {
+		app.invalidParameterResponse(w, r, err)
+		return nil, nil, false
+	}
+	accountID, err := app.readPathID(r, "account_id")
+	if err != nil {
+		app.invalidParameterResponse(w, r, err)
+		return nil, nil, false
+	}
+
+	project, ok := app.readProject(w, r, projectID)
+	if !ok {
+		return nil, nil, false
+	}
+
+	account, err := app.models.ServiceAccounts.Read(accountID)
+	if err == nil && account.ProjectID != project.ID {
+		err = data.ErrRecordNotFound
+	}
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.notFoundResponse(w, r, fmt.Sprintf("no service account with id %d found in project %d", accountID, project.ID))
+		} else {
+			app.logger.Error().Msg(fmt.Sprintf("Error while reading service account with id %d: %v", accountID, err))
+			app.serverErrorResponse(w, r)
+		}
+		return nil, nil, false
+	}
+
+	return project, account, true
+}
+
+// writeTokens answers the request with a list of API tokens.
+func (app *application) writeTokens(w http.ResponseWriter, r *http.Request, tokens []*data.APIToken, err error) {
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while reading API tokens: %v", err))
+		app.serverErrorResponse(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"tokens": tokens}, nil)
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+	}
+}
+
+// issueToken creates an API token for the owner set in token from the request body. The response contains the token,
+// which cannot be read again.
+func (app *application) issueToken(w http.ResponseWriter, r *http.Request, token *data.APIToken) {
+	var req TokenRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.invalidBodyResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if req.validate(v); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	secret, err := generateAPIToken()
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while generating API token: %v", err))
+		app.serverErrorResponse(w, r)
+		return
+	}
+
+	token.Name, token.Scopes, token.Token = req.Name, req.Scopes, secret
+	token.ExpiresAt = time.Now().Add(defaultTokenLifetime)
+	if req.ExpiresAt != nil {
+		token.ExpiresAt = *req.ExpiresAt
+	}
+
+	err = app.models.Tokens.Insert(token)
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while creating API token: %v", err))
+		app.serverErrorResponse(w, r)
+		return
+	}
+	app.logger.Info().Msg(fmt.Sprintf("created API token %d %s", token.ID, token.Prefix))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"token": token}, nil)
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+	}
+}
+
+// revokeOwnedToken revokes the token with the ID in the path parameter param if owned reports
+// that it belongs to the owner in the URL. Otherwise, it returns a 404 Not Found.
+func (app *application) revokeOwnedToken(w http.ResponseWriter, r *http.Request, param string, owned func(*data.APIToken) bool) {
+	id, err := app.readPathID(r, param)
+	if err != nil {
+		app.invalidParameterResponse(w, r, err)
+		return
+	}
+
+	token, err := app.models.Tokens.Read(id)
+	if err == nil && !owned(token) {
+		err = data.ErrRecordNotFound
+	}
+	if err == nil {
+		err = app.models.Tokens.Revoke(id)
+	}
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.notFoundResponse(w, r, fmt.Sprintf("no token with id %d found", id))
+		return
+	}
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while revoking API token with id %d: %v", id, err))
+		app.serverErrorResponse(w, r)
+		return
+	}
+	app.logger.Info().Msg(fmt.Sprintf("revoked API token %d %s", token.ID, token.Prefix))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getTokens handles the GET request for the personal access tokens of the signed-in user.
+func (app *application) getTokens(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.readSignedInUser(w, r)
+	if !ok {
+		return
+	}
+
+	tokens, err := app.models.Tokens.ReadByUser(user.ID)
+	app.writeTokens(w, r, tokens, err)
+}
+
+// createToken handles the POST request to create a personal access token of the signed-in user.
+// The token has the roles of the user in the projects, but never those of a global admin.
+func (app *application) createToken(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.readSignedInUser(w, r)
+	if !ok {
+		return
+	}
+
+	app.issueToken(w, r, &data.APIToken{UserID: data.NullInt64{NullInt64: sql.NullInt64{Int64: user.ID, Valid: true}}})
+}
+
+// revokeToken handles the DELETE request to revoke a personal access token of the signed-in user.
+func (app *application) revokeToken(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.readSignedInUser(w, r)
+	if !ok {
+		return
+	}
+
+	app.revokeOwnedToken(w, r, "id", func(token *data.APIToken) bool {
+		return token.UserID.Valid && token.UserID.Int64 == user.ID
+	})
+}
+
+// getServiceAccounts handles the GET request for the service accounts of a project.
+func (app *application) getServiceAccounts(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.invalidParameterResponse(w, r, err)
+		return
+	}
+	project, ok := app.readProject(w, r, id)
+	if !ok {
+		return
+	}
+
+	accounts, err := app.models.ServiceAccounts.ReadByProject(project.ID)
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while reading service accounts of project with id %d: %v", project.ID, err))
+		app.serverErrorResponse(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"service_accounts": accounts}, nil)
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+	}
+}
+
+// createServiceAccount handles the POST request to create a service account with a role in a project.
+//   - If the request is invalid or the project has a service account with the name, it returns a 400 Bad Request.
+func (app *application) createServiceAccount(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.invalidParameterResponse(w, r, err)
+		return
+	}
+	project, ok := app.readProject(w, r, id)
+	if !ok || !app.authorizeServiceAccounts(w, r, project.ID) {
+		return
+	}
+
+	var req ServiceAccountRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.invalidBodyResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if req.validate(v); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	account := data.ServiceAccount{ProjectID: project.ID, Name: req.Name, Role: req.Role}
+	err = app.models.ServiceAccounts.Insert(&account)
+	if errors.Is(err, data.ErrDuplicateServiceAccount) {
+		v.AddError("name", "is already used by another service account of the project")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while creating service account: %v", err))
+		app.serverErrorResponse(w, r)
+		return
+	}
+	app.logger.Info().Msg(fmt.Sprintf("service account %d is %s of project %d", account.ID, account.Role, project.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"service_account": account}, nil)
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+	}
+}
+
+// deleteServiceAccount handles the DELETE request for a service account, which revokes its tokens as well.
+func (app *application) deleteServiceAccount(w http.ResponseWriter, r *http.Request) {
+	project, account, ok := app.readServiceAccountParams(w, r)
+	if !ok || !app.authorizeServiceAccounts(w, r, project.ID) {
+		return
+	}
+
+	err := app.models.ServiceAccounts.Delete(account.ID)
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while deleting service account with id %d: %v", account.ID, err))
+		app.serverErrorResponse(w, r)
+		return
+	}
+	app.logger.Info().Msg(fmt.Sprintf("deleted service account %d of project %d", account.ID, project.ID))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getServiceAccountTokens handles the GET request for the tokens of a service account.
+func (app *application) getServiceAccountTokens(w http.ResponseWriter, r *http.Request) {
+	_, account, ok := app.readServiceAccountParams(w, r)
+	if !ok {
+		return
+	}
+
+	tokens, err := app.models.Tokens.ReadByServiceAccount(account.ID)
+	app.writeTokens(w, r, tokens, err)
+}
+
+// createServiceAccountToken handles the POST request to create a token of a service account.
+func (app *application) createServiceAccountToken(w http.ResponseWriter, r *http.Request) {
+	project, account, ok := app.readServiceAccountParams(w, r)
+	if !ok || !app.authorizeServiceAccounts(w, r, project.ID) {
+		return
+	}
+
+	app.issueToken(w, r, &data.APIToken{ServiceAccountID: data.NullInt64{NullInt64: sql.NullInt64{Int64: account.ID, Valid: true}}})
+}
+
+// revokeServiceAccountToken handles the DELETE request to revoke a token of a service account.
+func (app *application) revokeServiceAccountToken(w http.ResponseWriter, r *http.Request) {
+	project, account, ok := app.readServiceAccountParams(w, r)
+	if !ok || !app.authorizeServiceAccounts(w, r, project.ID) {
+		return
+	}
+
+	app.revokeOwnedToken(w, r, "token_id", func(token *data.APIToken) bool {
+		return token.ServiceAccountID.Valid && token.ServiceAccountID.Int64 == account.ID
+	})
+}
+
+// authorizeServiceAccounts checks that the principal may manage the service accounts of the project:
+// as its admin, signed in at the identity provider.
+// Returns: true if the request may proceed, otherwise the response has already been written.
+func (app *application) authorizeServiceAccounts(w http.ResponseWriter, r *http.Request, projectID int64) bool {
+	access := app.access(r)
+	err := access.requireSignIn()
+	if err == nil {
+		err = access.requireProjectRole(projectID, data.RoleAdmin)
+	}
+	return app.authorize(w, r, err)
+}
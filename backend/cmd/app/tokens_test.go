@@ -0,0 +1,218 @@
+package main
+
+import (
+	"Typecode-Registry/internal/data"
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const testAPIToken = "tcr_0123456789abcdef"
+
+func apiTokenRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "name", "prefix", "user_id", "service_account_id", "scopes", "expires_at", "last_used_at",
+		"revoked_at", "creation_date"})
+}
+
+// mockAuthenticateAPIToken expects testAPIToken to be looked up. Without scopes, the token is unknown.
+// The token belongs to the user alice, or to the service account 3 of project 2 if role is set.
+func mockAuthenticateAPIToken(mock sqlmock.Sqlmock, role string, lastUsed time.Time, scopes ...string) {
+	rows := sqlmock.NewRows([]string{"id", "name", "prefix", "user_id", "service_account_id", "scopes", "expires_at", "last_used_at",
+		"revoked_at", "creation_date", "oauth_identifier", "email", "user_name", "project_id", "account_name", "role"})
+	switch {
+	case len(scopes) == 0:
+	case role == "":
+		rows.AddRow(7, "CI", "tcr_01234567", 4, nil, "{"+strings.Join(scopes, ",")+"}", time.Now().Add(time.Hour), lastUsed, nil, time.Now(),
+			"alice", "alice@example.com", "Alice", 0, "", "")
+	default:
+		rows.AddRow(7, "CI", "tcr_01234567", nil, 3, "{"+strings.Join(scopes, ",")+"}", time.Now().Add(time.Hour), lastUsed, nil, time.Now(),
+			"", "", "", 2, "Jenkins", role)
+	}
+
+	hash := sha256.Sum256([]byte(testAPIToken))
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE t.token_hash = $1 AND t.revoked_at IS NULL AND t.expires_at > CURRENT_TIMESTAMP`)).
+		WithArgs(hex.EncodeToString(hash[:])).
+		WillReturnRows(rows)
+}
+
+func mockReadSignedInUser(mock sqlmock.Sqlmock, subject string, id int64) {
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM "user" WHERE oauth_identifier = $1`)).
+		WithArgs(subject).
+		WillReturnRows(userRows().AddRow(id, subject, subject+"@example.com", "", time.Now()))
+}
+
+// capture is a sqlmock argument matcher which stores the argument.
+type capture struct {
+	value driver.Value
+}
+
+func (c *capture) Match(v driver.Value) bool {
+	c.value = v
+	return true
+}
+
+func TestCreatePersonalAccessToken(t *testing.T) {
+	mock, app, idp := setupRoleApp(t)
+	mockReadSignedInUser(mock, "alice", 4)
+	var prefix, hash capture
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO api_token (name, prefix, token_hash, user_id, service_account_id, scopes, expires_at)`)).
+		WithArgs("CI", &prefix, &hash, int64(4), nil, `{"read","write"}`, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "creation_date"}).AddRow(7, time.Now()))
+
+	resp := serveAs(app, http.MethodPost, "/tokens", idp.Token(t, idp.Claims("alice")), `{"name": "CI", "scopes": ["read", "write"]}`)
+
+	if !assert.Equal(t, http.StatusCreated, resp.Code) {
+		t.FailNow()
+	}
+	var body struct {
+		Token data.APIToken `json:"token"`
+	}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	assert.True(t, strings.HasPrefix(body.Token.Token, data.APITokenPrefix))
+	assert.Equal(t, body.Token.Token[:12], prefix.value)
+	sum := sha256.Sum256([]byte(body.Token.Token))
+	assert.Equal(t, hex.EncodeToString(sum[:]), hash.value, "only the hash of the token is stored")
+	assert.WithinDuration(t, time.Now().Add(defaultTokenLifetime), body.Token.ExpiresAt, time.Minute)
+	checkExpectations(t, mock)
+}
+
+func TestPersonalAccessTokensRequireRegisteredUser(t *testing.T) {
+	mock, app, idp := setupRoleApp(t)
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM "user" WHERE oauth_identifier = $1`)).
+		WithArgs("bob").
+		WillReturnRows(userRows())
+
+	resp := serveAs(app, http.MethodGet, "/tokens", idp.Token(t, idp.Claims("bob")), "")
+
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Equal(t, "the user bob is not registered", decodeProblem(t, resp).Detail)
+	checkExpectations(t, mock)
+}
+
+func TestAPITokensAuthenticateRequests(t *testing.T) {
+	t.Run("read", func(t *testing.T) {
+		mock, app, _ := setupRoleApp(t)
+		mockAuthenticateAPIToken(mock, "", time.Now().Add(-time.Hour), data.TokenScopeRead)
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE api_token SET last_used_at = CURRENT_TIMESTAMP WHERE id = $1`)).
+			WithArgs(7).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mockReadAllProjectsQuery(mock, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}))
+
+		resp := serveAs(app, http.MethodGet, "/projects", testAPIToken, "")
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		checkExpectations(t, mock)
+	})
+
+	t.Run("change without write scope", func(t *testing.T) {
+		mock, app, _ := setupRoleApp(t)
+		mockAuthenticateAPIToken(mock, "", time.Now(), data.TokenScopeRead)
+
+		resp := serveAs(app, http.MethodDelete, "/projects/2", testAPIToken, "")
+
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+		assert.Equal(t, "the API token has no write scope", decodeProblem(t, resp).Detail)
+		checkExpectations(t, mock)
+	})
+
+	t.Run("unknown, revoked or expired", func(t *testing.T) {
+		mock, app, _ := setupRoleApp(t)
+		mockAuthenticateAPIToken(mock, "", time.Now())
+
+		resp := serveAs(app, http.MethodGet, "/projects", testAPIToken, "")
+
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		assert.Equal(t, `Bearer realm="typecode-registry", error="invalid_token"`, resp.Header().Get("WWW-Authenticate"))
+		checkExpectations(t, mock)
+	})
+}
+
+func TestAPITokensCannotCreateTokens(t *testing.T) {
+	mock, app, _ := setupRoleApp(t)
+	mockAuthenticateAPIToken(mock, "", time.Now(), data.TokenScopeRead, data.TokenScopeWrite)
+
+	resp := serveAs(app, http.MethodPost, "/tokens", testAPIToken, `{"name": "CI", "scopes": ["write"]}`)
+
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	checkExpectations(t, mock)
+}
+
+func TestServiceAccountsHaveTheirRoleInTheirProjectOnly(t *testing.T) {
+	mock, app, _ := setupRoleApp(t)
+	mockAuthenticateAPIToken(mock, data.RoleDeveloper, time.Now(), data.TokenScopeWrite)
+
+	var a *access
+	handler := app.authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a = app.access(r)
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/items", nil)
+	req.Header.Set("Authorization", "Bearer "+testAPIToken)
+	handler.ServeHTTP(httptest.NewRecorder(), req.WithContext(context.Background()))
+
+	if assert.NotNil(t, a) {
+		assert.Equal(t, "service-account:3", a.principal.Subject)
+		assert.NoError(t, a.requireProjectRole(2, data.RoleDeveloper))
+		assert.Error(t, a.requireProjectRole(2, data.RoleAdmin))
+		assert.Error(t, a.requireProjectRole(5, data.RoleDeveloper))
+		assert.Error(t, a.requireGlobalAdmin("create projects"))
+	}
+	checkExpectations(t, mock)
+}
+
+func TestRevokeTokenOfAnotherUserReturnsNotFound(t *testing.T) {
+	mock, app, idp := setupRoleApp(t)
+	mockReadSignedInUser(mock, "alice", 4)
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM api_token t WHERE t.id = $1`)).
+		WithArgs(9).
+		WillReturnRows(apiTokenRows().AddRow(9, "CI", "tcr_01234567", 5, nil, "{read}", time.Now().Add(time.Hour), nil, nil, time.Now()))
+
+	resp := serveAs(app, http.MethodDelete, "/tokens/9", idp.Token(t, idp.Claims("alice")), "")
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	checkExpectations(t, mock)
+}
+
+func TestRevokeServiceAccountToken(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	mockReadProjectByIDQuery(mock, 2, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}).
+		AddRow(2, "Shop", "", time.Now(), 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM service_account WHERE id = $1`)).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "name", "role", "creation_date"}).AddRow(3, 2, "Jenkins", "developer", time.Now()))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM api_token t WHERE t.id = $1`)).
+		WithArgs(9).
+		WillReturnRows(apiTokenRows().AddRow(9, "CI", "tcr_01234567", nil, 3, "{read}", time.Now().Add(time.Hour), nil, nil, time.Now()))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE api_token SET revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP) WHERE id = $1`)).
+		WithArgs(9).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	resp := serveRequest(app, http.MethodDelete, "/projects/2/service-accounts/3/tokens/9", "")
+
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	checkExpectations(t, mock)
+}
+
+func TestServiceAccountOfAnotherProjectIsNotFound(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	mockReadProjectByIDQuery(mock, 2, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}).
+		AddRow(2, "Shop", "", time.Now(), 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM service_account WHERE id = $1`)).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "name", "role", "creation_date"}).AddRow(3, 5, "Jenkins", "developer", time.Now()))
+
+	resp := serveRequest(app, http.MethodDelete, "/projects/2/service-accounts/3", "")
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	checkExpectations(t, mock)
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"Typecode-Registry/internal/data"
+	"Typecode-Registry/internal/validator"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// UserRequest is the body of a request to register a user.
+type UserRequest struct {
+	OAuthIdentifier string `json:"oauth_identifier"`
+	Email           string `json:"email"`
+	Name            string `json:"name,omitempty"`
+}
+
+// RoleRequest is the body of a request to assign a role in a project to a user.
+type RoleRequest struct {
+	Role string `json:"role"`
+}
+
+// readUser reads the user with the given ID and answers the request with 404 Not Found if it does not exist.
+// Returns: The user and true, or false if the response has already been written.
+func (app *application) readUser(w http.ResponseWriter, r *http.Request, id int64) (*data.User, bool) {
+	user, err := app.models.Users.Read(id)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.notFoundResponse(w, r, fmt.Sprintf("no user with id %d found", id))
+		} else {
+			app.logger.Error().Msg(fmt.Sprintf("Error while reading user with id %d: %v", id, err))
+			app.serverErrorResponse(w, r)
+		}
+		return nil, false
+	}
+
+	return user, true
+}
+
+// readUserParam reads the user with the {id} of the URL.
+// Returns: The user and true, or false if the response has already been written.
+func (app *application) readUserParam(w http.ResponseWriter, r *http.Request) (*data.User, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.invalidParameterResponse(w, r, err)
+		return nil, false
+	}
+
+	return app.readUser(w, r, id)
+}
+
+// readMemberParams reads the project with the {id} and the user with the {user_id} of the URL.
+// Returns: The project, the user and true, or false if the response has already been written.
+func (app *application) readMemberParams(w http.ResponseWriter, r *http.Request) (*data.Project, *data.User, bool) {
+	projectID, err := app.readIDParam(r)
+	if err != nil {
+		app.invalidParameterResponse(w, r, err)
+		return nil, nil, false
+	}
+	userID, err := app.readPathID(r, "user_id")
+	if err != nil {
+		app.invalidParameterResponse(w, r, err)
+		return nil, nil, false
+	}
+
+	project, ok := app.readProject(w, r, projectID)
+	if !ok {
+		return nil, nil, false
+	}
+	user, ok := app.readUser(w, r, userID)
+	if !ok {
+		return nil, nil, false
+	}
+
+	return project, user, true
+}
+
+// getUsers handles the GET request for all users.
+func (app *application) getUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := app.models.Users.ReadAll()
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while reading users: %v", err))
+		app.serverErrorResponse(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"users": users}, nil)
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+	}
+}
+
+// createUser handles the POST request to register a user, e.g. before assigning roles to the user.
+//   - If the request is invalid or a user with the OAuth identifier exists, it returns a 400 Bad Request.
+func (app *application) createUser(w http.ResponseWriter, r *http.Request) {
+	if !app.authorize(w, r, app.access(r).requireGlobalAdmin("register users")) {
+		return
+	}
+
+	var req UserRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.invalidBodyResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if req.validate(v); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := data.User{OAuthIdentifier: req.OAuthIdentifier, Email: req.Email, Name: req.Name}
+	err := app.models.Users.Insert(&user)
+	if errors.Is(err, data.ErrDuplicateUser) {
+		v.AddError("oauth_identifier", "is already registered")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while creating user: %v", err))
+		app.serverErrorResponse(w, r)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/users/%d", user.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"user": user}, headers)
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+	}
+}
+
+// getUser handles the GET request for a user.
+func (app *application) getUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.readUserParam(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+	}
+}
+
+// getUserRoles handles the GET request for the projects of a user together with the user's role in each of them.
+func (app *application) getUserRoles(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.readUserParam(w, r)
+	if !ok {
+		return
+	}
+
+	roles, err := app.models.Roles.ReadByUser(user.ID)
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while reading roles of user with id %d: %v", user.ID, err))
+		app.serverErrorResponse(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"roles": roles}, nil)
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+	}
+}
+
+// getProjectMembers handles the GET request for the users having a role in a project.
+func (app *application) getProjectMembers(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.invalidParameterResponse(w, r, err)
+		return
+	}
+	project, ok := app.readProject(w, r, id)
+	if !ok {
+		return
+	}
+
+	members, err := app.models.Roles.ReadByProject(project.ID)
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while reading members of project with id %d: %v", project.ID, err))
+		app.serverErrorResponse(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"members": members}, nil)
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+	}
+}
+
+// assignProjectRole handles the PUT request to give a user a role in a project, replacing the user's previous role.
+// It returns 201 Created if the user was no member of the project before, otherwise 200 OK.
+func (app *application) assignProjectRole(w http.ResponseWriter, r *http.Request) {
+	project, user, ok := app.readMemberParams(w, r)
+	if !ok || !app.authorize(w, r, app.access(r).requireProjectRole(project.ID, data.RoleAdmin)) {
+		return
+	}
+
+	var req RoleRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.invalidBodyResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if req.validate(v); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	tx, models, err := app.beginChanges()
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+		return
+	}
+	defer rollbackChanges(tx)
+
+	before, err := app.readAuditedRole(models, user.ID, project.ID)
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while reading role of project %d of user %d: %v", project.ID, user.ID, err))
+		app.serverErrorResponse(w, r)
+		return
+	}
+
+	member := data.RoleAssignment{
+		UserID:      user.ID,
+		Email:       user.Email,
+		ProjectID:   project.ID,
+		ProjectName: project.Name,
+		Role:        req.Role,
+	}
+	created, err := models.Roles.Assign(&member)
+	if err == nil {
+		action := changeUpdated
+		if created {
+			action = changeCreated
+		}
+		err = app.commitAudited(r, tx, models, pendingChange{typ: auditRoleAssignment, action: action, before: before, after: &member})
+	}
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while assigning role %s of project %d to user %d: %v", req.Role, project.ID, user.ID, err))
+		app.serverErrorResponse(w, r)
+		return
+	}
+	app.logger.Info().Msg(fmt.Sprintf("user %d is %s of project %d", user.ID, member.Role, project.ID))
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+
+	err = app.writeJSON(w, status, envelope{"member": member}, nil)
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+	}
+}
+
+// revokeProjectRole handles the DELETE request to remove a user from a project.
+//   - If the user has no role in the project, it returns a 404 Not Found.
+func (app *application) revokeProjectRole(w http.ResponseWriter, r *http.Request) {
+	project, user, ok := app.readMemberParams(w, r)
+	if !ok || !app.authorize(w, r, app.access(r).requireProjectRole(project.ID, data.RoleAdmin)) {
+		return
+	}
+
+	tx, models, err := app.beginChanges()
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+		return
+	}
+	defer rollbackChanges(tx)
+
+	before, err := app.readAuditedRole(models, user.ID, project.ID)
+	if err == nil {
+		err = models.Roles.Revoke(user.ID, project.ID)
+	}
+	if err == nil {
+		err = app.commitAudited(r, tx, models, pendingChange{typ: auditRoleAssignment, action: changeDeleted, before: before})
+	}
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.notFoundResponse(w, r, fmt.Sprintf("user %d is no member of project %d", user.ID, project.ID))
+		return
+	}
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while revoking role of project %d from user %d: %v", project.ID, user.ID, err))
+		app.serverErrorResponse(w, r)
+		return
+	}
+	app.logger.Info().Msg(fmt.Sprintf("user %d is no longer member of project %d", user.ID, project.ID))
+
+	w.WriteHeader(http.StatusNoContent)
+}
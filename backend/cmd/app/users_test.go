@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func userRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "oauth_identifier", "email", "name", "creation_date"})
+}
+
+func roleAssignmentRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"role_assignment_id", "user_id", "email", "project_id", "name", "role", "source", "creation_date"})
+}
+
+func mockReadUserQuery(mock sqlmock.Sqlmock, id int64, rows *sqlmock.Rows) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, oauth_identifier, email, name, creation_date FROM "user" WHERE id = $1`)).
+		WithArgs(id).
+		WillReturnRows(rows)
+}
+
+func mockReadMember(mock sqlmock.Sqlmock, projectID, userID int64) {
+	mockReadProjectByIDQuery(mock, projectID, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}).
+		AddRow(projectID, "Shop", "", time.Now(), 1))
+	mockReadUserQuery(mock, userID, userRows().AddRow(userID, "alice-sub", "alice@example.com", "Alice", time.Now()))
+}
+
+func serveRequest(app *application, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	resp := httptest.NewRecorder()
+	app.handler().ServeHTTP(resp, req)
+	return resp
+}
+
+func TestCreateUser(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "user" (oauth_identifier, email, name)`)).
+		WithArgs("alice-sub", "alice@example.com", "Alice").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "creation_date"}).AddRow(4, time.Now()))
+
+	resp := serveRequest(app, http.MethodPost, "/users", `{"oauth_identifier": "alice-sub", "email": "alice@example.com", "name": "Alice"}`)
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Equal(t, "/users/4", resp.Header().Get("Location"))
+	checkExpectations(t, mock)
+}
+
+func TestCreateDuplicateUserFailsValidation(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "user"`)).
+		WillReturnError(&pq.Error{Code: "23505"})
+
+	resp := serveRequest(app, http.MethodPost, "/users", `{"oauth_identifier": "alice-sub", "email": "alice@example.com"}`)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	p := decodeProblem(t, resp)
+	assert.Equal(t, codeValidationFailed, p.Code)
+	assert.Equal(t, "oauth_identifier", p.Errors[0].Field)
+	checkExpectations(t, mock)
+}
+
+func TestGetUserRoles(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	mockReadUserQuery(mock, 4, userRows().AddRow(4, "alice-sub", "alice@example.com", "Alice", time.Now()))
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE ra.user_id = $1 ORDER BY ra.project_id`)).
+		WithArgs(int64(4)).
+		WillReturnRows(roleAssignmentRows().
+			AddRow(1, 4, "alice@example.com", 2, "Shop", "admin", "manual", time.Now()).
+			AddRow(5, 4, "alice@example.com", 3, "Outlet", "developer", "group", time.Now()))
+
+	resp := serveRequest(app, http.MethodGet, "/users/4/roles", "")
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var body struct {
+		Roles []struct {
+			ProjectName string `json:"project_name"`
+			Role        string `json:"role"`
+		} `json:"roles"`
+	}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	if assert.Len(t, body.Roles, 2) {
+		assert.Equal(t, "Shop", body.Roles[0].ProjectName)
+		assert.Equal(t, "developer", body.Roles[1].Role)
+	}
+	checkExpectations(t, mock)
+}
+
+func TestGetMissingUser(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	mockReadUserQuery(mock, 9, userRows())
+
+	resp := serveRequest(app, http.MethodGet, "/users/9", "")
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	checkExpectations(t, mock)
+}
+
+func TestAssignProjectRole(t *testing.T) {
+	testCases := map[string]struct {
+		created bool
+		status  int
+	}{
+		"new member":     {true, http.StatusCreated},
+		"changed member": {false, http.StatusOK},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, mock, app := setupMockAndApp(t)
+			mockReadMember(mock, 2, 4)
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO role_assignment (user_id, project_id, role, source)`)).
+				WithArgs(int64(4), int64(2), "admin").
+				WillReturnRows(sqlmock.NewRows([]string{"role_assignment_id", "creation_date", "created"}).AddRow(7, time.Now(), tc.created))
+
+			resp := serveRequest(app, http.MethodPut, "/projects/2/members/4", `{"role": "admin"}`)
+
+			assert.Equal(t, tc.status, resp.Code)
+			var body struct {
+				Member map[string]any `json:"member"`
+			}
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+			assert.Equal(t, "alice@example.com", body.Member["email"])
+			assert.Equal(t, "Shop", body.Member["project_name"])
+			checkExpectations(t, mock)
+		})
+	}
+}
+
+func TestAssignUnknownProjectRoleFailsValidation(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	mockReadMember(mock, 2, 4)
+
+	resp := serveRequest(app, http.MethodPut, "/projects/2/members/4", `{"role": "owner"}`)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, "role", decodeProblem(t, resp).Errors[0].Field)
+	checkExpectations(t, mock)
+}
+
+func TestAssignProjectRoleToMissingUser(t *testing.T) {
+	_, mock, app := setupMockAndApp(t)
+	mockReadProjectByIDQuery(mock, 2, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}).
+		AddRow(2, "Shop", "", time.Now(), 1))
+	mockReadUserQuery(mock, 9, userRows())
+
+	resp := serveRequest(app, http.MethodPut, "/projects/2/members/9", `{"role": "developer"}`)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.Contains(t, decodeProblem(t, resp).Detail, "no user with id 9")
+	checkExpectations(t, mock)
+}
+
+func TestRevokeProjectRole(t *testing.T) {
+	testCases := map[string]struct {
+		rowsAffected int64
+		status       int
+	}{
+		"member":    {1, http.StatusNoContent},
+		"no member": {0, http.StatusNotFound},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, mock, app := setupMockAndApp(t)
+			mockReadMember(mock, 2, 4)
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM role_assignment WHERE user_id = $1 AND project_id = $2`)).
+				WithArgs(int64(4), int64(2)).
+				WillReturnResult(sqlmock.NewResult(0, tc.rowsAffected))
+
+			resp := serveRequest(app, http.MethodDelete, "/projects/2/members/4", "")
+
+			assert.Equal(t, tc.status, resp.Code)
+			checkExpectations(t, mock)
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"Typecode-Registry/internal/data"
+	"Typecode-Registry/internal/validator"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxNameLength is the length of the VARCHAR(255) columns of names.
+const maxNameLength = 255
+
+// maxWebhookURLLength is the length of the VARCHAR(2048) column of webhook URLs.
+const maxWebhookURLLength = 2048
+
+// minWebhookSecretLength is the minimum length of secrets chosen by clients, so signatures cannot be guessed.
+const minWebhookSecretLength = 16
+
+// identifierRX matches the names of item types, deployment tables and extensions,
+// which SAP Commerce requires to be identifiers.
+var identifierRX = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// creatableScopes are the scopes of extensions which can be registered.
+// Typecodes of the Hybris scope are reserved for the types of the platform, see calculateTypecode.
+var creatableScopes = []string{data.ScopeShared, data.ScopeProject}
+
+// checkName checks the name in field, which must be set, without surrounding whitespace,
+// at most maxNameLength characters long and, unless rx is nil, match rx.
+func checkName(v *validator.Validator, field, name string, rx *regexp.Regexp) {
+	switch {
+	case name == "":
+		v.AddError(field, "must be provided")
+	case !validator.NotBlank(name):
+		v.AddError(field, "must not be blank")
+	case !validator.Trimmed(name):
+		v.AddError(field, "must not start or end with whitespace")
+	case !validator.MaxLength(name, maxNameLength):
+		v.AddError(field, fmt.Sprintf("must not be longer than %d characters", maxNameLength))
+	case rx == nil && !validator.Printable(name):
+		v.AddError(field, "must not contain control characters")
+	case rx != nil && !validator.Matches(name, rx):
+		v.AddError(field, "must start with a letter and contain only letters, digits and underscores")
+	}
+}
+
+// checkOptionalName checks the name in field like checkName if it is set.
+// Update requests leave the name unchanged if it is empty.
+func checkOptionalName(v *validator.Validator, field, name string, rx *regexp.Regexp) {
+	if name != "" {
+		checkName(v, field, name, rx)
+	}
+}
+
+// validate checks the fields of a request to create an item.
+func (req ItemRequest) validate(v *validator.Validator) {
+	checkName(v, "name", req.Name, identifierRX)
+	checkName(v, "table_name", req.TableName, identifierRX)
+	v.Check(req.ExtensionId >= 1, "extension_id", "must be a positive integer")
+}
+
+// validateItemUpdate checks the fields of a request to update the item with the given ID.
+// The ID of the body is optional, but must match id if it is set.
+func validateItemUpdate(v *validator.Validator, item *data.Item, id int64) {
+	v.Check(item.ID == 0 || item.ID == id, "id", "must match the id in the URL")
+	checkName(v, "name", item.Name, identifierRX)
+	checkName(v, "table_name", item.TableName, identifierRX)
+}
+
+// validate checks the fields of a request to create an extension.
+// The project ID is required for and only allowed with the Project scope.
+func (req ExtensionRequest) validate(v *validator.Validator) {
+	checkName(v, "name", req.Name, identifierRX)
+
+	switch {
+	case req.Scope == "":
+		v.AddError("scope", "must be provided")
+	case !validator.PermittedValue(req.Scope, creatableScopes...):
+		v.AddError("scope", fmt.Sprintf("must be %s or %s", data.ScopeShared, data.ScopeProject))
+	case req.Scope == data.ScopeProject:
+		v.Check(req.ProjectID >= 1, "project_id", "must be provided for scope "+data.ScopeProject)
+	default:
+		v.Check(req.ProjectID == 0, "project_id", "must not be provided for scope "+req.Scope)
+	}
+}
+
+// validate checks the fields of a request to update an extension.
+func (req ExtensionUpdateRequest) validate(v *validator.Validator) {
+	checkOptionalName(v, "name", req.Name, identifierRX)
+}
+
+// validate checks the fields of a request to create a project. Project names are free text.
+func (req ProjectRequest) validate(v *validator.Validator) {
+	checkName(v, "name", req.Name, nil)
+}
+
+// validate checks the fields of a request to update a project.
+func (req ProjectUpdateRequest) validate(v *validator.Validator) {
+	checkOptionalName(v, "name", req.Name, nil)
+}
+
+// checkWebhookURL checks that the URL in field is an absolute http or https URL without credentials.
+func checkWebhookURL(v *validator.Validator, field, rawURL string) {
+	if rawURL == "" {
+		v.AddError(field, "must be provided")
+		return
+	}
+	if !validator.MaxLength(rawURL, maxWebhookURLLength) {
+		v.AddError(field, fmt.Sprintf("must not be longer than %d characters", maxWebhookURLLength))
+		return
+	}
+
+	u, err := url.Parse(rawURL)
+	switch {
+	case err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https"):
+		v.AddError(field, "must be an absolute http or https URL")
+	case u.User != nil:
+		v.AddError(field, "must not contain credentials")
+	}
+}
+
+// checkWebhookEvents checks that the events in field are a non-empty list of distinct webhookEvents.
+func checkWebhookEvents(v *validator.Validator, field string, events []string) {
+	if len(events) == 0 {
+		v.AddError(field, "must contain at least one event")
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, event := range events {
+		switch {
+		case !validator.PermittedValue(event, webhookEvents...):
+			v.AddError(field, fmt.Sprintf("contains the unknown event %q, must be one of %s", event, strings.Join(webhookEvents, ", ")))
+		case seen[event]:
+			v.AddError(field, fmt.Sprintf("contains the event %q twice", event))
+		}
+		seen[event] = true
+	}
+}
+
+// checkWebhookSecret checks the secret in field if it is set. A missing secret is generated.
+func checkWebhookSecret(v *validator.Validator, field, secret string) {
+	switch {
+	case secret == "":
+	case !validator.MaxLength(secret, maxNameLength):
+		v.AddError(field, fmt.Sprintf("must not be longer than %d characters", maxNameLength))
+	case len(secret) < minWebhookSecretLength:
+		v.AddError(field, fmt.Sprintf("must be at least %d characters long", minWebhookSecretLength))
+	case !validator.Printable(secret):
+		v.AddError(field, "must not contain control characters")
+	}
+}
+
+// validate checks the fields of a request to create a webhook.
+func (req WebhookRequest) validate(v *validator.Validator) {
+	checkWebhookURL(v, "url", req.URL)
+	checkWebhookEvents(v, "events", req.Events)
+	checkWebhookSecret(v, "secret", req.Secret)
+	v.Check(req.ProjectID >= 0, "project_id", "must be a positive integer")
+	v.Check(validator.MaxLength(req.Description, maxNameLength), "description", fmt.Sprintf("must not be longer than %d characters", maxNameLength))
+}
+
+// validate checks the fields of a request to update a webhook.
+func (req WebhookUpdateRequest) validate(v *validator.Validator) {
+	checkWebhookURL(v, "url", req.URL)
+	checkWebhookEvents(v, "events", req.Events)
+	checkWebhookSecret(v, "secret", req.Secret)
+	v.Check(validator.MaxLength(req.Description, maxNameLength), "description", fmt.Sprintf("must not be longer than %d characters", maxNameLength))
+}
+
+// checkEmail checks that the email in field is a plain address like alice@example.com.
+func checkEmail(v *validator.Validator, field, email string) {
+	switch {
+	case email == "":
+		v.AddError(field, "must be provided")
+	case !validator.MaxLength(email, maxNameLength):
+		v.AddError(field, fmt.Sprintf("must not be longer than %d characters", maxNameLength))
+	default:
+		addr, err := mail.ParseAddress(email)
+		v.Check(err == nil && addr.Address == email, field, "must be an email address")
+	}
+}
+
+// validate checks the fields of a request to register a user.
+func (req UserRequest) validate(v *validator.Validator) {
+	checkName(v, "oauth_identifier", req.OAuthIdentifier, nil)
+	checkEmail(v, "email", req.Email)
+	checkOptionalName(v, "name", req.Name, nil)
+}
+
+// validate checks the fields of a request to assign a role in a project.
+func (req RoleRequest) validate(v *validator.Validator) {
+	v.Check(validator.PermittedValue(req.Role, data.Roles...), "role", fmt.Sprintf("must be one of %s", strings.Join(data.Roles, ", ")))
+}
+
+// checkTokenScopes checks that the scopes in field are a non-empty list of distinct data.TokenScopes.
+func checkTokenScopes(v *validator.Validator, field string, scopes []string) {
+	if len(scopes) == 0 {
+		v.AddError(field, "must contain at least one scope")
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, scope := range scopes {
+		switch {
+		case !validator.PermittedValue(scope, data.TokenScopes...):
+			v.AddError(field, fmt.Sprintf("contains the unknown scope %q, must be one of %s", scope, strings.Join(data.TokenScopes, ", ")))
+		case seen[scope]:
+			v.AddError(field, fmt.Sprintf("contains the scope %q twice", scope))
+		}
+		seen[scope] = true
+	}
+}
+
+// validate checks the fields of a request to create an API token.
+func (req TokenRequest) validate(v *validator.Validator) {
+	checkName(v, "name", req.Name, nil)
+	checkTokenScopes(v, "scopes", req.Scopes)
+	if req.ExpiresAt != nil {
+		now := time.Now()
+		v.Check(req.ExpiresAt.After(now), "expires_at", "must be in the future")
+		v.Check(req.ExpiresAt.Before(now.Add(maxTokenLifetime)), "expires_at", fmt.Sprintf("must be within %d days", maxTokenLifetime/(24*time.Hour)))
+	}
+}
+
+// validate checks the fields of a request to create a service account.
+func (req ServiceAccountRequest) validate(v *validator.Validator) {
+	checkName(v, "name", req.Name, nil)
+	v.Check(validator.PermittedValue(req.Role, data.Roles...), "role", fmt.Sprintf("must be one of %s", strings.Join(data.Roles, ", ")))
+}
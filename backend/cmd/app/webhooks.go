@@ -0,0 +1,771 @@
+package main
+
+import (
+	"Typecode-Registry/internal/data"
+	"Typecode-Registry/internal/validator"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// webhookEventPing is the event of the deliveries sent by POST /webhooks/{id}/ping.
+	webhookEventPing = "ping"
+	// webhookClaimLimit is the maximum number of deliveries attempted at the same time.
+	webhookClaimLimit = 10
+	// webhookPollInterval is the interval in which due retries are looked for.
+	webhookPollInterval = 5 * time.Second
+	// webhookLeaseMargin is added to the timeout of an attempt for the time a claimed delivery is reserved.
+	webhookLeaseMargin = 30 * time.Second
+	// maxWebhookBackoff is the longest delay between two attempts.
+	maxWebhookBackoff = 24 * time.Hour
+	// maxWebhookResponseSize is the number of bytes of a response which are read before the connection is closed.
+	maxWebhookResponseSize = 64 << 10
+)
+
+// webhookEvents are the events webhooks can subscribe to: every action on every type of resource, e.g. item.created.
+var webhookEvents = func() []string {
+	var events []string
+	for _, typ := range []string{changeProject, changeExtension, changeItem} {
+		for _, action := range []string{changeCreated, changeUpdated, changeDeleted} {
+			events = append(events, typ+"."+action)
+		}
+	}
+	return events
+}()
+
+// WebhookRequest is the request object for creating a new webhook.
+type WebhookRequest struct {
+	URL         string   `json:"url"`
+	Events      []string `json:"events"`
+	ProjectID   int64    `json:"project_id,omitempty"`
+	Secret      string   `json:"secret,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Active      *bool    `json:"active,omitempty"`
+}
+
+// WebhookUpdateRequest is the request object for updating an existing webhook.
+// The secret and the active flag are kept if they are missing.
+type WebhookUpdateRequest struct {
+	URL         string   `json:"url"`
+	Events      []string `json:"events"`
+	Secret      string   `json:"secret,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Active      *bool    `json:"active,omitempty"`
+}
+
+// errPrivateWebhookHost is returned for webhook receivers with a loopback, link-local or private address.
+var errPrivateWebhookHost = errors.New("the receiver has a loopback, link-local or private address")
+
+// webhookPayload is the JSON body of a delivery. Change is missing for ping events.
+type webhookPayload struct {
+	Event     string  `json:"event"`
+	WebhookID int64   `json:"webhook_id"`
+	Change    *Change `json:"change,omitempty"`
+}
+
+// webhookDispatcher sends the deliveries of webhooks.
+type webhookDispatcher struct {
+	client *http.Client
+	// allowPrivateHosts permits receivers with loopback, link-local and private addresses, e.g. in development.
+	allowPrivateHosts bool
+	// lookup resolves the host of a webhook URL.
+	lookup func(ctx context.Context, network, host string) ([]netip.Addr, error)
+	// wake is signalled when a delivery has been queued, so it is attempted without waiting for the next poll.
+	wake chan struct{}
+}
+
+// newWebhookDispatcher returns a dispatcher whose attempts time out after timeout.
+// Redirects are not followed, the receiver has to answer with a 2xx status itself.
+// Unless allowPrivateHosts is set, the address is checked again when connecting, so a host which resolves
+// to a private address after the webhook has been validated does not receive deliveries either.
+func newWebhookDispatcher(timeout time.Duration, allowPrivateHosts bool) *webhookDispatcher {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivateHosts {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !isPublicAddr(addrPort.Addr()) {
+				return errPrivateWebhookHost
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+
+	return &webhookDispatcher{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		allowPrivateHosts: allowPrivateHosts,
+		lookup:            net.DefaultResolver.LookupNetIP,
+		wake:              make(chan struct{}, 1),
+	}
+}
+
+// isPublicAddr reports whether addr may receive deliveries: it is neither unspecified, loopback, link-local nor private.
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() && !addr.IsUnspecified() && !addr.IsLoopback() && !addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() && !addr.IsLinkLocalMulticast() && !addr.IsInterfaceLocalMulticast()
+}
+
+// checkHost resolves the host of the valid webhook URL rawURL and checks that it has only public addresses,
+// unless private hosts are allowed.
+func (d *webhookDispatcher) checkHost(ctx context.Context, rawURL string) error {
+	if d == nil || d.allowPrivateHosts {
+		return nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	addrs := []netip.Addr{}
+	if addr, err := netip.ParseAddr(u.Hostname()); err == nil {
+		addrs = append(addrs, addr)
+	} else {
+		ctx, cancel := context.WithTimeout(ctx, d.client.Timeout)
+		defer cancel()
+		if addrs, err = d.lookup(ctx, "ip", u.Hostname()); err != nil {
+			return err
+		}
+	}
+
+	for _, addr := range addrs {
+		if !isPublicAddr(addr) {
+			return errPrivateWebhookHost
+		}
+	}
+	return nil
+}
+
+// checkWebhookHost adds an error for field to v if the host of the valid webhook URL rawURL cannot be resolved
+// or has a loopback, link-local or private address.
+func (app *application) checkWebhookHost(ctx context.Context, v *validator.Validator, field, rawURL string) {
+	err := app.webhooks.checkHost(ctx, rawURL)
+	switch {
+	case errors.Is(err, errPrivateWebhookHost):
+		v.AddError(field, "must not resolve to a loopback, link-local or private address")
+	case err != nil:
+		v.AddError(field, fmt.Sprintf("must have a host which can be resolved: %v", err))
+	}
+}
+
+// notify wakes the dispatcher if it is running.
+func (d *webhookDispatcher) notify() {
+	if d == nil {
+		return
+	}
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// webhookSignature returns the value of the X-Typecode-Signature header: the HMAC-SHA256 of the timestamp,
+// a dot and the body, keyed with the secret of the webhook, e.g. sha256=5257a869...
+// Receivers compute it the same way and reject deliveries whose timestamp is too old to prevent replays.
+func webhookSignature(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff returns the delay after the given number of failed attempts:
+// base after the first one, doubling with every further one up to maxWebhookBackoff.
+func webhookBackoff(base time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < maxWebhookBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxWebhookBackoff)
+}
+
+// generateWebhookSecret returns a random secret for a webhook created without one.
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// queueDelivery stores a delivery and wakes the dispatcher to attempt it.
+func (app *application) queueDelivery(delivery *data.WebhookDelivery) error {
+	if err := app.models.Webhooks.InsertDelivery(delivery); err != nil {
+		return err
+	}
+	app.webhooks.notify()
+	return nil
+}
+
+// queueWebhookDeliveries queues a delivery of c for every active webhook subscribed to its event and project.
+func (app *application) queueWebhookDeliveries(c Change) {
+	webhooks, err := app.models.Webhooks.ReadSubscribed(c.ProjectID, c.Event())
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while reading the webhooks of change %d: %v", c.ID, err))
+		return
+	}
+
+	for _, webhook := range webhooks {
+		payload, err := json.Marshal(webhookPayload{Event: c.Event(), WebhookID: webhook.ID, Change: &c})
+		if err != nil {
+			app.logger.Error().Msg(fmt.Sprintf("Error while encoding change %d for webhook %d: %v", c.ID, webhook.ID, err))
+			continue
+		}
+
+		delivery := data.WebhookDelivery{WebhookID: webhook.ID, Event: c.Event(), Payload: string(payload)}
+		if err := app.queueDelivery(&delivery); err != nil {
+			app.logger.Error().Msg(fmt.Sprintf("Error while queuing change %d for webhook %d: %v", c.ID, webhook.ID, err))
+		}
+	}
+}
+
+// watchChangesForWebhooks queues the deliveries of all changes of the change feed.
+// If queuing lags behind so far that the subscription is dropped, it resumes after the last queued change.
+// It runs until the application exits.
+func (app *application) watchChangesForWebhooks() {
+	lastID := app.changes.latestID()
+	for {
+		sub, missed, complete := app.changes.subscribe(lastID)
+		if !complete {
+			app.logger.Error().Msg(fmt.Sprintf("webhooks missed the changes after %d, which are no longer kept", lastID))
+		}
+
+		for _, c := range missed {
+			app.queueWebhookDeliveries(c)
+			lastID = c.ID
+		}
+		for c := range sub.C {
+			app.queueWebhookDeliveries(c)
+			lastID = c.ID
+		}
+
+		app.logger.Warn().Msg(fmt.Sprintf("queuing webhook deliveries lags behind, resuming after change %d", lastID))
+	}
+}
+
+// deliverWebhooks attempts the due deliveries whenever one has been queued and every pollInterval for retries.
+// It runs until the application exits.
+func (app *application) deliverWebhooks(pollInterval time.Duration) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		attempted, err := app.deliverDueWebhooks()
+		if err != nil {
+			app.logger.Error().Msg(fmt.Sprintf("Error while claiming due webhook deliveries: %v", err))
+		}
+		if attempted == webhookClaimLimit {
+			// More deliveries may be due.
+			continue
+		}
+
+		select {
+		case <-ticker.C:
+		case <-app.webhooks.wake:
+		}
+	}
+}
+
+// deliverDueWebhooks attempts up to webhookClaimLimit due deliveries concurrently and waits for their outcome.
+// Returns: the number of attempted deliveries.
+func (app *application) deliverDueWebhooks() (int, error) {
+	deliveries, err := app.models.Webhooks.ClaimDueDeliveries(webhookClaimLimit, app.config.webhookTimeout+webhookLeaseMargin)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			app.attemptDelivery(delivery)
+		}()
+	}
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+// attemptDelivery sends delivery to its webhook and stores the outcome. A delivery succeeds if the receiver
+// answers with a 2xx status. Otherwise it is retried with exponential backoff until the configured number
+// of attempts is reached, then it has failed.
+// Deliveries of inactive webhooks are abandoned without an attempt, except pings.
+func (app *application) attemptDelivery(delivery *data.WebhookDelivery) {
+	webhook, err := app.models.Webhooks.Read(delivery.WebhookID)
+	if err != nil {
+		// Deleting a webhook deletes its deliveries. Otherwise the delivery is attempted again once its claim expires.
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			app.logger.Error().Msg(fmt.Sprintf("Error while reading webhook %d of delivery %d: %v", delivery.WebhookID, delivery.ID, err))
+		}
+		return
+	}
+
+	if !webhook.Active && delivery.Event != webhookEventPing {
+		delivery.Status = data.DeliveryAbandoned
+		delivery.Error = "the webhook is inactive"
+		delivery.NextAttempt = nil
+		app.logger.Debug().Msg(fmt.Sprintf("abandoning delivery %d to inactive webhook %d", delivery.ID, webhook.ID))
+		if err := app.models.Webhooks.CompleteAttempt(delivery); err != nil {
+			app.logger.Error().Msg(fmt.Sprintf("Error while abandoning delivery %d: %v", delivery.ID, err))
+		}
+		return
+	}
+
+	status, err := app.sendDelivery(webhook, delivery)
+	delivery.Attempts++
+	delivery.ResponseStatus = status
+	delivery.Error = ""
+	switch {
+	case err != nil:
+		delivery.Error = err.Error()
+	case status < 200 || status > 299:
+		delivery.Error = fmt.Sprintf("the receiver answered with status %d %s", status, http.StatusText(status))
+	}
+
+	switch {
+	case delivery.Error == "":
+		delivery.Status = data.DeliverySucceeded
+		delivery.NextAttempt = nil
+	case delivery.Attempts >= app.config.webhookAttempts:
+		delivery.Status = data.DeliveryFailed
+		delivery.NextAttempt = nil
+	default:
+		next := time.Now().Add(webhookBackoff(app.config.webhookBackoff, delivery.Attempts))
+		delivery.Status = data.DeliveryPending
+		delivery.NextAttempt = &next
+	}
+
+	app.logger.Debug().Msg(fmt.Sprintf("attempt %d of delivery %d to webhook %d: %s %s", delivery.Attempts, delivery.ID, webhook.ID, delivery.Status, delivery.Error))
+	if err := app.models.Webhooks.CompleteAttempt(delivery); err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while storing the attempt of delivery %d: %v", delivery.ID, err))
+	}
+}
+
+// sendDelivery posts the payload of delivery to the URL of webhook, signed with its secret.
+// Returns: the status of the response, or an error if no response has been received.
+func (app *application) sendDelivery(webhook *data.Webhook, delivery *data.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Typecode-Registry-Webhook")
+	req.Header.Set("X-Typecode-Event", delivery.Event)
+	req.Header.Set("X-Typecode-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set("X-Typecode-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Typecode-Signature", webhookSignature(webhook.Secret, timestamp, body))
+
+	resp, err := app.webhooks.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// Reading the response lets the connection be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxWebhookResponseSize))
+	return resp.StatusCode, nil
+}
+
+// deleteOldWebhookDeliveries deletes the completed deliveries older than the retention period every interval.
+// It runs until the application exits.
+func (app *application) deleteOldWebhookDeliveries(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		deleted, err := app.models.Webhooks.DeleteDeliveriesBefore(time.Now().Add(-app.config.webhookRetention))
+		if err != nil {
+			app.logger.Error().Msg(fmt.Sprintf("Error while deleting old webhook deliveries: %v", err))
+			continue
+		}
+		app.logger.Debug().Msg(fmt.Sprintf("deleted %d old webhook deliveries", deleted))
+	}
+}
+
+// readWebhook reads the webhook with the given ID and answers the request with 404 Not Found if it does not exist.
+// Returns: The webhook and true, or false if the response has already been written.
+func (app *application) readWebhook(w http.ResponseWriter, r *http.Request, id int64) (*data.Webhook, bool) {
+	webhook, err := app.models.Webhooks.Read(id)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.notFoundResponse(w, r, fmt.Sprintf("no webhook with id %d found", id))
+		} else {
+			app.logger.Error().Msg(fmt.Sprintf("Error while reading webhook with id %d: %v", id, err))
+			app.serverErrorResponse(w, r)
+		}
+		return nil, false
+	}
+
+	return webhook, true
+}
+
+// readWebhookParam reads the webhook with the {id} of the URL.
+// Returns: The webhook and true, or false if the response has already been written.
+func (app *application) readWebhookParam(w http.ResponseWriter, r *http.Request) (*data.Webhook, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.invalidParameterResponse(w, r, err)
+		return nil, false
+	}
+
+	return app.readWebhook(w, r, id)
+}
+
+// authorizeWebhook checks that the principal may manage the webhook with the given ID, see authorize.
+// It answers the request with 404 Not Found if the webhook does not exist.
+// Returns: true if the request may proceed.
+func (app *application) authorizeWebhook(w http.ResponseWriter, r *http.Request, webhookID int64) bool {
+	err := app.access(r).requireWebhookIDAdmin(webhookID)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.notFoundResponse(w, r, fmt.Sprintf("no webhook with id %d found", webhookID))
+		return false
+	}
+	return app.authorize(w, r, err)
+}
+
+// getWebhooks handles the GET request for all webhooks the principal may manage.
+func (app *application) getWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := app.models.Webhooks.ReadAll()
+	if err == nil {
+		webhooks, err = app.access(r).manageableWebhooks(webhooks)
+	}
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while reading webhooks: %v", err))
+		app.serverErrorResponse(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"webhooks": webhooks}, nil)
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+	}
+}
+
+// createWebhook handles the POST request to create a webhook. The response contains the secret of the webhook,
+// which is generated if the request has none. It is never returned again.
+//   - If the request is invalid or the project does not exist, it returns a 400 Bad Request.
+func (app *application) createWebhook(w http.ResponseWriter, r *http.Request) {
+	var req WebhookRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.invalidBodyResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if req.validate(v); v.Valid() {
+		app.checkWebhookHost(r.Context(), v, "url", req.URL)
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	webhook := data.Webhook{
+		URL:         req.URL,
+		Secret:      req.Secret,
+		Events:      req.Events,
+		Description: req.Description,
+		Active:      req.Active == nil || *req.Active,
+	}
+
+	if req.ProjectID != 0 {
+		_, err := app.models.Projects.Read(req.ProjectID)
+		if errors.Is(err, data.ErrRecordNotFound) {
+			v.AddError("project_id", fmt.Sprintf("no project with id %d found", req.ProjectID))
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+		if err != nil {
+			app.logger.Error().Msg(fmt.Sprintf("Error while reading project with id %d: %v", req.ProjectID, err))
+			app.serverErrorResponse(w, r)
+			return
+		}
+		webhook.ProjectID = data.NullInt64{NullInt64: sql.NullInt64{Int64: req.ProjectID, Valid: true}}
+	}
+
+	if !app.authorize(w, r, app.access(r).requireWebhookAdmin(webhook.ProjectID)) {
+		return
+	}
+
+	if webhook.Secret == "" {
+		secret, err := generateWebhookSecret()
+		if err != nil {
+			app.logger.Error().Msg(fmt.Sprintf("Error while generating webhook secret: %v", err))
+			app.serverErrorResponse(w, r)
+			return
+		}
+		webhook.Secret = secret
+	}
+
+	app.logger.Debug().Msg(fmt.Sprintf("creating webhook for %s", webhook.URL))
+	err := app.models.Webhooks.Insert(&webhook)
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while creating webhook: %v", err))
+		app.serverErrorResponse(w, r)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/webhooks/%d", webhook.ID))
+	headers.Set("ETag", etag(webhook.Version))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"webhook": webhook, "secret": webhook.Secret}, headers)
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+	}
+}
+
+// getWebhook handles the GET request for a webhook. Its secret is not returned.
+func (app *application) getWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := app.readWebhookParam(w, r)
+	if !ok || !app.authorize(w, r, app.access(r).requireWebhookAdmin(webhook.ProjectID)) {
+		return
+	}
+
+	if app.notModified(w, r, webhook.Version) {
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", etag(webhook.Version))
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"webhook": webhook}, headers)
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+	}
+}
+
+// updateWebhook handles the PUT request to update a webhook. URL, events and description are replaced,
+// secret and active flag only if they are set. The project of a webhook cannot be changed.
+func (app *application) updateWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := app.readWebhookParam(w, r)
+	if !ok || !app.authorize(w, r, app.access(r).requireWebhookAdmin(webhook.ProjectID)) {
+		return
+	}
+
+	if app.preconditionFailed(w, r, webhook.Version) {
+		return
+	}
+
+	var req WebhookUpdateRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.invalidBodyResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if req.validate(v); v.Valid() {
+		app.checkWebhookHost(r.Context(), v, "url", req.URL)
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	webhook.URL = req.URL
+	webhook.Events = req.Events
+	webhook.Description = req.Description
+	if req.Secret != "" {
+		webhook.Secret = req.Secret
+	}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+
+	err := app.models.Webhooks.Update(webhook)
+	if errors.Is(err, data.ErrEditConflict) {
+		app.editConflictResponse(w, r)
+		return
+	}
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while updating webhook with id %d: %v", webhook.ID, err))
+		app.serverErrorResponse(w, r)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/webhooks/%d", webhook.ID))
+	w.Header().Set("ETag", etag(webhook.Version))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteWebhook handles the DELETE request for a webhook. Its deliveries are deleted as well.
+func (app *application) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := app.readWebhookParam(w, r)
+	if !ok || !app.authorize(w, r, app.access(r).requireWebhookAdmin(webhook.ProjectID)) {
+		return
+	}
+
+	if app.preconditionFailed(w, r, webhook.Version) {
+		return
+	}
+
+	err := app.models.Webhooks.Delete(webhook.ID)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		app.logger.Error().Msg(fmt.Sprintf("Error while deleting webhook with id %d: %v", webhook.ID, err))
+		app.serverErrorResponse(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// pingWebhook handles the POST request to send a ping event to a webhook, e.g. to check a new receiver.
+// Pings are sent to inactive webhooks as well. It returns 202 Accepted with the queued delivery.
+func (app *application) pingWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := app.readWebhookParam(w, r)
+	if !ok || !app.authorize(w, r, app.access(r).requireWebhookAdmin(webhook.ProjectID)) {
+		return
+	}
+
+	payload, err := json.Marshal(webhookPayload{Event: webhookEventPing, WebhookID: webhook.ID})
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+		return
+	}
+
+	delivery := data.WebhookDelivery{WebhookID: webhook.ID, Event: webhookEventPing, Payload: string(payload)}
+	app.writeQueuedDelivery(w, r, &delivery)
+}
+
+// getWebhookDeliveries handles the GET request for the last deliveries of a webhook, newest first.
+// The query parameter limit sets their number, 50 by default and at most 500.
+func (app *application) getWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := app.readWebhookParam(w, r)
+	if !ok || !app.authorize(w, r, app.access(r).requireWebhookAdmin(webhook.ProjectID)) {
+		return
+	}
+
+	limit, err := app.readInt(r.URL.Query(), "limit", 50)
+	if err == nil && (limit < 1 || limit > 500) {
+		err = errors.New("limit must be between 1 and 500")
+	}
+	if err != nil {
+		app.invalidParameterResponse(w, r, err)
+		return
+	}
+
+	deliveries, err := app.models.Webhooks.ReadDeliveries(webhook.ID, limit)
+	if err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while reading deliveries of webhook %d: %v", webhook.ID, err))
+		app.serverErrorResponse(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"deliveries": deliveries}, nil)
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+	}
+}
+
+// readDeliveryParams reads the delivery with the {delivery_id} of the webhook with the {id} of the URL.
+// Returns: The delivery and true, or false if the response has already been written.
+func (app *application) readDeliveryParams(w http.ResponseWriter, r *http.Request) (*data.WebhookDelivery, bool) {
+	webhookID, err := app.readIDParam(r)
+	if err != nil {
+		app.invalidParameterResponse(w, r, err)
+		return nil, false
+	}
+	id, err := app.readPathID(r, "delivery_id")
+	if err != nil {
+		app.invalidParameterResponse(w, r, err)
+		return nil, false
+	}
+
+	delivery, err := app.models.Webhooks.ReadDelivery(webhookID, id)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.notFoundResponse(w, r, fmt.Sprintf("no delivery with id %d of webhook %d found", id, webhookID))
+		} else {
+			app.logger.Error().Msg(fmt.Sprintf("Error while reading delivery %d of webhook %d: %v", id, webhookID, err))
+			app.serverErrorResponse(w, r)
+		}
+		return nil, false
+	}
+
+	return delivery, true
+}
+
+// getWebhookDelivery handles the GET request for a delivery of a webhook including its payload and outcome.
+func (app *application) getWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	delivery, ok := app.readDeliveryParams(w, r)
+	if !ok || !app.authorizeWebhook(w, r, delivery.WebhookID) {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"delivery": delivery}, nil)
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+	}
+}
+
+// redeliverWebhookDelivery handles the POST request to send the payload of a delivery again, e.g. after a receiver
+// has been fixed. The payload is sent as a new delivery referencing the original one, with a fresh signature.
+// It returns 202 Accepted with the queued delivery.
+func (app *application) redeliverWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	original, ok := app.readDeliveryParams(w, r)
+	if !ok || !app.authorizeWebhook(w, r, original.WebhookID) {
+		return
+	}
+
+	delivery := data.WebhookDelivery{
+		WebhookID:    original.WebhookID,
+		Event:        original.Event,
+		Payload:      original.Payload,
+		RedeliveryOf: data.NullInt64{NullInt64: sql.NullInt64{Int64: original.ID, Valid: true}},
+	}
+	app.writeQueuedDelivery(w, r, &delivery)
+}
+
+// writeQueuedDelivery queues delivery and answers the request with 202 Accepted and the delivery.
+func (app *application) writeQueuedDelivery(w http.ResponseWriter, r *http.Request, delivery *data.WebhookDelivery) {
+	if err := app.queueDelivery(delivery); err != nil {
+		app.logger.Error().Msg(fmt.Sprintf("Error while queuing %s delivery of webhook %d: %v", delivery.Event, delivery.WebhookID, err))
+		app.serverErrorResponse(w, r)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/webhooks/%d/deliveries/%d", delivery.WebhookID, delivery.ID))
+
+	err := app.writeJSON(w, http.StatusAccepted, envelope{"delivery": delivery}, headers)
+	if err != nil {
+		app.logger.Err(err)
+		app.serverErrorResponse(w, r)
+	}
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"Typecode-Registry/internal/data"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const testWebhookSecret = "0123456789abcdef"
+
+func webhookRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "project_id", "url", "secret", "events", "description", "active", "creation_date", "version"})
+}
+
+func deliveryRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "webhook_id", "event", "payload", "redelivery_of", "status", "attempts",
+		"response_status", "error", "next_attempt", "creation_date", "completion_date"})
+}
+
+func mockReadWebhookQuery(mock sqlmock.Sqlmock, id int64, url string) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, project_id, url, secret, events, description, active, creation_date, version FROM webhook WHERE id = $1`)).
+		WithArgs(id).
+		WillReturnRows(webhookRows().AddRow(id, 2, url, testWebhookSecret, "{item.created,item.deleted}", "build", true, time.Now(), 1))
+}
+
+func mockCompleteAttempt(mock sqlmock.Sqlmock, id int64, status string, attempts int, responseStatus, deliveryError, nextAttempt driver.Value) {
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE webhook_delivery`)).
+		WithArgs(status, attempts, responseStatus, deliveryError, nextAttempt, id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+// payloadArg matches the JSON payload of a delivery with the given event and change.
+type payloadArg struct {
+	event    string
+	changeID int64
+}
+
+func (a payloadArg) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+	var payload struct {
+		Event  string `json:"event"`
+		Change *struct {
+			ID int64 `json:"id"`
+		} `json:"change"`
+	}
+	if json.Unmarshal([]byte(s), &payload) != nil || payload.Event != a.event {
+		return false
+	}
+	if a.changeID == 0 {
+		return payload.Change == nil
+	}
+	return payload.Change != nil && payload.Change.ID == a.changeID
+}
+
+func setupWebhookApp(t *testing.T) (sqlmock.Sqlmock, *application) {
+	_, mock, app := setupMockAndApp(t)
+	app.webhooks = newWebhookDispatcher(time.Second, true)
+	app.config.webhookAttempts = 3
+	app.config.webhookBackoff = time.Minute
+	app.config.webhookTimeout = time.Second
+	return mock, app
+}
+
+func TestWebhookSignature(t *testing.T) {
+	signature := webhookSignature(testWebhookSecret, 1700000000, []byte(`{"event":"ping","webhook_id":3}`))
+	assert.Equal(t, "sha256=b88ac8a28c8515aba5bb5c1db18c3566c80635f0464eab1591ebd0c923c70922", signature)
+	assert.NotEqual(t, signature, webhookSignature(testWebhookSecret, 1700000001, []byte(`{"event":"ping","webhook_id":3}`)))
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, webhookBackoff(30*time.Second, 1))
+	assert.Equal(t, time.Minute, webhookBackoff(30*time.Second, 2))
+	assert.Equal(t, 4*time.Minute, webhookBackoff(30*time.Second, 4))
+	assert.Equal(t, maxWebhookBackoff, webhookBackoff(30*time.Second, 100))
+}
+
+func TestChangesAreQueuedForSubscribedWebhooks(t *testing.T) {
+	mock, app := setupWebhookApp(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE active AND (project_id IS NULL OR project_id = $1) AND $2 = ANY(events)`)).
+		WithArgs(int64(2), "item.created").
+		WillReturnRows(webhookRows().
+			AddRow(3, nil, "http://localhost/a", testWebhookSecret, "{item.created}", "", true, time.Now(), 1).
+			AddRow(4, 2, "http://localhost/b", testWebhookSecret, "{item.created,item.deleted}", "", true, time.Now(), 1))
+	for _, id := range []int64{3, 4} {
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO webhook_delivery (webhook_id, event, payload, redelivery_of)`)).
+			WithArgs(id, "item.created", payloadArg{"item.created", 17}, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "next_attempt", "creation_date"}).AddRow(id*10, "pending", time.Now(), time.Now()))
+	}
+
+	app.queueWebhookDeliveries(Change{ID: 17, Type: changeItem, Action: changeCreated, ProjectID: 2, Resource: &data.Item{ID: 9}})
+
+	select {
+	case <-app.webhooks.wake:
+	default:
+		t.Error("the dispatcher has not been woken")
+	}
+	checkExpectations(t, mock)
+}
+
+func TestDeliveryIsSignedAndSentToReceiver(t *testing.T) {
+	mock, app := setupWebhookApp(t)
+	payload := `{"event":"item.deleted","webhook_id":3,"change":{"id":17}}`
+
+	received := make(chan *http.Request, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp := r.Header.Get("X-Typecode-Timestamp")
+
+		mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+		mac.Write([]byte(timestamp + "." + string(body)))
+		if !hmac.Equal([]byte(r.Header.Get("X-Typecode-Signature")), []byte("sha256="+hex.EncodeToString(mac.Sum(nil)))) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, payload, string(body))
+		received <- r
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE webhook_delivery`)).
+		WithArgs(webhookClaimLimit, (time.Second + webhookLeaseMargin).Seconds()).
+		WillReturnRows(deliveryRows().AddRow(8, 3, "item.deleted", payload, nil, "pending", 0, nil, nil, time.Now(), time.Now(), nil))
+	mockReadWebhookQuery(mock, 3, receiver.URL+"/hooks")
+	mockCompleteAttempt(mock, 8, data.DeliverySucceeded, 1, int64(http.StatusNoContent), nil, nil)
+
+	attempted, err := app.deliverDueWebhooks()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, attempted)
+
+	r := <-received
+	assert.Equal(t, http.MethodPost, r.Method)
+	assert.Equal(t, "/hooks", r.URL.Path)
+	assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+	assert.Equal(t, "item.deleted", r.Header.Get("X-Typecode-Event"))
+	assert.Equal(t, "8", r.Header.Get("X-Typecode-Delivery"))
+	timestamp, err := strconv.ParseInt(r.Header.Get("X-Typecode-Timestamp"), 10, 64)
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Now().Unix(), timestamp, 5)
+	checkExpectations(t, mock)
+}
+
+func TestFailedDeliveryIsRetriedWithBackoff(t *testing.T) {
+	mock, app := setupWebhookApp(t)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	mockReadWebhookQuery(mock, 3, receiver.URL)
+	mockCompleteAttempt(mock, 8, data.DeliveryPending, 2, int64(http.StatusServiceUnavailable),
+		"the receiver answered with status 503 Service Unavailable", sqlmock.AnyArg())
+	delivery := &data.WebhookDelivery{ID: 8, WebhookID: 3, Event: "item.created", Payload: "{}", Attempts: 1}
+	before := time.Now()
+	app.attemptDelivery(delivery)
+
+	assert.Equal(t, data.DeliveryPending, delivery.Status)
+	if assert.NotNil(t, delivery.NextAttempt) {
+		assert.WithinDuration(t, before.Add(2*time.Minute), *delivery.NextAttempt, 5*time.Second)
+	}
+
+	mockReadWebhookQuery(mock, 3, receiver.URL)
+	mockCompleteAttempt(mock, 8, data.DeliveryFailed, 3, int64(http.StatusServiceUnavailable),
+		"the receiver answered with status 503 Service Unavailable", nil)
+	app.attemptDelivery(delivery)
+
+	assert.Equal(t, data.DeliveryFailed, delivery.Status)
+	assert.Nil(t, delivery.NextAttempt)
+	checkExpectations(t, mock)
+}
+
+func TestUnreachableReceiverIsRecorded(t *testing.T) {
+	mock, app := setupWebhookApp(t)
+	receiver := httptest.NewServer(http.NotFoundHandler())
+	receiver.Close()
+
+	mockReadWebhookQuery(mock, 3, receiver.URL)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE webhook_delivery`)).
+		WithArgs(data.DeliveryPending, 1, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), int64(8)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	delivery := &data.WebhookDelivery{ID: 8, WebhookID: 3, Event: "item.created", Payload: "{}"}
+	app.attemptDelivery(delivery)
+
+	assert.Equal(t, 0, delivery.ResponseStatus)
+	assert.Contains(t, delivery.Error, "connection refused")
+	checkExpectations(t, mock)
+}
+
+func TestRedirectsOfReceiverAreNotFollowed(t *testing.T) {
+	mock, app := setupWebhookApp(t)
+	receiver := httptest.NewServer(http.RedirectHandler("https://example.com/", http.StatusFound))
+	defer receiver.Close()
+
+	mockReadWebhookQuery(mock, 3, receiver.URL)
+	mockCompleteAttempt(mock, 8, data.DeliveryPending, 1, int64(http.StatusFound), "the receiver answered with status 302 Found", sqlmock.AnyArg())
+	app.attemptDelivery(&data.WebhookDelivery{ID: 8, WebhookID: 3, Event: "item.created", Payload: "{}"})
+	checkExpectations(t, mock)
+}
+
+func TestDeliveryToPrivateAddressIsRefused(t *testing.T) {
+	mock, app := setupWebhookApp(t)
+	app.webhooks = newWebhookDispatcher(time.Second, false)
+	received := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = true
+	}))
+	defer receiver.Close()
+
+	mockReadWebhookQuery(mock, 3, receiver.URL)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE webhook_delivery`)).
+		WithArgs(data.DeliveryPending, 1, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), int64(8)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	delivery := &data.WebhookDelivery{ID: 8, WebhookID: 3, Event: "item.created", Payload: "{}"}
+	app.attemptDelivery(delivery)
+
+	assert.False(t, received)
+	assert.Contains(t, delivery.Error, errPrivateWebhookHost.Error())
+	checkExpectations(t, mock)
+}
+
+func TestDeliveryOfInactiveWebhookIsAbandoned(t *testing.T) {
+	mock, app := setupWebhookApp(t)
+	received := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = true
+	}))
+	defer receiver.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM webhook WHERE id = $1`)).
+		WithArgs(int64(3)).
+		WillReturnRows(webhookRows().AddRow(3, 2, receiver.URL, testWebhookSecret, "{item.created}", "", false, time.Now(), 2))
+	mockCompleteAttempt(mock, 8, data.DeliveryAbandoned, 1, nil, "the webhook is inactive", nil)
+
+	delivery := &data.WebhookDelivery{ID: 8, WebhookID: 3, Event: "item.created", Payload: "{}", Attempts: 1}
+	app.attemptDelivery(delivery)
+
+	assert.False(t, received)
+	assert.Equal(t, data.DeliveryAbandoned, delivery.Status)
+	assert.Nil(t, delivery.NextAttempt)
+	checkExpectations(t, mock)
+}
+
+func TestWebhookForPrivateHostFailsValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{"Loopback", "http://127.0.0.1:8080/hooks"},
+		{"Metadata", "http://169.254.169.254/latest"},
+		{"IPv6Loopback", "http://[::1]/hooks"},
+		{"Private", "https://ci.internal/hooks"},
+		{"Localhost", "http://localhost/hooks"},
+		{"Unresolvable", "https://unknown.example.com/hooks"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock, app := setupWebhookApp(t)
+			app.webhooks = newWebhookDispatcher(time.Second, false)
+			app.webhooks.lookup = func(_ context.Context, _, host string) ([]netip.Addr, error) {
+				switch host {
+				case "ci.internal":
+					return []netip.Addr{netip.MustParseAddr("203.0.113.7"), netip.MustParseAddr("10.0.0.5")}, nil
+				case "localhost":
+					return []netip.Addr{netip.MustParseAddr("127.0.0.1")}, nil
+				}
+				return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+			}
+
+			resp := serveWebhookRequest(app, http.MethodPost, "/webhooks", `{"url": "`+tt.url+`", "events": ["item.created"]}`)
+
+			assert.Equal(t, http.StatusBadRequest, resp.Code)
+			p := decodeProblem(t, resp)
+			assert.Equal(t, codeValidationFailed, p.Code)
+			if assert.Len(t, p.Errors, 1) {
+				assert.Equal(t, "url", p.Errors[0].Field)
+			}
+			checkExpectations(t, mock)
+		})
+	}
+}
+
+func TestWebhookForPublicHostPassesHostCheck(t *testing.T) {
+	dispatcher := newWebhookDispatcher(time.Second, false)
+	dispatcher.lookup = func(context.Context, string, string) ([]netip.Addr, error) {
+		return []netip.Addr{netip.MustParseAddr("203.0.113.7"), netip.MustParseAddr("2001:db8::7")}, nil
+	}
+
+	assert.NoError(t, dispatcher.checkHost(context.Background(), "https://ci.example.com/hooks"))
+	assert.NoError(t, dispatcher.checkHost(context.Background(), "https://198.51.100.1/hooks"))
+	assert.ErrorIs(t, dispatcher.checkHost(context.Background(), "https://[::ffff:192.168.1.1]/hooks"), errPrivateWebhookHost)
+	assert.NoError(t, newWebhookDispatcher(time.Second, true).checkHost(context.Background(), "http://localhost/hooks"))
+}
+
+func serveWebhookRequest(app *application, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	resp := httptest.NewRecorder()
+	app.handler().ServeHTTP(resp, req)
+	return resp
+}
+
+func TestCreateWebhookReturnsGeneratedSecret(t *testing.T) {
+	mock, app := setupWebhookApp(t)
+	mockReadProjectByIDQuery(mock, 2, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}).
+		AddRow(2, "Shop", "", time.Now(), 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO webhook (project_id, url, secret, events, description, active)`)).
+		WithArgs(int64(2), "https://ci.example.com/hooks", sqlmock.AnyArg(), `{"item.created","item.deleted"}`, "", true).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "creation_date", "version"}).AddRow(3, time.Now(), 1))
+
+	resp := serveWebhookRequest(app, http.MethodPost, "/webhooks",
+		`{"url": "https://ci.example.com/hooks", "events": ["item.created", "item.deleted"], "project_id": 2}`)
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Equal(t, "/webhooks/3", resp.Header().Get("Location"))
+	var body struct {
+		Webhook map[string]any `json:"webhook"`
+		Secret  string         `json:"secret"`
+	}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	assert.Len(t, body.Secret, 64)
+	assert.Equal(t, float64(2), body.Webhook["project_id"])
+	assert.NotContains(t, body.Webhook, "secret")
+	checkExpectations(t, mock)
+}
+
+func TestCreateWebhookIgnoresIdempotencyKey(t *testing.T) {
+	mock, app := setupWebhookApp(t)
+	mockReadProjectByIDQuery(mock, 2, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}).
+		AddRow(2, "Shop", "", time.Now(), 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO webhook (project_id, url, secret, events, description, active)`)).
+		WithArgs(int64(2), "https://ci.example.com/hooks", sqlmock.AnyArg(), `{"item.created"}`, "", true).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "creation_date", "version"}).AddRow(3, time.Now(), 1))
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks",
+		strings.NewReader(`{"url": "https://ci.example.com/hooks", "events": ["item.created"], "project_id": 2}`))
+	req.Header.Set("Idempotency-Key", "webhook-1")
+	resp := httptest.NewRecorder()
+	app.handler().ServeHTTP(resp, req)
+
+	// The key is neither reserved nor stored, so the response with the secret is never replayed.
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Empty(t, resp.Header().Get("Idempotent-Replayed"))
+	checkExpectations(t, mock)
+}
+
+func TestCreateWebhookForMissingProjectFailsValidation(t *testing.T) {
+	mock, app := setupWebhookApp(t)
+	mockReadProjectByIDQuery(mock, 9, sqlmock.NewRows([]string{"id", "name", "description", "creation_date", "version"}))
+
+	resp := serveWebhookRequest(app, http.MethodPost, "/webhooks",
+		`{"url": "https://ci.example.com/hooks", "events": ["item.created"], "project_id": 9}`)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	p := decodeProblem(t, resp)
+	assert.Equal(t, codeValidationFailed, p.Code)
+	assert.Equal(t, "project_id", p.Errors[0].Field)
+	checkExpectations(t, mock)
+}
+
+func TestUpdateWebhookKeepsSecretAndActiveFlag(t *testing.T) {
+	mock, app := setupWebhookApp(t)
+	mockReadWebhookQuery(mock, 3, "https://ci.example.com/hooks")
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE webhook`)).
+		WithArgs("https://docs.example.com/hooks", testWebhookSecret, `{"item.deleted"}`, "", true, int64(3), int32(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+
+	resp := serveWebhookRequest(app, http.MethodPut, "/webhooks/3", `{"url": "https://docs.example.com/hooks", "events": ["item.deleted"]}`)
+
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Equal(t, etag(2), resp.Header().Get("ETag"))
+	assert.Empty(t, resp.Header().Get("Content-Type"))
+	assert.Empty(t, resp.Body.String())
+	checkExpectations(t, mock)
+}
+
+func TestPingWebhookQueuesDelivery(t *testing.T) {
+	mock, app := setupWebhookApp(t)
+	mockReadWebhookQuery(mock, 3, "https://ci.example.com/hooks")
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO webhook_delivery (webhook_id, event, payload, redelivery_of)`)).
+		WithArgs(int64(3), webhookEventPing, payloadArg{webhookEventPing, 0}, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "next_attempt", "creation_date"}).AddRow(12, "pending", time.Now(), time.Now()))
+
+	resp := serveWebhookRequest(app, http.MethodPost, "/webhooks/3/ping", "")
+
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+	assert.Equal(t, "/webhooks/3/deliveries/12", resp.Header().Get("Location"))
+	checkExpectations(t, mock)
+}
+
+func TestRedeliverQueuesPayloadAgain(t *testing.T) {
+	mock, app := setupWebhookApp(t)
+	payload := `{"event":"item.created","webhook_id":3,"change":{"id":17}}`
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM webhook_delivery WHERE id = $1 AND webhook_id = $2`)).
+		WithArgs(int64(8), int64(3)).
+		WillReturnRows(deliveryRows().AddRow(8, 3, "item.created", payload, nil, "failed", 3, 500, "the receiver answered with status 500", nil, time.Now(), time.Now()))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO webhook_delivery (webhook_id, event, payload, redelivery_of)`)).
+		WithArgs(int64(3), "item.created", payload, int64(8)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "next_attempt", "creation_date"}).AddRow(13, "pending", time.Now(), time.Now()))
+
+	resp := serveWebhookRequest(app, http.MethodPost, "/webhooks/3/deliveries/8/redeliver", "")
+
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+	var body struct {
+		Delivery data.WebhookDelivery `json:"delivery"`
+	}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	assert.Equal(t, int64(13), body.Delivery.ID)
+	assert.Equal(t, data.NullInt64{NullInt64: sql.NullInt64{Int64: 8, Valid: true}}, body.Delivery.RedeliveryOf)
+	assert.Equal(t, data.DeliveryPending, body.Delivery.Status)
+	checkExpectations(t, mock)
+}
+
+func TestWebhookDeliveriesRejectInvalidRequests(t *testing.T) {
+	mock, app := setupWebhookApp(t)
+
+	resp := serveWebhookRequest(app, http.MethodGet, "/webhooks/3/deliveries/abc", "")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, `invalid delivery_id "abc"`, decodeProblem(t, resp).Detail)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM webhook_delivery WHERE id = $1 AND webhook_id = $2`)).
+		WithArgs(int64(8), int64(4)).
+		WillReturnRows(deliveryRows())
+	resp = serveWebhookRequest(app, http.MethodPost, "/webhooks/4/deliveries/8/redeliver", "")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	mockReadWebhookQuery(mock, 3, "https://ci.example.com/hooks")
+	resp = serveWebhookRequest(app, http.MethodGet, "/webhooks/3/deliveries?limit=1000", "")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	checkExpectations(t, mock)
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"Typecode-Registry/pkg/client"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Rules reported by the check command.
+const (
+	ruleUnregistered = "unregistered-type"
+	ruleDuplicate    = "duplicate-typecode"
+	ruleForeign      = "foreign-typecode"
+)
+
+// ruleDescriptions documents the rules in reports that support it.
+var ruleDescriptions = map[string]string{
+	ruleUnregistered: "The type uses a typecode which is not registered in the Typecode Registry.",
+	ruleDuplicate:    "The typecode is used by more than one type.",
+	ruleForeign:      "The typecode is registered for a type of another project.",
+}
+
+// Supported report formats of the check command.
+const (
+	reportText  = "text"
+	reportJUnit = "junit"
+	reportSARIF = "sarif"
+)
+
+// errFindings is returned by the check command if at least one problem was found.
+var errFindings = errors.New("typecode check failed")
+
+// finding is a single problem detected by the check command.
+type finding struct {
+	Rule    string
+	Message string
+	Type    declaredType
+}
+
+// checkResult holds everything needed to render a report.
+type checkResult struct {
+	Types    []declaredType
+	Findings []finding
+}
+
+// check scans the given directories for items.xml files and compares the declared types with the registry.
+func (c *cli) check(args []string) error {
+	fs := c.newFlagSet("check")
+	project := fs.String("project", "", "Name of the project the checkout belongs to")
+	format := fs.String("format", reportText, "Report format (text, junit, sarif)")
+	outPath := fs.String("out", "", "Write the report to this file instead of stdout")
+	dirs, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if *format != reportText && *format != reportJUnit && *format != reportSARIF {
+		return &usageError{fmt.Sprintf("unknown report format %q", *format)}
+	}
+
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+
+	var types []declaredType
+	for _, dir := range dirs {
+		files, err := findItemsXML(dir)
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			declared, err := parseItemsXMLFile(file)
+			if err != nil {
+				return err
+			}
+			types = append(types, declared...)
+		}
+	}
+
+	registry, err := c.client.ListItems(c.ctx)
+	if err != nil {
+		return err
+	}
+
+	result := checkResult{
+		Types:    types,
+		Findings: compareWithRegistry(types, registry, *project),
+	}
+
+	out := c.out
+	if *outPath != "" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if err := writeReport(out, *format, result); err != nil {
+		return err
+	}
+
+	if len(result.Findings) > 0 {
+		return fmt.Errorf("%w: %d problem(s) in %d type(s)", errFindings, len(result.Findings), len(types))
+	}
+
+	return nil
+}
+
+// compareWithRegistry returns the problems of the declared types, ordered by file and line.
+// If project is set, project scoped registrations of other projects do not count as registered.
+func compareWithRegistry(types []declaredType, registry []client.Item, project string) []finding {
+	var findings []finding
+
+	byTypecode := make(map[int32][]client.Item)
+	for _, item := range registry {
+		byTypecode[item.Typecode] = append(byTypecode[item.Typecode], item)
+	}
+
+	ownProject := func(item client.Item) bool {
+		return project == "" || item.Scope != client.ScopeProject || strings.EqualFold(item.Project, project)
+	}
+
+	firstUse := make(map[int32]declaredType)
+	for _, t := range types {
+		if first, ok := firstUse[t.Typecode]; ok && !strings.EqualFold(first.Code, t.Code) {
+			findings = append(findings, finding{
+				Rule:    ruleDuplicate,
+				Message: fmt.Sprintf("typecode %d of %s is already used by %s (%s:%d)", t.Typecode, t.Code, first.Code, first.File, first.Line),
+				Type:    t,
+			})
+			continue
+		} else if !ok {
+			firstUse[t.Typecode] = t
+		}
+
+		own := false
+		var conflicting, foreign *client.Item
+		for _, item := range byTypecode[t.Typecode] {
+			switch {
+			case !ownProject(item):
+				if foreign == nil {
+					foreign = &item
+				}
+			case strings.EqualFold(item.Name, t.Code):
+				own = true
+			case conflicting == nil:
+				conflicting = &item
+			}
+		}
+
+		switch {
+		case own:
+		case conflicting != nil:
+			findings = append(findings, finding{
+				Rule:    ruleDuplicate,
+				Message: fmt.Sprintf("typecode %d of %s is registered for type %s", t.Typecode, t.Code, conflicting.Name),
+				Type:    t,
+			})
+		case foreign != nil:
+			findings = append(findings, finding{
+				Rule:    ruleForeign,
+				Message: fmt.Sprintf("typecode %d of %s belongs to type %s of project %s", t.Typecode, t.Code, foreign.Name, foreign.Project),
+				Type:    t,
+			})
+		default:
+			findings = append(findings, finding{
+				Rule:    ruleUnregistered,
+				Message: fmt.Sprintf("typecode %d of %s is not registered", t.Typecode, t.Code),
+				Type:    t,
+			})
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Type.File != findings[j].Type.File {
+			return findings[i].Type.File < findings[j].Type.File
+		}
+		return findings[i].Type.Line < findings[j].Type.Line
+	})
+
+	return findings
+}
+
+// writeReport renders the result of the check command in the given format.
+func writeReport(w io.Writer, format string, result checkResult) error {
+	switch format {
+	case reportJUnit:
+		return writeJUnitReport(w, result)
+	case reportSARIF:
+		return writeSARIFReport(w, result)
+	default:
+		return writeTextReport(w, result)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"Typecode-Registry/pkg/client"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testItemsXML = `<?xml version="1.0" encoding="ISO-8859-1"?>
+<items>
+	<relations>
+		<relation code="Product2Order" localized="false">
+			<deployment table="product2order" typecode="14002"/>
+		</relation>
+	</relations>
+	<itemtypes>
+		<typegroup name="Custom">
+			<itemtype code="CustomProduct" extends="Product" autocreate="true" generate="true">
+				<deployment table="customproducts" typecode="14000"/>
+			</itemtype>
+			<itemtype code="Product" autocreate="false" generate="false">
+				<attributes/>
+			</itemtype>
+			<itemtype code="CustomOrder" extends="Order">
+				<deployment table="customorders" typecode="14001"/>
+			</itemtype>
+		</typegroup>
+	</itemtypes>
+</items>
+`
+
+func TestParseItemsXMLReturnsTypesWithDeployment(t *testing.T) {
+	types, err := parseItemsXML(strings.NewReader(testItemsXML), "core-items.xml")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []declaredType{
+		{Code: "Product2Order", Table: "product2order", Typecode: 14002, File: "core-items.xml", Line: 4},
+		{Code: "CustomProduct", Table: "customproducts", Typecode: 14000, File: "core-items.xml", Line: 10},
+		{Code: "CustomOrder", Table: "customorders", Typecode: 14001, File: "core-items.xml", Line: 16},
+	}, types)
+}
+
+func TestParseItemsXMLReturnsErrorForInvalidTypecode(t *testing.T) {
+	xmlContent := `<items><itemtypes><itemtype code="A"><deployment table="a" typecode="abc"/></itemtype></itemtypes></items>`
+
+	_, err := parseItemsXML(strings.NewReader(xmlContent), "items.xml")
+
+	assert.Error(t, err)
+}
+
+func TestCompareWithRegistryReportsProblems(t *testing.T) {
+	types := []declaredType{
+		{Code: "Registered", Typecode: 14000, File: "items.xml", Line: 1},
+		{Code: "Unregistered", Typecode: 14001, File: "items.xml", Line: 2},
+		{Code: "LocalDuplicate", Typecode: 14000, File: "items.xml", Line: 3},
+		{Code: "Renamed", Typecode: 14002, File: "items.xml", Line: 4},
+		{Code: "Foreign", Typecode: 14003, File: "items.xml", Line: 5},
+		{Code: "SharedType", Typecode: 20000, File: "items.xml", Line: 6},
+	}
+
+	registry := []client.Item{
+		{Name: "Registered", Typecode: 14000, Scope: client.ScopeProject, Project: "Alpha"},
+		{Name: "Other", Typecode: 14002, Scope: client.ScopeProject, Project: "Alpha"},
+		{Name: "Foreign", Typecode: 14003, Scope: client.ScopeProject, Project: "Beta"},
+		{Name: "SharedType", Typecode: 20000, Scope: client.ScopeShared, Project: "-"},
+	}
+
+	findings := compareWithRegistry(types, registry, "alpha")
+
+	var rules []string
+	for _, f := range findings {
+		rules = append(rules, f.Type.Code+":"+f.Rule)
+	}
+
+	assert.Equal(t, []string{
+		"Unregistered:" + ruleUnregistered,
+		"LocalDuplicate:" + ruleDuplicate,
+		"Renamed:" + ruleDuplicate,
+		"Foreign:" + ruleForeign,
+	}, rules)
+}
+
+func TestCompareWithRegistryWithoutProjectAcceptsAllProjects(t *testing.T) {
+	types := []declaredType{{Code: "Foreign", Typecode: 14003}}
+	registry := []client.Item{{Name: "Foreign", Typecode: 14003, Scope: client.ScopeProject, Project: "Beta"}}
+
+	assert.Empty(t, compareWithRegistry(types, registry, ""))
+}
+
+func writeTestCheckout(t *testing.T) string {
+	dir := t.TempDir()
+	extension := filepath.Join(dir, "custom", "core", "resources")
+	if err := os.MkdirAll(extension, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(extension, "core-items.xml"), []byte(testItemsXML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestCheckSucceedsWhenEverythingIsRegistered(t *testing.T) {
+	dir := writeTestCheckout(t)
+	server, _ := setupFakeAPI(t, http.StatusOK, `{"items": [
+		{"name": "Product2Order", "typecode": 14002, "scope": "Project", "project": "Alpha"},
+		{"name": "CustomProduct", "typecode": 14000, "scope": "Project", "project": "Alpha"},
+		{"name": "CustomOrder", "typecode": 14001, "scope": "Project", "project": "Alpha"}
+	]}`)
+
+	code, stdout, _ := runAgainst(server, "check", "-project", "Alpha", dir)
+
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "3 type(s) checked, 0 problem(s) found")
+}
+
+func TestCheckFailsWithSARIFReport(t *testing.T) {
+	dir := writeTestCheckout(t)
+	server, _ := setupFakeAPI(t, http.StatusOK, `{"items": [
+		{"name": "CustomProduct", "typecode": 14000, "scope": "Project", "project": "Alpha"}
+	]}`)
+
+	code, stdout, stderr := runAgainst(server, "check", "-format", "sarif", dir)
+
+	assert.Equal(t, exitFindings, code)
+	assert.Contains(t, stderr, "2 problem(s)")
+
+	var log sarifLog
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	assert.Len(t, log.Runs[0].Results, 2)
+	assert.Equal(t, ruleUnregistered, log.Runs[0].Results[0].RuleID)
+	assert.Equal(t, 4, log.Runs[0].Results[0].Locations[0].PhysicalLocation.Region.StartLine)
+}
+
+func TestCheckWritesJUnitReportToFile(t *testing.T) {
+	dir := writeTestCheckout(t)
+	server, _ := setupFakeAPI(t, http.StatusOK, `{"items": []}`)
+	out := filepath.Join(t.TempDir(), "report.xml")
+
+	code, _, _ := runAgainst(server, "check", "-format", "junit", "-out", out, dir)
+
+	assert.Equal(t, exitFindings, code)
+
+	content, err := os.ReadFile(out)
+	assert.NoError(t, err)
+
+	var report junitTestSuites
+	assert.NoError(t, xml.Unmarshal(content, &report))
+	assert.Equal(t, 3, report.Tests)
+	assert.Equal(t, 3, report.Failures)
+}
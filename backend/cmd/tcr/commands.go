@@ -0,0 +1,374 @@
+package main
+
+import (
+	"Typecode-Registry/pkg/client"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// command executes a single action on a resource using the remaining command-line arguments.
+type command func(c *cli, args []string) error
+
+// commands maps resources and actions to their implementation.
+var commands = map[string]map[string]command{
+	"items": {
+		"list":   listItems,
+		"get":    getItem,
+		"create": createItem,
+		"update": updateItem,
+		"delete": deleteItem,
+	},
+	"extensions": {
+		"list":   listExtensions,
+		"create": createExtension,
+		"update": updateExtension,
+		"delete": deleteExtension,
+	},
+	"projects": {
+		"list":   listProjects,
+		"create": createProject,
+		"update": updateProject,
+		"delete": deleteProject,
+	},
+}
+
+// dispatch looks up and executes the command for the given resource and action.
+func (c *cli) dispatch(resource, action string, args []string) error {
+	actions, ok := commands[resource]
+	if !ok {
+		return &usageError{fmt.Sprintf("unknown resource %q", resource)}
+	}
+
+	cmd, ok := actions[action]
+	if !ok {
+		return &usageError{fmt.Sprintf("unknown action %q for %s, expected one of: %s", action, resource, actionNames(actions))}
+	}
+
+	return cmd(c, args)
+}
+
+func actionNames(actions map[string]command) string {
+	names := make([]string, 0, len(actions))
+	for name := range actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// newFlagSet creates the flag set of an action, writing its usage to the error output of the cli.
+func (c *cli) newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.errOut)
+	return fs
+}
+
+// parseFlags parses args into fs and returns the positional arguments.
+// Errors are returned as usageError, except flag.ErrHelp which is passed through unchanged.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil, err
+		}
+		return nil, &usageError{err.Error()}
+	}
+	return fs.Args(), nil
+}
+
+// parseWithID parses an action which expects exactly one ID argument.
+// The ID may be given before or after the flags.
+func parseWithID(fs *flag.FlagSet, args []string) (int64, error) {
+	var idArg string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		idArg, args = args[0], args[1:]
+	}
+
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return 0, err
+	}
+
+	if idArg == "" && len(rest) == 1 {
+		idArg, rest = rest[0], nil
+	}
+
+	if idArg == "" || len(rest) > 0 {
+		return 0, &usageError{fmt.Sprintf("%s expects exactly one id", fs.Name())}
+	}
+
+	id, err := strconv.ParseInt(idArg, 10, 64)
+	if err != nil || id < 1 {
+		return 0, &usageError{fmt.Sprintf("invalid id %q", idArg)}
+	}
+
+	return id, nil
+}
+
+// parseNoArgs parses an action which does not accept positional arguments.
+func parseNoArgs(fs *flag.FlagSet, args []string) error {
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(rest) > 0 {
+		return &usageError{fmt.Sprintf("%s does not accept arguments", fs.Name())}
+	}
+
+	return nil
+}
+
+// deleted confirms a deletion in table mode. JSON output stays empty so it can be piped safely.
+func (c *cli) deleted(kind string, id int64) error {
+	if c.format == outputTable {
+		_, err := fmt.Fprintf(c.out, "deleted %s %d\n", kind, id)
+		return err
+	}
+	return nil
+}
+
+func listItems(c *cli, args []string) error {
+	if err := parseNoArgs(c.newFlagSet("items list"), args); err != nil {
+		return err
+	}
+
+	items, err := c.client.ListItems(c.ctx)
+	if err != nil {
+		return err
+	}
+
+	return renderItems(c.out, c.format, items)
+}
+
+func getItem(c *cli, args []string) error {
+	id, err := parseWithID(c.newFlagSet("items get"), args)
+	if err != nil {
+		return err
+	}
+
+	item, err := c.client.GetItem(c.ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return renderItems(c.out, c.format, []client.Item{*item})
+}
+
+func createItem(c *cli, args []string) error {
+	fs := c.newFlagSet("items create")
+	name := fs.String("name", "", "Name of the type (required)")
+	table := fs.String("table", "", "Database table of the type (required)")
+	extension := fs.Int64("extension", 0, "ID of the extension the type belongs to (required)")
+	if err := parseNoArgs(fs, args); err != nil {
+		return err
+	}
+
+	if *name == "" || *table == "" || *extension < 1 {
+		return &usageError{"items create requires -name, -table and -extension"}
+	}
+
+	item, err := c.client.CreateItem(c.ctx, client.ItemRequest{Name: *name, TableName: *table, ExtensionID: *extension})
+	if err != nil {
+		return err
+	}
+
+	return renderItems(c.out, c.format, []client.Item{*item})
+}
+
+// updateItem changes name and table of an item.
+// The API expects both values, so missing ones are taken from the current item.
+func updateItem(c *cli, args []string) error {
+	fs := c.newFlagSet("items update")
+	name := fs.String("name", "", "New name of the type")
+	table := fs.String("table", "", "New database table of the type")
+	id, err := parseWithID(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if *name == "" && *table == "" {
+		return &usageError{"items update requires -name or -table"}
+	}
+
+	item, err := c.client.GetItem(c.ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if *name != "" {
+		item.Name = *name
+	}
+	if *table != "" {
+		item.TableName = *table
+	}
+
+	err = c.client.UpdateItem(c.ctx, id, client.ItemUpdateRequest{Name: item.Name, TableName: item.TableName})
+	if err != nil {
+		return err
+	}
+
+	return renderItems(c.out, c.format, []client.Item{*item})
+}
+
+func deleteItem(c *cli, args []string) error {
+	id, err := parseWithID(c.newFlagSet("items delete"), args)
+	if err != nil {
+		return err
+	}
+
+	if err := c.client.DeleteItem(c.ctx, id); err != nil {
+		return err
+	}
+
+	return c.deleted("item", id)
+}
+
+func listExtensions(c *cli, args []string) error {
+	fs := c.newFlagSet("extensions list")
+	scope := fs.String("scope", "", "Only list extensions of this scope (project, shared)")
+	if err := parseNoArgs(fs, args); err != nil {
+		return err
+	}
+
+	var extensions []client.Extension
+	var err error
+	if *scope != "" {
+		extensions, err = c.client.ListExtensionsByScope(c.ctx, *scope)
+	} else {
+		extensions, err = c.client.ListExtensions(c.ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	return renderExtensions(c.out, c.format, extensions)
+}
+
+func createExtension(c *cli, args []string) error {
+	fs := c.newFlagSet("extensions create")
+	name := fs.String("name", "", "Name of the extension (required)")
+	scope := fs.String("scope", "", "Scope of the extension, Shared or Project (required)")
+	description := fs.String("description", "", "Description of the extension")
+	project := fs.Int64("project", 0, "ID of the project, required for scope Project")
+	if err := parseNoArgs(fs, args); err != nil {
+		return err
+	}
+
+	if *name == "" || *scope == "" {
+		return &usageError{"extensions create requires -name and -scope"}
+	}
+
+	extension, err := c.client.CreateExtension(c.ctx, client.ExtensionRequest{
+		Name:        *name,
+		Scope:       *scope,
+		Description: *description,
+		ProjectID:   *project,
+	})
+	if err != nil {
+		return err
+	}
+
+	return renderExtensions(c.out, c.format, []client.Extension{*extension})
+}
+
+func updateExtension(c *cli, args []string) error {
+	fs := c.newFlagSet("extensions update")
+	name := fs.String("name", "", "New name of the extension")
+	description := fs.String("description", "", "New description of the extension, replaces the current one")
+	id, err := parseWithID(fs, args)
+	if err != nil {
+		return err
+	}
+
+	err = c.client.UpdateExtension(c.ctx, id, client.ExtensionUpdateRequest{Name: *name, Description: *description})
+	if err != nil {
+		return err
+	}
+
+	if c.format == outputTable {
+		_, err = fmt.Fprintf(c.out, "updated extension %d\n", id)
+	}
+	return err
+}
+
+func deleteExtension(c *cli, args []string) error {
+	id, err := parseWithID(c.newFlagSet("extensions delete"), args)
+	if err != nil {
+		return err
+	}
+
+	if err := c.client.DeleteExtension(c.ctx, id); err != nil {
+		return err
+	}
+
+	return c.deleted("extension", id)
+}
+
+func listProjects(c *cli, args []string) error {
+	if err := parseNoArgs(c.newFlagSet("projects list"), args); err != nil {
+		return err
+	}
+
+	projects, err := c.client.ListProjects(c.ctx)
+	if err != nil {
+		return err
+	}
+
+	return renderProjects(c.out, c.format, projects)
+}
+
+func createProject(c *cli, args []string) error {
+	fs := c.newFlagSet("projects create")
+	name := fs.String("name", "", "Name of the project (required)")
+	description := fs.String("description", "", "Description of the project")
+	if err := parseNoArgs(fs, args); err != nil {
+		return err
+	}
+
+	if *name == "" {
+		return &usageError{"projects create requires -name"}
+	}
+
+	project, err := c.client.CreateProject(c.ctx, client.ProjectRequest{Name: *name, Description: *description})
+	if err != nil {
+		return err
+	}
+
+	return renderProjects(c.out, c.format, []client.Project{*project})
+}
+
+func updateProject(c *cli, args []string) error {
+	fs := c.newFlagSet("projects update")
+	name := fs.String("name", "", "New name of the project")
+	description := fs.String("description", "", "New description of the project, replaces the current one")
+	id, err := parseWithID(fs, args)
+	if err != nil {
+		return err
+	}
+
+	err = c.client.UpdateProject(c.ctx, id, client.ProjectUpdateRequest{Name: *name, Description: *description})
+	if err != nil {
+		return err
+	}
+
+	if c.format == outputTable {
+		_, err = fmt.Fprintf(c.out, "updated project %d\n", id)
+	}
+	return err
+}
+
+func deleteProject(c *cli, args []string) error {
+	id, err := parseWithID(c.newFlagSet("projects delete"), args)
+	if err != nil {
+		return err
+	}
+
+	if err := c.client.DeleteProject(c.ctx, id); err != nil {
+		return err
+	}
+
+	return c.deleted("project", id)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordedRequest stores what the fake API received.
+type recordedRequest struct {
+	method string
+	path   string
+	auth   string
+	body   map[string]any
+}
+
+// setupFakeAPI starts a server answering every request with status and body and records the requests.
+func setupFakeAPI(t *testing.T, status int, body string) (*httptest.Server, *[]recordedRequest) {
+	var requests []recordedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := recordedRequest{method: r.Method, path: r.URL.Path, auth: r.Header.Get("Authorization")}
+		content, _ := io.ReadAll(r.Body)
+		if len(content) > 0 {
+			_ = json.Unmarshal(content, &rec.body)
+		}
+		requests = append(requests, rec)
+
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func runAgainst(server *httptest.Server, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	args = append([]string{"-config", "", "-url", server.URL, "-token", "secret"}, args...)
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestItemsListPrintsTable(t *testing.T) {
+	server, requests := setupFakeAPI(t, http.StatusOK, `{"items": [
+		{"id": 1, "scope": "Shared", "project": "-", "name": "Product", "table_name": "products", "extension_id": 2, "typecode": 20000}
+	]}`)
+
+	code, stdout, _ := runAgainst(server, "items", "list")
+
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "TYPECODE")
+	assert.Contains(t, stdout, "20000")
+	assert.Contains(t, stdout, "products")
+	assert.Equal(t, "/items", (*requests)[0].path)
+	assert.Equal(t, "Bearer secret", (*requests)[0].auth)
+}
+
+func TestExtensionsListPrintsJSON(t *testing.T) {
+	server, requests := setupFakeAPI(t, http.StatusOK, `{"extensions": [
+		{"id": 3, "project_id": 1, "name": "core", "scope": "Project", "item_count": 4}
+	]}`)
+
+	code, stdout, _ := runAgainst(server, "-o", "json", "extensions", "list", "-scope", "Project")
+
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "/extensions/project", (*requests)[0].path)
+
+	var extensions []map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &extensions))
+	assert.Equal(t, float64(1), extensions[0]["project_id"])
+}
+
+func TestItemsCreateSendsRequestBody(t *testing.T) {
+	server, requests := setupFakeAPI(t, http.StatusCreated, `{"item": {"id": 7, "name": "Order", "table_name": "orders", "extension_id": 1, "typecode": 14000}}`)
+
+	code, stdout, _ := runAgainst(server, "items", "create", "-name", "Order", "-table", "orders", "-extension", "1")
+
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "14000")
+	assert.Equal(t, http.MethodPost, (*requests)[0].method)
+	assert.Equal(t, map[string]any{"name": "Order", "table_name": "orders", "extension_id": float64(1)}, (*requests)[0].body)
+}
+
+func TestProjectsDeletePrintsConfirmation(t *testing.T) {
+	server, requests := setupFakeAPI(t, http.StatusNoContent, "")
+
+	code, stdout, _ := runAgainst(server, "projects", "delete", "12")
+
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "deleted project 12\n", stdout)
+	assert.Equal(t, http.MethodDelete, (*requests)[0].method)
+	assert.Equal(t, "/projects/12", (*requests)[0].path)
+}
+
+func TestAPIErrorsResultInMatchingExitCodes(t *testing.T) {
+	testCases := map[string]struct {
+		status   int
+		expected int
+	}{
+		"notFound":    {http.StatusNotFound, exitNotFound},
+		"badRequest":  {http.StatusBadRequest, exitBadRequest},
+		"serverError": {http.StatusInternalServerError, exitServerError},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			server, _ := setupFakeAPI(t, tc.status, "something went wrong")
+
+			code, stdout, stderr := runAgainst(server, "items", "get", "1")
+
+			assert.Equal(t, tc.expected, code)
+			assert.Empty(t, stdout)
+			assert.True(t, strings.Contains(stderr, "something went wrong"), stderr)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Environment variables which override the values of the configuration file.
+const (
+	envURL    = "TCR_URL"
+	envToken  = "TCR_TOKEN"
+	envConfig = "TCR_CONFIG"
+)
+
+// defaultURL is used if neither the configuration file, the environment nor a flag define a base URL.
+const defaultURL = "http://localhost:8080"
+
+// config holds the settings needed to talk to the registry API.
+type config struct {
+	URL    string `json:"url"`
+	Token  string `json:"token,omitempty"`
+	Output string `json:"output,omitempty"`
+}
+
+// defaultConfigPath returns the location of the configuration file if TCR_CONFIG is not set.
+// On Linux this is usually ~/.config/tcr/config.json.
+func defaultConfigPath() string {
+	if path := os.Getenv(envConfig); path != "" {
+		return path
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "tcr", "config.json")
+}
+
+// loadConfig builds the configuration in the order file < environment.
+// A missing configuration file is not an error, an unreadable or malformed one is.
+// Flags are applied afterwards by the caller.
+func loadConfig(path string) (config, error) {
+	cfg := config{URL: defaultURL, Output: outputTable}
+
+	if path != "" {
+		content, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			return cfg, fmt.Errorf("reading config file %s: %w", path, err)
+		default:
+			if err := json.Unmarshal(content, &cfg); err != nil {
+				return cfg, fmt.Errorf("parsing config file %s: %w", path, err)
+			}
+		}
+	}
+
+	if url := os.Getenv(envURL); url != "" {
+		cfg.URL = url
+	}
+
+	if token := os.Getenv(envToken); token != "" {
+		cfg.Token = token
+	}
+
+	cfg.URL = strings.TrimRight(cfg.URL, "/")
+	return cfg, nil
+}
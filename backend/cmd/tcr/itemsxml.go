@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// declaredType is an item type or relation with a deployment found in an items.xml file.
+type declaredType struct {
+	Code     string
+	Table    string
+	Typecode int32
+	File     string
+	Line     int
+}
+
+// isItemsXML reports whether name follows the SAP Commerce naming of type definition files,
+// either items.xml or <extension>-items.xml.
+func isItemsXML(name string) bool {
+	return name == "items.xml" || strings.HasSuffix(name, "-items.xml")
+}
+
+// findItemsXML walks root and returns all items.xml files below it.
+// Hidden directories and node_modules are skipped.
+func findItemsXML(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (strings.HasPrefix(name, ".") || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if isItemsXML(d.Name()) {
+			files = append(files, path)
+		}
+		return nil
+	})
+
+	return files, err
+}
+
+// parseItemsXMLFile reads the declared types of a single items.xml file.
+func parseItemsXMLFile(path string) ([]declaredType, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	types, err := parseItemsXML(f, filepath.ToSlash(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return types, nil
+}
+
+// parseItemsXML extracts every itemtype and relation which carries a deployment with a typecode.
+// Types without a deployment do not own a typecode and are ignored.
+func parseItemsXML(r io.Reader, file string) ([]declaredType, error) {
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = charsetReader
+
+	var types []declaredType
+	var current *declaredType
+
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return types, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch el := tok.(type) {
+		case xml.StartElement:
+			switch el.Name.Local {
+			case "itemtype", "relation":
+				line, _ := dec.InputPos()
+				current = &declaredType{Code: attr(el, "code"), File: file, Line: line}
+			case "deployment":
+				if current == nil {
+					continue
+				}
+
+				typecode := attr(el, "typecode")
+				value, err := strconv.ParseInt(typecode, 10, 32)
+				if err != nil {
+					line, _ := dec.InputPos()
+					return nil, fmt.Errorf("line %d: invalid typecode %q for type %s", line, typecode, current.Code)
+				}
+
+				current.Table = attr(el, "table")
+				current.Typecode = int32(value)
+				types = append(types, *current)
+			}
+		case xml.EndElement:
+			if el.Name.Local == "itemtype" || el.Name.Local == "relation" {
+				current = nil
+			}
+		}
+	}
+}
+
+// charsetReader converts the ISO-8859-1 encoding declared by most items.xml files to UTF-8.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "iso8859-1", "latin1", "us-ascii":
+		return &latin1Reader{r: bufio.NewReader(input)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", charset)
+	}
+}
+
+// latin1Reader decodes ISO-8859-1, where every byte is the code point of the same value.
+type latin1Reader struct {
+	r *bufio.Reader
+}
+
+func (l *latin1Reader) Read(p []byte) (int, error) {
+	n := 0
+	for n+utf8.UTFMax <= len(p) {
+		if n > 0 && l.r.Buffered() == 0 {
+			break
+		}
+		b, err := l.r.ReadByte()
+		if err != nil {
+			return n, err
+		}
+		n += utf8.EncodeRune(p[n:], rune(b))
+	}
+	return n, nil
+}
+
+func attr(el xml.StartElement, name string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"Typecode-Registry/pkg/client"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+)
+
+// Exit codes returned by tcr so scripts can react on the kind of failure.
+const (
+	exitOK          = 0
+	exitFailure     = 1 // unexpected errors, e.g. the API could not be reached.
+	exitUsage       = 2 // invalid command line.
+	exitBadRequest  = 3 // the API rejected the request (4xx other than 404).
+	exitNotFound    = 4 // the requested resource does not exist.
+	exitServerError = 5 // the API failed to process the request (5xx).
+	exitFindings    = 6 // the check command found typecode problems.
+)
+
+const usageText = `Usage: tcr [flags] <resource> <action> [arguments]
+       tcr [flags] check [-project name] [-format text|junit|sarif] [-out file] [dir...]
+
+Resources and actions:
+  items       list | get <id> | create | update <id> | delete <id>
+  extensions  list | create | update <id> | delete <id>
+  projects    list | create | update <id> | delete <id>
+
+The check command scans items.xml files below the given directories and
+fails if types use unregistered, duplicated or foreign typecodes.
+
+Run "tcr <resource> <action> -h" to see the flags of an action.
+
+Configuration is read from the config file, then from the environment
+(TCR_URL, TCR_TOKEN, TCR_CONFIG) and finally from the flags below.
+
+Flags:
+`
+
+// usageError signals that the command line could not be parsed.
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+// cli holds everything a command needs to execute.
+type cli struct {
+	ctx    context.Context
+	client *client.Client
+	out    io.Writer
+	errOut io.Writer
+	format string
+}
+
+// main is the entry point of the command-line client.
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run parses the arguments, executes the requested command and returns the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("tcr", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", defaultConfigPath(), "Path to the JSON configuration file")
+	url := fs.String("url", "", "Base URL of the registry API (overrides TCR_URL)")
+	token := fs.String("token", "", "Bearer token sent to the API (overrides TCR_TOKEN)")
+	output := fs.String("o", "", "Output format (table, json)")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usageText)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "tcr: %v\n", err)
+		return exitUsage
+	}
+
+	if *url != "" {
+		cfg.URL = *url
+	}
+	if *token != "" {
+		cfg.Token = *token
+	}
+	if *output != "" {
+		cfg.Output = *output
+	}
+
+	if cfg.Output != outputTable && cfg.Output != outputJSON {
+		fmt.Fprintf(stderr, "tcr: unknown output format %q\n", cfg.Output)
+		return exitUsage
+	}
+
+	if fs.NArg() < 2 && fs.Arg(0) != "check" {
+		fs.Usage()
+		return exitUsage
+	}
+
+	api, err := client.New(cfg.URL, client.WithToken(cfg.Token))
+	if err != nil {
+		fmt.Fprintf(stderr, "tcr: %v\n", err)
+		return exitUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	c := &cli{
+		ctx:    ctx,
+		client: api,
+		out:    stdout,
+		errOut: stderr,
+		format: cfg.Output,
+	}
+
+	if fs.Arg(0) == "check" {
+		err = c.check(fs.Args()[1:])
+	} else {
+		err = c.dispatch(fs.Arg(0), fs.Arg(1), fs.Args()[2:])
+	}
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(stderr, "tcr: %v\n", err)
+	}
+
+	return exitCode(err)
+}
+
+// exitCode maps the error of a command to the exit code of the process.
+func exitCode(err error) int {
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+
+	if errors.Is(err, errFindings) {
+		return exitFindings
+	}
+
+	var usageErr *usageError
+	if errors.As(err, &usageErr) {
+		return exitUsage
+	}
+
+	var apiErr *client.Error
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusNotFound:
+			return exitNotFound
+		case apiErr.StatusCode >= http.StatusInternalServerError:
+			return exitServerError
+		default:
+			return exitBadRequest
+		}
+	}
+
+	return exitFailure
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"Typecode-Registry/pkg/client"
+	"bytes"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCodeMapsErrors(t *testing.T) {
+	testCases := map[string]struct {
+		err      error
+		expected int
+	}{
+		"nil":         {nil, exitOK},
+		"usage":       {&usageError{"bad"}, exitUsage},
+		"notFound":    {&client.Error{StatusCode: http.StatusNotFound}, exitNotFound},
+		"badRequest":  {&client.Error{StatusCode: http.StatusBadRequest}, exitBadRequest},
+		"serverError": {&client.Error{StatusCode: http.StatusInternalServerError}, exitServerError},
+		"other":       {errors.New("connection refused"), exitFailure},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, exitCode(tc.err))
+		})
+	}
+}
+
+func TestLoadConfigReadsFileAndEnvironmentOverridesIt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(path, []byte(`{"url": "http://file:8080/", "token": "file-token", "output": "json"}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(envURL, "")
+	t.Setenv(envToken, "")
+	cfg, err := loadConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, config{URL: "http://file:8080", Token: "file-token", Output: outputJSON}, cfg)
+
+	t.Setenv(envURL, "http://env:9090")
+	t.Setenv(envToken, "env-token")
+	cfg, err = loadConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://env:9090", cfg.URL)
+	assert.Equal(t, "env-token", cfg.Token)
+}
+
+func TestLoadConfigIgnoresMissingFile(t *testing.T) {
+	t.Setenv(envURL, "")
+	t.Setenv(envToken, "")
+	cfg, err := loadConfig(filepath.Join(t.TempDir(), "missing.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, defaultURL, cfg.URL)
+}
+
+func TestRunReturnsUsageExitCodeForInvalidCommandLines(t *testing.T) {
+	testCases := map[string][]string{
+		"noArgs":          {},
+		"unknownResource": {"typecodes", "list"},
+		"unknownAction":   {"items", "purge"},
+		"missingID":       {"items", "delete"},
+		"invalidID":       {"items", "get", "abc"},
+		"unknownOutput":   {"-o", "xml", "items", "list"},
+		"missingFlags":    {"items", "create", "-name", "Test"},
+	}
+
+	for name, args := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			args = append([]string{"-config", ""}, args...)
+			assert.Equal(t, exitUsage, run(args, &stdout, &stderr))
+			assert.Empty(t, stdout.String())
+			assert.NotEmpty(t, stderr.String())
+		})
+	}
+}
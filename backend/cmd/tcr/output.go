@@ -0,0 +1,104 @@
+package main
+
+import (
+	"Typecode-Registry/pkg/client"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Supported output formats.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// render writes v either as indented JSON or as a table built from header and rows.
+func render(w io.Writer, format string, v any, header []string, rows [][]string) error {
+	switch format {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "\t")
+		return enc.Encode(v)
+	case outputTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+		for _, row := range rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+// renderItems writes a list of items in the given format.
+func renderItems(w io.Writer, format string, items []client.Item) error {
+	header := []string{"ID", "TYPECODE", "NAME", "TABLE", "SCOPE", "PROJECT", "EXTENSION", "CREATED"}
+	rows := make([][]string, 0, len(items))
+	for _, item := range items {
+		rows = append(rows, []string{
+			strconv.FormatInt(item.ID, 10),
+			strconv.FormatInt(int64(item.Typecode), 10),
+			item.Name,
+			item.TableName,
+			item.Scope,
+			item.Project,
+			strconv.FormatInt(item.ExtensionID, 10),
+			formatDate(item.CreationDate),
+		})
+	}
+
+	return render(w, format, items, header, rows)
+}
+
+// renderExtensions writes a list of extensions in the given format.
+func renderExtensions(w io.Writer, format string, extensions []client.Extension) error {
+	header := []string{"ID", "NAME", "SCOPE", "PROJECT", "ITEMS", "DESCRIPTION", "CREATED"}
+	rows := make([][]string, 0, len(extensions))
+	for _, extension := range extensions {
+		project := "-"
+		if extension.ProjectID != nil {
+			project = strconv.FormatInt(*extension.ProjectID, 10)
+		}
+
+		rows = append(rows, []string{
+			strconv.FormatInt(extension.ID, 10),
+			extension.Name,
+			extension.Scope,
+			project,
+			strconv.Itoa(extension.ItemCount),
+			extension.Description,
+			formatDate(extension.CreationDate),
+		})
+	}
+
+	return render(w, format, extensions, header, rows)
+}
+
+// renderProjects writes a list of projects in the given format.
+func renderProjects(w io.Writer, format string, projects []client.Project) error {
+	header := []string{"ID", "NAME", "DESCRIPTION", "CREATED"}
+	rows := make([][]string, 0, len(projects))
+	for _, project := range projects {
+		rows = append(rows, []string{
+			strconv.FormatInt(project.ID, 10),
+			project.Name,
+			project.Description,
+			formatDate(project.CreationDate),
+		})
+	}
+
+	return render(w, format, projects, header, rows)
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.DateTime)
+}
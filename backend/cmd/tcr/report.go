@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// writeTextReport prints one line per finding in the file:line: message format understood by most editors.
+func writeTextReport(w io.Writer, result checkResult) error {
+	for _, f := range result.Findings {
+		if _, err := fmt.Fprintf(w, "%s:%d: %s [%s]\n", f.Type.File, f.Type.Line, f.Message, f.Rule); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%d type(s) checked, %d problem(s) found\n", len(result.Types), len(result.Findings))
+	return err
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string         `xml:"classname,attr"`
+	Name      string         `xml:"name,attr"`
+	Failures  []junitFailure `xml:"failure"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnitReport writes one test suite per items.xml file and one test case per declared type.
+func writeJUnitReport(w io.Writer, result checkResult) error {
+	failures := make(map[declaredType][]finding)
+	for _, f := range result.Findings {
+		failures[f.Type] = append(failures[f.Type], f)
+	}
+
+	suites := make(map[string]*junitTestSuite)
+	var files []string
+	for _, t := range result.Types {
+		suite, ok := suites[t.File]
+		if !ok {
+			suite = &junitTestSuite{Name: t.File}
+			suites[t.File] = suite
+			files = append(files, t.File)
+		}
+
+		tc := junitTestCase{ClassName: t.File, Name: fmt.Sprintf("%s (%d)", t.Code, t.Typecode)}
+		for _, f := range failures[t] {
+			tc.Failures = append(tc.Failures, junitFailure{
+				Message: f.Message,
+				Type:    f.Rule,
+				Text:    fmt.Sprintf("%s:%d: %s", t.File, t.Line, f.Message),
+			})
+		}
+
+		suite.Tests++
+		if len(tc.Failures) > 0 {
+			suite.Failures++
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+
+	sort.Strings(files)
+	report := junitTestSuites{}
+	for _, file := range files {
+		suite := suites[file]
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Suites = append(report.Suites, *suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// writeSARIFReport writes the findings as a SARIF 2.1.0 log.
+func writeSARIFReport(w io.Writer, result checkResult) error {
+	rules := make([]sarifRule, 0, len(ruleDescriptions))
+	for _, id := range []string{ruleUnregistered, ruleDuplicate, ruleForeign} {
+		rules = append(rules, sarifRule{ID: id, ShortDescription: sarifMessage{Text: ruleDescriptions[id]}})
+	}
+
+	results := make([]sarifResult, 0, len(result.Findings))
+	for _, f := range result.Findings {
+		results = append(results, sarifResult{
+			RuleID:  f.Rule,
+			Level:   "error",
+			Message: sarifMessage{Text: f.Message},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: f.Type.File},
+					Region:           sarifRegion{StartLine: f.Type.Line},
+				},
+			}},
+		})
+	}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool:    sarifTool{Driver: sarifDriver{Name: "tcr", Rules: rules}},
+			Results: results,
+		}},
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
@@ -1,3 +1,12 @@
+-- Schema for disposable development databases, e.g. the postgres container of docker-compose.yml.
+-- It drops all tables first! Use "myserver admin migrate" for databases holding real data.
+
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS api_token;
+DROP TABLE IF EXISTS service_account;
+DROP TABLE IF EXISTS webhook_delivery;
+DROP TABLE IF EXISTS webhook;
+DROP TABLE IF EXISTS idempotency_key;
 DROP TABLE IF EXISTS role_assignment;
 DROP TABLE IF EXISTS item;
 DROP TABLE IF EXISTS extension;
@@ -7,14 +16,17 @@ DROP TABLE IF EXISTS "user";
 CREATE TABLE "user" (
         id SERIAL PRIMARY KEY,
         oauth_identifier VARCHAR(255) UNIQUE NOT NULL,
-        email VARCHAR(255) NOT NULL
+        email VARCHAR(255) NOT NULL,
+        name VARCHAR(255) NOT NULL DEFAULT '',
+        creation_date TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
 CREATE TABLE project (
         id SERIAL PRIMARY KEY,
         name VARCHAR(255) NOT NULL UNIQUE,
         description TEXT,
-        creation_date TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+        creation_date TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        version INTEGER NOT NULL DEFAULT 1
 );
 
 CREATE TABLE extension (
@@ -24,6 +36,7 @@ CREATE TABLE extension (
        description TEXT,
        scope VARCHAR(50),
        creation_date TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+       version INTEGER NOT NULL DEFAULT 1,
        FOREIGN KEY (project_id) REFERENCES project(id)
 );
 
@@ -33,19 +46,130 @@ CREATE TABLE item (
       extension_id INT REFERENCES extension (id),
       table_name VARCHAR(255),
       typecode INT NOT NULL,
-      creation_date TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+      creation_date TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+      version INTEGER NOT NULL DEFAULT 1
 );
 
 CREATE TABLE role_assignment (
      role_assignment_id SERIAL PRIMARY KEY,
-     user_id INT REFERENCES "user"(id) NOT NULL,
-     project_id INT REFERENCES project(id) NOT NULL,
-     role VARCHAR(255) NOT NULL
+     user_id INT REFERENCES "user"(id) ON DELETE CASCADE NOT NULL,
+     project_id INT REFERENCES project(id) ON DELETE CASCADE NOT NULL,
+     role VARCHAR(255) NOT NULL,
+     source VARCHAR(16) NOT NULL DEFAULT 'manual',
+     creation_date TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+     UNIQUE (user_id, project_id)
+);
+
+CREATE INDEX role_assignment_project_id_idx ON role_assignment (project_id);
+
+CREATE TABLE idempotency_key (
+        key VARCHAR(255) NOT NULL,
+        endpoint VARCHAR(255) NOT NULL,
+        request_hash CHAR(64) NOT NULL,
+        status_code INT,
+        headers TEXT,
+        body BYTEA,
+        creation_date TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (key, endpoint)
+);
+
+CREATE INDEX idempotency_key_creation_date_idx ON idempotency_key (creation_date);
+
+CREATE TABLE webhook (
+        id SERIAL PRIMARY KEY,
+        project_id INT REFERENCES project(id) ON DELETE CASCADE,
+        url VARCHAR(2048) NOT NULL,
+        secret VARCHAR(255) NOT NULL,
+        events TEXT[] NOT NULL,
+        description VARCHAR(255) NOT NULL DEFAULT '',
+        active BOOLEAN NOT NULL DEFAULT TRUE,
+        creation_date TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        version INT NOT NULL DEFAULT 1
+);
+
+CREATE TABLE webhook_delivery (
+        id BIGSERIAL PRIMARY KEY,
+        webhook_id INT NOT NULL REFERENCES webhook(id) ON DELETE CASCADE,
+        event VARCHAR(64) NOT NULL,
+        payload TEXT NOT NULL,
+        redelivery_of BIGINT,
+        status VARCHAR(16) NOT NULL DEFAULT 'pending',
+        attempts INT NOT NULL DEFAULT 0,
+        response_status INT,
+        error TEXT,
+        next_attempt TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+        creation_date TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        completion_date TIMESTAMPTZ
+);
+
+CREATE INDEX webhook_delivery_webhook_id_idx ON webhook_delivery (webhook_id, id);
+CREATE INDEX webhook_delivery_next_attempt_idx ON webhook_delivery (next_attempt) WHERE next_attempt IS NOT NULL;
+
+CREATE TABLE service_account (
+        id SERIAL PRIMARY KEY,
+        project_id INT NOT NULL REFERENCES project(id) ON DELETE CASCADE,
+        name VARCHAR(255) NOT NULL,
+        role VARCHAR(255) NOT NULL,
+        creation_date TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE (project_id, name)
 );
 
+CREATE TABLE api_token (
+        id SERIAL PRIMARY KEY,
+        name VARCHAR(255) NOT NULL,
+        prefix VARCHAR(16) NOT NULL,
+        token_hash CHAR(64) UNIQUE NOT NULL,
+        user_id INT REFERENCES "user"(id) ON DELETE CASCADE,
+        service_account_id INT REFERENCES service_account(id) ON DELETE CASCADE,
+        scopes TEXT[] NOT NULL,
+        expires_at TIMESTAMPTZ NOT NULL,
+        last_used_at TIMESTAMPTZ,
+        revoked_at TIMESTAMPTZ,
+        creation_date TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        CHECK ((user_id IS NULL) <> (service_account_id IS NULL))
+);
+
+CREATE INDEX api_token_user_id_idx ON api_token (user_id);
+CREATE INDEX api_token_service_account_id_idx ON api_token (service_account_id);
+
+CREATE TABLE audit_log (
+        id BIGSERIAL PRIMARY KEY,
+        time TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        actor VARCHAR(255) NOT NULL,
+        action VARCHAR(16) NOT NULL,
+        entity_type VARCHAR(32) NOT NULL,
+        entity_id BIGINT NOT NULL,
+        project_id BIGINT,
+        before JSONB,
+        after JSONB,
+        request_id VARCHAR(128) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX audit_log_time_idx ON audit_log (time);
+CREATE INDEX audit_log_entity_idx ON audit_log (entity_type, entity_id);
+CREATE INDEX audit_log_project_id_idx ON audit_log (project_id) WHERE project_id IS NOT NULL;
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS TRIGGER AS $$
+BEGIN
+        RAISE EXCEPTION 'the audit log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only BEFORE UPDATE ON audit_log
+        FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
 -- Index for `item` table
 -- CREATE INDEX idx_item_extension_id ON item(extension_id);
 -- CREATE INDEX idx_item_typecode ON item(typecode);
 
 -- Index for `extension` table
 -- CREATE INDEX idx_extension_scope ON extension(scope);
+
+-- Trigram indexes for the search endpoint, see internal/data/migrations/0002_search_indexes.sql
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX item_name_trgm_idx ON item USING GIN (name gin_trgm_ops);
+CREATE INDEX item_table_name_trgm_idx ON item USING GIN (table_name gin_trgm_ops);
+CREATE INDEX extension_name_trgm_idx ON extension USING GIN (name gin_trgm_ops);
+CREATE INDEX extension_description_trgm_idx ON extension USING GIN (description gin_trgm_ops);
+CREATE INDEX project_name_trgm_idx ON project USING GIN (name gin_trgm_ops);
+CREATE INDEX project_description_trgm_idx ON project USING GIN (description gin_trgm_ops);
@@ -0,0 +1,146 @@
+// Package authtest provides a local identity provider for tests: a generated key set
+// and tokens signed with it, so no real identity provider is needed.
+//
+//	idp := authtest.NewIssuer(t)
+//	verifier := &auth.Verifier{Keys: idp.KeySet(t), Issuer: idp.Issuer, Audience: []string{idp.Audience}}
+//	token := idp.Token(t, idp.Claims("alice"))
+package authtest
+
+import (
+	"Typecode-Registry/internal/auth"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// Issuer signs tokens with a generated RSA key (kid "rsa") and a generated P-256 key (kid "ec").
+type Issuer struct {
+	Issuer   string
+	Audience string
+
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+}
+
+// NewIssuer generates the keys of a local identity provider.
+func NewIssuer(t testing.TB) *Issuer {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Issuer{
+		Issuer:   "https://login.example.com/tenant/v2.0",
+		Audience: "api://typecode-registry",
+		rsaKey:   rsaKey,
+		ecKey:    ecKey,
+	}
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// padded returns the big-endian bytes of n left padded to size bytes.
+func padded(n *big.Int, size int) []byte {
+	return n.FillBytes(make([]byte, size))
+}
+
+// KeySetJSON returns the JSON Web Key Set with the public keys of the issuer.
+func (i *Issuer) KeySetJSON(t testing.TB) []byte {
+	t.Helper()
+	set := map[string]any{"keys": []map[string]string{
+		{
+			"kty": "RSA", "kid": "rsa", "use": "sig", "alg": "RS256",
+			"n": encode(i.rsaKey.N.Bytes()),
+			"e": encode(big.NewInt(int64(i.rsaKey.E)).Bytes()),
+		},
+		{
+			"kty": "EC", "kid": "ec", "use": "sig", "alg": "ES256", "crv": "P-256",
+			"x": encode(padded(i.ecKey.X, 32)),
+			"y": encode(padded(i.ecKey.Y, 32)),
+		},
+	}}
+
+	b, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// KeySet returns the parsed key set of the issuer.
+func (i *Issuer) KeySet(t testing.TB) *auth.KeySet {
+	t.Helper()
+	ks, err := auth.ParseKeySet(i.KeySetJSON(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ks
+}
+
+// Claims returns valid claims for the subject: issuer, audience, an expiry in an hour, name and email.
+func (i *Issuer) Claims(subject string) map[string]any {
+	return map[string]any{
+		"iss":   i.Issuer,
+		"aud":   i.Audience,
+		"sub":   subject,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"name":  subject,
+		"email": subject + "@example.com",
+	}
+}
+
+// Token returns a token with the claims signed with the RSA key of the issuer.
+func (i *Issuer) Token(t testing.TB, claims map[string]any) string {
+	t.Helper()
+	return i.SignedToken(t, map[string]any{"alg": "RS256", "typ": "JWT", "kid": "rsa"}, claims)
+}
+
+// SignedToken returns a token with the header and claims, signed according to the alg of the header
+// with the RSA key for RS256 and the EC key for ES256. Other algorithms get an empty signature.
+func (i *Issuer) SignedToken(t testing.TB, header, claims map[string]any) string {
+	t.Helper()
+	h, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := encode(h) + "." + encode(c)
+	digest := sha256.Sum256([]byte(input))
+
+	var signature []byte
+	switch header["alg"] {
+	case "RS256":
+		signature, err = rsa.SignPKCS1v15(rand.Reader, i.rsaKey, crypto.SHA256, digest[:])
+	case "ES256":
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, i.ecKey, digest[:])
+		if err == nil {
+			signature = append(padded(r, 32), padded(s, 32)...)
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return input + "." + encode(signature)
+}
@@ -0,0 +1,249 @@
+// Package auth validates the OAuth 2.0 bearer tokens of the API: JSON Web Tokens signed by an OpenID Connect
+// identity provider such as Microsoft Entra ID, whose public keys are published as JSON Web Key Set (RFC 7517).
+//
+//	keys := auth.NewRemoteKeySet("https://login.microsoftonline.com/{tenant}/discovery/v2.0/keys", nil)
+//	verifier := &auth.Verifier{Keys: keys, Issuer: "https://login.microsoftonline.com/{tenant}/v2.0", Audience: []string{clientID}}
+//	principal, err := verifier.Verify(token)
+//	if errors.Is(err, auth.ErrInvalidToken) {
+//		// answer with 401 Unauthorized
+//	}
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrUnknownKey is returned by key providers if they have no key with the requested ID.
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// KeyProvider returns the public key with the given key ID to verify the signature of a token.
+// The ID is empty if the token names no key.
+type KeyProvider interface {
+	Key(kid string) (crypto.PublicKey, error)
+}
+
+// KeySet is a static set of public keys by their IDs.
+type KeySet struct {
+	keys map[string]crypto.PublicKey
+}
+
+// jsonWebKey is a key of a JSON Web Key Set. Only the members of RSA and elliptic curve public keys are decoded.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseKeySet decodes a JSON Web Key Set. Keys for encryption and of unsupported types are skipped,
+// RSA and elliptic curve keys (P-256, P-384 and P-521) for signatures are kept.
+// Returns: an error if the set is malformed or contains no usable key.
+func ParseKeySet(b []byte) (*KeySet, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("invalid key set: %w", err)
+	}
+
+	ks := &KeySet{keys: make(map[string]crypto.PublicKey)}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		var key crypto.PublicKey
+		var err error
+		switch jwk.Kty {
+		case "RSA":
+			key, err = jwk.rsaKey()
+		case "EC":
+			key, err = jwk.ecdsaKey()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", jwk.Kid, err)
+		}
+		ks.keys[jwk.Kid] = key
+	}
+
+	if len(ks.keys) == 0 {
+		return nil, errors.New("invalid key set: no RSA or EC signing keys")
+	}
+	return ks, nil
+}
+
+// LoadKeySetFile reads a JSON Web Key Set from a file, see ParseKeySet.
+func LoadKeySetFile(path string) (*KeySet, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKeySet(b)
+}
+
+// Key returns the key with the given ID. A token naming no key is verified with the only key of a set with one key.
+func (ks *KeySet) Key(kid string) (crypto.PublicKey, error) {
+	if key, ok := ks.keys[kid]; ok {
+		return key, nil
+	}
+	if kid == "" && len(ks.keys) == 1 {
+		for _, key := range ks.keys {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownKey, kid)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("missing value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func (jwk jsonWebKey) rsaKey() (*rsa.PublicKey, error) {
+	n, err := decodeBigInt(jwk.N)
+	if err != nil {
+		return nil, fmt.Errorf("modulus: %w", err)
+	}
+	e, err := decodeBigInt(jwk.E)
+	if err != nil {
+		return nil, fmt.Errorf("exponent: %w", err)
+	}
+	if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+		return nil, errors.New("exponent out of range")
+	}
+	if n.BitLen() < 2048 {
+		return nil, errors.New("modulus must have at least 2048 bits")
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func (jwk jsonWebKey) ecdsaKey() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch jwk.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+	}
+
+	x, err := decodeBigInt(jwk.X)
+	if err != nil {
+		return nil, fmt.Errorf("x: %w", err)
+	}
+	y, err := decodeBigInt(jwk.Y)
+	if err != nil {
+		return nil, fmt.Errorf("y: %w", err)
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, errors.New("point is not on the curve")
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+const (
+	// keySetMaxAge is how long a fetched key set is used before it is fetched again.
+	keySetMaxAge = time.Hour
+	// keySetMinRefresh is the minimum time between two fetches for unknown key IDs,
+	// so tokens with made up key IDs cannot flood the identity provider.
+	keySetMinRefresh = time.Minute
+	// maxKeySetSize is the maximum size of a fetched key set.
+	maxKeySetSize = 1 << 20
+)
+
+// RemoteKeySet fetches the key set published by an identity provider and caches it. It is fetched again
+// after keySetMaxAge and when a token names an unknown key, as identity providers rotate their keys.
+// It is safe for concurrent use.
+type RemoteKeySet struct {
+	url    string
+	client *http.Client
+
+	mu      sync.Mutex
+	keys    *KeySet
+	fetched time.Time
+}
+
+// NewRemoteKeySet returns a key set fetched from url with client, or a client with a 10 second timeout if it is nil.
+func NewRemoteKeySet(url string, client *http.Client) *RemoteKeySet {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &RemoteKeySet{url: url, client: client}
+}
+
+// Key returns the key with the given ID, fetching the key set if needed.
+// Returns: ErrUnknownKey if the key set has no such key, or an error if the key set cannot be fetched.
+func (s *RemoteKeySet) Key(kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.keys == nil || time.Since(s.fetched) > keySetMaxAge {
+		if err := s.refresh(); err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := s.keys.Key(kid)
+	if errors.Is(err, ErrUnknownKey) && time.Since(s.fetched) > keySetMinRefresh {
+		if err := s.refresh(); err != nil {
+			return nil, err
+		}
+		key, err = s.keys.Key(kid)
+	}
+	return key, err
+}
+
+// refresh fetches the key set. The keys fetched before are kept if it fails.
+func (s *RemoteKeySet) refresh() error {
+	resp, err := s.client.Get(s.url)
+	if err != nil {
+		return fmt.Errorf("fetching key set: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching key set: %s answered with status %d", s.url, resp.StatusCode)
+	}
+
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxKeySetSize))
+	if err != nil {
+		return fmt.Errorf("fetching key set: %w", err)
+	}
+
+	keys, err := ParseKeySet(b)
+	if err != nil {
+		return err
+	}
+
+	s.keys = keys
+	s.fetched = time.Now()
+	return nil
+}
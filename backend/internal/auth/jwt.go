@@ -0,0 +1,244 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrInvalidToken is returned by Verify for every token which is malformed, not signed by a key of the
+// identity provider or has invalid claims. The wrapping error tells the reason.
+var ErrInvalidToken = errors.New("invalid token")
+
+// Principal is the authenticated caller of a request, taken from the claims of its token.
+type Principal struct {
+	// Subject identifies the caller at the issuer.
+	Subject string
+	Issuer  string
+	Name    string
+	// Email is the email claim or, as sent by Microsoft Entra ID, the preferred_username claim.
+	Email  string
+	Groups []string
+	Roles  []string
+	// ExpiresAt is the expiry of the token.
+	ExpiresAt time.Time
+	// Claims are all claims of the token.
+	Claims map[string]any
+}
+
+// Verifier validates tokens: their signature with a key of Keys, their issuer, audience and period of validity.
+type Verifier struct {
+	Keys KeyProvider
+	// Issuer is the required iss claim.
+	Issuer string
+	// Audience are the accepted values of the aud claim, usually the client ID of the API.
+	Audience []string
+	// Leeway is the tolerated clock skew of exp and nbf.
+	Leeway time.Duration
+	// Now returns the current time, time.Now if it is nil.
+	Now func() time.Time
+}
+
+// signingMethod is a supported signature algorithm of JSON Web Signatures (RFC 7518).
+type signingMethod struct {
+	hash crypto.Hash
+	// curveBits is the key size of the curve of ECDSA algorithms, 0 for RSA.
+	curveBits int
+}
+
+var signingMethods = map[string]signingMethod{
+	"RS256": {crypto.SHA256, 0},
+	"RS384": {crypto.SHA384, 0},
+	"RS512": {crypto.SHA512, 0},
+	"ES256": {crypto.SHA256, 256},
+	"ES384": {crypto.SHA384, 384},
+	"ES512": {crypto.SHA512, 521},
+}
+
+// invalid returns an error wrapping ErrInvalidToken with the reason.
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidToken, fmt.Sprintf(format, args...))
+}
+
+// claims are the registered and profile claims of a token checked or copied into the Principal.
+type claims struct {
+	Issuer            string       `json:"iss"`
+	Subject           string       `json:"sub"`
+	Audience          audience     `json:"aud"`
+	ExpiresAt         *numericDate `json:"exp"`
+	NotBefore         *numericDate `json:"nbf"`
+	Name              string       `json:"name"`
+	Email             string       `json:"email"`
+	PreferredUsername string       `json:"preferred_username"`
+	Groups            []string     `json:"groups"`
+	Roles             []string     `json:"roles"`
+}
+
+// audience is the aud claim, which is either a string or an array of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(a))
+}
+
+// numericDate is a time claim in seconds since the epoch, which may have a fraction.
+type numericDate struct {
+	time.Time
+}
+
+func (d *numericDate) UnmarshalJSON(b []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(b, &seconds); err != nil {
+		return err
+	}
+	d.Time = time.Unix(0, int64(seconds*float64(time.Second)))
+	return nil
+}
+
+// Verify validates token and returns its principal.
+// Returns: an error wrapping ErrInvalidToken if the token is invalid, or another error if the keys are unavailable.
+func (v *Verifier) Verify(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, invalid("token must consist of three parts")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, invalid("malformed header: %v", err)
+	}
+
+	method, ok := signingMethods[header.Alg]
+	if !ok {
+		return nil, invalid("unsupported algorithm %q", header.Alg)
+	}
+
+	key, err := v.Keys.Key(header.Kid)
+	if errors.Is(err, ErrUnknownKey) {
+		return nil, invalid("%v", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, invalid("malformed signature: %v", err)
+	}
+	if err := verifySignature(method, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var c claims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return nil, invalid("malformed claims: %v", err)
+	}
+	var all map[string]any
+	if err := decodeSegment(parts[1], &all); err != nil {
+		return nil, invalid("malformed claims: %v", err)
+	}
+
+	now := time.Now()
+	if v.Now != nil {
+		now = v.Now()
+	}
+	switch {
+	case c.Issuer != v.Issuer:
+		return nil, invalid("issuer %q is not accepted", c.Issuer)
+	case !slices.ContainsFunc(c.Audience, func(aud string) bool { return slices.Contains(v.Audience, aud) }):
+		return nil, invalid("audience %q is not accepted", strings.Join(c.Audience, ", "))
+	case c.ExpiresAt == nil:
+		return nil, invalid("token has no expiry")
+	case now.After(c.ExpiresAt.Add(v.Leeway)):
+		return nil, invalid("token expired at %s", c.ExpiresAt.UTC().Format(time.RFC3339))
+	case c.NotBefore != nil && now.Add(v.Leeway).Before(c.NotBefore.Time):
+		return nil, invalid("token is not valid before %s", c.NotBefore.UTC().Format(time.RFC3339))
+	case c.Subject == "":
+		return nil, invalid("token has no subject")
+	}
+
+	principal := &Principal{
+		Subject:   c.Subject,
+		Issuer:    c.Issuer,
+		Name:      c.Name,
+		Email:     c.Email,
+		Groups:    c.Groups,
+		Roles:     c.Roles,
+		ExpiresAt: c.ExpiresAt.Time,
+		Claims:    all,
+	}
+	if principal.Email == "" {
+		principal.Email = c.PreferredUsername
+	}
+	return principal, nil
+}
+
+// decodeSegment decodes a base64url encoded JSON segment of a token into dst.
+func decodeSegment(segment string, dst any) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, dst)
+}
+
+// verifySignature checks the signature of the signing input (header and claims segments) with key.
+func verifySignature(method signingMethod, key crypto.PublicKey, signingInput string, signature []byte) error {
+	var h hash.Hash
+	switch method.hash {
+	case crypto.SHA256:
+		h = sha256.New()
+	case crypto.SHA384:
+		h = sha512.New384()
+	default:
+		h = sha512.New()
+	}
+	h.Write([]byte(signingInput))
+	digest := h.Sum(nil)
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if method.curveBits != 0 {
+			return invalid("algorithm does not match the RSA key")
+		}
+		if err := rsa.VerifyPKCS1v15(key, method.hash, digest, signature); err != nil {
+			return invalid("signature is invalid")
+		}
+	case *ecdsa.PublicKey:
+		if method.curveBits != key.Curve.Params().BitSize {
+			return invalid("algorithm does not match the curve of the key")
+		}
+		size := (method.curveBits + 7) / 8
+		if len(signature) != 2*size {
+			return invalid("signature is invalid")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(key, digest, r, s) {
+			return invalid("signature is invalid")
+		}
+	default:
+		return invalid("unsupported key type %T", key)
+	}
+
+	return nil
+}
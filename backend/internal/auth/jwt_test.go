@@ -0,0 +1,154 @@
+package auth_test
+
+import (
+	"Typecode-Registry/internal/auth"
+	"Typecode-Registry/internal/auth/authtest"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newVerifier(t *testing.T, idp *authtest.Issuer) *auth.Verifier {
+	return &auth.Verifier{Keys: idp.KeySet(t), Issuer: idp.Issuer, Audience: []string{"other", idp.Audience}, Leeway: time.Minute}
+}
+
+func TestVerifyReturnsPrincipal(t *testing.T) {
+	idp := authtest.NewIssuer(t)
+	claims := idp.Claims("alice")
+	claims["email"] = ""
+	claims["preferred_username"] = "alice@contoso.com"
+	claims["groups"] = []string{"typecode-admins"}
+	claims["roles"] = []string{"Registry.Admin"}
+
+	principal, err := newVerifier(t, idp).Verify(idp.Token(t, claims))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", principal.Subject)
+	assert.Equal(t, idp.Issuer, principal.Issuer)
+	assert.Equal(t, "alice@contoso.com", principal.Email)
+	assert.Equal(t, []string{"typecode-admins"}, principal.Groups)
+	assert.Equal(t, []string{"Registry.Admin"}, principal.Roles)
+	assert.Equal(t, "alice", principal.Claims["name"])
+	assert.WithinDuration(t, time.Now().Add(time.Hour), principal.ExpiresAt, 5*time.Second)
+}
+
+func TestVerifyAcceptsECDSAAndAudienceArrays(t *testing.T) {
+	idp := authtest.NewIssuer(t)
+	claims := idp.Claims("build-bot")
+	claims["aud"] = []string{"something-else", idp.Audience}
+
+	principal, err := newVerifier(t, idp).Verify(idp.SignedToken(t, map[string]any{"alg": "ES256", "kid": "ec"}, claims))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "build-bot", principal.Subject)
+}
+
+func TestVerifyRejectsInvalidTokens(t *testing.T) {
+	idp := authtest.NewIssuer(t)
+	other := authtest.NewIssuer(t)
+	verifier := newVerifier(t, idp)
+
+	with := func(name string, value any) map[string]any {
+		claims := idp.Claims("alice")
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+	valid := idp.Token(t, idp.Claims("alice"))
+	parts := strings.Split(valid, ".")
+
+	testCases := map[string]struct {
+		token  string
+		reason string
+	}{
+		"malformed":          {"abc.def", "three parts"},
+		"unsigned":           {idp.SignedToken(t, map[string]any{"alg": "none"}, idp.Claims("alice")), `unsupported algorithm "none"`},
+		"symmetric":          {idp.SignedToken(t, map[string]any{"alg": "HS256"}, idp.Claims("alice")), `unsupported algorithm "HS256"`},
+		"other issuer's key": {other.Token(t, idp.Claims("alice")), "signature is invalid"},
+		"unknown key":        {idp.SignedToken(t, map[string]any{"alg": "RS256", "kid": "old"}, idp.Claims("alice")), `unknown signing key "old"`},
+		"algorithm mismatch": {idp.SignedToken(t, map[string]any{"alg": "ES256", "kid": "rsa"}, idp.Claims("alice")), "does not match the RSA key"},
+		"tampered claims":    {parts[0] + "." + strings.Split(idp.Token(t, idp.Claims("mallory")), ".")[1] + "." + parts[2], "signature is invalid"},
+		"wrong issuer":       {idp.Token(t, with("iss", "https://evil.example.com")), "issuer"},
+		"wrong audience":     {idp.Token(t, with("aud", "api://other-app")), "audience"},
+		"no audience":        {idp.Token(t, with("aud", nil)), "audience"},
+		"expired":            {idp.Token(t, with("exp", time.Now().Add(-2*time.Minute).Unix())), "token expired"},
+		"no expiry":          {idp.Token(t, with("exp", nil)), "no expiry"},
+		"not yet valid":      {idp.Token(t, with("nbf", time.Now().Add(5*time.Minute).Unix())), "not valid before"},
+		"no subject":         {idp.Token(t, with("sub", nil)), "no subject"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := verifier.Verify(tc.token)
+			assert.ErrorIs(t, err, auth.ErrInvalidToken)
+			assert.ErrorContains(t, err, tc.reason)
+		})
+	}
+}
+
+func TestVerifyToleratesClockSkew(t *testing.T) {
+	idp := authtest.NewIssuer(t)
+	claims := idp.Claims("alice")
+	claims["exp"] = time.Now().Add(-30 * time.Second).Unix()
+
+	_, err := newVerifier(t, idp).Verify(idp.Token(t, claims))
+	assert.NoError(t, err)
+}
+
+func TestParseKeySet(t *testing.T) {
+	_, err := auth.ParseKeySet([]byte(`{"keys": [{"kty": "oct", "k": "c2VjcmV0"}]}`))
+	assert.EqualError(t, err, "invalid key set: no RSA or EC signing keys")
+
+	_, err = auth.ParseKeySet([]byte(`{"keys": [{"kty": "RSA", "kid": "short", "n": "AQAB", "e": "AQAB"}]}`))
+	assert.EqualError(t, err, `invalid key "short": modulus must have at least 2048 bits`)
+
+	_, err = auth.ParseKeySet([]byte(`{"keys": [{"kty": "EC", "kid": "p", "crv": "P-256", "x": "AQ", "y": "AQ"}]}`))
+	assert.EqualError(t, err, `invalid key "p": point is not on the curve`)
+
+	_, err = auth.ParseKeySet([]byte(`[]`))
+	assert.Error(t, err)
+}
+
+func TestRemoteKeySetRefetchesForUnknownKeys(t *testing.T) {
+	idp := authtest.NewIssuer(t)
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		_, _ = w.Write(idp.KeySetJSON(t))
+	}))
+	defer server.Close()
+
+	keys := auth.NewRemoteKeySet(server.URL, nil)
+	verifier := &auth.Verifier{Keys: keys, Issuer: idp.Issuer, Audience: []string{idp.Audience}}
+
+	for i := 0; i < 3; i++ {
+		_, err := verifier.Verify(idp.Token(t, idp.Claims("alice")))
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), fetches.Load(), "the key set is cached")
+
+	_, err := keys.Key("rotated")
+	assert.ErrorIs(t, err, auth.ErrUnknownKey)
+	assert.Equal(t, int32(1), fetches.Load(), "unknown keys are refetched at most once a minute")
+}
+
+func TestRemoteKeySetReportsUnavailableKeys(t *testing.T) {
+	idp := authtest.NewIssuer(t)
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	verifier := &auth.Verifier{Keys: auth.NewRemoteKeySet(server.URL, nil), Issuer: idp.Issuer, Audience: []string{idp.Audience}}
+	_, err := verifier.Verify(idp.Token(t, idp.Claims("alice")))
+
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, auth.ErrInvalidToken), "unavailable keys are no fault of the token")
+}
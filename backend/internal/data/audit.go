@@ -0,0 +1,182 @@
+package data
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AuditEntry records a change of an entity: who made it in which request, and the entity before and after.
+// Before is null for created entities, After for deleted ones.
+type AuditEntry struct {
+	ID         int64     `json:"id"`
+	Time       time.Time `json:"time"`
+	Actor      string    `json:"actor"`
+	Action     string    `json:"action"`
+	EntityType string    `json:"entity_type"`
+	EntityID   int64     `json:"entity_id"`
+	// ProjectID is the project the entity belongs to, or null if it belongs to no project.
+	ProjectID NullInt64       `json:"project_id"`
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+	RequestID string          `json:"request_id"`
+}
+
+// AuditFilter restricts the entries returned by ReadAll. Zero values and nil pointers are ignored.
+type AuditFilter struct {
+	Actor      string
+	Action     string
+	EntityType string
+	EntityID   int64
+	ProjectID  int64
+	RequestID  string
+	Since      *time.Time
+	// Until is exclusive.
+	Until *time.Time
+}
+
+// AuditModel wraps the database connection pool. The audit log is append-only, entries are never updated.
+// Bound to a transaction by Models.BeginTx, the entries are inserted together with the changes they record.
+type AuditModel struct {
+	DB *sql.DB
+	Tx *sql.Tx
+}
+
+func (m AuditModel) conn() querier {
+	return conn(m.DB, m.Tx)
+}
+
+// auditColumns is the number of values inserted per entry by Insert.
+const auditColumns = 8
+
+// maxAuditEntriesPerInsert is the number of entries inserted per statement,
+// PostgreSQL accepts at most 65535 parameters.
+const maxAuditEntriesPerInsert = 65535 / auditColumns
+
+// jsonValue returns raw as string for a JSONB parameter, or nil for NULL.
+func jsonValue(raw json.RawMessage) any {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
+
+// Insert appends entries to the audit log with one statement per maxAuditEntriesPerInsert entries.
+// Bound to a transaction, either all or none are stored. The time of the entries is set by the database.
+func (m AuditModel) Insert(entries ...*AuditEntry) error {
+	for len(entries) > maxAuditEntriesPerInsert {
+		if err := m.insert(entries[:maxAuditEntriesPerInsert]); err != nil {
+			return err
+		}
+		entries = entries[maxAuditEntriesPerInsert:]
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	return m.insert(entries)
+}
+
+// insert appends entries to the audit log with a single statement.
+func (m AuditModel) insert(entries []*AuditEntry) error {
+	values := make([]string, 0, len(entries))
+	args := make([]any, 0, len(entries)*auditColumns)
+	for i, e := range entries {
+		n := i * auditColumns
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d::JSONB, $%d::JSONB, $%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8))
+		args = append(args, e.Actor, e.Action, e.EntityType, e.EntityID, e.ProjectID, jsonValue(e.Before), jsonValue(e.After), e.RequestID)
+	}
+
+	query := `
+		INSERT INTO audit_log (actor, action, entity_type, entity_id, project_id, before, after, request_id)
+		VALUES ` + strings.Join(values, ", ")
+	_, err := m.conn().Exec(query, args...)
+	return err
+}
+
+// auditFilterConditions is the WHERE clause of the audit log queries, its parameters are returned by auditFilterArgs.
+const auditFilterConditions = `
+		WHERE ($1 = '' OR actor = $1)
+		AND ($2 = '' OR action = $2)
+		AND ($3 = '' OR entity_type = $3)
+		AND ($4 = 0 OR entity_id = $4)
+		AND ($5 = 0 OR project_id = $5)
+		AND ($6 = '' OR request_id = $6)
+		AND ($7::TIMESTAMPTZ IS NULL OR time >= $7::TIMESTAMPTZ)
+		AND ($8::TIMESTAMPTZ IS NULL OR time < $8::TIMESTAMPTZ)`
+
+func auditFilterArgs(filter AuditFilter) []any {
+	return []any{
+		filter.Actor,
+		filter.Action,
+		filter.EntityType,
+		filter.EntityID,
+		filter.ProjectID,
+		filter.RequestID,
+		filter.Since,
+		filter.Until,
+	}
+}
+
+// ReadAll returns the entries matching filter, newest first and paginated according to filters.
+// The total number of matching entries is returned in the metadata.
+func (m AuditModel) ReadAll(filter AuditFilter, filters Filters) ([]*AuditEntry, Metadata, error) {
+	query := `
+		SELECT count(*) OVER(), id, time, actor, action, entity_type, entity_id, project_id, before, after, request_id
+		FROM audit_log` + auditFilterConditions + `
+		ORDER BY id DESC
+		LIMIT $9 OFFSET $10`
+
+	args := append(auditFilterArgs(filter), filters.limit(), filters.offset())
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	entries := []*AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		var before, after []byte
+		err := rows.Scan(&totalRecords, &e.ID, &e.Time, &e.Actor, &e.Action, &e.EntityType, &e.EntityID, &e.ProjectID,
+			&before, &after, &e.RequestID)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		e.Before, e.After = before, after
+		entries = append(entries, &e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	// The total is counted along with the rows of the page, a page behind the last page has none.
+	if len(entries) == 0 && filters.offset() > 0 {
+		if totalRecords, err = m.count(filter); err != nil {
+			return nil, Metadata{}, err
+		}
+	}
+
+	return entries, calculateMetadata(totalRecords, filters), nil
+}
+
+// count returns the number of entries matching filter.
+func (m AuditModel) count(filter AuditFilter) (int, error) {
+	query := `SELECT count(*) FROM audit_log` + auditFilterConditions
+
+	var total int
+	err := m.DB.QueryRow(query, auditFilterArgs(filter)...).Scan(&total)
+	return total, err
+}
+
+// DeleteBefore deletes the entries recorded before t and returns the number of deleted entries.
+func (m AuditModel) DeleteBefore(t time.Time) (int64, error) {
+	result, err := m.DB.Exec(`DELETE FROM audit_log WHERE time < $1`, t)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -21,6 +21,21 @@ func (v NullInt64) MarshalJSON() ([]byte, error) {
 	}
 }
 
+// UnmarshalJSON decodes a JSON number or null into the NullInt64.
+func (v *NullInt64) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		v.Int64, v.Valid = 0, false
+		return nil
+	}
+
+	if err := json.Unmarshal(b, &v.Int64); err != nil {
+		return err
+	}
+
+	v.Valid = true
+	return nil
+}
+
 // Extension represents an extension in the database.
 type Extension struct {
 	ID           int64     `json:"id"`
@@ -29,24 +44,32 @@ type Extension struct {
 	Description  string    `json:"description"`
 	Scope        string    `json:"scope"`
 	CreationDate time.Time `json:"creation_date"`
+	Version      int32     `json:"version"`
 	ItemCount    int       `json:"item_count,omitempty"`
 }
 
 // ExtensionModel wraps the database connection pool.
+// If Tx is set, all queries run inside of this transaction, see Models.BeginTx.
 type ExtensionModel struct {
 	DB *sql.DB
+	Tx *sql.Tx
+}
+
+func (e ExtensionModel) conn() querier {
+	return conn(e.DB, e.Tx)
 }
 
 // Read retrieves an extension with the specified ID from the database.
 // It returns a pointer to an Extension struct and an error.
+// If no extension with the ID exists, ErrRecordNotFound is returned.
 // If an error occurs during the database query or while scanning the row, it will return the error.
 func (e ExtensionModel) Read(id int64) (*Extension, error) {
 	if id < 1 {
-		return nil, errors.New("record not found")
+		return nil, ErrRecordNotFound
 	}
 
 	query := `
-		SELECT e.id, e.project_id, e.name, e.description, e.scope, e.creation_date, COUNT(i.id) AS item_count
+		SELECT e.id, e.project_id, e.name, e.description, e.scope, e.creation_date, e.version, COUNT(i.id) AS item_count
 		FROM extension e
 		LEFT JOIN item i ON e.id = i.extension_id
 		WHERE e.id = $1
@@ -54,20 +77,21 @@ func (e ExtensionModel) Read(id int64) (*Extension, error) {
 
 	var extension Extension
 
-	err := e.DB.QueryRow(query, id).Scan(
+	err := e.conn().QueryRow(query, id).Scan(
 		&extension.ID,
 		&extension.ProjectID,
 		&extension.Name,
 		&extension.Description,
 		&extension.Scope,
 		&extension.CreationDate,
+		&extension.Version,
 		&extension.ItemCount, // Hier fügst du das neue Feld hinzu
 	)
 
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
-			return nil, errors.New("record not found")
+			return nil, ErrRecordNotFound
 		default:
 			return nil, err
 		}
@@ -102,21 +126,21 @@ func (e ExtensionModel) ReadAll(scope ...string) ([]*Extension, error) {
 
 	if len(scope) > 0 && scope[0] != "" {
 		query = `
-            SELECT e.id, e.project_id, e.name, e.description, e.scope, e.creation_date, COUNT(i.id) AS item_count
+            SELECT e.id, e.project_id, e.name, e.description, e.scope, e.creation_date, e.version, COUNT(i.id) AS item_count
             FROM extension e
             LEFT JOIN item i ON e.id = i.extension_id
             WHERE LOWER(e.scope) = LOWER($1)
             GROUP BY e.id
             ORDER BY e.id`
-		rows, err = e.DB.Query(query, scope[0])
+		rows, err = e.conn().Query(query, scope[0])
 	} else {
 		query = `
-            SELECT e.id, e.project_id, e.name, e.description, e.scope, e.creation_date, COUNT(i.id) AS item_count
+            SELECT e.id, e.project_id, e.name, e.description, e.scope, e.creation_date, e.version, COUNT(i.id) AS item_count
             FROM extension e
             LEFT JOIN item i ON e.id = i.extension_id
             GROUP BY e.id
             ORDER BY e.id`
-		rows, err = e.DB.Query(query)
+		rows, err = e.conn().Query(query)
 	}
 
 	if err != nil {
@@ -134,6 +158,51 @@ func (e ExtensionModel) ReadAll(scope ...string) ([]*Extension, error) {
 			&extension.Description,
 			&extension.Scope,
 			&extension.CreationDate,
+			&extension.Version,
+			&extension.ItemCount)
+
+		if err != nil {
+			return nil, err
+		}
+
+		extensions = append(extensions, &extension)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return extensions, nil
+}
+
+// ReadAllByProject retrieves all extensions belonging to the project with the specified ID, ordered by ID.
+// It returns an empty slice if the project has no extensions.
+func (e ExtensionModel) ReadAllByProject(projectID int64) ([]*Extension, error) {
+	query := `
+		SELECT e.id, e.project_id, e.name, e.description, e.scope, e.creation_date, e.version, COUNT(i.id) AS item_count
+		FROM extension e
+		LEFT JOIN item i ON e.id = i.extension_id
+		WHERE e.project_id = $1
+		GROUP BY e.id
+		ORDER BY e.id`
+
+	rows, err := e.conn().Query(query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	extensions := []*Extension{}
+	for rows.Next() {
+		var extension Extension
+		err = rows.Scan(
+			&extension.ID,
+			&extension.ProjectID,
+			&extension.Name,
+			&extension.Description,
+			&extension.Scope,
+			&extension.CreationDate,
+			&extension.Version,
 			&extension.ItemCount)
 
 		if err != nil {
@@ -154,49 +223,47 @@ func (e ExtensionModel) Insert(d *Extension) error {
 	query := `
 		INSERT INTO extension (name, description, scope, project_id)
 		VALUES ($1, $2, $3, $4)
-		RETURNING id, creation_date`
+		RETURNING id, creation_date, version`
 
 	args := []interface{}{d.Name, d.Description, d.Scope, d.ProjectID}
-	return e.DB.QueryRow(query, args...).Scan(&d.ID, &d.CreationDate)
+	return e.conn().QueryRow(query, args...).Scan(&d.ID, &d.CreationDate, &d.Version)
 }
 
+// Update changes name and description of the extension d and increments its version.
+// If altName is empty, the name is not changed.
+// The update only succeeds if the extension still has the version of d, otherwise ErrEditConflict is returned.
+// On success the new version is stored in d.
 func (e ExtensionModel) Update(d *Extension, altName, altDescription string) error {
 	query := `
         UPDATE extension
         SET name = COALESCE(NULLIF($1, ''), name), 
-            description = $2
-        WHERE id = $3`
+            description = $2,
+            version = version + 1
+        WHERE id = $3 AND version = $4
+        RETURNING version`
 
-	args := []interface{}{altName, altDescription, d.ID}
-	result, err := e.DB.Exec(query, args...)
-	if err != nil {
-		return err
+	args := []interface{}{altName, altDescription, d.ID, d.Version}
+	err := e.conn().QueryRow(query, args...).Scan(&d.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrEditConflict
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-
-	if rowsAffected == 0 {
-		return errors.New("no record found")
-	}
-
-	return nil
+	return err
 }
 
 // Delete removes an extension with the specified ID from the database.
-// It returns an error if the deletion fails or if no record is found with the given ID.
+// It returns an error if the deletion fails, or ErrEditConflict if the extension does not have the given version anymore.
 //
 // Parameters:
 // id (int64): The ID of the extension to delete.
+// version (int32): The version of the extension the client has read.
 //
 // Returns:
 // error: An error that will be nil if the deletion was successful, or an error message if failed.
-func (e ExtensionModel) Delete(id int64) error {
-	query := `DELETE FROM extension WHERE id = $1`
+func (e ExtensionModel) Delete(id int64, version int32) error {
+	query := `DELETE FROM extension WHERE id = $1 AND version = $2`
 
-	result, err := e.DB.Exec(query, id)
+	result, err := e.conn().Exec(query, id, version)
 	if err != nil {
 		return err
 	}
@@ -207,8 +274,48 @@ func (e ExtensionModel) Delete(id int64) error {
 	}
 
 	if rowsAffected == 0 {
-		return errors.New("no record found")
+		return ErrEditConflict
 	}
 
 	return nil
 }
+
+// DeleteWithItems removes the extension with the given ID together with its items.
+// The deletion only succeeds if the extension still has the given version, otherwise ErrEditConflict is returned
+// and nothing is deleted. If the model is not bound to a transaction, the deletion runs in a transaction of its own.
+// Returns: the deleted items as they were before the deletion.
+func (e ExtensionModel) DeleteWithItems(id int64, version int32, itemModel ItemModel) ([]Item, error) {
+	if e.Tx != nil {
+		itemModel.Tx = e.Tx
+		return e.deleteCascade(id, version, itemModel)
+	}
+
+	tx, err := e.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	e.Tx = tx
+	itemModel.Tx = tx
+	items, err := e.deleteCascade(id, version, itemModel)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return items, tx.Commit()
+}
+
+// deleteCascade deletes the items of the extension and the extension itself if it has the given version.
+// The caller rolls the transaction back on ErrEditConflict.
+func (e ExtensionModel) deleteCascade(id int64, version int32, itemModel ItemModel) ([]Item, error) {
+	items, err := itemModel.DeleteItemsByExtension(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := e.Delete(id, version); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
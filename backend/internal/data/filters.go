@@ -0,0 +1,88 @@
+package data
+
+import (
+	"math"
+	"strings"
+)
+
+// MaxPageSize is the largest number of records a single page may contain.
+const MaxPageSize = 1000
+
+// Filters holds the pagination and sorting parameters of a listing request.
+// A PageSize of 0 disables pagination and returns all records.
+type Filters struct {
+	Page     int
+	PageSize int
+	// Sort is the name of the field to sort by. A leading "-" sorts in descending order.
+	Sort string
+	// SortSafelist maps the sortable field names to their database columns.
+	SortSafelist map[string]string
+}
+
+// Metadata describes the page returned by a listing request.
+type Metadata struct {
+	CurrentPage  int `json:"current_page,omitempty"`
+	PageSize     int `json:"page_size,omitempty"`
+	FirstPage    int `json:"first_page,omitempty"`
+	LastPage     int `json:"last_page,omitempty"`
+	TotalRecords int `json:"total_records"`
+}
+
+// SortValid reports whether the sort field of f is part of the safelist.
+func (f Filters) SortValid() bool {
+	_, ok := f.SortSafelist[strings.TrimPrefix(f.Sort, "-")]
+	return ok
+}
+
+// sortColumn returns the database column for the sort field.
+// The safelist check guards against SQL injection, which is why an unknown field panics instead of being passed to the query.
+func (f Filters) sortColumn() string {
+	column, ok := f.SortSafelist[strings.TrimPrefix(f.Sort, "-")]
+	if !ok {
+		panic("unsafe sort parameter: " + f.Sort)
+	}
+	return column
+}
+
+// sortDirection returns the SQL sort direction for the sort field.
+func (f Filters) sortDirection() string {
+	if strings.HasPrefix(f.Sort, "-") {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+// limit returns the LIMIT of the query. nil is passed as NULL which means no limit in PostgreSQL.
+func (f Filters) limit() any {
+	if f.PageSize == 0 {
+		return nil
+	}
+	return f.PageSize
+}
+
+// offset returns the OFFSET of the query.
+func (f Filters) offset() int {
+	if f.PageSize == 0 {
+		return 0
+	}
+	return (f.Page - 1) * f.PageSize
+}
+
+// calculateMetadata builds the metadata of a page. Pagination fields are left empty if pagination is disabled.
+func calculateMetadata(totalRecords int, filters Filters) Metadata {
+	if filters.PageSize == 0 {
+		return Metadata{TotalRecords: totalRecords}
+	}
+
+	if totalRecords == 0 {
+		return Metadata{CurrentPage: filters.Page, PageSize: filters.PageSize}
+	}
+
+	return Metadata{
+		CurrentPage:  filters.Page,
+		PageSize:     filters.PageSize,
+		FirstPage:    1,
+		LastPage:     int(math.Ceil(float64(totalRecords) / float64(filters.PageSize))),
+		TotalRecords: totalRecords,
+	}
+}
@@ -0,0 +1,102 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// IdempotentResponse is the stored response of a request sent with an Idempotency-Key header.
+type IdempotentResponse struct {
+	Key      string
+	Endpoint string
+	// RequestHash is the SHA-256 hash of the request body, hex encoded.
+	RequestHash string
+	// StatusCode is 0 while the first request with the key is still being processed.
+	StatusCode   int
+	Headers      string
+	Body         []byte
+	CreationDate time.Time
+}
+
+// IdempotencyModel wraps the database connection pool.
+type IdempotencyModel struct {
+	DB *sql.DB
+}
+
+// Reserve claims key for the endpoint before the request is processed.
+// Keys created before expiredBefore are treated as unused and claimed again.
+// It returns nil if the key has been claimed by this call. Otherwise the stored response of the earlier request
+// is returned, which has a StatusCode of 0 if that request is still being processed.
+func (m IdempotencyModel) Reserve(key, endpoint, requestHash string, expiredBefore time.Time) (*IdempotentResponse, error) {
+	query := `
+		INSERT INTO idempotency_key (key, endpoint, request_hash)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key, endpoint) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash, status_code = NULL, headers = NULL, body = NULL, creation_date = CURRENT_TIMESTAMP
+		WHERE idempotency_key.creation_date < $4
+		RETURNING key`
+
+	var claimed string
+	err := m.DB.QueryRow(query, key, endpoint, requestHash, expiredBefore).Scan(&claimed)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	query = `
+		SELECT key, endpoint, request_hash, status_code, headers, body, creation_date
+		FROM idempotency_key
+		WHERE key = $1 AND endpoint = $2`
+
+	var response IdempotentResponse
+	var statusCode sql.NullInt32
+	var headers sql.NullString
+	err = m.DB.QueryRow(query, key, endpoint).Scan(
+		&response.Key,
+		&response.Endpoint,
+		&response.RequestHash,
+		&statusCode,
+		&headers,
+		&response.Body,
+		&response.CreationDate,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	response.StatusCode = int(statusCode.Int32)
+	response.Headers = headers.String
+	return &response, nil
+}
+
+// Complete stores the response of the request which reserved key.
+func (m IdempotencyModel) Complete(key, endpoint string, statusCode int, headers string, body []byte) error {
+	query := `
+		UPDATE idempotency_key
+		SET status_code = $3, headers = $4, body = $5
+		WHERE key = $1 AND endpoint = $2`
+
+	_, err := m.DB.Exec(query, key, endpoint, statusCode, headers, body)
+	return err
+}
+
+// Release deletes a reserved key, e.g. because the request failed and may be retried with the same key.
+func (m IdempotencyModel) Release(key, endpoint string) error {
+	query := `DELETE FROM idempotency_key WHERE key = $1 AND endpoint = $2`
+	_, err := m.DB.Exec(query, key, endpoint)
+	return err
+}
+
+// DeleteExpired deletes all keys created before expiredBefore and returns the number of deleted keys.
+func (m IdempotencyModel) DeleteExpired(expiredBefore time.Time) (int64, error) {
+	query := `DELETE FROM idempotency_key WHERE creation_date < $1`
+	result, err := m.DB.Exec(query, expiredBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -3,6 +3,8 @@ package data
 import (
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -18,28 +20,30 @@ type Item struct {
 	ExtensionID  int64     `json:"extension_id"`
 	Typecode     int32     `json:"typecode"`
 	CreationDate time.Time `json:"creation_date"`
+	Version      int32     `json:"version"`
 }
 
 // ItemModel wraps the database connection pool.
+// If Tx is set, all queries run inside of this transaction, see Models.BeginTx.
 type ItemModel struct {
 	DB *sql.DB
 	Tx *sql.Tx
 }
 
+func (i *ItemModel) conn() querier {
+	return conn(i.DB, i.Tx)
+}
+
 // Insert adds a new item to the database.
 // It returns an error if the SQL query or scan fails.
 func (i *ItemModel) Insert(item *Item) error {
 	query := `INSERT INTO item (name, extension_id, table_name, typecode)
 		VALUES ($1, $2, $3, $4)
-		RETURNING id, creation_date`
+		RETURNING id, creation_date, version`
 
 	args := []interface{}{item.Name, item.ExtensionID, item.TableName, item.Typecode}
 
-	if i.Tx != nil {
-		return i.Tx.QueryRow(query, args...).Scan(&item.ID, &item.CreationDate)
-	}
-
-	return i.DB.QueryRow(query, args...).Scan(&item.ID, &item.CreationDate)
+	return i.conn().QueryRow(query, args...).Scan(&item.ID, &item.CreationDate, &item.Version)
 }
 
 // ReadItems executes a SQL query to retrieve detailed information about items.
@@ -56,12 +60,14 @@ func (i *ItemModel) ReadItems() ([]Item, error) {
        item.table_name,
        extension.id, 
        item.typecode,
-	   item.creation_date
+	   item.creation_date,
+	   item.version
 	FROM item
 	JOIN extension ON item.extension_id = extension.id
-	LEFT JOIN project ON extension.project_id = project.id`
+	LEFT JOIN project ON extension.project_id = project.id
+	ORDER BY item.id`
 
-	rows, err := i.DB.Query(query)
+	rows, err := i.conn().Query(query)
 	if err != nil {
 		return nil, err
 	}
@@ -70,7 +76,7 @@ func (i *ItemModel) ReadItems() ([]Item, error) {
 
 	for rows.Next() {
 		var item Item
-		err = rows.Scan(&item.ID, &item.Scope, &item.Project, &item.Name, &item.TableName, &item.ExtensionID, &item.Typecode, &item.CreationDate)
+		err = rows.Scan(&item.ID, &item.Scope, &item.Project, &item.Name, &item.TableName, &item.ExtensionID, &item.Typecode, &item.CreationDate, &item.Version)
 		items = append(items, item)
 	}
 
@@ -83,6 +89,152 @@ func (i *ItemModel) ReadItems() ([]Item, error) {
 	return items, err
 }
 
+// ItemFilter restricts the items returned by ReadFilteredItems. Zero values and nil pointers are ignored.
+type ItemFilter struct {
+	Scope        string
+	Project      string
+	ProjectID    int64
+	ExtensionID  int64
+	TypecodeMin  *int32
+	TypecodeMax  *int32
+	Name         string
+	CreatedAfter *time.Time
+	// CreatedBefore is exclusive.
+	CreatedBefore *time.Time
+}
+
+// ItemSortSafelist maps the fields items can be sorted by to their database columns.
+var ItemSortSafelist = map[string]string{
+	"id":            "item.id",
+	"name":          "item.name",
+	"table_name":    "item.table_name",
+	"typecode":      "item.typecode",
+	"scope":         "extension.scope",
+	"project":       "project_name",
+	"extension_id":  "extension.id",
+	"creation_date": "item.creation_date",
+}
+
+// itemFilterConditions is the WHERE clause of the filters of ItemFilter, see itemFilterArgs.
+// LIKE wildcards in the name are escaped by itemFilterArgs, so the name is matched literally.
+const itemFilterConditions = `
+	WHERE ($1 = '' OR LOWER(extension.scope) = LOWER($1))
+	AND ($2 = '' OR LOWER(project.name) = LOWER($2))
+	AND ($3 = 0 OR extension.project_id = $3)
+	AND ($4 = 0 OR extension.id = $4)
+	AND ($5::INTEGER IS NULL OR item.typecode >= $5::INTEGER)
+	AND ($6::INTEGER IS NULL OR item.typecode <= $6::INTEGER)
+	AND ($7 = '' OR item.name ILIKE '%' || $7 || '%')
+	AND ($8::TIMESTAMPTZ IS NULL OR item.creation_date >= $8::TIMESTAMPTZ)
+	AND ($9::TIMESTAMPTZ IS NULL OR item.creation_date < $9::TIMESTAMPTZ)`
+
+// itemFilterArgs returns the arguments of itemFilterConditions.
+func itemFilterArgs(filter ItemFilter) []any {
+	return []any{
+		filter.Scope,
+		filter.Project,
+		filter.ProjectID,
+		filter.ExtensionID,
+		filter.TypecodeMin,
+		filter.TypecodeMax,
+		escapeLike(filter.Name),
+		filter.CreatedAfter,
+		filter.CreatedBefore,
+	}
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern using the default escape character backslash.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// ReadFilteredItems returns the items matching filter, sorted and paginated according to filters.
+// Scope and project are compared case-insensitively, name matches any item containing the given text.
+// The total number of matching items is returned in the metadata, also for a page behind the last page.
+func (i *ItemModel) ReadFilteredItems(filter ItemFilter, filters Filters) ([]Item, Metadata, error) {
+	totalRecords := 0
+	items := []Item{}
+
+	err := i.queryFilteredItems(filter, filters, func(item Item, total int) error {
+		totalRecords = total
+		items = append(items, item)
+		return nil
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	// The total is counted along with the rows of the page, a page behind the last page has none.
+	if len(items) == 0 && filters.offset() > 0 {
+		if totalRecords, err = i.countFilteredItems(filter); err != nil {
+			return nil, Metadata{}, err
+		}
+	}
+
+	return items, calculateMetadata(totalRecords, filters), nil
+}
+
+// countFilteredItems returns the number of items matching filter.
+func (i *ItemModel) countFilteredItems(filter ItemFilter) (int, error) {
+	query := `SELECT count(*)
+	FROM item
+	JOIN extension ON item.extension_id = extension.id
+	LEFT JOIN project ON extension.project_id = project.id` + itemFilterConditions
+
+	var total int
+	err := i.conn().QueryRow(query, itemFilterArgs(filter)...).Scan(&total)
+	return total, err
+}
+
+// StreamFilteredItems calls fn for every item matching filter in the order and page of filters,
+// like ReadFilteredItems, but without keeping the items in memory. It stops at the first error of fn.
+func (i *ItemModel) StreamFilteredItems(filter ItemFilter, filters Filters, fn func(Item) error) error {
+	return i.queryFilteredItems(filter, filters, func(item Item, _ int) error {
+		return fn(item)
+	})
+}
+
+// queryFilteredItems runs the query of ReadFilteredItems and calls fn with every item and the total number of matching items.
+func (i *ItemModel) queryFilteredItems(filter ItemFilter, filters Filters, fn func(item Item, total int) error) error {
+	query := fmt.Sprintf(`SELECT count(*) OVER(),
+		item.id,
+		extension.scope,
+		COALESCE(project.name, '-') AS project_name,
+		item.name,
+		item.table_name,
+		extension.id,
+		item.typecode,
+		item.creation_date,
+		item.version
+	FROM item
+	JOIN extension ON item.extension_id = extension.id
+	LEFT JOIN project ON extension.project_id = project.id%s
+	ORDER BY %s %s, item.id ASC
+	LIMIT $10 OFFSET $11`, itemFilterConditions, filters.sortColumn(), filters.sortDirection())
+
+	args := append(itemFilterArgs(filter), filters.limit(), filters.offset())
+
+	rows, err := i.conn().Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item Item
+		var total int
+		err = rows.Scan(&total, &item.ID, &item.Scope, &item.Project, &item.Name, &item.TableName, &item.ExtensionID, &item.Typecode, &item.CreationDate, &item.Version)
+		if err != nil {
+			return err
+		}
+		if err = fn(item, total); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
 // GetNextSharedFreeTypecode returns the next available typecode for a given scope within a specified range.
 // It returns a sql.NullInt32 and an error.
 // If an error occurs during the database query or while scanning the row, it will return the error.
@@ -106,7 +258,7 @@ func (i *ItemModel) GetNextSharedFreeTypecode(scope string, rangeStart int32, ra
     `
 
 	var nextFreeTypecode sql.NullInt32
-	err := i.DB.QueryRow(query, rangeStart, rangeEnd, scope).Scan(&nextFreeTypecode)
+	err := i.conn().QueryRow(query, rangeStart, rangeEnd, scope).Scan(&nextFreeTypecode)
 	return nextFreeTypecode, err
 }
 
@@ -141,7 +293,7 @@ func (i *ItemModel) GetNextProjectFreeTypeCode(projectId int64, rangeStart, rang
 	`
 
 	var nextFreeTypecode sql.NullInt32
-	err := i.DB.QueryRow(query, projectId, rangeStart, rangeEnd).Scan(&nextFreeTypecode)
+	err := i.conn().QueryRow(query, projectId, rangeStart, rangeEnd).Scan(&nextFreeTypecode)
 	return nextFreeTypecode, err
 }
 
@@ -153,20 +305,23 @@ func (i *ItemModel) ReadItem(id int64) (Item, error) {
        item.table_name,
        extension.id, 
        item.typecode,
-	   item.creation_date
+	   item.creation_date,
+	   item.version
 	FROM item
 	JOIN extension ON item.extension_id = extension.id
 	LEFT JOIN project ON extension.project_id = project.id
 	WHERE item.id = $1`
 
 	var item Item
-	err := i.DB.QueryRow(query, id).Scan(&item.ID, &item.Scope, &item.Project, &item.Name, &item.TableName, &item.ExtensionID, &item.Typecode, &item.CreationDate)
+	err := i.conn().QueryRow(query, id).Scan(&item.ID, &item.Scope, &item.Project, &item.Name, &item.TableName, &item.ExtensionID, &item.Typecode, &item.CreationDate, &item.Version)
 	return item, err
 }
 
-func (i *ItemModel) DeleteItem(id int64) error {
-	query := `DELETE FROM item WHERE id = $1`
-	result, err := i.DB.Exec(query, id)
+// DeleteItem deletes the item with the given ID.
+// The deletion only succeeds if the item still has the given version, otherwise ErrEditConflict is returned.
+func (i *ItemModel) DeleteItem(id int64, version int32) error {
+	query := `DELETE FROM item WHERE id = $1 AND version = $2`
+	result, err := i.conn().Exec(query, id, version)
 	if err != nil {
 		return err
 	}
@@ -177,56 +332,66 @@ func (i *ItemModel) DeleteItem(id int64) error {
 	}
 
 	if rowsAffected == 0 {
-		return errors.New("no record found")
+		return ErrEditConflict
 	}
 
 	return nil
 }
 
-// UpdateItem updates an existing item in the database.
+// UpdateItem updates name and table name of an existing item in the database.
+// The update only succeeds if the item still has the version of d, otherwise ErrEditConflict is returned.
+// The version is incremented if name or table name change and the new version is stored in d.
 // It returns an error if the SQL query fails.
 func (i *ItemModel) UpdateItem(d *Item) error {
 	query := `UPDATE item
-	SET name = $1, table_name = $2
+	SET name = $1, table_name = $2,
+		version = CASE WHEN name = $1 AND table_name = $2 THEN version ELSE version + 1 END
 	WHERE id = $3
-	AND (name != $1 OR table_name != $2)`
-	_, err := i.DB.Exec(query, d.Name, d.TableName, d.ID)
+	AND version = $4
+	RETURNING version`
+	err := i.conn().QueryRow(query, d.Name, d.TableName, d.ID, d.Version).Scan(&d.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrEditConflict
+	}
 	return err
 }
 
-func (i *ItemModel) BeginTransaction() error {
-	tx, err := i.DB.Begin()
+// DeleteItemsByExtension deletes all items of the extension with the given ID.
+// Returns: the deleted items as they were before the deletion, ordered by ID.
+func (i *ItemModel) DeleteItemsByExtension(extensionID int64) ([]Item, error) {
+	query := `WITH deleted AS (
+		DELETE FROM item
+		USING extension LEFT JOIN project ON extension.project_id = project.id
+		WHERE item.extension_id = extension.id AND extension.id = $1
+		RETURNING item.id,
+			extension.scope,
+			COALESCE(project.name, '-') AS project_name,
+			item.name,
+			item.table_name,
+			extension.id AS extension_id,
+			item.typecode,
+			item.creation_date,
+			item.version)
+	SELECT * FROM deleted ORDER BY id`
+
+	rows, err := i.conn().Query(query, extensionID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	i.Tx = tx
-	return nil
-}
+	defer rows.Close()
 
-func (i *ItemModel) Rollback() error {
-	if i.Tx != nil {
-		err := i.Tx.Rollback()
+	items := []Item{}
+	for rows.Next() {
+		var item Item
+		err = rows.Scan(&item.ID, &item.Scope, &item.Project, &item.Name, &item.TableName, &item.ExtensionID, &item.Typecode, &item.CreationDate, &item.Version)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		i.Tx = nil
+		items = append(items, item)
 	}
-	return nil
-}
-
-func (i *ItemModel) CommitTransaction() error {
-	if i.Tx != nil {
-		err := i.Tx.Commit()
-		if err != nil {
-			return err
-		}
-		i.Tx = nil
+	if err = rows.Err(); err != nil {
+		return nil, err
 	}
-	return nil
-}
 
-func (i *ItemModel) DeleteItemsByExtension(extensionID int64) error {
-	query := `DELETE FROM item WHERE extension_id = $1`
-	_, err := i.DB.Exec(query, extensionID)
-	return err
+	return items, nil
 }
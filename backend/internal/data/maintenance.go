@@ -0,0 +1,166 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// IntegrityProblem describes an inconsistency found by CheckIntegrity.
+type IntegrityProblem struct {
+	Check  string `json:"check"`
+	Detail string `json:"detail"`
+}
+
+// ReservedTypecode is a typecode which is used by the SAP Commerce platform and must never be allocated.
+type ReservedTypecode struct {
+	Typecode  int32
+	Name      string
+	TableName string
+}
+
+// MaintenanceModel wraps the database connection pool for administrative tasks.
+type MaintenanceModel struct {
+	DB *sql.DB
+}
+
+// integrityCheck is a query which returns one row with a description per problem.
+type integrityCheck struct {
+	name  string
+	query string
+	args  []any
+}
+
+// CheckIntegrity runs consistency checks which the schema cannot enforce by itself.
+// It returns all problems found; an empty slice means the database is consistent.
+func (m MaintenanceModel) CheckIntegrity() ([]IntegrityProblem, error) {
+	checks := []integrityCheck{
+		{
+			name: "duplicate-global-typecode",
+			query: `
+				SELECT 'typecode ' || i.typecode || ' is used by ' || string_agg(i.name, ', ' ORDER BY i.id)
+				FROM item i
+				JOIN extension e ON i.extension_id = e.id
+				WHERE e.scope <> $1
+				GROUP BY i.typecode
+				HAVING COUNT(*) > 1`,
+			args: []any{ScopeProject},
+		},
+		{
+			name: "duplicate-project-typecode",
+			query: `
+				SELECT 'typecode ' || i.typecode || ' is used by ' || string_agg(i.name, ', ' ORDER BY i.id) || ' in project ' || e.project_id
+				FROM item i
+				JOIN extension e ON i.extension_id = e.id
+				WHERE e.scope = $1
+				GROUP BY e.project_id, i.typecode
+				HAVING COUNT(*) > 1`,
+			args: []any{ScopeProject},
+		},
+		{
+			name: "item-without-extension",
+			query: `
+				SELECT 'item ' || id || ' (' || name || ') has no extension'
+				FROM item
+				WHERE extension_id IS NULL`,
+		},
+		{
+			name: "extension-scope-mismatch",
+			query: `
+				SELECT 'extension ' || id || ' (' || name || ') has scope ' || COALESCE(scope, 'NULL') ||
+					CASE WHEN project_id IS NULL THEN ' without a project' ELSE ' but belongs to project ' || project_id END
+				FROM extension
+				WHERE (scope = $1 AND project_id IS NULL)
+				OR (scope IS DISTINCT FROM $1 AND project_id IS NOT NULL)
+				OR scope IS NULL OR scope NOT IN ($1, $2, $3)`,
+			args: []any{ScopeProject, ScopeShared, ScopeHybris},
+		},
+	}
+
+	for _, scope := range []string{ScopeShared, ScopeHybris, ScopeProject} {
+		r := ScopeRanges[scope]
+		checks = append(checks, integrityCheck{
+			name: "typecode-out-of-range",
+			query: `
+				SELECT 'item ' || i.id || ' (' || i.name || ') of scope ' || e.scope || ' has typecode ' || i.typecode ||
+					' outside of ' || $2::INTEGER || '-' || $3::INTEGER
+				FROM item i
+				JOIN extension e ON i.extension_id = e.id
+				WHERE e.scope = $1 AND (i.typecode < $2::INTEGER OR i.typecode > $3::INTEGER)`,
+			args: []any{scope, r.Start, r.End},
+		})
+	}
+
+	problems := []IntegrityProblem{}
+	for _, check := range checks {
+		rows, err := m.DB.Query(check.query, check.args...)
+		if err != nil {
+			return nil, fmt.Errorf("check %s failed: %w", check.name, err)
+		}
+
+		for rows.Next() {
+			problem := IntegrityProblem{Check: check.name}
+			if err := rows.Scan(&problem.Detail); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			problems = append(problems, problem)
+		}
+
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return problems, nil
+}
+
+// ImportReserved registers the given typecodes as items of the Hybris scoped extension with the given name.
+// The extension is created if it does not exist. Typecodes which are already registered in the Hybris scope
+// are skipped. Everything happens in one transaction, the number of inserted items is returned.
+func (m MaintenanceModel) ImportReserved(extensionName string, reserved []ReservedTypecode) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var extensionID int64
+	query := `SELECT id FROM extension WHERE name = $1 AND scope = $2`
+	err = tx.QueryRow(query, extensionName, ScopeHybris).Scan(&extensionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		query = `INSERT INTO extension (name, description, scope) VALUES ($1, $2, $3) RETURNING id`
+		err = tx.QueryRow(query, extensionName, "Typecodes reserved by the SAP Commerce platform", ScopeHybris).Scan(&extensionID)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	query = `
+		INSERT INTO item (name, extension_id, table_name, typecode)
+		SELECT $1::VARCHAR, $2::INTEGER, $3::VARCHAR, $4::INTEGER
+		WHERE NOT EXISTS (
+			SELECT 1
+			FROM item
+			JOIN extension ON item.extension_id = extension.id
+			WHERE extension.scope = $5 AND item.typecode = $4::INTEGER
+		)`
+
+	imported := 0
+	for _, r := range reserved {
+		result, err := tx.Exec(query, r.Name, extensionID, r.TableName, r.Typecode, ScopeHybris)
+		if err != nil {
+			return 0, fmt.Errorf("importing typecode %d failed: %w", r.Typecode, err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		imported += int(rowsAffected)
+	}
+
+	return imported, tx.Commit()
+}
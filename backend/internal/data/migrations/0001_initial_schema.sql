@@ -0,0 +1,41 @@
+-- Initial schema of the Typecode Registry.
+-- Uses IF NOT EXISTS so databases created with database/001_initial_schema.sql can be adopted.
+
+CREATE TABLE IF NOT EXISTS "user" (
+        id SERIAL PRIMARY KEY,
+        oauth_identifier VARCHAR(255) UNIQUE NOT NULL,
+        email VARCHAR(255) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS project (
+        id SERIAL PRIMARY KEY,
+        name VARCHAR(255) NOT NULL UNIQUE,
+        description TEXT,
+        creation_date TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS extension (
+       id SERIAL PRIMARY KEY,
+       project_id INT,
+       name VARCHAR(255) NOT NULL,
+       description TEXT,
+       scope VARCHAR(50),
+       creation_date TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+       FOREIGN KEY (project_id) REFERENCES project(id)
+);
+
+CREATE TABLE IF NOT EXISTS item (
+      id SERIAL PRIMARY KEY,
+      name VARCHAR(255) NOT NULL,
+      extension_id INT REFERENCES extension (id),
+      table_name VARCHAR(255),
+      typecode INT NOT NULL,
+      creation_date TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS role_assignment (
+     role_assignment_id SERIAL PRIMARY KEY,
+     user_id INT REFERENCES "user"(id) NOT NULL,
+     project_id INT REFERENCES project(id) NOT NULL,
+     role VARCHAR(255) NOT NULL
+);
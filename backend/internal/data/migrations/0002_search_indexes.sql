@@ -0,0 +1,12 @@
+-- Trigram indexes for the search endpoint. They speed up the case-insensitive substring matches
+-- (ILIKE '%term%') on names, table names and descriptions.
+-- Creating the extension requires the CREATE privilege on the database.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS item_name_trgm_idx ON item USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS item_table_name_trgm_idx ON item USING GIN (table_name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS extension_name_trgm_idx ON extension USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS extension_description_trgm_idx ON extension USING GIN (description gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS project_name_trgm_idx ON project USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS project_description_trgm_idx ON project USING GIN (description gin_trgm_ops);
@@ -0,0 +1,6 @@
+-- Version numbers for optimistic concurrency control. Every update of a row increments its version,
+-- which is returned as ETag by the API and compared with the If-Match header of PUT and DELETE requests.
+
+ALTER TABLE project ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE extension ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE item ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
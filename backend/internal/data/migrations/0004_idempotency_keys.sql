@@ -0,0 +1,16 @@
+-- Responses of POST requests sent with an Idempotency-Key header. A repeated request with the same key
+-- is answered with the stored response instead of being executed again.
+-- status_code is NULL while the first request is still being processed.
+
+CREATE TABLE IF NOT EXISTS idempotency_key (
+        key VARCHAR(255) NOT NULL,
+        endpoint VARCHAR(255) NOT NULL,
+        request_hash CHAR(64) NOT NULL,
+        status_code INT,
+        headers TEXT,
+        body BYTEA,
+        creation_date TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (key, endpoint)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_key_creation_date_idx ON idempotency_key (creation_date);
@@ -0,0 +1,34 @@
+-- Webhook subscriptions and the log of their deliveries. A webhook receives the changes of the given
+-- event types, e.g. item.created, of its project or, if project_id is NULL, of all projects.
+-- Deliveries are retried until next_attempt is NULL, status is then succeeded, failed or,
+-- for deliveries of inactive webhooks, abandoned.
+
+CREATE TABLE IF NOT EXISTS webhook (
+        id SERIAL PRIMARY KEY,
+        project_id INT REFERENCES project(id) ON DELETE CASCADE,
+        url VARCHAR(2048) NOT NULL,
+        secret VARCHAR(255) NOT NULL,
+        events TEXT[] NOT NULL,
+        description VARCHAR(255) NOT NULL DEFAULT '',
+        active BOOLEAN NOT NULL DEFAULT TRUE,
+        creation_date TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        version INT NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery (
+        id BIGSERIAL PRIMARY KEY,
+        webhook_id INT NOT NULL REFERENCES webhook(id) ON DELETE CASCADE,
+        event VARCHAR(64) NOT NULL,
+        payload TEXT NOT NULL,
+        redelivery_of BIGINT,
+        status VARCHAR(16) NOT NULL DEFAULT 'pending',
+        attempts INT NOT NULL DEFAULT 0,
+        response_status INT,
+        error TEXT,
+        next_attempt TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+        creation_date TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        completion_date TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS webhook_delivery_webhook_id_idx ON webhook_delivery (webhook_id, id);
+CREATE INDEX IF NOT EXISTS webhook_delivery_next_attempt_idx ON webhook_delivery (next_attempt) WHERE next_attempt IS NOT NULL;
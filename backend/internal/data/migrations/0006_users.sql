@@ -0,0 +1,23 @@
+-- Users and their roles in projects. A user has at most one role per project. The role assignments
+-- are deleted together with their user or project, which they blocked before.
+
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS name VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS creation_date TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+ALTER TABLE role_assignment ADD COLUMN IF NOT EXISTS creation_date TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+-- Only the latest of several roles of a user in the same project is kept.
+DELETE FROM role_assignment a
+USING role_assignment b
+WHERE a.user_id = b.user_id AND a.project_id = b.project_id AND a.role_assignment_id < b.role_assignment_id;
+
+ALTER TABLE role_assignment DROP CONSTRAINT IF EXISTS role_assignment_user_id_fkey;
+ALTER TABLE role_assignment ADD CONSTRAINT role_assignment_user_id_fkey
+        FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE;
+ALTER TABLE role_assignment DROP CONSTRAINT IF EXISTS role_assignment_project_id_fkey;
+ALTER TABLE role_assignment ADD CONSTRAINT role_assignment_project_id_fkey
+        FOREIGN KEY (project_id) REFERENCES project(id) ON DELETE CASCADE;
+ALTER TABLE role_assignment DROP CONSTRAINT IF EXISTS role_assignment_user_id_project_id_key;
+ALTER TABLE role_assignment ADD CONSTRAINT role_assignment_user_id_project_id_key UNIQUE (user_id, project_id);
+
+CREATE INDEX IF NOT EXISTS role_assignment_project_id_idx ON role_assignment (project_id);
@@ -0,0 +1,30 @@
+-- API tokens for automation and the service accounts owning some of them. A token belongs either to a user
+-- (personal access token) or to a service account, which has a fixed role in one project. Only the SHA-256
+-- hash of a token is stored; prefix is its start, which identifies the token in listings.
+
+CREATE TABLE IF NOT EXISTS service_account (
+        id SERIAL PRIMARY KEY,
+        project_id INT NOT NULL REFERENCES project(id) ON DELETE CASCADE,
+        name VARCHAR(255) NOT NULL,
+        role VARCHAR(255) NOT NULL,
+        creation_date TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE (project_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS api_token (
+        id SERIAL PRIMARY KEY,
+        name VARCHAR(255) NOT NULL,
+        prefix VARCHAR(16) NOT NULL,
+        token_hash CHAR(64) UNIQUE NOT NULL,
+        user_id INT REFERENCES "user"(id) ON DELETE CASCADE,
+        service_account_id INT REFERENCES service_account(id) ON DELETE CASCADE,
+        scopes TEXT[] NOT NULL,
+        expires_at TIMESTAMPTZ NOT NULL,
+        last_used_at TIMESTAMPTZ,
+        revoked_at TIMESTAMPTZ,
+        creation_date TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        CHECK ((user_id IS NULL) <> (service_account_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS api_token_user_id_idx ON api_token (user_id);
+CREATE INDEX IF NOT EXISTS api_token_service_account_id_idx ON api_token (service_account_id);
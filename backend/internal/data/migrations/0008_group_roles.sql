@@ -0,0 +1,4 @@
+-- Roles in projects are assigned manually or granted by the groups of the identity provider a user is member of.
+-- Group roles are re-evaluated whenever the user signs in, manual roles are never changed by that.
+
+ALTER TABLE role_assignment ADD COLUMN IF NOT EXISTS source VARCHAR(16) NOT NULL DEFAULT 'manual';
@@ -0,0 +1,30 @@
+-- The audit log records every change of projects, extensions, items and role assignments: who made it,
+-- in which request, and the state of the entity before and after. project_id and entity_id are no foreign keys,
+-- so the entries outlive the entities. Entries are never changed; the only deletions are those of the retention.
+
+CREATE TABLE IF NOT EXISTS audit_log (
+        id BIGSERIAL PRIMARY KEY,
+        time TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        actor VARCHAR(255) NOT NULL,
+        action VARCHAR(16) NOT NULL,
+        entity_type VARCHAR(32) NOT NULL,
+        entity_id BIGINT NOT NULL,
+        project_id BIGINT,
+        before JSONB,
+        after JSONB,
+        request_id VARCHAR(128) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_time_idx ON audit_log (time);
+CREATE INDEX IF NOT EXISTS audit_log_entity_idx ON audit_log (entity_type, entity_id);
+CREATE INDEX IF NOT EXISTS audit_log_project_id_idx ON audit_log (project_id) WHERE project_id IS NOT NULL;
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS TRIGGER AS $$
+BEGIN
+        RAISE EXCEPTION 'the audit log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+CREATE TRIGGER audit_log_append_only BEFORE UPDATE ON audit_log
+        FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
@@ -1,20 +1,80 @@
 package data
 
-import "database/sql"
+import (
+	"database/sql"
+	"errors"
+)
+
+// ErrRecordNotFound is returned when a single record is requested which does not exist.
+var ErrRecordNotFound = errors.New("record not found")
+
+// ErrEditConflict is returned when a record was changed or deleted after it has been read, i.e. its version differs.
+var ErrEditConflict = errors.New("edit conflict")
+
+// querier is implemented by *sql.DB and *sql.Tx, so the models can run their queries with or without a transaction.
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// conn returns tx if a model is bound to a transaction, otherwise db.
+func conn(db *sql.DB, tx *sql.Tx) querier {
+	if tx != nil {
+		return tx
+	}
+	return db
+}
 
 // Models wraps the models for the application.
 // Used in the application struct to access the models from the handlers.
 type Models struct {
-	Items      ItemModel
-	Extensions ExtensionModel
-	Projects   ProjectModel
+	Items           ItemModel
+	Extensions      ExtensionModel
+	Projects        ProjectModel
+	Migrations      MigrationModel
+	Maintenance     MaintenanceModel
+	Search          SearchModel
+	Idempotency     IdempotencyModel
+	Webhooks        WebhookModel
+	Users           UserModel
+	Roles           RoleAssignmentModel
+	Tokens          APITokenModel
+	ServiceAccounts ServiceAccountModel
+	Audit           AuditModel
 }
 
 // NewModels creates a new Models struct and initializes the models.
 func NewModels(db *sql.DB) Models {
 	return Models{
-		Items:      ItemModel{DB: db},
-		Extensions: ExtensionModel{DB: db},
-		Projects:   ProjectModel{DB: db},
+		Items:           ItemModel{DB: db},
+		Extensions:      ExtensionModel{DB: db},
+		Projects:        ProjectModel{DB: db},
+		Migrations:      MigrationModel{DB: db},
+		Maintenance:     MaintenanceModel{DB: db},
+		Search:          SearchModel{DB: db},
+		Idempotency:     IdempotencyModel{DB: db},
+		Webhooks:        WebhookModel{DB: db},
+		Users:           UserModel{DB: db},
+		Roles:           RoleAssignmentModel{DB: db},
+		Tokens:          APITokenModel{DB: db},
+		ServiceAccounts: ServiceAccountModel{DB: db},
+		Audit:           AuditModel{DB: db},
 	}
 }
+
+// BeginTx starts a transaction and returns a copy of m whose item, extension, project, role and audit models
+// run all their queries inside of it. The caller has to commit or roll back the transaction.
+func (m Models) BeginTx() (*sql.Tx, Models, error) {
+	tx, err := m.Items.DB.Begin()
+	if err != nil {
+		return nil, Models{}, err
+	}
+
+	m.Items.Tx = tx
+	m.Extensions.Tx = tx
+	m.Projects.Tx = tx
+	m.Roles.Tx = tx
+	m.Audit.Tx = tx
+	return tx, m, nil
+}
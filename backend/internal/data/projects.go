@@ -12,20 +12,27 @@ type Project struct {
 	Name         string    `json:"name"`
 	Description  string    `json:"description"`
 	CreationDate time.Time `json:"creation_date"`
+	Version      int32     `json:"version"`
 }
 
 // ProjectModel wraps the database connection pool.
+// If Tx is set, all queries run inside of this transaction, see Models.BeginTx.
 type ProjectModel struct {
 	DB *sql.DB
+	Tx *sql.Tx
+}
+
+func (pm ProjectModel) conn() querier {
+	return conn(pm.DB, pm.Tx)
 }
 
 // ReadAll retrieves all projects from the database.
 // It returns a slice of pointers to Project structs and an error.
 // If an error occurs during the database query or while scanning the rows, it will return the error.
 func (pm ProjectModel) ReadAll() (projects []*Project, err error) {
-	query := `SELECT * FROM project ORDER BY id`
+	query := `SELECT id, name, description, creation_date, version FROM project ORDER BY id`
 
-	rows, err := pm.DB.Query(query)
+	rows, err := pm.conn().Query(query)
 
 	if err != nil {
 		return nil, err
@@ -41,6 +48,7 @@ func (pm ProjectModel) ReadAll() (projects []*Project, err error) {
 			&project.Name,
 			&project.Description,
 			&project.CreationDate,
+			&project.Version,
 		)
 
 		projects = append(projects, &project)
@@ -63,7 +71,7 @@ func (pm ProjectModel) ReadAll() (projects []*Project, err error) {
 // Returns:
 func (pm ProjectModel) ReadProjectName(id int64, projectName *string) error {
 	query := `SELECT name FROM project WHERE id = $1`
-	return pm.DB.QueryRow(query, id).Scan(projectName)
+	return pm.conn().QueryRow(query, id).Scan(projectName)
 }
 
 // Insert adds a new project to the database.
@@ -74,66 +82,62 @@ func (pm ProjectModel) ReadProjectName(id int64, projectName *string) error {
 func (pm ProjectModel) Insert(project *Project) error {
 	query := `INSERT INTO project (name, description)
 		VALUES ($1, $2)
-		RETURNING id, creation_date`
+		RETURNING id, creation_date, version`
 
 	args := []interface{}{project.Name, project.Description}
 
-	return pm.DB.QueryRow(query, args...).Scan(&project.ID, &project.CreationDate)
+	return pm.conn().QueryRow(query, args...).Scan(&project.ID, &project.CreationDate, &project.Version)
 }
 
-// Update modifies the name and description of a project in the database.
+// Update modifies the name and description of a project in the database and increments its version.
 // If the provided altName is an empty string, the existing name in the database is retained.
 // The description is always updated to the provided altDescription.
 //
 // Parameters:
-// - d: A pointer to the Project struct containing the ID of the project to be updated.
+// - d: A pointer to the Project struct containing the ID and the version of the project to be updated.
 // - altName: The new name for the project. If this is an empty string, the name is not changed.
 // - altDescription: The new description for the project.
 //
 // Returns:
-// - error: An error if the update operation fails or if no record is found with the given ID.
+//   - error: ErrEditConflict if the project no longer has the version of d, e.g. because it has been changed
+//     or deleted in the meantime, or an error if the update operation fails. On success the new version is stored in d.
 func (pm ProjectModel) Update(d *Project, altName, altDescription string) error {
 	query := `
         UPDATE project
         SET name = COALESCE(NULLIF($1, ''), name), 
-            description = $2
-        WHERE id = $3`
-
-	args := []interface{}{altName, altDescription, d.ID}
-	result, err := pm.DB.Exec(query, args...)
-	if err != nil {
-		return err
+            description = $2,
+            version = version + 1
+        WHERE id = $3 AND version = $4
+        RETURNING version`
+
+	args := []interface{}{altName, altDescription, d.ID, d.Version}
+	err := pm.conn().QueryRow(query, args...).Scan(&d.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrEditConflict
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-
-	if rowsAffected == 0 {
-		return errors.New("no record found")
-	}
-
-	return nil
+	return err
 }
 
 // Read retrieves a project from the database based on the provided project ID.
 // It returns a pointer to a Project struct and an error.
+// If no project with the ID exists, ErrRecordNotFound is returned.
 // If an error occurs during the database query or while scanning the row, it will return the error.
 func (pm ProjectModel) Read(id int64) (*Project, error) {
-	query := `SELECT id, name, description, creation_date FROM project WHERE id = $1`
+	query := `SELECT id, name, description, creation_date, version FROM project WHERE id = $1`
 
 	var project Project
-	err := pm.DB.QueryRow(query, id).Scan(
+	err := pm.conn().QueryRow(query, id).Scan(
 		&project.ID,
 		&project.Name,
 		&project.Description,
 		&project.CreationDate,
+		&project.Version,
 	)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("no record found")
+			return nil, ErrRecordNotFound
 		}
 		return nil, err
 	}
@@ -144,56 +148,73 @@ func (pm ProjectModel) Read(id int64) (*Project, error) {
 // DeleteExtensionsByProjectID deletes all extensions associated with a given project ID.
 func (pm ProjectModel) DeleteExtensionsByProjectID(projectID int64) error {
 	query := `DELETE FROM extension WHERE project_id = $1`
-	_, err := pm.DB.Exec(query, projectID)
+	_, err := pm.conn().Exec(query, projectID)
 	return err
 }
 
-func (pm ProjectModel) Delete(id int64, itemModel ItemModel) error {
+// Delete removes the project with the given ID together with its extensions and their items.
+// The deletion only succeeds if the project still has the given version, otherwise ErrEditConflict is returned
+// and nothing is deleted. If the model is not bound to a transaction, the deletion runs in a transaction of its own.
+// Returns: the deleted extensions and items as they were before the deletion.
+func (pm ProjectModel) Delete(id int64, version int32, itemModel ItemModel) ([]*Extension, []Item, error) {
+	if pm.Tx != nil {
+		itemModel.Tx = pm.Tx
+		return pm.deleteCascade(id, version, itemModel)
+	}
+
 	tx, err := pm.DB.Begin()
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	// Retrieve all extension IDs for the project
-	query := `SELECT id FROM extension WHERE project_id = $1`
-	rows, err := tx.Query(query, id)
+	pm.Tx = tx
+	itemModel.Tx = tx
+	extensions, items, err := pm.deleteCascade(id, version, itemModel)
 	if err != nil {
 		tx.Rollback()
-		return err
+		return nil, nil, err
 	}
-	defer rows.Close()
 
-	var extensionIDs []int64
-	for rows.Next() {
-		var extensionID int64
-		if err := rows.Scan(&extensionID); err != nil {
-			tx.Rollback()
-			return err
-		}
-		extensionIDs = append(extensionIDs, extensionID)
+	return extensions, items, tx.Commit()
+}
+
+// deleteCascade deletes the items and extensions of the project and the project itself if it has the given version.
+// The caller rolls the transaction back on ErrEditConflict.
+func (pm ProjectModel) deleteCascade(id int64, version int32, itemModel ItemModel) ([]*Extension, []Item, error) {
+	// Read the extensions of the project before they are deleted
+	extensions, err := ExtensionModel{DB: pm.DB, Tx: pm.Tx}.ReadAllByProject(id)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// Delete all items for each extension
-	for _, extensionID := range extensionIDs {
-		if err := itemModel.DeleteItemsByExtension(extensionID); err != nil {
-			tx.Rollback()
-			return err
+	var items []Item
+	for _, extension := range extensions {
+		deleted, err := itemModel.DeleteItemsByExtension(extension.ID)
+		if err != nil {
+			return nil, nil, err
 		}
+		items = append(items, deleted...)
 	}
 
 	// Delete all extensions for the project
 	if err := pm.DeleteExtensionsByProjectID(id); err != nil {
-		tx.Rollback()
-		return err
+		return nil, nil, err
 	}
 
 	// Delete the project itself
-	query = `DELETE FROM project WHERE id = $1`
-	_, err = tx.Exec(query, id)
+	query := `DELETE FROM project WHERE id = $1 AND version = $2`
+	result, err := pm.conn().Exec(query, id, version)
 	if err != nil {
-		tx.Rollback()
-		return err
+		return nil, nil, err
 	}
 
-	return tx.Commit()
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, nil, ErrEditConflict
+	}
+	return extensions, items, nil
 }
@@ -0,0 +1,113 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Types of search hits.
+const (
+	SearchTypeItem      = "item"
+	SearchTypeExtension = "extension"
+	SearchTypeProject   = "project"
+)
+
+// SearchTypes lists all types the search covers, in the order hits of equal rank are returned.
+var SearchTypes = []string{SearchTypeItem, SearchTypeExtension, SearchTypeProject}
+
+// SearchHit is a single result of a search.
+type SearchHit struct {
+	Type string `json:"type"`
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// Detail is the table name of an item or the description of an extension or project.
+	Detail   string  `json:"detail,omitempty"`
+	Typecode *int32  `json:"typecode,omitempty"`
+	Rank     float64 `json:"rank"`
+	Link     string  `json:"link"`
+}
+
+// SearchModel wraps the database connection pool.
+type SearchModel struct {
+	DB *sql.DB
+}
+
+// The queries of the searchable types. They share the parameters of Search:
+// $1 is the search term, $2 the LIKE pattern matching the term anywhere and $3 the pattern matching a prefix.
+// The rank is 1 for exact matches of a name or typecode, 0.75 for name prefixes, 0.5 for other name matches
+// and typecode prefixes and 0.25 for matches in table names or descriptions.
+var searchQueries = map[string]string{
+	SearchTypeItem: `
+		SELECT 'item', id, name, COALESCE(table_name, ''), typecode,
+			GREATEST(
+				CASE WHEN LOWER(name) = LOWER($1) THEN 1.0 WHEN name ILIKE $3 THEN 0.75 WHEN name ILIKE $2 THEN 0.5 ELSE 0 END,
+				CASE WHEN typecode::TEXT = $1 THEN 1.0 WHEN typecode::TEXT LIKE $3 THEN 0.5 ELSE 0 END,
+				CASE WHEN table_name ILIKE $2 THEN 0.25 ELSE 0 END
+			) AS rank
+		FROM item
+		WHERE name ILIKE $2 OR table_name ILIKE $2 OR typecode::TEXT LIKE $3`,
+	SearchTypeExtension: `
+		SELECT 'extension', id, name, COALESCE(description, ''), NULL::INTEGER,
+			GREATEST(
+				CASE WHEN LOWER(name) = LOWER($1) THEN 1.0 WHEN name ILIKE $3 THEN 0.75 WHEN name ILIKE $2 THEN 0.5 ELSE 0 END,
+				CASE WHEN description ILIKE $2 THEN 0.25 ELSE 0 END
+			) AS rank
+		FROM extension
+		WHERE name ILIKE $2 OR description ILIKE $2`,
+	SearchTypeProject: `
+		SELECT 'project', id, name, COALESCE(description, ''), NULL::INTEGER,
+			GREATEST(
+				CASE WHEN LOWER(name) = LOWER($1) THEN 1.0 WHEN name ILIKE $3 THEN 0.75 WHEN name ILIKE $2 THEN 0.5 ELSE 0 END,
+				CASE WHEN description ILIKE $2 THEN 0.25 ELSE 0 END
+			) AS rank
+		FROM project
+		WHERE name ILIKE $2 OR description ILIKE $2`,
+}
+
+// Search returns up to limit hits for term in the given types, best matches first.
+// The term matches case-insensitively anywhere in names, table names and descriptions and as prefix of typecodes.
+// LIKE wildcards in the term are matched literally.
+func (s SearchModel) Search(term string, types []string, limit int) ([]SearchHit, error) {
+	if len(types) == 0 {
+		return nil, errors.New("no search types given")
+	}
+
+	parts := make([]string, 0, len(types))
+	for _, t := range types {
+		query, ok := searchQueries[t]
+		if !ok {
+			return nil, fmt.Errorf("unknown search type %q", t)
+		}
+		parts = append(parts, query)
+	}
+
+	query := fmt.Sprintf(`SELECT type, id, name, detail, typecode, rank FROM (%s
+	) AS hits (type, id, name, detail, typecode, rank)
+	ORDER BY rank DESC, type, id
+	LIMIT $4`, strings.Join(parts, "\n\t\tUNION ALL"))
+
+	escaped := escapeLike(term)
+	rows, err := s.DB.Query(query, term, "%"+escaped+"%", escaped+"%", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hits := []SearchHit{}
+	for rows.Next() {
+		var hit SearchHit
+		var typecode sql.NullInt32
+		err = rows.Scan(&hit.Type, &hit.ID, &hit.Name, &hit.Detail, &typecode, &hit.Rank)
+		if err != nil {
+			return nil, err
+		}
+		if typecode.Valid {
+			hit.Typecode = &typecode.Int32
+		}
+		hits = append(hits, hit)
+	}
+
+	return hits, rows.Err()
+}
@@ -0,0 +1,297 @@
+package data
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Scopes of API tokens.
+const (
+	// TokenScopeRead permits reading.
+	TokenScopeRead = "read"
+	// TokenScopeWrite permits changes within the roles of the owner of the token.
+	TokenScopeWrite = "write"
+)
+
+// TokenScopes are all scopes which can be granted to API tokens.
+var TokenScopes = []string{TokenScopeRead, TokenScopeWrite}
+
+// APITokenPrefix starts every API token, so they can be told apart from the tokens of the identity provider
+// and are recognized by secret scanners.
+const APITokenPrefix = "tcr_"
+
+// tokenPrefixLength is the length of the start of a token which is stored to identify it in listings.
+const tokenPrefixLength = len(APITokenPrefix) + 8
+
+// ErrDuplicateServiceAccount is returned when a service account is inserted whose name is already used in the project.
+var ErrDuplicateServiceAccount = errors.New("duplicate service account")
+
+// ServiceAccount is an identity of automation, e.g. a build job, with a fixed role in one project.
+type ServiceAccount struct {
+	ID           int64     `json:"id"`
+	ProjectID    int64     `json:"project_id"`
+	Name         string    `json:"name"`
+	Role         string    `json:"role"`
+	CreationDate time.Time `json:"creation_date"`
+}
+
+// APIToken is a bearer token for automation owned by a user or by a service account.
+// Only the hash of the token is stored, the token itself is known only when it is created.
+type APIToken struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// Prefix is the start of the token, which identifies it in listings.
+	Prefix           string     `json:"prefix"`
+	UserID           NullInt64  `json:"user_id"`
+	ServiceAccountID NullInt64  `json:"service_account_id"`
+	Scopes           []string   `json:"scopes"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	LastUsedAt       *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	CreationDate     time.Time  `json:"creation_date"`
+	// Token is set only in the response to the creation of the token.
+	Token string `json:"token,omitempty"`
+	// User is the owner of a personal access token, read by Authenticate.
+	User *User `json:"-"`
+	// ServiceAccount is the owner of the token of a service account, read by Authenticate.
+	ServiceAccount *ServiceAccount `json:"-"`
+}
+
+// hashToken returns the hex encoded SHA-256 hash of token. Tokens are random, so they need no salt.
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// APITokenModel wraps the database connection pool.
+type APITokenModel struct {
+	DB *sql.DB
+}
+
+const apiTokenColumns = `t.id, t.name, t.prefix, t.user_id, t.service_account_id, t.scopes, t.expires_at, t.last_used_at,
+		t.revoked_at, t.creation_date`
+
+func scanAPIToken(s scanner, extra ...any) (*APIToken, error) {
+	var token APIToken
+	var lastUsedAt, revokedAt sql.NullTime
+	dest := []any{
+		&token.ID,
+		&token.Name,
+		&token.Prefix,
+		&token.UserID,
+		&token.ServiceAccountID,
+		pq.Array(&token.Scopes),
+		&token.ExpiresAt,
+		&lastUsedAt,
+		&revokedAt,
+		&token.CreationDate,
+	}
+	if err := s.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+	return &token, nil
+}
+
+func (m APITokenModel) query(where string, args ...any) ([]*APIToken, error) {
+	rows, err := m.DB.Query(`SELECT `+apiTokenColumns+` FROM api_token t WHERE `+where+` ORDER BY t.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*APIToken{}
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// ReadByUser retrieves the personal access tokens of a user, including revoked and expired ones.
+func (m APITokenModel) ReadByUser(userID int64) ([]*APIToken, error) {
+	return m.query(`t.user_id = $1`, userID)
+}
+
+// ReadByServiceAccount retrieves the tokens of a service account, including revoked and expired ones.
+func (m APITokenModel) ReadByServiceAccount(serviceAccountID int64) ([]*APIToken, error) {
+	return m.query(`t.service_account_id = $1`, serviceAccountID)
+}
+
+// Read retrieves the token with the given ID.
+// Returns: ErrRecordNotFound if no token with the ID exists.
+func (m APITokenModel) Read(id int64) (*APIToken, error) {
+	token, err := scanAPIToken(m.DB.QueryRow(`SELECT `+apiTokenColumns+` FROM api_token t WHERE t.id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRecordNotFound
+	}
+
+	return token, err
+}
+
+// Insert stores the hash of token.Token for the user or service account of token
+// and stores the ID, prefix and creation date in token.
+func (m APITokenModel) Insert(token *APIToken) error {
+	query := `
+		INSERT INTO api_token (name, prefix, token_hash, user_id, service_account_id, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, creation_date`
+
+	token.Prefix = token.Token[:min(tokenPrefixLength, len(token.Token))]
+	args := []any{
+		token.Name,
+		token.Prefix,
+		hashToken(token.Token),
+		token.UserID,
+		token.ServiceAccountID,
+		pq.Array(token.Scopes),
+		token.ExpiresAt,
+	}
+	return m.DB.QueryRow(query, args...).Scan(&token.ID, &token.CreationDate)
+}
+
+// Authenticate retrieves the valid token matching token together with its owner.
+// Returns: ErrRecordNotFound if the token is unknown, revoked or expired.
+func (m APITokenModel) Authenticate(token string) (*APIToken, error) {
+	query := `
+		SELECT ` + apiTokenColumns + `,
+			COALESCE(u.oauth_identifier, ''), COALESCE(u.email, ''), COALESCE(u.name, ''),
+			COALESCE(sa.project_id, 0), COALESCE(sa.name, ''), COALESCE(sa.role, '')
+		FROM api_token t
+		LEFT JOIN "user" u ON u.id = t.user_id
+		LEFT JOIN service_account sa ON sa.id = t.service_account_id
+		WHERE t.token_hash = $1 AND t.revoked_at IS NULL AND t.expires_at > CURRENT_TIMESTAMP`
+
+	var user User
+	var account ServiceAccount
+	result, err := scanAPIToken(m.DB.QueryRow(query, hashToken(token)),
+		&user.OAuthIdentifier, &user.Email, &user.Name, &account.ProjectID, &account.Name, &account.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRecordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if result.UserID.Valid {
+		user.ID = result.UserID.Int64
+		result.User = &user
+	} else {
+		account.ID = result.ServiceAccountID.Int64
+		result.ServiceAccount = &account
+	}
+	return result, nil
+}
+
+// Touch sets the time the token with the given ID was last used to now.
+func (m APITokenModel) Touch(id int64) error {
+	_, err := m.DB.Exec(`UPDATE api_token SET last_used_at = CURRENT_TIMESTAMP WHERE id = $1`, id)
+	return err
+}
+
+// Revoke invalidates the token with the given ID. Revoking a revoked token keeps its revocation date.
+// Returns: ErrRecordNotFound if no token with the ID exists.
+func (m APITokenModel) Revoke(id int64) error {
+	query := `UPDATE api_token SET revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP) WHERE id = $1`
+	result, err := m.DB.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// ServiceAccountModel wraps the database connection pool.
+type ServiceAccountModel struct {
+	DB *sql.DB
+}
+
+const serviceAccountColumns = `id, project_id, name, role, creation_date`
+
+func scanServiceAccount(s scanner) (*ServiceAccount, error) {
+	var account ServiceAccount
+	err := s.Scan(&account.ID, &account.ProjectID, &account.Name, &account.Role, &account.CreationDate)
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// ReadByProject retrieves the service accounts of a project ordered by ID.
+func (m ServiceAccountModel) ReadByProject(projectID int64) ([]*ServiceAccount, error) {
+	query := `SELECT ` + serviceAccountColumns + ` FROM service_account WHERE project_id = $1 ORDER BY id`
+	rows, err := m.DB.Query(query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []*ServiceAccount{}
+	for rows.Next() {
+		account, err := scanServiceAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+
+	return accounts, rows.Err()
+}
+
+// Read retrieves the service account with the given ID.
+// Returns: ErrRecordNotFound if no service account with the ID exists.
+func (m ServiceAccountModel) Read(id int64) (*ServiceAccount, error) {
+	query := `SELECT ` + serviceAccountColumns + ` FROM service_account WHERE id = $1`
+	account, err := scanServiceAccount(m.DB.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRecordNotFound
+	}
+
+	return account, err
+}
+
+// Insert adds a new service account and stores its ID and creation date in account.
+// Returns: ErrDuplicateServiceAccount if the project has a service account with the same name.
+func (m ServiceAccountModel) Insert(account *ServiceAccount) error {
+	query := `
+		INSERT INTO service_account (project_id, name, role)
+		VALUES ($1, $2, $3)
+		RETURNING id, creation_date`
+
+	err := m.DB.QueryRow(query, account.ProjectID, account.Name, account.Role).Scan(&account.ID, &account.CreationDate)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrDuplicateServiceAccount
+	}
+
+	return err
+}
+
+// Delete removes the service account with the given ID together with its tokens.
+func (m ServiceAccountModel) Delete(id int64) error {
+	_, err := m.DB.Exec(`DELETE FROM service_account WHERE id = $1`, id)
+	return err
+}
@@ -0,0 +1,334 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Roles of users in a project.
+const (
+	// RoleAdmin manages the project, its extensions and its members.
+	RoleAdmin = "admin"
+	// RoleDeveloper registers the items of the project.
+	RoleDeveloper = "developer"
+)
+
+// Roles are all roles which can be assigned to users in a project.
+var Roles = []string{RoleAdmin, RoleDeveloper}
+
+// Sources of role assignments.
+const (
+	// RoleSourceManual marks roles assigned by an admin.
+	RoleSourceManual = "manual"
+	// RoleSourceGroup marks roles granted by a group of the identity provider, see RoleAssignmentModel.SyncGroupRoles.
+	RoleSourceGroup = "group"
+)
+
+// ErrDuplicateUser is returned when a user is inserted whose OAuth identifier is already registered.
+var ErrDuplicateUser = errors.New("duplicate user")
+
+// User is a person known to the registry by the subject of the tokens of the identity provider.
+type User struct {
+	ID int64 `json:"id"`
+	// OAuthIdentifier is the subject (sub claim) of the tokens of the user.
+	OAuthIdentifier string    `json:"oauth_identifier"`
+	Email           string    `json:"email"`
+	Name            string    `json:"name"`
+	CreationDate    time.Time `json:"creation_date"`
+}
+
+// RoleAssignment is the role of a user in a project. Email and ProjectName are read for display only.
+type RoleAssignment struct {
+	ID          int64  `json:"id"`
+	UserID      int64  `json:"user_id"`
+	Email       string `json:"email"`
+	ProjectID   int64  `json:"project_id"`
+	ProjectName string `json:"project_name"`
+	Role        string `json:"role"`
+	// Source tells whether the role was assigned manually or granted by a group.
+	Source       string    `json:"source"`
+	CreationDate time.Time `json:"creation_date"`
+}
+
+// UserModel wraps the database connection pool.
+type UserModel struct {
+	DB *sql.DB
+}
+
+const userColumns = `id, oauth_identifier, email, name, creation_date`
+
+func scanUser(s scanner) (*User, error) {
+	var user User
+	err := s.Scan(&user.ID, &user.OAuthIdentifier, &user.Email, &user.Name, &user.CreationDate)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ReadAll retrieves all users ordered by ID.
+func (m UserModel) ReadAll() ([]*User, error) {
+	query := `SELECT ` + userColumns + ` FROM "user" ORDER BY id`
+	rows, err := m.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// Read retrieves the user with the given ID.
+// Returns: ErrRecordNotFound if no user with the ID exists.
+func (m UserModel) Read(id int64) (*User, error) {
+	query := `SELECT ` + userColumns + ` FROM "user" WHERE id = $1`
+	user, err := scanUser(m.DB.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRecordNotFound
+	}
+
+	return user, err
+}
+
+// ReadByOAuthIdentifier retrieves the user with the given OAuth identifier, i.e. the subject of the user's tokens.
+// Returns: ErrRecordNotFound if the user is not registered.
+func (m UserModel) ReadByOAuthIdentifier(oauthIdentifier string) (*User, error) {
+	query := `SELECT ` + userColumns + ` FROM "user" WHERE oauth_identifier = $1`
+	user, err := scanUser(m.DB.QueryRow(query, oauthIdentifier))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRecordNotFound
+	}
+
+	return user, err
+}
+
+// Provision registers the user with the OAuth identifier of user if it is unknown, otherwise it updates
+// the email and name of the user if they are set. The ID and creation date are stored in user.
+// Returns: true if the user has been registered.
+func (m UserModel) Provision(user *User) (bool, error) {
+	query := `
+		INSERT INTO "user" (oauth_identifier, email, name)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (oauth_identifier) DO UPDATE
+		SET email = COALESCE(NULLIF(EXCLUDED.email, ''), "user".email), name = COALESCE(NULLIF(EXCLUDED.name, ''), "user".name)
+		RETURNING id, creation_date, xmax = 0`
+
+	var created bool
+	err := m.DB.QueryRow(query, user.OAuthIdentifier, user.Email, user.Name).Scan(&user.ID, &user.CreationDate, &created)
+	return created, err
+}
+
+// Insert adds a new user and stores its ID and creation date in user.
+// Returns: ErrDuplicateUser if a user with the OAuth identifier already exists.
+func (m UserModel) Insert(user *User) error {
+	query := `
+		INSERT INTO "user" (oauth_identifier, email, name)
+		VALUES ($1, $2, $3)
+		RETURNING id, creation_date`
+
+	err := m.DB.QueryRow(query, user.OAuthIdentifier, user.Email, user.Name).Scan(&user.ID, &user.CreationDate)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrDuplicateUser
+	}
+
+	return err
+}
+
+// RoleAssignmentModel wraps the database connection pool.
+type RoleAssignmentModel struct {
+	DB *sql.DB
+	Tx *sql.Tx
+}
+
+func (m RoleAssignmentModel) conn() querier {
+	return conn(m.DB, m.Tx)
+}
+
+const roleAssignmentQuery = `
+		SELECT ra.role_assignment_id, ra.user_id, u.email, ra.project_id, p.name, ra.role, ra.source, ra.creation_date
+		FROM role_assignment ra
+		JOIN "user" u ON u.id = ra.user_id
+		JOIN project p ON p.id = ra.project_id`
+
+func (m RoleAssignmentModel) query(where, orderBy string, args ...any) ([]*RoleAssignment, error) {
+	rows, err := m.conn().Query(roleAssignmentQuery+` WHERE `+where+` ORDER BY `+orderBy, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assignments := []*RoleAssignment{}
+	for rows.Next() {
+		var a RoleAssignment
+		err := rows.Scan(&a.ID, &a.UserID, &a.Email, &a.ProjectID, &a.ProjectName, &a.Role, &a.Source, &a.CreationDate)
+		if err != nil {
+			return nil, err
+		}
+		assignments = append(assignments, &a)
+	}
+
+	return assignments, rows.Err()
+}
+
+// ReadByUser retrieves the roles of a user in all projects, ordered by project.
+func (m RoleAssignmentModel) ReadByUser(userID int64) ([]*RoleAssignment, error) {
+	return m.query(`ra.user_id = $1`, `ra.project_id`, userID)
+}
+
+// ReadByProject retrieves the roles of all members of a project, ordered by user.
+func (m RoleAssignmentModel) ReadByProject(projectID int64) ([]*RoleAssignment, error) {
+	return m.query(`ra.project_id = $1`, `ra.user_id`, projectID)
+}
+
+// Read retrieves the role of a user in a project.
+// Returns: ErrRecordNotFound if the user has no role in the project.
+func (m RoleAssignmentModel) Read(userID, projectID int64) (*RoleAssignment, error) {
+	assignments, err := m.query(`ra.user_id = $1 AND ra.project_id = $2`, `ra.project_id`, userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if len(assignments) == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return assignments[0], nil
+}
+
+// ReadRole retrieves the role in a project of the user with the given OAuth identifier.
+// Returns: ErrRecordNotFound if the user is not registered or has no role in the project.
+func (m RoleAssignmentModel) ReadRole(oauthIdentifier string, projectID int64) (string, error) {
+	query := `
+		SELECT ra.role
+		FROM role_assignment ra
+		JOIN "user" u ON u.id = ra.user_id
+		WHERE u.oauth_identifier = $1 AND ra.project_id = $2`
+
+	var role string
+	err := m.conn().QueryRow(query, oauthIdentifier, projectID).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrRecordNotFound
+	}
+
+	return role, err
+}
+
+// Assign manually gives a user the role of assignment in a project, replacing a role the user had before,
+// and stores the ID, source and creation date of the assignment in it.
+// Returns: true if the user had no role in the project before.
+func (m RoleAssignmentModel) Assign(assignment *RoleAssignment) (bool, error) {
+	query := `
+		INSERT INTO role_assignment (user_id, project_id, role, source)
+		VALUES ($1, $2, $3, 'manual')
+		ON CONFLICT (user_id, project_id) DO UPDATE SET role = EXCLUDED.role, source = EXCLUDED.source
+		RETURNING role_assignment_id, creation_date, xmax = 0`
+
+	assignment.Source = RoleSourceManual
+	var created bool
+	err := m.conn().QueryRow(query, assignment.UserID, assignment.ProjectID, assignment.Role).
+		Scan(&assignment.ID, &assignment.CreationDate, &created)
+	return created, err
+}
+
+// RoleChange is a change of a role by SyncGroupRoles. Before is nil for granted roles, After for revoked ones.
+type RoleChange struct {
+	Before *RoleAssignment
+	After  *RoleAssignment
+}
+
+// SyncGroupRoles replaces the roles of a user granted by groups in the given projects with roles,
+// which maps project IDs to the roles the groups of the user grant. Manually assigned roles are kept,
+// roles in projects which do not exist are skipped.
+// Returns: the roles which have been granted, changed or revoked.
+func (m RoleAssignmentModel) SyncGroupRoles(userID int64, projectIDs []int64, roles map[int64]string) ([]RoleChange, error) {
+	granted := make([]int64, 0, len(roles))
+	for projectID := range roles {
+		granted = append(granted, projectID)
+	}
+	slices.Sort(granted)
+
+	query := `
+		DELETE FROM role_assignment
+		WHERE user_id = $1 AND source = 'group' AND project_id = ANY($2) AND NOT project_id = ANY($3)
+		RETURNING role_assignment_id, project_id, role, creation_date`
+	rows, err := m.conn().Query(query, userID, pq.Array(projectIDs), pq.Array(granted))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []RoleChange
+	for rows.Next() {
+		revoked := RoleAssignment{UserID: userID, Source: RoleSourceGroup}
+		if err := rows.Scan(&revoked.ID, &revoked.ProjectID, &revoked.Role, &revoked.CreationDate); err != nil {
+			return nil, err
+		}
+		changes = append(changes, RoleChange{Before: &revoked})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// The previous role is read from the snapshot of the statement, i.e. before the upsert.
+	query = `
+		WITH previous AS (SELECT role FROM role_assignment WHERE user_id = $1 AND project_id = $2)
+		INSERT INTO role_assignment (user_id, project_id, role, source)
+		SELECT $1, id, $3, 'group' FROM project WHERE id = $2
+		ON CONFLICT (user_id, project_id) DO UPDATE SET role = EXCLUDED.role
+		WHERE role_assignment.source = 'group' AND role_assignment.role <> EXCLUDED.role
+		RETURNING role_assignment_id, creation_date, COALESCE((SELECT role FROM previous), '')`
+	for _, projectID := range granted {
+		assignment := RoleAssignment{UserID: userID, ProjectID: projectID, Role: roles[projectID], Source: RoleSourceGroup}
+		var previousRole string
+		err := m.conn().QueryRow(query, userID, projectID, assignment.Role).Scan(&assignment.ID, &assignment.CreationDate, &previousRole)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		change := RoleChange{After: &assignment}
+		if previousRole != "" {
+			before := assignment
+			before.Role = previousRole
+			change.Before = &before
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// Revoke removes the role of a user in a project.
+// Returns: ErrRecordNotFound if the user has no role in the project.
+func (m RoleAssignmentModel) Revoke(userID, projectID int64) error {
+	query := `DELETE FROM role_assignment WHERE user_id = $1 AND project_id = $2`
+	result, err := m.conn().Exec(query, userID, projectID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
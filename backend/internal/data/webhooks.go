@@ -0,0 +1,326 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Statuses of webhook deliveries.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+	DeliveryAbandoned = "abandoned"
+)
+
+// Webhook is a subscription of another system to the changes of a project or, without project, of all projects.
+// The secret signs the payloads of the deliveries and is never part of the JSON representation.
+type Webhook struct {
+	ID           int64     `json:"id"`
+	ProjectID    NullInt64 `json:"project_id"`
+	URL          string    `json:"url"`
+	Secret       string    `json:"-"`
+	Events       []string  `json:"events"`
+	Description  string    `json:"description"`
+	Active       bool      `json:"active"`
+	CreationDate time.Time `json:"creation_date"`
+	Version      int32     `json:"version"`
+}
+
+// WebhookDelivery is the delivery of an event to a webhook together with the outcome of its last attempt.
+type WebhookDelivery struct {
+	ID        int64  `json:"id"`
+	WebhookID int64  `json:"webhook_id"`
+	Event     string `json:"event"`
+	// Payload is the JSON body sent to the webhook.
+	Payload string `json:"payload"`
+	// RedeliveryOf is the ID of the delivery this one repeats, if any.
+	RedeliveryOf   NullInt64 `json:"redelivery_of"`
+	Status         string    `json:"status"`
+	Attempts       int       `json:"attempts"`
+	ResponseStatus int       `json:"response_status,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	// NextAttempt is nil once the delivery has succeeded or failed for good.
+	NextAttempt    *time.Time `json:"next_attempt,omitempty"`
+	CreationDate   time.Time  `json:"creation_date"`
+	CompletionDate *time.Time `json:"completion_date,omitempty"`
+}
+
+// WebhookModel wraps the database connection pool.
+type WebhookModel struct {
+	DB *sql.DB
+}
+
+const webhookColumns = `id, project_id, url, secret, events, description, active, creation_date, version`
+
+const deliveryColumns = `id, webhook_id, event, payload, redelivery_of, status, attempts, response_status, error,
+		next_attempt, creation_date, completion_date`
+
+// scanner is implemented by *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWebhook(s scanner) (*Webhook, error) {
+	var webhook Webhook
+	err := s.Scan(
+		&webhook.ID,
+		&webhook.ProjectID,
+		&webhook.URL,
+		&webhook.Secret,
+		pq.Array(&webhook.Events),
+		&webhook.Description,
+		&webhook.Active,
+		&webhook.CreationDate,
+		&webhook.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+func scanDelivery(s scanner) (*WebhookDelivery, error) {
+	var delivery WebhookDelivery
+	var responseStatus sql.NullInt32
+	var deliveryError sql.NullString
+	var nextAttempt, completionDate sql.NullTime
+	err := s.Scan(
+		&delivery.ID,
+		&delivery.WebhookID,
+		&delivery.Event,
+		&delivery.Payload,
+		&delivery.RedeliveryOf,
+		&delivery.Status,
+		&delivery.Attempts,
+		&responseStatus,
+		&deliveryError,
+		&nextAttempt,
+		&delivery.CreationDate,
+		&completionDate,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.ResponseStatus = int(responseStatus.Int32)
+	delivery.Error = deliveryError.String
+	if nextAttempt.Valid {
+		delivery.NextAttempt = &nextAttempt.Time
+	}
+	if completionDate.Valid {
+		delivery.CompletionDate = &completionDate.Time
+	}
+	return &delivery, nil
+}
+
+// ReadAll retrieves all webhooks ordered by their IDs.
+func (m WebhookModel) ReadAll() ([]*Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhook ORDER BY id`
+	return m.query(query)
+}
+
+// ReadSubscribed retrieves the active webhooks subscribed to event of the project with the given ID.
+// Webhooks without project receive the events of all projects and of resources belonging to no project (projectID 0).
+func (m WebhookModel) ReadSubscribed(projectID int64, event string) ([]*Webhook, error) {
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhook
+		WHERE active AND (project_id IS NULL OR project_id = $1) AND $2 = ANY(events)
+		ORDER BY id`
+	return m.query(query, projectID, event)
+}
+
+func (m WebhookModel) query(query string, args ...any) ([]*Webhook, error) {
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []*Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+// Read retrieves the webhook with the given ID.
+// If no webhook with the ID exists, ErrRecordNotFound is returned.
+func (m WebhookModel) Read(id int64) (*Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhook WHERE id = $1`
+	webhook, err := scanWebhook(m.DB.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRecordNotFound
+	}
+
+	return webhook, err
+}
+
+// Insert adds a new webhook and stores its ID, creation date and version in webhook.
+func (m WebhookModel) Insert(webhook *Webhook) error {
+	query := `
+		INSERT INTO webhook (project_id, url, secret, events, description, active)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, creation_date, version`
+
+	args := []any{webhook.ProjectID, webhook.URL, webhook.Secret, pq.Array(webhook.Events), webhook.Description, webhook.Active}
+	return m.DB.QueryRow(query, args...).Scan(&webhook.ID, &webhook.CreationDate, &webhook.Version)
+}
+
+// Update stores the URL, secret, events, description and active flag of webhook and increments its version.
+// The project of a webhook cannot be changed.
+// Returns: ErrEditConflict if the webhook no longer has the version of webhook. On success the new version is stored in webhook.
+func (m WebhookModel) Update(webhook *Webhook) error {
+	query := `
+		UPDATE webhook
+		SET url = $1, secret = $2, events = $3, description = $4, active = $5, version = version + 1
+		WHERE id = $6 AND version = $7
+		RETURNING version`
+
+	args := []any{webhook.URL, webhook.Secret, pq.Array(webhook.Events), webhook.Description, webhook.Active, webhook.ID, webhook.Version}
+	err := m.DB.QueryRow(query, args...).Scan(&webhook.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrEditConflict
+	}
+
+	return err
+}
+
+// Delete removes the webhook with the given ID together with its deliveries.
+// If no webhook with the ID exists, ErrRecordNotFound is returned.
+func (m WebhookModel) Delete(id int64) error {
+	query := `DELETE FROM webhook WHERE id = $1`
+	result, err := m.DB.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// InsertDelivery queues a delivery for its first attempt and stores its ID, status, next attempt and creation date.
+func (m WebhookModel) InsertDelivery(delivery *WebhookDelivery) error {
+	query := `
+		INSERT INTO webhook_delivery (webhook_id, event, payload, redelivery_of)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, status, next_attempt, creation_date`
+
+	var nextAttempt time.Time
+	args := []any{delivery.WebhookID, delivery.Event, delivery.Payload, delivery.RedeliveryOf}
+	err := m.DB.QueryRow(query, args...).Scan(&delivery.ID, &delivery.Status, &nextAttempt, &delivery.CreationDate)
+	if err != nil {
+		return err
+	}
+
+	delivery.NextAttempt = &nextAttempt
+	return nil
+}
+
+// ReadDeliveries retrieves the last deliveries of the webhook with the given ID, newest first.
+func (m WebhookModel) ReadDeliveries(webhookID int64, limit int) ([]*WebhookDelivery, error) {
+	query := `
+		SELECT ` + deliveryColumns + `
+		FROM webhook_delivery
+		WHERE webhook_id = $1
+		ORDER BY id DESC
+		LIMIT $2`
+	return m.queryDeliveries(query, webhookID, limit)
+}
+
+// ReadDelivery retrieves the delivery with the given ID of the webhook with the given ID.
+// If the webhook has no such delivery, ErrRecordNotFound is returned.
+func (m WebhookModel) ReadDelivery(webhookID, id int64) (*WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_delivery WHERE id = $1 AND webhook_id = $2`
+	delivery, err := scanDelivery(m.DB.QueryRow(query, id, webhookID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRecordNotFound
+	}
+
+	return delivery, err
+}
+
+// ClaimDueDeliveries returns up to limit pending deliveries whose next attempt is due.
+// Their next attempt is postponed by lease, so other servers do not attempt them at the same time
+// and they are attempted again should this server stop before storing the outcome with CompleteAttempt.
+func (m WebhookModel) ClaimDueDeliveries(limit int, lease time.Duration) ([]*WebhookDelivery, error) {
+	query := `
+		UPDATE webhook_delivery
+		SET next_attempt = CURRENT_TIMESTAMP + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id FROM webhook_delivery
+			WHERE next_attempt <= CURRENT_TIMESTAMP
+			ORDER BY next_attempt
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED)
+		RETURNING ` + deliveryColumns
+	return m.queryDeliveries(query, limit, lease.Seconds())
+}
+
+func (m WebhookModel) queryDeliveries(query string, args ...any) ([]*WebhookDelivery, error) {
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// CompleteAttempt stores the outcome of an attempt: the status, number of attempts, response status, error
+// and next attempt of delivery. A delivery without next attempt is completed.
+func (m WebhookModel) CompleteAttempt(delivery *WebhookDelivery) error {
+	query := `
+		UPDATE webhook_delivery
+		SET status = $1, attempts = $2, response_status = $3, error = $4, next_attempt = $5,
+			completion_date = CASE WHEN $5::timestamptz IS NULL THEN CURRENT_TIMESTAMP END
+		WHERE id = $6`
+
+	args := []any{
+		delivery.Status,
+		delivery.Attempts,
+		sql.NullInt32{Int32: int32(delivery.ResponseStatus), Valid: delivery.ResponseStatus != 0},
+		sql.NullString{String: delivery.Error, Valid: delivery.Error != ""},
+		delivery.NextAttempt,
+		delivery.ID,
+	}
+	_, err := m.DB.Exec(query, args...)
+	return err
+}
+
+// DeleteDeliveriesBefore deletes the completed deliveries created before t and returns the number of deleted deliveries.
+func (m WebhookModel) DeleteDeliveriesBefore(t time.Time) (int64, error) {
+	query := `DELETE FROM webhook_delivery WHERE next_attempt IS NULL AND creation_date < $1`
+	result, err := m.DB.Exec(query, t)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
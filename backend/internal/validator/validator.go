@@ -0,0 +1,108 @@
+// Package validator collects the violations of the fields of a request, so all of them
+// can be reported to the client at once instead of only the first one.
+//
+//	v := validator.New()
+//	v.Check(validator.NotBlank(req.Name), "name", "must be provided")
+//	v.Check(validator.MaxLength(req.Name, 255), "name", "must not be longer than 255 characters")
+//	if !v.Valid() {
+//		return v.Errors
+//	}
+package validator
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// FieldError describes why the value of a single field is invalid.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Validator collects the violations of a request in the order they are checked.
+type Validator struct {
+	Errors []FieldError
+}
+
+// New returns a Validator without violations.
+func New() *Validator {
+	return &Validator{}
+}
+
+// Valid reports whether no violation has been added.
+func (v *Validator) Valid() bool {
+	return len(v.Errors) == 0
+}
+
+// AddError adds a violation of field. Only the first violation of a field is kept,
+// since later checks of a field usually depend on the earlier ones, e.g. a pattern on presence.
+func (v *Validator) AddError(field, message string) {
+	if v.HasError(field) {
+		return
+	}
+	v.Errors = append(v.Errors, FieldError{Field: field, Message: message})
+}
+
+// Check adds a violation of field if ok is false.
+func (v *Validator) Check(ok bool, field, message string) {
+	if !ok {
+		v.AddError(field, message)
+	}
+}
+
+// HasError reports whether a violation of field has been added.
+func (v *Validator) HasError(field string) bool {
+	for _, err := range v.Errors {
+		if err.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// NotBlank reports whether s contains at least one character which is not whitespace.
+func NotBlank(s string) bool {
+	return strings.TrimSpace(s) != ""
+}
+
+// Trimmed reports whether s neither starts nor ends with whitespace.
+func Trimmed(s string) bool {
+	return strings.TrimSpace(s) == s
+}
+
+// MaxLength reports whether s has at most n characters. Characters are counted as runes,
+// like the lengths of VARCHAR columns.
+func MaxLength(s string, n int) bool {
+	return utf8.RuneCountInString(s) <= n
+}
+
+// Printable reports whether s is valid UTF-8 without control characters such as line breaks.
+func Printable(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches reports whether s matches the regular expression rx.
+func Matches(s string, rx *regexp.Regexp) bool {
+	return rx.MatchString(s)
+}
+
+// PermittedValue reports whether value is one of permitted.
+func PermittedValue[T comparable](value T, permitted ...T) bool {
+	for _, p := range permitted {
+		if value == p {
+			return true
+		}
+	}
+	return false
+}
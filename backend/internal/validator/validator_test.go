@@ -0,0 +1,78 @@
+package validator
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatorReportsAllViolations(t *testing.T) {
+	v := New()
+	assert.True(t, v.Valid())
+
+	v.Check(true, "name", "must be provided")
+	v.Check(false, "name", "must be provided")
+	v.Check(false, "scope", "must be Shared or Project")
+
+	assert.False(t, v.Valid())
+	assert.Equal(t, []FieldError{
+		{Field: "name", Message: "must be provided"},
+		{Field: "scope", Message: "must be Shared or Project"},
+	}, v.Errors)
+}
+
+func TestValidatorKeepsFirstViolationOfField(t *testing.T) {
+	v := New()
+
+	v.Check(false, "name", "must be provided")
+	v.Check(false, "name", "must not be longer than 255 characters")
+
+	assert.True(t, v.HasError("name"))
+	assert.False(t, v.HasError("table_name"))
+	assert.Equal(t, []FieldError{{Field: "name", Message: "must be provided"}}, v.Errors)
+}
+
+func TestNotBlank(t *testing.T) {
+	assert.True(t, NotBlank("core"))
+	assert.True(t, NotBlank(" core "))
+	assert.False(t, NotBlank(""))
+	assert.False(t, NotBlank(" \t\n"))
+	assert.False(t, NotBlank(" "))
+}
+
+func TestTrimmed(t *testing.T) {
+	assert.True(t, Trimmed("core"))
+	assert.True(t, Trimmed("shop core"))
+	assert.True(t, Trimmed(""))
+	assert.False(t, Trimmed(" core"))
+	assert.False(t, Trimmed("core\n"))
+}
+
+func TestMaxLengthCountsCharacters(t *testing.T) {
+	assert.True(t, MaxLength(strings.Repeat("a", 255), 255))
+	assert.False(t, MaxLength(strings.Repeat("a", 256), 255))
+	// Two bytes per character, but only 255 characters.
+	assert.True(t, MaxLength(strings.Repeat("ä", 255), 255))
+}
+
+func TestPrintable(t *testing.T) {
+	assert.True(t, Printable("Größe in €"))
+	assert.True(t, Printable(""))
+	assert.False(t, Printable("line\nbreak"))
+	assert.False(t, Printable("null\x00byte"))
+	assert.False(t, Printable("invalid \xff utf-8"))
+}
+
+func TestMatches(t *testing.T) {
+	rx := regexp.MustCompile(`^[a-z]+$`)
+	assert.True(t, Matches("core", rx))
+	assert.False(t, Matches("Core", rx))
+}
+
+func TestPermittedValue(t *testing.T) {
+	assert.True(t, PermittedValue("Shared", "Shared", "Project"))
+	assert.False(t, PermittedValue("shared", "Shared", "Project"))
+	assert.False(t, PermittedValue(3, 1, 2))
+}
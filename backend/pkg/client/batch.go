@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// Actions and types of batch operations.
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+
+	BatchProject   = "project"
+	BatchExtension = "extension"
+	BatchItem      = "item"
+)
+
+// BatchOperation creates, updates or deletes a single project, extension or item as part of Batch.
+// Body is the request of the corresponding single call, e.g. ItemRequest to create an item.
+// ID and ID fields of the body may reference the resource of an earlier operation as "$<ref>.<field>",
+// e.g. "$core.id", which is why they are not typed.
+type BatchOperation struct {
+	Ref    string `json:"ref,omitempty"`
+	Action string `json:"action"`
+	Type   string `json:"type"`
+	ID     any    `json:"id,omitempty"`
+	// Version makes the operation fail with 412 Precondition Failed if the resource has another version.
+	Version *int32 `json:"version,omitempty"`
+	Body    any    `json:"body,omitempty"`
+}
+
+// BatchResult is the result of a single operation of a batch.
+// Resource is the created or updated resource; decode it into Project, Extension or Item according to Type.
+type BatchResult struct {
+	Index    int             `json:"index"`
+	Ref      string          `json:"ref,omitempty"`
+	Action   string          `json:"action"`
+	Type     string          `json:"type"`
+	Status   int             `json:"status"`
+	Resource json.RawMessage `json:"resource,omitempty"`
+}
+
+// Batch executes the operations in order in a single transaction and returns their results.
+// If an operation fails, nothing is changed and an *Error with the status of the failed operation
+// is returned. Its message names the failed operation and its fields the invalid fields of its body.
+func (c *Client) Batch(ctx context.Context, ops []BatchOperation) ([]BatchResult, error) {
+	var resp struct {
+		Results []BatchResult `json:"results"`
+	}
+	err := c.do(ctx, http.MethodPost, "/batch", nil, struct {
+		Operations []BatchOperation `json:"operations"`
+	}{ops}, &resp)
+	return resp.Results, err
+}
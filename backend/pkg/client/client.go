@@ -0,0 +1,248 @@
+// Package client is the Go client for the REST API of the Typecode Registry.
+//
+// The package follows semantic versioning, see Version. Backwards incompatible changes of the
+// exported API are only made together with a new major version.
+//
+//	c, err := client.New("http://localhost:8080", client.WithToken(token))
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	item, err := c.CreateItem(ctx, client.ItemRequest{Name: "Product", TableName: "products", ExtensionID: 3})
+//	if errors.Is(err, client.ErrNotFound) {
+//		log.Fatal("extension 3 does not exist")
+//	}
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version is the version of the client package.
+const Version = "1.0.0"
+
+// Client talks to a single Typecode Registry instance. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	token      string
+	userAgent  string
+	maxRetries int
+	backoff    time.Duration
+	// idempotencyKeys enables Idempotency-Key headers and retries for POST requests.
+	idempotencyKeys bool
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient replaces the http.Client used to send requests.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithToken sends the token as bearer token with every request.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithUserAgent replaces the default User-Agent header.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithRetries sets how often idempotent requests (GET, PUT, DELETE) are retried after network errors,
+// 429 and 502-504 responses, and the delay before the first retry. The delay doubles with every retry
+// unless the server sends a Retry-After header. Zero retries disable the behaviour.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.backoff = backoff
+	}
+}
+
+// WithIdempotencyKeys sends every POST request with a random Idempotency-Key header, which is kept
+// for all attempts of the request. This makes POST requests safe to retry, so they are retried like
+// idempotent requests, see WithRetries. The server answers repeated attempts with the stored response
+// of the first one, e.g. the item created with the first allocated typecode.
+func WithIdempotencyKeys() Option {
+	return func(c *Client) {
+		c.idempotencyKeys = true
+	}
+}
+
+// New creates a client for the API reachable at baseURL, e.g. http://localhost:8080.
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base url: %w", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base url %q: scheme must be http or https", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		userAgent:  "typecode-registry-client/" + Version,
+		maxRetries: 2,
+		backoff:    200 * time.Millisecond,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// Healthcheck returns nil if the API is up and running.
+func (c *Client) Healthcheck(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/healthcheck", nil, nil, nil)
+}
+
+// isIdempotent reports whether a request with the given method may safely be sent more than once.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
+
+// isRetryableStatus reports whether a response with the given status is worth retrying.
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// do sends a request with an optional JSON body and decodes the JSON response into dst.
+// dst may be nil if the response body is not of interest.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, dst any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	u := c.baseURL.JoinPath(path)
+	u.RawQuery = query.Encode()
+
+	retries := 0
+	if isIdempotent(method) {
+		retries = c.maxRetries
+	}
+
+	idempotencyKey := ""
+	if method == http.MethodPost && c.idempotencyKeys {
+		key := make([]byte, 16)
+		if _, err := rand.Read(key); err != nil {
+			return err
+		}
+		idempotencyKey = hex.EncodeToString(key)
+		retries = c.maxRetries
+	}
+
+	delay := c.backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, u.String(), payload, idempotencyKey)
+		if err == nil && (attempt >= retries || !isRetryableStatus(resp.StatusCode)) {
+			defer resp.Body.Close()
+			return decodeResponse(method, path, resp, dst)
+		}
+
+		if err != nil && (attempt >= retries || ctx.Err() != nil) {
+			return err
+		}
+
+		wait := delay
+		if resp != nil {
+			if seconds, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil && seconds >= 0 {
+				wait = time.Duration(seconds) * time.Second
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// send executes a single attempt of a request. An empty idempotencyKey is not sent.
+func (c *Client) send(ctx context.Context, method, target string, payload []byte, idempotencyKey string) (*http.Response, error) {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	return c.httpClient.Do(req)
+}
+
+// decodeResponse turns error responses into *Error and decodes successful responses into dst.
+func decodeResponse(method, path string, resp *http.Response, dst any) error {
+	if resp.StatusCode >= http.StatusBadRequest {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return newError(method, path, resp.StatusCode, resp.Header.Get("Content-Type"), msg)
+	}
+
+	if dst == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(dst); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("decoding response of %s %s: %w", method, path, err)
+	}
+
+	return nil
+}
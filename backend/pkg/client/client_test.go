@@ -0,0 +1,277 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupTestClient(t *testing.T, handler http.HandlerFunc, opts ...Option) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	opts = append([]Option{WithRetries(2, time.Millisecond)}, opts...)
+	c, err := New(server.URL, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestNewRejectsInvalidBaseURL(t *testing.T) {
+	_, err := New("localhost:8080")
+	assert.Error(t, err)
+
+	_, err = New("ftp://localhost")
+	assert.Error(t, err)
+}
+
+func TestRequestsCarryTokenAndUserAgent(t *testing.T) {
+	c := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Equal(t, "typecode-registry-client/"+Version, r.Header.Get("User-Agent"))
+		_, _ = w.Write([]byte(`{"projects": [{"id": 1, "name": "Alpha"}]}`))
+	}, WithToken("secret"))
+
+	projects, err := c.ListProjects(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Project{{ID: 1, Name: "Alpha"}}, projects)
+}
+
+func TestErrorsAreMappedFromStatusCodes(t *testing.T) {
+	testCases := map[int]error{
+		http.StatusBadRequest:          ErrBadRequest,
+		http.StatusUnauthorized:        ErrUnauthorized,
+		http.StatusForbidden:           ErrForbidden,
+		http.StatusNotFound:            ErrNotFound,
+		http.StatusMethodNotAllowed:    ErrMethodNotAllowed,
+		http.StatusConflict:            ErrConflict,
+		http.StatusInternalServerError: ErrServer,
+	}
+
+	for status, expected := range testCases {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			c := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "details", status)
+			})
+
+			_, err := c.GetItem(context.Background(), 1)
+
+			assert.True(t, errors.Is(err, expected), "expected %v, got %v", expected, err)
+
+			var apiErr *Error
+			assert.True(t, errors.As(err, &apiErr))
+			assert.Equal(t, status, apiErr.StatusCode)
+			assert.Equal(t, "details", apiErr.Message)
+			assert.Equal(t, "/items/1", apiErr.Path)
+		})
+	}
+}
+
+func TestProblemDetailsAreDecoded(t *testing.T) {
+	c := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"type": "about:blank", "title": "Bad Request", "status": 400, "detail": "the request contains invalid fields",
+			"code": "validation_failed", "request_id": "trace-42", "errors": [{"field": "table_name", "message": "must be provided"}]}`))
+	})
+
+	_, err := c.CreateItem(context.Background(), ItemRequest{Name: "A", ExtensionID: 1})
+
+	assert.ErrorIs(t, err, ErrBadRequest)
+	var apiErr *Error
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "the request contains invalid fields", apiErr.Message)
+	assert.Equal(t, "validation_failed", apiErr.Code)
+	assert.Equal(t, "trace-42", apiErr.RequestID)
+	assert.Equal(t, []FieldError{{Field: "table_name", Message: "must be provided"}}, apiErr.Fields)
+	assert.Equal(t, "POST /items: 400 Bad Request: the request contains invalid fields; table_name must be provided", apiErr.Error())
+}
+
+func TestIdempotentRequestsAreRetried(t *testing.T) {
+	var calls atomic.Int32
+	c := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	err := c.DeleteItem(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestRetriesStopAfterMaximum(t *testing.T) {
+	var calls atomic.Int32
+	c := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	_, err := c.ListItems(context.Background())
+
+	assert.True(t, errors.Is(err, ErrServer))
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestCreateItemIsNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	c := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	_, err := c.CreateItem(context.Background(), ItemRequest{Name: "A", TableName: "a", ExtensionID: 1})
+
+	assert.True(t, errors.Is(err, ErrServer))
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestCreateItemWithIdempotencyKeysIsRetriedWithSameKey(t *testing.T) {
+	var calls atomic.Int32
+	keys := make(chan string, 2)
+	c := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		keys <- r.Header.Get("Idempotency-Key")
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"item": {"id": 7, "typecode": 20001}}`))
+	}, WithIdempotencyKeys())
+
+	item, err := c.CreateItem(context.Background(), ItemRequest{Name: "A", TableName: "a", ExtensionID: 1})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(20001), item.Typecode)
+	first, second := <-keys, <-keys
+	assert.NotEmpty(t, first)
+	assert.Equal(t, first, second)
+}
+
+func TestCanceledContextAbortsRetries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, WithRetries(5, time.Minute))
+
+	_, err := c.ListItems(ctx)
+
+	assert.True(t, errors.Is(err, context.Canceled), "got %v", err)
+}
+
+func TestExtensionProjectIDIsOptional(t *testing.T) {
+	c := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/extensions/shared", r.URL.Path)
+		_, _ = w.Write([]byte(`{"extensions": [{"id": 1, "project_id": null, "scope": "Shared"}, {"id": 2, "project_id": 4, "scope": "Project"}]}`))
+	})
+
+	extensions, err := c.ListExtensionsByScope(context.Background(), ScopeShared)
+
+	assert.NoError(t, err)
+	assert.Nil(t, extensions[0].ProjectID)
+	assert.Equal(t, int64(4), *extensions[1].ProjectID)
+}
+
+func TestListItemsPageSendsOptions(t *testing.T) {
+	c := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "extension_id=3&limit=20&page=2&scope=Project&sort=-typecode&typecode_min=14000", r.URL.RawQuery)
+		_, _ = w.Write([]byte(`{"items": [{"id": 1}], "metadata": {"current_page": 2, "page_size": 20, "first_page": 1, "last_page": 2, "total_records": 21}}`))
+	})
+
+	typecodeMin := int32(14000)
+	items, metadata, err := c.ListItemsPage(context.Background(), ItemListOptions{
+		Scope:       ScopeProject,
+		ExtensionID: 3,
+		TypecodeMin: &typecodeMin,
+		Sort:        "-typecode",
+		Page:        2,
+		Limit:       20,
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, items, 1)
+	assert.Equal(t, 21, metadata.TotalRecords)
+	assert.Equal(t, 2, metadata.LastPage)
+}
+
+func TestSearchSendsTermAndTypes(t *testing.T) {
+	c := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/search", r.URL.Path)
+		assert.Equal(t, "limit=5&q=prod&type=item%2Cproject", r.URL.RawQuery)
+		_, _ = w.Write([]byte(`{"hits": [{"type": "item", "id": 3, "name": "Product", "typecode": 14000, "rank": 0.75, "link": "/items/3"}]}`))
+	})
+
+	hits, err := c.Search(context.Background(), "prod", []string{SearchTypeItem, SearchTypeProject}, 5)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/items/3", hits[0].Link)
+	assert.Equal(t, int32(14000), *hits[0].Typecode)
+}
+
+func TestNestedResourcesUseResourcePaths(t *testing.T) {
+	c := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/projects/2/extensions":
+			_, _ = w.Write([]byte(`{"extensions": [{"id": 5, "project_id": 2, "name": "core"}]}`))
+		case "/projects/2/items":
+			assert.False(t, r.URL.Query().Has("project"))
+			_, _ = w.Write([]byte(`{"items": [{"id": 9, "extension_id": 5}, {"id": 10, "extension_id": 5}], "metadata": {"total_records": 2}}`))
+		case "/extensions/5/items":
+			assert.Equal(t, "typecode", r.URL.Query().Get("sort"))
+			_, _ = w.Write([]byte(`{"items": [{"id": 9, "extension_id": 5}], "metadata": {"total_records": 1}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	extensions, err := c.ListProjectExtensions(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "core", extensions[0].Name)
+
+	items, metadata, err := c.ListExtensionItems(context.Background(), 5, ItemListOptions{Sort: "typecode"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), items[0].ID)
+	assert.Equal(t, 1, metadata.TotalRecords)
+
+	items, _, err = c.ListProjectItems(context.Background(), 2, ItemListOptions{Project: "Other"})
+	assert.NoError(t, err)
+	assert.Len(t, items, 2)
+
+	_, err = c.GetExtension(context.Background(), 6)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestBatchSendsOperationsWithReferences(t *testing.T) {
+	c := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/batch", r.URL.Path)
+		var req struct {
+			Operations []map[string]any `json:"operations"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "$core.id", req.Operations[1]["body"].(map[string]any)["extension_id"])
+		_, _ = w.Write([]byte(`{"results": [{"index": 0, "ref": "core", "status": 201, "resource": {"id": 9}}, {"index": 1, "status": 201, "resource": {"id": 3, "typecode": 20001}}]}`))
+	})
+
+	results, err := c.Batch(context.Background(), []BatchOperation{
+		{Ref: "core", Action: BatchCreate, Type: BatchExtension, Body: ExtensionRequest{Name: "core", Scope: ScopeShared}},
+		{Action: BatchCreate, Type: BatchItem, Body: map[string]any{"name": "Product", "table_name": "products", "extension_id": "$core.id"}},
+	})
+
+	assert.NoError(t, err)
+	var item Item
+	assert.NoError(t, json.Unmarshal(results[1].Resource, &item))
+	assert.Equal(t, int32(20001), item.Typecode)
+}
@@ -0,0 +1,107 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors for the classes of HTTP errors returned by the API.
+// Use errors.Is to check an error returned by the client against them.
+var (
+	ErrBadRequest       = errors.New("bad request")
+	ErrUnauthorized     = errors.New("unauthorized")
+	ErrForbidden        = errors.New("forbidden")
+	ErrNotFound         = errors.New("not found")
+	ErrMethodNotAllowed = errors.New("method not allowed")
+	ErrConflict         = errors.New("conflict")
+	ErrTooManyRequests  = errors.New("too many requests")
+	ErrServer           = errors.New("server error")
+)
+
+// Error is returned for every response with a status code of 400 or above.
+// The server answers errors with problem details (RFC 7807), which are decoded into
+// Message, Code, RequestID and Fields. Other bodies, e.g. of a proxy, are kept as Message.
+type Error struct {
+	Method     string
+	Path       string
+	StatusCode int
+	// Message is the detail of the problem or the body of the response as sent by the server.
+	Message string
+	// Code identifies the error independent of the wording of Message, e.g. "validation_failed".
+	Code string
+	// RequestID identifies the request in the logs of the server.
+	RequestID string
+	// Fields are the invalid fields of the request body.
+	Fields []FieldError
+}
+
+// FieldError describes why the value of a single field of a request body is invalid.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func newError(method, path string, status int, contentType string, body []byte) *Error {
+	e := &Error{
+		Method:     method,
+		Path:       path,
+		StatusCode: status,
+		Message:    strings.TrimSpace(string(body)),
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "application/problem+json" {
+		var problem struct {
+			Detail    string       `json:"detail"`
+			Code      string       `json:"code"`
+			RequestID string       `json:"request_id"`
+			Errors    []FieldError `json:"errors"`
+		}
+		if err := json.Unmarshal(body, &problem); err == nil {
+			e.Message = problem.Detail
+			e.Code = problem.Code
+			e.RequestID = problem.RequestID
+			e.Fields = problem.Errors
+		}
+	}
+
+	return e
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%s %s: %d %s", e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	for _, field := range e.Fields {
+		msg += fmt.Sprintf("; %s %s", field.Field, field.Message)
+	}
+	return msg
+}
+
+// Is maps the status code of the error to the sentinel errors of this package.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrMethodNotAllowed:
+		return e.StatusCode == http.StatusMethodNotAllowed
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict || e.StatusCode == http.StatusPreconditionFailed
+	case ErrTooManyRequests:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= http.StatusInternalServerError
+	default:
+		return false
+	}
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Scopes of extensions.
+const (
+	ScopeShared  = "Shared"
+	ScopeHybris  = "Hybris"
+	ScopeProject = "Project"
+)
+
+// Extension groups items. Project scoped extensions belong to a project, all others have no ProjectID.
+type Extension struct {
+	ID           int64     `json:"id"`
+	ProjectID    *int64    `json:"project_id"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description"`
+	Scope        string    `json:"scope"`
+	CreationDate time.Time `json:"creation_date"`
+	Version      int32     `json:"version"`
+	ItemCount    int       `json:"item_count"`
+}
+
+// ExtensionRequest is the request to create a new extension. ProjectID is required for the Project scope.
+type ExtensionRequest struct {
+	Name        string `json:"name"`
+	Scope       string `json:"scope"`
+	Description string `json:"description,omitempty"`
+	ProjectID   int64  `json:"project_id,omitempty"`
+}
+
+// ExtensionUpdateRequest is the request to update an extension.
+// An empty name keeps the current one, the description is always replaced.
+type ExtensionUpdateRequest struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// ListExtensions returns all extensions.
+func (c *Client) ListExtensions(ctx context.Context) ([]Extension, error) {
+	return c.listExtensions(ctx, "/extensions")
+}
+
+// ListExtensionsByScope returns all extensions of the given scope.
+func (c *Client) ListExtensionsByScope(ctx context.Context, scope string) ([]Extension, error) {
+	return c.listExtensions(ctx, "/extensions/"+strings.ToLower(scope))
+}
+
+// GetExtension returns the extension with the given ID.
+func (c *Client) GetExtension(ctx context.Context, id int64) (*Extension, error) {
+	var resp struct {
+		Extension Extension `json:"extension"`
+	}
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/extensions/%d", id), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Extension, nil
+}
+
+// ListExtensionItems returns the items of the extension with the given ID matching opts.
+// The ExtensionID of opts is ignored.
+func (c *Client) ListExtensionItems(ctx context.Context, id int64, opts ItemListOptions) ([]Item, Metadata, error) {
+	var resp struct {
+		Items    []Item   `json:"items"`
+		Metadata Metadata `json:"metadata"`
+	}
+	opts.ExtensionID = 0
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/extensions/%d/items", id), opts.values(), nil, &resp)
+	return resp.Items, resp.Metadata, err
+}
+
+func (c *Client) listExtensions(ctx context.Context, path string) ([]Extension, error) {
+	var resp struct {
+		Extensions []Extension `json:"extensions"`
+	}
+	err := c.do(ctx, http.MethodGet, path, nil, nil, &resp)
+	return resp.Extensions, err
+}
+
+// CreateExtension creates a new extension and returns it.
+func (c *Client) CreateExtension(ctx context.Context, req ExtensionRequest) (*Extension, error) {
+	var resp struct {
+		Extension Extension `json:"extension"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/extensions", nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Extension, nil
+}
+
+// UpdateExtension updates name and description of the extension with the given ID.
+func (c *Client) UpdateExtension(ctx context.Context, id int64, req ExtensionUpdateRequest) error {
+	return c.do(ctx, http.MethodPut, fmt.Sprintf("/extensions/%d", id), nil, req, nil)
+}
+
+// DeleteExtension deletes the extension with the given ID together with all its items.
+func (c *Client) DeleteExtension(ctx context.Context, id int64) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/extensions/%d", id), nil, nil, nil)
+}
@@ -0,0 +1,152 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Item is a type registered in the registry together with its typecode.
+type Item struct {
+	ID           int64     `json:"id"`
+	Scope        string    `json:"scope"`
+	Project      string    `json:"project"`
+	Name         string    `json:"name"`
+	TableName    string    `json:"table_name"`
+	ExtensionID  int64     `json:"extension_id"`
+	Typecode     int32     `json:"typecode"`
+	CreationDate time.Time `json:"creation_date"`
+	Version      int32     `json:"version"`
+}
+
+// ItemRequest is the request to register a new type. The typecode is allocated by the server.
+type ItemRequest struct {
+	Name        string `json:"name"`
+	TableName   string `json:"table_name"`
+	ExtensionID int64  `json:"extension_id"`
+}
+
+// ItemUpdateRequest is the request to rename a type or change its table. Both values are required.
+type ItemUpdateRequest struct {
+	Name      string `json:"name"`
+	TableName string `json:"table_name"`
+}
+
+// ItemListOptions filters, sorts and paginates ListItemsPage. Zero values and nil pointers are not sent.
+type ItemListOptions struct {
+	Scope         string
+	Project       string
+	ExtensionID   int64
+	TypecodeMin   *int32
+	TypecodeMax   *int32
+	Name          string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// Sort is the field to sort by, e.g. "typecode". A leading "-" sorts in descending order.
+	Sort  string
+	Page  int
+	Limit int
+}
+
+// Metadata describes the page returned by a listing.
+type Metadata struct {
+	CurrentPage  int `json:"current_page,omitempty"`
+	PageSize     int `json:"page_size,omitempty"`
+	FirstPage    int `json:"first_page,omitempty"`
+	LastPage     int `json:"last_page,omitempty"`
+	TotalRecords int `json:"total_records"`
+}
+
+func (o ItemListOptions) values() url.Values {
+	q := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			q.Set(key, value)
+		}
+	}
+	set("scope", o.Scope)
+	set("project", o.Project)
+	set("name", o.Name)
+	set("sort", o.Sort)
+	if o.ExtensionID > 0 {
+		q.Set("extension_id", strconv.FormatInt(o.ExtensionID, 10))
+	}
+	if o.TypecodeMin != nil {
+		q.Set("typecode_min", strconv.FormatInt(int64(*o.TypecodeMin), 10))
+	}
+	if o.TypecodeMax != nil {
+		q.Set("typecode_max", strconv.FormatInt(int64(*o.TypecodeMax), 10))
+	}
+	if !o.CreatedAfter.IsZero() {
+		q.Set("created_after", o.CreatedAfter.Format(time.RFC3339))
+	}
+	if !o.CreatedBefore.IsZero() {
+		q.Set("created_before", o.CreatedBefore.Format(time.RFC3339))
+	}
+	if o.Page > 0 {
+		q.Set("page", strconv.Itoa(o.Page))
+	}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+	return q
+}
+
+// ListItemsPage returns the items matching opts together with the pagination metadata.
+func (c *Client) ListItemsPage(ctx context.Context, opts ItemListOptions) ([]Item, Metadata, error) {
+	var resp struct {
+		Items    []Item   `json:"items"`
+		Metadata Metadata `json:"metadata"`
+	}
+	err := c.do(ctx, http.MethodGet, "/items", opts.values(), nil, &resp)
+	return resp.Items, resp.Metadata, err
+}
+
+// ListItems returns all registered items.
+func (c *Client) ListItems(ctx context.Context) ([]Item, error) {
+	var resp struct {
+		Items []Item `json:"items"`
+	}
+	err := c.do(ctx, http.MethodGet, "/items", nil, nil, &resp)
+	return resp.Items, err
+}
+
+// GetItem returns the item with the given ID.
+func (c *Client) GetItem(ctx context.Context, id int64) (*Item, error) {
+	var resp struct {
+		Item Item `json:"item"`
+	}
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/items/%d", id), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Item, nil
+}
+
+// CreateItem registers a new item and returns it including the allocated typecode.
+// The request is only retried with WithIdempotencyKeys, since a repeated request would otherwise allocate a second typecode.
+func (c *Client) CreateItem(ctx context.Context, req ItemRequest) (*Item, error) {
+	var resp struct {
+		Item Item `json:"item"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/items", nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Item, nil
+}
+
+// UpdateItem changes name and table of the item with the given ID.
+func (c *Client) UpdateItem(ctx context.Context, id int64, req ItemUpdateRequest) error {
+	body := struct {
+		ID int64 `json:"id"`
+		ItemUpdateRequest
+	}{id, req}
+	return c.do(ctx, http.MethodPut, fmt.Sprintf("/items/%d", id), nil, body, nil)
+}
+
+// DeleteItem deletes the item with the given ID and frees its typecode.
+func (c *Client) DeleteItem(ctx context.Context, id int64) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/items/%d", id), nil, nil, nil)
+}
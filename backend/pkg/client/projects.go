@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Project groups project scoped extensions.
+type Project struct {
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description"`
+	CreationDate time.Time `json:"creation_date"`
+	Version      int32     `json:"version"`
+}
+
+// ProjectRequest is the request to create a new project.
+type ProjectRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// ProjectUpdateRequest is the request to update a project.
+// An empty name keeps the current one, the description is always replaced.
+type ProjectUpdateRequest struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// ListProjects returns all projects.
+func (c *Client) ListProjects(ctx context.Context) ([]Project, error) {
+	var resp struct {
+		Projects []Project `json:"projects"`
+	}
+	err := c.do(ctx, http.MethodGet, "/projects", nil, nil, &resp)
+	return resp.Projects, err
+}
+
+// GetProject returns the project with the given ID.
+func (c *Client) GetProject(ctx context.Context, id int64) (*Project, error) {
+	var resp struct {
+		Project Project `json:"project"`
+	}
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/projects/%d", id), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Project, nil
+}
+
+// ListProjectExtensions returns the extensions of the project with the given ID.
+func (c *Client) ListProjectExtensions(ctx context.Context, id int64) ([]Extension, error) {
+	return c.listExtensions(ctx, fmt.Sprintf("/projects/%d/extensions", id))
+}
+
+// ListProjectItems returns the items of the extensions of the project with the given ID matching opts.
+// The Project of opts is ignored.
+func (c *Client) ListProjectItems(ctx context.Context, id int64, opts ItemListOptions) ([]Item, Metadata, error) {
+	var resp struct {
+		Items    []Item   `json:"items"`
+		Metadata Metadata `json:"metadata"`
+	}
+	opts.Project = ""
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/projects/%d/items", id), opts.values(), nil, &resp)
+	return resp.Items, resp.Metadata, err
+}
+
+// CreateProject creates a new project and returns it.
+func (c *Client) CreateProject(ctx context.Context, req ProjectRequest) (*Project, error) {
+	var resp struct {
+		Project Project `json:"project"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/projects", nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Project, nil
+}
+
+// UpdateProject updates name and description of the project with the given ID.
+func (c *Client) UpdateProject(ctx context.Context, id int64, req ProjectUpdateRequest) error {
+	return c.do(ctx, http.MethodPut, fmt.Sprintf("/projects/%d", id), nil, req, nil)
+}
+
+// DeleteProject deletes the project with the given ID together with its extensions and items.
+func (c *Client) DeleteProject(ctx context.Context, id int64) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/projects/%d", id), nil, nil, nil)
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Types of search hits.
+const (
+	SearchTypeItem      = "item"
+	SearchTypeExtension = "extension"
+	SearchTypeProject   = "project"
+)
+
+// SearchHit is a single result of Search.
+type SearchHit struct {
+	Type string `json:"type"`
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// Detail is the table name of an item or the description of an extension or project.
+	Detail   string  `json:"detail,omitempty"`
+	Typecode *int32  `json:"typecode,omitempty"`
+	Rank     float64 `json:"rank"`
+	Link     string  `json:"link"`
+}
+
+// Search searches names, table names, descriptions and typecodes for term and returns the best hits first.
+// types restricts the search to the given hit types and limit the number of hits; zero values use the server defaults.
+func (c *Client) Search(ctx context.Context, term string, types []string, limit int) ([]SearchHit, error) {
+	q := url.Values{"q": {term}}
+	if len(types) > 0 {
+		q.Set("type", strings.Join(types, ","))
+	}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+
+	var resp struct {
+		Hits []SearchHit `json:"hits"`
+	}
+	err := c.do(ctx, http.MethodGet, "/search", q, nil, &resp)
+	return resp.Hits, err
+}